@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -16,18 +22,31 @@ type APIHandler struct {
 	todoStore TodoStore
 }
 
-var ErrTodoNotFound = errors.New("todo not found")
+var (
+	ErrTodoNotFound    = errors.New("todo not found")
+	ErrVersionConflict = errors.New("todo has been modified by someone else")
+)
 
 func NewTodoHandler(todoStore TodoStore) *APIHandler {
 	return &APIHandler{todoStore: todoStore}
 }
 
 // @Summary Get all todos
-// @Description Retrieve all todo items from the database
+// @Description Retrieve all todo items from the database. With any filter or limit the result is ordered by creation time and paged: pass X-Next-Cursor back as after to get the next page.
 // @Tags Todos
 // @Accept json
 // @Produce json
+// @Param done query bool false "Only done or only open todos"
+// @Param list query string false "List ID"
+// @Param tag query string false "Tag"
+// @Param q query string false "Search in title and description"
+// @Param due_before query string false "RFC3339 time"
+// @Param limit query int false "Page size, 1 to 100"
+// @Param after query string false "Cursor from X-Next-Cursor"
 // @Success 200 {array} Todo "OK"
+// @Header 200 {string} X-Next-Cursor "Cursor of the next page, absent on the last page"
+// @Header 200 {integer} X-Total-Count "Number of todos matching the filter"
+// @Failure 400 {object} ErrorResponse "Invalid query"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /todos [get]
 // Hàm xử lý lỗi trả về JSON hợp lệ
@@ -43,10 +62,183 @@ func (h *APIHandler) GetAllTodos(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if len(r.URL.Query()) > 0 {
+		filter, after, limit, err := parseTodoQuery(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if limit == 0 {
+			limit = len(todos)
+		}
+		page, total, hasNext := pageTodos(todos, filter, after, limit)
+		if hasNext {
+			w.Header().Set("X-Next-Cursor", encodeCursor(page[len(page)-1]))
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		todos = page
+	}
+
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(todos)
 }
 
+// todoFilter là bộ lọc todo của GET /todos, POST /todos/print và truy vấn
+// todos của GraphQL; trường nil là không lọc.
+type todoFilter struct {
+	Done      *bool
+	ListID    *string
+	Tag       *string
+	Search    *string
+	DueBefore *time.Time
+}
+
+func (f *todoFilter) matches(todo Todo) bool {
+	if f == nil {
+		return true
+	}
+	if f.Done != nil && todo.Done != *f.Done {
+		return false
+	}
+	if f.ListID != nil && todo.ListID != *f.ListID {
+		return false
+	}
+	if f.Tag != nil && !containsString(todo.Tags, strings.ToLower(*f.Tag)) {
+		return false
+	}
+	if f.Search != nil {
+		q := strings.ToLower(*f.Search)
+		if !strings.Contains(strings.ToLower(todo.Title), q) && !strings.Contains(strings.ToLower(todo.Desc), q) {
+			return false
+		}
+	}
+	if f.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*f.DueBefore)) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTodoQuery đọc bộ lọc và phân trang của GET /todos. limit = 0 nghĩa là
+// không giới hạn.
+func parseTodoQuery(q url.Values) (*todoFilter, *todoCursor, int, error) {
+	filter := &todoFilter{}
+	if v := q.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid done %q", v)
+		}
+		filter.Done = &done
+	}
+	if v := q.Get("list"); v != "" {
+		filter.ListID = &v
+	}
+	if v := q.Get("tag"); v != "" {
+		filter.Tag = &v
+	}
+	if v := q.Get("q"); v != "" {
+		filter.Search = &v
+	}
+	if v := q.Get("due_before"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid due_before %q", v)
+		}
+		filter.DueBefore = &t
+	}
+
+	limit := 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			return nil, nil, 0, fmt.Errorf("limit must be between 1 and 100")
+		}
+		limit = n
+	}
+
+	var after *todoCursor
+	if v := q.Get("after"); v != "" {
+		var err error
+		if after, err = decodeCursor(v); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	return filter, after, limit, nil
+}
+
+// todoCursor là vị trí của một todo theo thứ tự của pageTodos. Cursor giữ cả
+// created_at nên vẫn dùng được khi todo đó đã bị xóa, sửa hoặc không còn khớp
+// bộ lọc giữa hai trang.
+type todoCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func cursorOf(todo Todo) todoCursor {
+	return todoCursor{CreatedAt: todo.CreatedAt, ID: todo.ID}
+}
+
+// before cho biết c đứng trước todo theo thời gian tạo rồi ID.
+func (c todoCursor) before(todo Todo) bool {
+	if !c.CreatedAt.Equal(todo.CreatedAt) {
+		return c.CreatedAt.Before(todo.CreatedAt)
+	}
+	return c.ID < todo.ID
+}
+
+func encodeCursor(todo Todo) string {
+	return base64.StdEncoding.EncodeToString([]byte("todo:" + strconv.FormatInt(todo.CreatedAt.UnixNano(), 10) + ":" + todo.ID))
+}
+
+func decodeCursor(cursor string) (*todoCursor, error) {
+	b, err := base64.StdEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(b), "todo:") {
+		nanos, id, ok := strings.Cut(strings.TrimPrefix(string(b), "todo:"), ":")
+		if n, err := strconv.ParseInt(nanos, 10, 64); ok && err == nil {
+			return &todoCursor{CreatedAt: time.Unix(0, n), ID: id}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid cursor %q", cursor)
+}
+
+// pageTodos sắp xếp todos theo thời gian tạo rồi ID, lọc theo filter và lấy
+// tối đa first todo nằm sau after. Dùng chung cho GET /todos và GraphQL.
+func pageTodos(todos []Todo, filter *todoFilter, after *todoCursor, first int) (page []Todo, total int, hasNext bool) {
+	// Thứ tự ổn định để cursor có nghĩa giữa các trang.
+	sort.Slice(todos, func(i, j int) bool {
+		return cursorOf(todos[i]).before(todos[j])
+	})
+
+	matched := []Todo{}
+	for _, todo := range todos {
+		if filter.matches(todo) {
+			matched = append(matched, todo)
+		}
+	}
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool {
+			return after.before(matched[i])
+		})
+	}
+
+	end := start + first
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched), end < len(matched)
+}
+
 // @Summary Get a todo by ID
 // @Description Retrieve a todo item by its ID from the database
 // @Tags Todos
@@ -88,6 +280,7 @@ func (h *APIHandler) GetTodoByID(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param todo body Todo true "Todo to create"
 // @Success 201 {object} Todo "Created"
+// @Header 201 {integer} X-Operation-Id "Pass to POST /undo/{operationId}"
 // @Failure 400 {object} ErrorResponse "Invalid input"
 // @Failure 500 {object} ErrorResponse "Failed to create todo"
 // @Router /todo [post]
@@ -104,13 +297,20 @@ func (h *APIHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, op := WithOperation(ctx)
 	createdTodo, err := h.todoStore.CreateTodoDB(ctx, todo)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create todo: " + err.Error()})
+		if errors.Is(err, ErrInvalidRecurrence) || errors.Is(err, ErrInvalidReminder) || errors.Is(err, ErrInvalidPayment) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create todo: " + err.Error()})
+		}
 		return
 	}
 
+	setOperationHeader(w, op)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(createdTodo)
 }
@@ -123,8 +323,10 @@ func (h *APIHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 // @Param id path string true "Todo ID"
 // @Param todo body Todo true "Updated todo data"
 // @Success 200 {object} Todo "Updated"
+// @Header 200 {integer} X-Operation-Id "Pass to POST /undo/{operationId}"
 // @Failure 400 {object} ErrorResponse "Invalid input"
 // @Failure 404 {object} ErrorResponse "Todo not found"
+// @Failure 409 {object} ErrorResponse "Version does not match the current revision"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /todo/{id} [put]
 func (h *APIHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
@@ -144,11 +346,18 @@ func (h *APIHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, op := WithOperation(ctx)
 	updatedTodo, err := h.todoStore.UpdateTodoDB(ctx, idStr, todo)
 	if err != nil {
 		if errors.Is(err, ErrTodoNotFound) {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{"error": "todo not found"})
+		} else if errors.Is(err, ErrVersionConflict) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		} else if errors.Is(err, ErrInvalidRecurrence) || errors.Is(err, ErrInvalidReminder) || errors.Is(err, ErrInvalidPayment) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Internal Server Error"})
@@ -157,17 +366,20 @@ func (h *APIHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the updated Todo as the response
+	setOperationHeader(w, op)
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedTodo)
 }
 
 // @Summary Delete a todo by ID
-// @Description Delete a todo item from the database by ID
+// @Description Move a todo item to the trash by ID, or remove it for good with permanent=true
 // @Tags Todos
 // @Accept json
 // @Produce json
 // @Param id path string true "Todo ID"
+// @Param permanent query bool false "Skip the trash and delete permanently"
 // @Success 204 "No Content"
+// @Header 204 {integer} X-Operation-Id "Pass to POST /undo/{operationId}"
 // @Failure 404 {object} ErrorResponse "Todo not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /todo/{id} [delete]
@@ -178,6 +390,7 @@ func (h *APIHandler) DeleteTodoByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
+	ctx, op := WithOperation(ctx)
 	err := h.todoStore.DeleteTodoByIdDB(ctx, idStr)
 	if err != nil {
 		if errors.Is(err, ErrTodoNotFound) {
@@ -190,6 +403,7 @@ func (h *APIHandler) DeleteTodoByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setOperationHeader(w, op)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -200,6 +414,7 @@ func (h *APIHandler) DeleteTodoByID(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Todo ID"
 // @Success 200 {object} StatusResponse "Status changed successfully"
+// @Header 200 {integer} X-Operation-Id "Pass to POST /undo/{operationId}"
 // @Failure 404 {object} ErrorResponse "Todo not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /todo/changeStatus/{id} [post]
@@ -210,6 +425,7 @@ func (h *APIHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
+	ctx, op := WithOperation(ctx)
 	err := h.todoStore.ChangeStatusDB(ctx, idStr)
 	if err != nil {
 		if errors.Is(err, ErrTodoNotFound) {
@@ -221,6 +437,7 @@ func (h *APIHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	setOperationHeader(w, op)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"status":"success"}`))
 }
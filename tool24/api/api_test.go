@@ -58,6 +58,29 @@ func (m *MockTodoStore) ChangeStatusDB(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+// versionedStore đóng vai transaction của ChangeStatusDB: kiểm tra version
+// trong context với version hiện tại của todo, và trả written qua Operation
+// như todo mà transaction vừa ghi.
+type versionedStore struct {
+	*MockTodoStore
+	version int
+	written Todo
+}
+
+func (s versionedStore) ChangeStatusDB(ctx context.Context, id string) error {
+	if err := checkVersion(Todo{ID: id, Version: s.version}, expectedVersionFromContext(ctx)); err != nil {
+		return err
+	}
+	if err := s.MockTodoStore.ChangeStatusDB(ctx, id); err != nil {
+		return err
+	}
+	if op := operationFromContext(ctx); op != nil {
+		written := s.written
+		op.Todo = &written
+	}
+	return nil
+}
+
 func (m *MockTodoStore) Connect(ctx context.Context, connStr string) (*pgxpool.Pool, error) {
 	args := m.Called(ctx, connStr)
 	return nil, args.Error(1)
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// Các loại thao tác được ghi vào todo_audit.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditStatus  = "status"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+	AuditRevert  = "revert"
+	AuditUndo    = "undo"
+)
+
+// ActorHeader is the request header that names who is making a change.
+const ActorHeader = "X-Actor"
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that attributes store changes to actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or "anonymous".
+func ActorFromContext(ctx context.Context) string {
+	if ctx != nil {
+		if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+			return actor
+		}
+	}
+	return "anonymous"
+}
+
+// ActorMiddleware copies the X-Actor header into the request context.
+func ActorMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if actor := strings.TrimSpace(r.Header.Get(ActorHeader)); actor != "" {
+			r = r.WithContext(WithActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	TodoID    string                 `json:"todo_id"`
+	Revision  int                    `json:"revision"`
+	Actor     string                 `json:"actor"`
+	Operation string                 `json:"operation"`
+	CreatedAt time.Time              `json:"created_at"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Snapshot  *Todo                  `json:"snapshot,omitempty"`
+}
+
+type AuditFilter struct {
+	TodoID    string
+	Actor     string
+	Operation string
+	Since     *time.Time
+	Until     *time.Time
+	Limit     int
+}
+
+type AuditStore interface {
+	GetTodoHistoryDB(ctx context.Context, id string) ([]AuditEntry, error)
+	ListAuditDB(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
+
+// todoFields trả về các trường của todo được theo dõi trong audit, theo tên JSON.
+func todoFields(todo *Todo) map[string]interface{} {
+	if todo == nil {
+		return map[string]interface{}{}
+	}
+	return map[string]interface{}{
+		"title":       todo.Title,
+		"description": todo.Desc,
+		"done":        todo.Done,
+		"done_at":     todo.DoneAt,
+		"deleted_at":  todo.DeletedAt,
+		"due_at":      todo.DueAt,
+		"recurrence":  todo.Recurrence,
+		"remind_at":   todo.RemindAt,
+		"list_id":     todo.ListID,
+		"tags":        todo.Tags,
+		"payment":     todo.Payment,
+	}
+}
+
+// diffTodos returns the fields that differ between before and after. A nil
+// before (create) or after (purge) reports every field.
+func diffTodos(before, after *Todo) map[string]FieldChange {
+	b, a := todoFields(before), todoFields(after)
+	changes := map[string]FieldChange{}
+	for _, field := range []string{"title", "description", "done", "done_at", "deleted_at", "due_at", "recurrence", "remind_at", "list_id", "tags", "payment"} {
+		bv, bok := b[field]
+		av, aok := a[field]
+		bj, _ := json.Marshal(bv)
+		aj, _ := json.Marshal(av)
+		if bok && aok && string(bj) == string(aj) {
+			continue
+		}
+		changes[field] = FieldChange{Before: bv, After: av}
+	}
+	return changes
+}
+
+// writeAudit ghi một bản ghi audit trong cùng transaction với thay đổi và trả về ID của nó.
+// Bản ghi lưu ảnh chụp đầy đủ của after để có thể revert về revision đó.
+func writeAudit(ctx context.Context, tx pgx.Tx, operation string, before, after *Todo) (int64, error) {
+	var todoID string
+	var revision int
+	var snapshot []byte
+	if after != nil {
+		todoID, revision = after.ID, after.Version
+		b, err := json.Marshal(after)
+		if err != nil {
+			return 0, err
+		}
+		snapshot = b
+	} else if before != nil {
+		todoID, revision = before.ID, before.Version
+	}
+
+	changes, err := json.Marshal(diffTodos(before, after))
+	if err != nil {
+		return 0, err
+	}
+
+	var id int64
+	err = tx.QueryRow(ctx,
+		"INSERT INTO todo_audit (todo_id, revision, actor, operation, changes, snapshot) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		todoID, revision, ActorFromContext(ctx), operation, changes, snapshot).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write audit: %v", err)
+	}
+
+	// Một thao tác có thể ghi nhiều bản ghi (vd hoàn thành todo lặp lại còn tạo
+	// lần lặp kế tiếp); bản ghi đầu tiên là thay đổi client yêu cầu nên X-Operation-Id
+	// và todo trả về cho client phải lấy từ nó.
+	if op := operationFromContext(ctx); op != nil && op.ID == 0 {
+		op.ID = id
+		if after != nil {
+			written := *after
+			op.Todo = &written
+		}
+	}
+
+	return id, nil
+}
+
+const auditColumns = "id, todo_id, revision, actor, operation, created_at, changes, snapshot"
+
+func scanAuditEntries(rows pgx.Rows) ([]AuditEntry, error) {
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var changes, snapshot []byte
+		if err := rows.Scan(&entry.ID, &entry.TodoID, &entry.Revision, &entry.Actor, &entry.Operation, &entry.CreatedAt, &changes, &snapshot); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			if err := json.Unmarshal(snapshot, &entry.Snapshot); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+func (db *Db) GetTodoHistoryDB(ctx context.Context, id string) ([]AuditEntry, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT "+auditColumns+" FROM todo_audit WHERE todo_id = $1 ORDER BY id", id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve history: %v", err)
+	}
+
+	entries, err := scanAuditEntries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve history: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no history for todo with ID %s: %w", id, ErrTodoNotFound)
+	}
+
+	return entries, nil
+}
+
+func (db *Db) ListAuditDB(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.TodoID != "" {
+		add("todo_id = $%d", filter.TodoID)
+	}
+	if filter.Actor != "" {
+		add("actor = $%d", filter.Actor)
+	}
+	if filter.Operation != "" {
+		add("operation = $%d", filter.Operation)
+	}
+	if filter.Since != nil {
+		add("created_at >= $%d", *filter.Since)
+	}
+	if filter.Until != nil {
+		add("created_at < $%d", *filter.Until)
+	}
+
+	query := "SELECT " + auditColumns + " FROM todo_audit"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 100
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := db.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve audit log: %v", err)
+	}
+
+	return scanAuditEntries(rows)
+}
+
+type AuditHandler struct {
+	auditStore AuditStore
+}
+
+func NewAuditHandler(auditStore AuditStore) *AuditHandler {
+	return &AuditHandler{auditStore: auditStore}
+}
+
+// @Summary Get the change history of a todo
+// @Description Retrieve every audit record of a todo, oldest first
+// @Tags Audit
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {array} AuditEntry "OK"
+// @Failure 404 {object} ErrorResponse "Todo not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /todo/{id}/history [get]
+func (h *AuditHandler) GetTodoHistory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	entries, err := h.auditStore.GetTodoHistoryDB(ctx, idStr)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Todo not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get history: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+// @Summary Query the audit log
+// @Description Retrieve audit records across all todos, newest first
+// @Tags Audit
+// @Produce json
+// @Param todo_id query string false "Only records for this todo"
+// @Param actor query string false "Only records made by this actor"
+// @Param operation query string false "create, update, status, delete, restore, purge, revert or undo"
+// @Param since query string false "RFC 3339 lower bound (inclusive)"
+// @Param until query string false "RFC 3339 upper bound (exclusive)"
+// @Param limit query int false "Maximum number of records (default 100, max 1000)"
+// @Success 200 {array} AuditEntry "OK"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /audit [get]
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.auditStore.ListAuditDB(ctx, filter)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get audit log: " + err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func parseAuditFilter(r *http.Request) (AuditFilter, error) {
+	q := r.URL.Query()
+	filter := AuditFilter{
+		TodoID:    q.Get("todo_id"),
+		Actor:     q.Get("actor"),
+		Operation: q.Get("operation"),
+		Limit:     100,
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return AuditFilter{}, fmt.Errorf("invalid %s: must be RFC 3339", name)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return AuditFilter{}, fmt.Errorf("invalid limit: must be a positive integer")
+		}
+		if limit > 1000 {
+			limit = 1000
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
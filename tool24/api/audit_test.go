@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditStore struct {
+	mock.Mock
+}
+
+func (m *MockAuditStore) GetTodoHistoryDB(ctx context.Context, id string) ([]AuditEntry, error) {
+	args := m.Called(id)
+	return args.Get(0).([]AuditEntry), args.Error(1)
+}
+
+func (m *MockAuditStore) ListAuditDB(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	args := m.Called(filter)
+	return args.Get(0).([]AuditEntry), args.Error(1)
+}
+
+func newAuditRouter(auditStore AuditStore) *mux.Router {
+	ah := NewAuditHandler(auditStore)
+	router := mux.NewRouter()
+	router.Use(ActorMiddleware)
+	router.HandleFunc("/todo/{id}/history", ah.GetTodoHistory).Methods("GET")
+	router.HandleFunc("/audit", ah.ListAudit).Methods("GET")
+	return router
+}
+
+func TestDiffTodos(t *testing.T) {
+	before := &Todo{ID: "1", Title: "Old", Desc: "Same"}
+	after := &Todo{ID: "1", Title: "New", Desc: "Same"}
+
+	changes := diffTodos(before, after)
+
+	assert.Equal(t, map[string]FieldChange{"title": {Before: "Old", After: "New"}}, changes)
+	assert.Len(t, diffTodos(nil, after), 11, "create should record every field")
+
+	// Chỉ đổi payment vẫn phải có trong diff.
+	payment := &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000}
+	changes = diffTodos(before, &Todo{ID: "1", Title: "Old", Desc: "Same", Payment: payment})
+	assert.Equal(t, map[string]FieldChange{"payment": {Before: (*TodoPayment)(nil), After: payment}}, changes)
+	changed := *payment
+	changed.Amount = 70000
+	changes = diffTodos(&Todo{Payment: payment}, &Todo{Payment: &changed})
+	assert.Equal(t, map[string]FieldChange{"payment": {Before: payment, After: &changed}}, changes)
+}
+
+func TestActorMiddleware(t *testing.T) {
+	var actor string
+	handler := ActorMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = ActorFromContext(r.Context())
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "anonymous", actor)
+
+	req.Header.Set(ActorHeader, "alice")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "alice", actor)
+}
+
+func TestGetTodoHistory(t *testing.T) {
+	mockAudit := new(MockAuditStore)
+	mockAudit.On("GetTodoHistoryDB", "1").Return([]AuditEntry{
+		{ID: 1, TodoID: "1", Actor: "alice", Operation: AuditCreate},
+		{ID: 2, TodoID: "1", Actor: "bob", Operation: AuditStatus, Changes: map[string]FieldChange{"done": {Before: false, After: true}}},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/todo/1/history", nil)
+	rr := httptest.NewRecorder()
+	newAuditRouter(mockAudit).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	var entries []AuditEntry
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &entries))
+	assert.Len(t, entries, 2)
+	mockAudit.AssertExpectations(t)
+}
+
+func TestGetTodoHistory_NotFound(t *testing.T) {
+	mockAudit := new(MockAuditStore)
+	mockAudit.On("GetTodoHistoryDB", "999").Return([]AuditEntry(nil), ErrTodoNotFound)
+
+	req, _ := http.NewRequest("GET", "/todo/999/history", nil)
+	rr := httptest.NewRecorder()
+	newAuditRouter(mockAudit).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status code 404")
+	mockAudit.AssertExpectations(t)
+}
+
+func TestListAudit_Filters(t *testing.T) {
+	since := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	mockAudit := new(MockAuditStore)
+	mockAudit.On("ListAuditDB", AuditFilter{Actor: "alice", Operation: AuditDelete, Since: &since, Limit: 10}).Return([]AuditEntry{}, nil)
+
+	req, _ := http.NewRequest("GET", "/audit?actor=alice&operation=delete&since=2024-11-01T00:00:00Z&limit=10", nil)
+	rr := httptest.NewRecorder()
+	newAuditRouter(mockAudit).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	mockAudit.AssertExpectations(t)
+}
+
+func TestListAudit_BadFilter(t *testing.T) {
+	mockAudit := new(MockAuditStore)
+
+	req, _ := http.NewRequest("GET", "/audit?since=yesterday", nil)
+	rr := httptest.NewRecorder()
+	newAuditRouter(mockAudit).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status code 400")
+	mockAudit.AssertNotCalled(t, "ListAuditDB", mock.Anything)
+}
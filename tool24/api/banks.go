@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"api/vietqr"
+)
+
+// BankListResponse là danh bạ ngân hàng (hoặc kết quả tìm kiếm) kèm phiên bản
+// danh bạ đang dùng.
+type BankListResponse struct {
+	Version string        `json:"version"`
+	Banks   []vietqr.Bank `json:"banks"`
+}
+
+type BankHandler struct {
+	banks *vietqr.Registry
+}
+
+func NewBankHandler(banks *vietqr.Registry) *BankHandler {
+	return &BankHandler{banks: banks}
+}
+
+// @Summary List or search banks
+// @Description List the NAPAS banks VietQR payments can be sent to. q searches code, short name, full name, BIN and SWIFT code, ignoring case and Vietnamese diacritics and tolerating small typos; results are ordered best match first.
+// @Tags QR
+// @Produce json
+// @Param q query string false "Search text, e.g. ngoai thuong or vietcombank"
+// @Param limit query int false "Maximum number of banks to return"
+// @Success 200 {object} BankListResponse "OK"
+// @Failure 400 {object} ErrorResponse "Invalid limit"
+// @Router /banks [get]
+func (h *BankHandler) ListBanks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+
+	dir := h.banks.Directory()
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(BankListResponse{Version: dir.Version, Banks: dir.Search(r.URL.Query().Get("q"), limit)})
+}
+
+// @Summary Look up a bank by BIN
+// @Tags QR
+// @Produce json
+// @Param bin path string true "6-digit NAPAS BIN"
+// @Success 200 {object} vietqr.Bank "OK"
+// @Failure 404 {object} ErrorResponse "Unknown BIN"
+// @Router /banks/{bin} [get]
+func (h *BankHandler) GetBank(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	bank, ok := h.banks.Directory().Lookup(mux.Vars(r)["bin"])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bank not found"})
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bank)
+}
+
+// RunBankDirectoryReloader loads the bank directory from the JSON file at
+// path whenever its modification time changes, checking every interval until
+// ctx is cancelled. An invalid file is logged and the previous directory is
+// kept.
+func RunBankDirectoryReloader(ctx context.Context, banks *vietqr.Registry, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var loaded time.Time
+	for {
+		if info, err := os.Stat(path); err != nil {
+			log.Printf("Không đọc được danh bạ ngân hàng %s: %v", path, err)
+		} else if !info.ModTime().Equal(loaded) {
+			if dir, err := vietqr.LoadDirectoryFile(path); err != nil {
+				log.Printf("Danh bạ ngân hàng %s không hợp lệ, giữ bản đang dùng: %v", path, err)
+			} else {
+				banks.Set(dir)
+				log.Printf("Đã nạp danh bạ ngân hàng phiên bản %s (%d ngân hàng) từ %s", dir.Version, len(dir.Banks), path)
+			}
+			// Ghi nhận cả file lỗi để không log lại mỗi lần kiểm tra cho tới khi file được sửa.
+			loaded = info.ModTime()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"api/vietqr"
+)
+
+func newBankRouter(banks *vietqr.Registry) *mux.Router {
+	h := NewBankHandler(banks)
+	router := mux.NewRouter()
+	router.HandleFunc("/banks", h.ListBanks).Methods("GET")
+	router.HandleFunc("/banks/{bin}", h.GetBank).Methods("GET")
+	return router
+}
+
+func TestListBanks(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/banks?q=Ngo%E1%BA%A1i+th%C6%B0%C6%A1ng", nil)
+	rr := httptest.NewRecorder()
+	newBankRouter(vietqr.NewRegistry(vietqr.DefaultDirectory())).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp BankListResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, vietqr.DefaultDirectory().Version, resp.Version)
+	if assert.Len(t, resp.Banks, 1) {
+		assert.Equal(t, "970436", resp.Banks[0].BIN)
+	}
+}
+
+func TestListBanks_Limit(t *testing.T) {
+	router := newBankRouter(vietqr.NewRegistry(vietqr.DefaultDirectory()))
+
+	req, _ := http.NewRequest("GET", "/banks?limit=3", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp BankListResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Len(t, resp.Banks, 3)
+
+	for _, limit := range []string{"0", "-1", "abc"} {
+		req, _ := http.NewRequest("GET", "/banks?limit="+limit, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, limit)
+	}
+}
+
+func TestGetBank(t *testing.T) {
+	router := newBankRouter(vietqr.NewRegistry(vietqr.DefaultDirectory()))
+
+	req, _ := http.NewRequest("GET", "/banks/970415", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"short_name":"VietinBank"`)
+
+	req, _ = http.NewRequest("GET", "/banks/000000", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestRunBankDirectoryReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banks.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":"test-1","banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương"}]}`), 0o644))
+	banks := vietqr.NewRegistry(vietqr.DefaultDirectory())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunBankDirectoryReloader(ctx, banks, path, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool { return banks.Directory().Version == "test-1" }, time.Second, 5*time.Millisecond)
+
+	// File hỏng không thay thế danh bạ đang dùng.
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":"test-2","banks":[]}`), 0o644))
+	assert.NoError(t, os.Chtimes(path, later, later))
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, "test-1", banks.Directory().Version)
+
+	later = later.Add(time.Minute)
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version":"test-3","banks":[{"bin":"970415","code":"ICB","short_name":"VietinBank","name":"Công thương"}]}`), 0o644))
+	assert.NoError(t, os.Chtimes(path, later, later))
+	assert.Eventually(t, func() bool { return banks.Directory().Version == "test-3" }, time.Second, 5*time.Millisecond)
+	_, ok := banks.Directory().Lookup("970415")
+	assert.True(t, ok)
+}
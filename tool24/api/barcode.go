@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"api/barcoderender"
+	"api/barcodescan"
+)
+
+// BarcodeRenderRequest là body của POST /barcode. GET /barcode nhận các trường
+// này làm tham số query.
+type BarcodeRenderRequest struct {
+	Data string `json:"data"`
+	// Type là code128 (mặc định), ean13 hoặc datamatrix.
+	Type string `json:"type,omitempty"`
+	// Format là png (mặc định) hoặc svg.
+	Format     string `json:"format,omitempty"`
+	ModuleSize *int   `json:"module_size,omitempty"`
+	// Height là chiều cao vạch tính bằng module; DataMatrix bỏ qua.
+	Height     *int   `json:"height,omitempty"`
+	QuietZone  *int   `json:"quiet_zone,omitempty"`
+	Foreground string `json:"foreground,omitempty"`
+	Background string `json:"background,omitempty"`
+}
+
+const maxBarcodeRenderBytes = 64 << 10
+
+// ScanRequest là body JSON của POST /scan.
+type ScanRequest struct {
+	// Code là nội dung đã quét: mã ngắn của todo (vd T000042) hoặc ID todo.
+	Code string `json:"code"`
+	// Action là open (mặc định, chỉ trả về todo) hoặc toggle (đổi trạng thái
+	// xong/chưa xong như POST /todo/changeStatus/{id}).
+	Action string `json:"action,omitempty"`
+}
+
+// ScanResponse là todo tìm được sau khi quét, đã đổi trạng thái nếu action là
+// toggle.
+type ScanResponse struct {
+	Action string `json:"action"`
+	Code   string `json:"code"`
+	Todo   Todo   `json:"todo"`
+}
+
+const (
+	ScanActionOpen   = "open"
+	ScanActionToggle = "toggle"
+)
+
+// shortCodePattern khớp mã ngắn do database cấp (xem migration
+// add_short_code_to_todo).
+var shortCodePattern = regexp.MustCompile(`^T[0-9]{6,}$`)
+
+var errNoScanCode = errors.New("code or image is required")
+
+type ScanStore interface {
+	GetTodoByShortCodeDB(ctx context.Context, code string) (Todo, error)
+}
+
+func (db *Db) GetTodoByShortCodeDB(ctx context.Context, code string) (Todo, error) {
+	var todo Todo
+	err := scanTodo(db.Conn.QueryRow(ctx, "SELECT "+todoColumns+" FROM todo WHERE short_code = $1 AND deleted_at IS NULL", code), &todo)
+	if err == pgx.ErrNoRows {
+		return Todo{}, fmt.Errorf("todo not found with short code %s: %w", code, ErrTodoNotFound)
+	}
+	if err != nil {
+		return Todo{}, fmt.Errorf("failed to retrieve todo: %v", err)
+	}
+	return todo, nil
+}
+
+type BarcodeHandler struct {
+	todoStore TodoStore
+	scanStore ScanStore
+}
+
+func NewBarcodeHandler(todoStore TodoStore, scanStore ScanStore) *BarcodeHandler {
+	return &BarcodeHandler{todoStore: todoStore, scanStore: scanStore}
+}
+
+// @Summary Render a barcode
+// @Description Render data as a Code128, EAN-13 or DataMatrix barcode in PNG or SVG. EAN-13 takes 12 digits, the check digit is added, or 13 digits with a valid check digit. GET takes the same fields as query parameters.
+// @Tags Barcode
+// @Produce png
+// @Produce image/svg+xml
+// @Param data query string true "Data to encode, e.g. a todo short_code"
+// @Param type query string false "code128 (default), ean13 or datamatrix"
+// @Param format query string false "png (default) or svg"
+// @Param module_size query int false "Module size in pixels (default 2, 8 for datamatrix)"
+// @Param height query int false "Bar height in modules (default 50, 70 for ean13); ignored by datamatrix"
+// @Param quiet_zone query int false "Quiet zone in modules (default 10, 11 for ean13, 2 for datamatrix)"
+// @Param foreground query string false "Foreground color, e.g. #000000"
+// @Param background query string false "Background color, e.g. #ffffff or #ffffff00 for transparent"
+// @Success 200 {file} file "Barcode image"
+// @Failure 400 {object} ErrorResponse "Invalid data or options"
+// @Router /barcode [get]
+// @Router /barcode [post]
+func (h *BarcodeHandler) RenderBarcode(w http.ResponseWriter, r *http.Request) {
+	var req BarcodeRenderRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBarcodeRenderBytes)).Decode(&req); err != nil {
+			barcodeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req = BarcodeRenderRequest{Data: q.Get("data"), Type: q.Get("type"), Format: q.Get("format"),
+			Foreground: q.Get("foreground"), Background: q.Get("background")}
+		for name, dst := range map[string]**int{"module_size": &req.ModuleSize, "height": &req.Height, "quiet_zone": &req.QuietZone} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					barcodeError(w, http.StatusBadRequest, "invalid "+name+" "+strconv.Quote(v))
+					return
+				}
+				*dst = &n
+			}
+		}
+	}
+
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		barcodeError(w, http.StatusBadRequest, "format must be png or svg")
+		return
+	}
+	sym, opts, err := req.options()
+	if err != nil {
+		barcodeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	code, err := barcoderender.New(sym, req.Data, opts)
+	if err != nil {
+		barcodeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Vẽ vào buffer trước để lỗi (nếu có) vẫn trả được 500 thay vì ảnh hỏng.
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf)
+	} else {
+		err = code.WritePNG(&buf)
+	}
+	if err != nil {
+		barcodeError(w, http.StatusInternalServerError, "Failed to render barcode: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (req BarcodeRenderRequest) options() (barcoderender.Symbology, barcoderender.Options, error) {
+	sym, err := barcoderender.ParseSymbology(req.Type)
+	if err != nil {
+		return sym, barcoderender.Options{}, err
+	}
+	opts := barcoderender.DefaultOptions(sym)
+	if req.ModuleSize != nil {
+		opts.ModuleSize = *req.ModuleSize
+	}
+	if req.Height != nil {
+		opts.Height = *req.Height
+	}
+	if req.QuietZone != nil {
+		opts.QuietZone = *req.QuietZone
+	}
+	if req.Foreground != "" {
+		if opts.Foreground, err = barcoderender.ParseColor(req.Foreground); err != nil {
+			return sym, opts, err
+		}
+	}
+	if req.Background != "" {
+		if opts.Background, err = barcoderender.ParseColor(req.Background); err != nil {
+			return sym, opts, err
+		}
+	}
+	return sym, opts, nil
+}
+
+// @Summary Decode a barcode image
+// @Description Read the first Code128, EAN-13, DataMatrix or QR code in an uploaded image, sent as multipart field image or as a raw image body. The image should be a clean scan or screenshot; it may be rotated by a multiple of 90°.
+// @Tags Barcode
+// @Accept mpfd
+// @Accept png
+// @Accept jpeg
+// @Produce json
+// @Param image formData file true "PNG, JPEG or GIF image of the barcode"
+// @Success 200 {object} barcodescan.Result "OK"
+// @Failure 400 {object} ErrorResponse "Invalid image"
+// @Failure 422 {object} ErrorResponse "No barcode found in the image"
+// @Router /barcode/decode [post]
+func (h *BarcodeHandler) DecodeBarcode(w http.ResponseWriter, r *http.Request) {
+	img, ok := readScanImage(w, r)
+	if !ok {
+		return
+	}
+	if img == nil {
+		barcodeError(w, http.StatusBadRequest, "image is required")
+		return
+	}
+	res, ok := scanBarcodeImage(w, img)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+// @Summary Scan a todo barcode
+// @Description Find the todo whose short_code (or ID) was scanned and, with action toggle, flip its done status. The code is sent as JSON, or as an image (multipart field image, or a raw image body) to decode like POST /barcode/decode; with an image, action is a form field or query parameter. Built for checklists printed from GET /todos/print.
+// @Tags Barcode
+// @Accept json
+// @Accept mpfd
+// @Accept png
+// @Accept jpeg
+// @Produce json
+// @Param request body ScanRequest false "Scanned code and action"
+// @Param image formData file false "PNG, JPEG or GIF image of the barcode"
+// @Param action query string false "open (default) or toggle, for image uploads"
+// @Success 200 {object} ScanResponse "OK"
+// @Header 200 {integer} X-Operation-Id "With action toggle: pass to POST /undo/{operationId}"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "No todo has this code"
+// @Failure 422 {object} ErrorResponse "No barcode found in the image"
+// @Router /scan [post]
+func (h *BarcodeHandler) Scan(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var req ScanRequest
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" || strings.HasPrefix(mediaType, "image/") {
+		img, ok := readScanImage(w, r)
+		if !ok {
+			return
+		}
+		req = ScanRequest{Code: r.FormValue("code"), Action: r.FormValue("action")}
+		if img != nil {
+			res, ok := scanBarcodeImage(w, img)
+			if !ok {
+				return
+			}
+			req.Code = res.Text
+		}
+	} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBarcodeRenderBytes)).Decode(&req); err != nil {
+		barcodeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	req.Code = strings.TrimSpace(req.Code)
+	action := strings.ToLower(strings.TrimSpace(req.Action))
+	if action == "" {
+		action = ScanActionOpen
+	}
+	if action != ScanActionOpen && action != ScanActionToggle {
+		barcodeError(w, http.StatusBadRequest, "action must be open or toggle")
+		return
+	}
+	if req.Code == "" {
+		barcodeError(w, http.StatusBadRequest, errNoScanCode.Error())
+		return
+	}
+
+	todo, err := h.findScanned(ctx, req.Code)
+	if err == nil && action == ScanActionToggle {
+		var op *Operation
+		ctx, op = WithOperation(ctx)
+		// Trả todo do chính lần đảo trạng thái ghi, không đọc lại.
+		if err = h.todoStore.ChangeStatusDB(ctx, todo.ID); err == nil {
+			setOperationHeader(w, op)
+			todo = *op.Todo
+		}
+	}
+	if errors.Is(err, ErrTodoNotFound) {
+		barcodeError(w, http.StatusNotFound, "No todo with code "+strconv.Quote(req.Code))
+		return
+	}
+	if err != nil {
+		barcodeError(w, http.StatusInternalServerError, "Failed to scan todo: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ScanResponse{Action: action, Code: req.Code, Todo: todo})
+}
+
+// findScanned tìm todo theo mã ngắn (không phân biệt hoa thường, vì máy quét
+// có thể bật Caps Lock) hoặc theo ID.
+func (h *BarcodeHandler) findScanned(ctx context.Context, code string) (Todo, error) {
+	if upper := strings.ToUpper(code); shortCodePattern.MatchString(upper) {
+		return h.scanStore.GetTodoByShortCodeDB(ctx, upper)
+	}
+	return h.todoStore.GetTodoByIdDB(ctx, code)
+}
+
+// readScanImage đọc ảnh từ trường multipart image hoặc từ body image/*; trả
+// về ảnh nil khi request multipart không có ảnh. Khi thất bại nó đã ghi
+// response lỗi và trả về false.
+func readScanImage(w http.ResponseWriter, r *http.Request) (image.Image, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxQRDecodeBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(maxQRDecodeBytes); err != nil {
+			barcodeError(w, http.StatusBadRequest, "Invalid multipart body")
+			return nil, false
+		}
+		file, _, err := r.FormFile("image")
+		if errors.Is(err, http.ErrMissingFile) {
+			return nil, true
+		}
+		if err != nil {
+			barcodeError(w, http.StatusBadRequest, "Invalid multipart body")
+			return nil, false
+		}
+		defer file.Close()
+		return readUploadedImage(w, file)
+	case strings.HasPrefix(mediaType, "image/"):
+		return readUploadedImage(w, r.Body)
+	}
+	barcodeError(w, http.StatusBadRequest, "body must be multipart/form-data or an image")
+	return nil, false
+}
+
+// scanBarcodeImage đọc mã trong ảnh; khi không thấy mã nó đã ghi 422 và trả
+// về false.
+func scanBarcodeImage(w http.ResponseWriter, img image.Image) (barcodescan.Result, bool) {
+	res, err := barcodescan.Scan(img)
+	if err != nil {
+		barcodeError(w, http.StatusUnprocessableEntity, "No barcode found in the image")
+		return res, false
+	}
+	return res, true
+}
+
+func barcodeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
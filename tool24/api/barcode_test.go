@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"api/barcoderender"
+	"api/barcodescan"
+)
+
+type MockScanStore struct {
+	mock.Mock
+}
+
+func (m *MockScanStore) GetTodoByShortCodeDB(ctx context.Context, code string) (Todo, error) {
+	args := m.Called(code)
+	return args.Get(0).(Todo), args.Error(1)
+}
+
+func newBarcodeRouter(todoStore TodoStore, scanStore ScanStore) *mux.Router {
+	h := NewBarcodeHandler(todoStore, scanStore)
+	router := mux.NewRouter()
+	router.HandleFunc("/barcode", h.RenderBarcode).Methods("GET", "POST")
+	router.HandleFunc("/barcode/decode", h.DecodeBarcode).Methods("POST")
+	router.HandleFunc("/scan", h.Scan).Methods("POST")
+	return router
+}
+
+func barcodePNG(t *testing.T, sym barcoderender.Symbology, data string) []byte {
+	t.Helper()
+	code, err := barcoderender.New(sym, data, barcoderender.DefaultOptions(sym))
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, code.WritePNG(&buf))
+	return buf.Bytes()
+}
+
+func multipartImage(fields map[string]string, img []byte) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	if img != nil {
+		part, _ := form.CreateFormFile("image", "code.png")
+		part.Write(img)
+	}
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestRenderBarcode(t *testing.T) {
+	router := newBarcodeRouter(new(MockTodoStore), new(MockScanStore))
+	for _, tc := range []struct {
+		method, target, body string
+		want                 barcodescan.Result
+	}{
+		{"GET", "/barcode?data=T000042", "", barcodescan.Result{Format: "code128", Text: "T000042"}},
+		{"GET", "/barcode?data=T000042&type=datamatrix&module_size=4", "", barcodescan.Result{Format: "datamatrix", Text: "T000042"}},
+		{"POST", "/barcode", `{"data":"893452100001","type":"ean13","height":40}`, barcodescan.Result{Format: "ean13", Text: "8934521000015"}},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, tc.target)
+		assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+		img, err := png.Decode(rr.Body)
+		assert.NoError(t, err)
+		res, err := barcodescan.Scan(img)
+		assert.NoError(t, err, tc.target)
+		assert.Equal(t, tc.want, res, tc.target)
+	}
+}
+
+func TestRenderBarcode_SVG(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/barcode?data=T000042&format=svg&foreground=%231a237e&background=%23ffffff00", nil)
+	rr := httptest.NewRecorder()
+	newBarcodeRouter(new(MockTodoStore), new(MockScanStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), `<path fill="#1a237e" d="M10 10h2v50h-2z`)
+	assert.NotContains(t, rr.Body.String(), "<rect")
+}
+
+func TestRenderBarcode_Invalid(t *testing.T) {
+	router := newBarcodeRouter(new(MockTodoStore), new(MockScanStore))
+	for _, target := range []string{
+		"/barcode",
+		"/barcode?data=x&type=pdf417",
+		"/barcode?data=x&format=gif",
+		"/barcode?data=x&module_size=big",
+		"/barcode?data=x&module_size=0",
+		"/barcode?data=x&height=-1",
+		"/barcode?data=x&foreground=red",
+		"/barcode?data=12345&type=ean13",
+		"/barcode?data=Ki%E1%BB%83m&type=code128",
+	} {
+		req, _ := http.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), target)
+	}
+}
+
+func TestDecodeBarcode(t *testing.T) {
+	router := newBarcodeRouter(new(MockTodoStore), new(MockScanStore))
+
+	body, contentType := multipartImage(nil, barcodePNG(t, barcoderender.DataMatrix, "Kệ A-12"))
+	req, _ := http.NewRequest("POST", "/barcode/decode", body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"format":"datamatrix","text":"Kệ A-12"}`, rr.Body.String())
+
+	req, _ = http.NewRequest("POST", "/barcode/decode", bytes.NewReader(barcodePNG(t, barcoderender.Code128, "T000042")))
+	req.Header.Set("Content-Type", "image/png")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"format":"code128","text":"T000042"}`, rr.Body.String())
+}
+
+func TestDecodeBarcode_Invalid(t *testing.T) {
+	var blank bytes.Buffer
+	png.Encode(&blank, image.NewGray(image.Rect(0, 0, 100, 100)))
+	empty, emptyType := multipartImage(map[string]string{"code": "T000042"}, nil)
+
+	for _, tc := range []struct {
+		contentType string
+		body        *bytes.Buffer
+		code        int
+	}{
+		{"image/png", &blank, http.StatusUnprocessableEntity},
+		{"image/png", bytes.NewBufferString("not an image"), http.StatusBadRequest},
+		{"application/json", bytes.NewBufferString(`{"code":"T000042"}`), http.StatusBadRequest},
+		{emptyType, empty, http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest("POST", "/barcode/decode", tc.body)
+		req.Header.Set("Content-Type", tc.contentType)
+		rr := httptest.NewRecorder()
+		newBarcodeRouter(new(MockTodoStore), new(MockScanStore)).ServeHTTP(rr, req)
+		assert.Equal(t, tc.code, rr.Code, tc.contentType)
+	}
+}
+
+func TestScan_Open(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	scanStore := new(MockScanStore)
+	scanStore.On("GetTodoByShortCodeDB", "T000042").Return(Todo{ID: "42", Title: "Kiểm kê kho", ShortCode: "T000042"}, nil)
+	todoStore.On("GetTodoByIdDB", "3f2b8c1e").Return(Todo{ID: "3f2b8c1e", Title: "By ID"}, nil)
+	router := newBarcodeRouter(todoStore, scanStore)
+
+	// Máy quét bật Caps Lock vẫn ra đúng todo.
+	for body, id := range map[string]string{`{"code":" t000042 "}`: "42", `{"code":"3f2b8c1e","action":"open"}`: "3f2b8c1e"} {
+		req, _ := http.NewRequest("POST", "/scan", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, body)
+		var resp ScanResponse
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, ScanActionOpen, resp.Action)
+		assert.Equal(t, id, resp.Todo.ID)
+	}
+	todoStore.AssertNotCalled(t, "ChangeStatusDB", mock.Anything)
+}
+
+func TestScan_ToggleFromImage(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	scanStore := new(MockScanStore)
+	scanStore.On("GetTodoByShortCodeDB", "T000042").Return(Todo{ID: "42", ShortCode: "T000042"}, nil)
+	todoStore.On("ChangeStatusDB", "42").Return(nil).Once()
+	store := versionedStore{MockTodoStore: todoStore, written: Todo{ID: "42", Done: true, ShortCode: "T000042"}}
+
+	body, contentType := multipartImage(map[string]string{"action": "toggle"}, barcodePNG(t, barcoderender.Code128, "T000042"))
+	req, _ := http.NewRequest("POST", "/scan", body)
+	req.Header.Set("Content-Type", contentType)
+	rr := httptest.NewRecorder()
+	newBarcodeRouter(store, scanStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp ScanResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, ScanResponse{Action: ScanActionToggle, Code: "T000042", Todo: Todo{ID: "42", Done: true, ShortCode: "T000042"}}, resp)
+	todoStore.AssertExpectations(t)
+	todoStore.AssertNotCalled(t, "GetTodoByIdDB", mock.Anything)
+}
+
+func TestScan_Errors(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	scanStore := new(MockScanStore)
+	scanStore.On("GetTodoByShortCodeDB", "T999999").Return(Todo{}, ErrTodoNotFound)
+	todoStore.On("GetTodoByIdDB", "nope").Return(Todo{}, ErrTodoNotFound)
+	router := newBarcodeRouter(todoStore, scanStore)
+
+	for body, code := range map[string]int{
+		`{"code":"T999999","action":"toggle"}`: http.StatusNotFound,
+		`{"code":"nope"}`:                      http.StatusNotFound,
+		`{"code":"T000042","action":"delete"}`: http.StatusBadRequest,
+		`{"code":"  "}`:                        http.StatusBadRequest,
+		`not json`:                             http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest("POST", "/scan", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, body)
+	}
+	todoStore.AssertNotCalled(t, "ChangeStatusDB", mock.Anything)
+}
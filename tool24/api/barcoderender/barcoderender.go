@@ -0,0 +1,313 @@
+// Package barcoderender draws Code128, EAN-13 and DataMatrix barcodes as PNG
+// or SVG. It is the linear and 2D counterpart of qrrender and takes the same
+// kind of options.
+//
+//	code, err := barcoderender.New(barcoderender.Code128, "T000042", barcoderender.DefaultOptions(barcoderender.Code128))
+//	err = code.WriteSVG(w)
+package barcoderender
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/datamatrix"
+	"github.com/boombuler/barcode/ean"
+
+	"api/qrrender"
+)
+
+// ErrInvalidOptions is wrapped by every validation error of options.
+var ErrInvalidOptions = errors.New("invalid barcode options")
+
+// ErrInvalidData is wrapped when the data cannot be encoded in the symbology,
+// e.g. letters in an EAN-13 or a wrong EAN-13 check digit.
+var ErrInvalidData = errors.New("invalid barcode data")
+
+// Symbology is the kind of barcode.
+type Symbology int
+
+const (
+	// Code128 encodes any ASCII text; it is the default.
+	Code128 Symbology = iota
+	// EAN13 encodes 12 digits plus a check digit, as on retail products.
+	EAN13
+	// DataMatrix is a square 2D code for up to ~1500 characters.
+	DataMatrix
+)
+
+// ParseSymbology reads "code128", "ean13" or "datamatrix", case-insensitively
+// and ignoring dashes and underscores; an empty string is Code128.
+func ParseSymbology(s string) (Symbology, error) {
+	name := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(s)))
+	switch name {
+	case "", "code128":
+		return Code128, nil
+	case "ean13":
+		return EAN13, nil
+	case "datamatrix":
+		return DataMatrix, nil
+	}
+	return Code128, fmt.Errorf("%w: type must be code128, ean13 or datamatrix", ErrInvalidOptions)
+}
+
+func (s Symbology) String() string {
+	switch s {
+	case Code128:
+		return "code128"
+	case EAN13:
+		return "ean13"
+	case DataMatrix:
+		return "datamatrix"
+	}
+	return "symbology(" + strconv.Itoa(int(s)) + ")"
+}
+
+// Is2D reports whether the symbology is a matrix code rather than bars.
+func (s Symbology) Is2D() bool {
+	return s == DataMatrix
+}
+
+// Limits of Options.
+const (
+	MaxModuleSize = 64
+	MaxHeight     = 512
+	MaxQuietZone  = 32
+	MaxImageSize  = 4096
+)
+
+// Options controls how a code is drawn. Sizes are in modules, the width of
+// the narrowest bar; use DefaultOptions for values a handheld scanner reads
+// well.
+type Options struct {
+	// ModuleSize is the width of one module in pixels.
+	ModuleSize int
+	// Height is the bar height in modules; DataMatrix ignores it.
+	Height int
+	// QuietZone is the blank border in modules, on every side.
+	QuietZone int
+	// Foreground and Background default to black and white.
+	Foreground color.Color
+	Background color.Color
+}
+
+// DefaultOptions returns the options used when a caller sets nothing. Linear
+// codes need a 10-module quiet zone (11 for EAN-13) to be found by a
+// scanner; DataMatrix only needs one module, two is kinder to cameras.
+func DefaultOptions(sym Symbology) Options {
+	switch sym {
+	case EAN13:
+		return Options{ModuleSize: 2, Height: 70, QuietZone: 11}
+	case DataMatrix:
+		return Options{ModuleSize: 8, QuietZone: 2}
+	}
+	return Options{ModuleSize: 2, Height: 50, QuietZone: 10}
+}
+
+// Code is an encoded barcode ready to be drawn.
+type Code struct {
+	sym  Symbology
+	text string
+	opts Options
+	// modules là lưới module không tính quiet zone, true là module tối; mã
+	// một chiều chỉ có một hàng.
+	modules [][]bool
+}
+
+// New encodes data and validates opts. EAN-13 data is 12 digits, the check
+// digit is then added, or 13 digits whose check digit must be right.
+func New(sym Symbology, data string, opts Options) (*Code, error) {
+	if data == "" {
+		return nil, fmt.Errorf("%w: data is required", ErrInvalidData)
+	}
+	if opts.ModuleSize < 1 || opts.ModuleSize > MaxModuleSize {
+		return nil, fmt.Errorf("%w: module size must be between 1 and %d", ErrInvalidOptions, MaxModuleSize)
+	}
+	if !sym.Is2D() && (opts.Height < 1 || opts.Height > MaxHeight) {
+		return nil, fmt.Errorf("%w: height must be between 1 and %d", ErrInvalidOptions, MaxHeight)
+	}
+	if opts.QuietZone < 0 || opts.QuietZone > MaxQuietZone {
+		return nil, fmt.Errorf("%w: quiet zone must be between 0 and %d", ErrInvalidOptions, MaxQuietZone)
+	}
+	if opts.Foreground == nil {
+		opts.Foreground = color.Black
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+
+	var bc barcode.Barcode
+	var err error
+	switch sym {
+	case Code128:
+		for _, r := range data {
+			if r > 127 {
+				return nil, fmt.Errorf("%w: code128 only encodes ASCII", ErrInvalidData)
+			}
+		}
+		bc, err = code128.Encode(data)
+	case EAN13:
+		if len(data) != 12 && len(data) != 13 || strings.Trim(data, "0123456789") != "" {
+			return nil, fmt.Errorf("%w: ean13 needs 12 or 13 digits", ErrInvalidData)
+		}
+		bc, err = ean.Encode(data)
+	case DataMatrix:
+		bc, err = datamatrix.Encode(data)
+	default:
+		return nil, fmt.Errorf("%w: unknown symbology %d", ErrInvalidOptions, sym)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	code := &Code{sym: sym, text: bc.Content(), opts: opts, modules: modules(bc)}
+	if w, h := code.Size(); max(w, h)*opts.ModuleSize > MaxImageSize {
+		return nil, fmt.Errorf("%w: image would be larger than %dpx, use a smaller module size or less data", ErrInvalidOptions, MaxImageSize)
+	}
+	return code, nil
+}
+
+// modules đọc lưới module từ ảnh 1 pixel/module của boombuler.
+func modules(bc barcode.Barcode) [][]bool {
+	b := bc.Bounds()
+	rows := make([][]bool, b.Dy())
+	for y := range rows {
+		rows[y] = make([]bool, b.Dx())
+		for x := range rows[y] {
+			rows[y][x] = color.GrayModel.Convert(bc.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y < 128
+		}
+	}
+	return rows
+}
+
+// Symbology is the kind of barcode.
+func (c *Code) Symbology() Symbology {
+	return c.sym
+}
+
+// Text is the encoded content, including the EAN-13 check digit.
+func (c *Code) Text() string {
+	return c.text
+}
+
+// Modules returns the module grid without quiet zone, true for dark. Linear
+// codes have a single row.
+func (c *Code) Modules() [][]bool {
+	return c.modules
+}
+
+// Size is the width and height of the drawing in modules, including the
+// quiet zone.
+func (c *Code) Size() (width, height int) {
+	width = len(c.modules[0]) + 2*c.opts.QuietZone
+	height = len(c.modules) + 2*c.opts.QuietZone
+	if !c.sym.Is2D() {
+		height = c.opts.Height + 2*c.opts.QuietZone
+	}
+	return width, height
+}
+
+// dark cho biết module (x, y), không tính quiet zone, có được tô không; mã
+// một chiều kéo dài hàng duy nhất theo chiều cao.
+func (c *Code) dark(x, y int) bool {
+	if !c.sym.Is2D() {
+		y = 0
+	}
+	return c.modules[y][x]
+}
+
+// rows là số hàng module cần vẽ, không tính quiet zone.
+func (c *Code) rows() int {
+	if c.sym.Is2D() {
+		return len(c.modules)
+	}
+	return c.opts.Height
+}
+
+// Image draws the code.
+func (c *Code) Image() image.Image {
+	m := c.opts.ModuleSize
+	w, h := c.Size()
+	img := image.NewNRGBA(image.Rect(0, 0, w*m, h*m))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.opts.Background), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(c.opts.Foreground)
+	offset := c.opts.QuietZone * m
+	for y := 0; y < c.rows(); y++ {
+		for x := range c.modules[0] {
+			if c.dark(x, y) {
+				r := image.Rect(offset+x*m, offset+y*m, offset+(x+1)*m, offset+(y+1)*m)
+				draw.Draw(img, r, fg, image.Point{}, draw.Over)
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the code as a PNG image.
+func (c *Code) WritePNG(w io.Writer) error {
+	return png.Encode(w, c.Image())
+}
+
+// WriteSVG writes the code as an SVG document. Linear codes are drawn as one
+// rectangle per bar, DataMatrix as one rectangle per run of dark modules.
+func (c *Code) WriteSVG(w io.Writer) error {
+	var b strings.Builder
+	width, height := c.Size()
+	m := c.opts.ModuleSize
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		width*m, height*m, width, height)
+	if _, _, _, a := c.opts.Background.RGBA(); a > 0 {
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" %s/>`+"\n", width, height, svgFill(c.opts.Background))
+	}
+
+	fmt.Fprintf(&b, `<path %s d="`, svgFill(c.opts.Foreground))
+	q := c.opts.QuietZone
+	for y, row := range c.modules {
+		rowHeight := 1
+		if !c.sym.Is2D() {
+			rowHeight = c.opts.Height
+		}
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv%dh-%dz", start+q, y+q, x-start, rowHeight, x-start)
+		}
+	}
+	b.WriteString("\"/>\n</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A < 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(n.A)/0xff, 'f', 3, 64))
+	}
+	return fill
+}
+
+// ParseColor reads a CSS-style hex color like qrrender.ParseColor, wrapping
+// ErrInvalidOptions of this package.
+func ParseColor(s string) (color.NRGBA, error) {
+	c, err := qrrender.ParseColor(s)
+	if err != nil {
+		return c, fmt.Errorf("%w: color %q must be #rgb, #rrggbb or #rrggbbaa", ErrInvalidOptions, s)
+	}
+	return c, nil
+}
@@ -0,0 +1,135 @@
+package barcoderender
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "ghi lại các file golden trong testdata")
+
+var goldenCases = []struct {
+	name string
+	sym  Symbology
+	data string
+	opts Options
+}{
+	{"code128", Code128, "T000042", DefaultOptions(Code128)},
+	{"ean13", EAN13, "893452100001", DefaultOptions(EAN13)},
+	{"datamatrix", DataMatrix, "https://example.com/todo/42", Options{ModuleSize: 4, QuietZone: 1,
+		Foreground: color.NRGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}, Background: color.NRGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0x80}}},
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err, "chạy go test ./barcoderender -update để tạo file golden") {
+		assert.True(t, bytes.Equal(want, got), "%s khác file golden", name)
+	}
+}
+
+func TestGoldenSVG(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := New(tc.sym, tc.data, tc.opts)
+			assert.NoError(t, err)
+			var buf bytes.Buffer
+			assert.NoError(t, code.WriteSVG(&buf))
+			checkGolden(t, tc.name+".svg", buf.Bytes())
+		})
+	}
+}
+
+func TestImageMatchesModules(t *testing.T) {
+	for _, tc := range goldenCases {
+		code, err := New(tc.sym, tc.data, Options{ModuleSize: 3, Height: 5, QuietZone: 2})
+		assert.NoError(t, err)
+		img := code.Image()
+		w, h := code.Size()
+		assert.Equal(t, w*3, img.Bounds().Dx(), tc.name)
+		assert.Equal(t, h*3, img.Bounds().Dy(), tc.name)
+		for y := 0; y < code.rows(); y++ {
+			for x := range code.modules[0] {
+				r, _, _, _ := img.At((x+2)*3+1, (y+2)*3+1).RGBA()
+				assert.Equal(t, code.dark(x, y), r == 0, "%s module (%d, %d)", tc.name, x, y)
+			}
+		}
+	}
+}
+
+func TestCode128(t *testing.T) {
+	code, err := New(Code128, "T000042", DefaultOptions(Code128))
+	assert.NoError(t, err)
+	// Start, 7 ký tự, checksum: 11 module mỗi ký tự, cộng Stop 13 module.
+	assert.LessOrEqual(t, len(code.Modules()[0]), 11*9+13)
+	assert.Len(t, code.Modules(), 1)
+	w, h := code.Size()
+	assert.Equal(t, len(code.Modules()[0])+20, w)
+	assert.Equal(t, 70, h)
+}
+
+func TestEAN13CheckDigit(t *testing.T) {
+	code, err := New(EAN13, "893452100001", DefaultOptions(EAN13))
+	assert.NoError(t, err)
+	assert.Equal(t, "8934521000015", code.Text())
+	assert.Len(t, code.Modules()[0], 95)
+
+	_, err = New(EAN13, "8934521000011", DefaultOptions(EAN13))
+	assert.True(t, errors.Is(err, ErrInvalidData))
+}
+
+func TestNewInvalid(t *testing.T) {
+	valid := DefaultOptions(Code128)
+	for name, tc := range map[string]struct {
+		sym  Symbology
+		data string
+		opts Options
+		err  error
+	}{
+		"empty data":    {Code128, "", valid, ErrInvalidData},
+		"non-ascii":     {Code128, "Kiểm kê", valid, ErrInvalidData},
+		"ean letters":   {EAN13, "89345210000A", DefaultOptions(EAN13), ErrInvalidData},
+		"ean length":    {EAN13, "12345", DefaultOptions(EAN13), ErrInvalidData},
+		"too much data": {DataMatrix, strings.Repeat("x", 2000), DefaultOptions(DataMatrix), ErrInvalidData},
+		"module size":   {Code128, "x", Options{Height: 10}, ErrInvalidOptions},
+		"height":        {Code128, "x", Options{ModuleSize: 1}, ErrInvalidOptions},
+		"quiet zone":    {DataMatrix, "x", Options{ModuleSize: 1, QuietZone: MaxQuietZone + 1}, ErrInvalidOptions},
+		"too large":     {Code128, strings.Repeat("x", 70), Options{ModuleSize: 8, Height: 10}, ErrInvalidOptions},
+		"symbology":     {Symbology(9), "x", valid, ErrInvalidOptions},
+	} {
+		_, err := New(tc.sym, tc.data, tc.opts)
+		assert.True(t, errors.Is(err, tc.err), "%s: %v", name, err)
+	}
+}
+
+func TestParseSymbology(t *testing.T) {
+	for in, want := range map[string]Symbology{"": Code128, "Code128": Code128, "EAN-13": EAN13, "data_matrix": DataMatrix} {
+		got, err := ParseSymbology(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+		back, _ := ParseSymbology(got.String())
+		assert.Equal(t, got, back, in)
+	}
+	_, err := ParseSymbology("qr")
+	assert.True(t, errors.Is(err, ErrInvalidOptions))
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("#1a237e")
+	assert.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}, c)
+	_, err = ParseColor("red")
+	assert.True(t, errors.Is(err, ErrInvalidOptions))
+}
@@ -0,0 +1,119 @@
+// Package barcodescan reads Code128, EAN-13, DataMatrix and QR codes from
+// clean digital images, such as screenshots, exported PNGs and flatbed
+// scans. The code may be upright or rotated by a multiple of 90°. Linear
+// codes are read along several scan lines. A DataMatrix must be the only
+// dark object in the image, because it is located by its bounding box.
+//
+//	res, err := barcodescan.Scan(img)
+//	fmt.Println(res.Format, res.Text)
+package barcodescan
+
+import (
+	"errors"
+	"image"
+
+	"api/qrscan"
+)
+
+// ErrNotFound is returned when the image contains no readable code.
+var ErrNotFound = errors.New("no barcode found")
+
+// Formats reported in Result.Format; the first three match the names of
+// barcoderender.Symbology.
+const (
+	FormatCode128    = "code128"
+	FormatEAN13      = "ean13"
+	FormatDataMatrix = "datamatrix"
+	FormatQR         = "qr"
+)
+
+// Result is a decoded code.
+type Result struct {
+	Format string `json:"format"`
+	Text   string `json:"text"`
+}
+
+// Scan decodes the first code it can read in img. DataMatrix is tried
+// first, then linear codes, then QR. Light-on-dark codes are tried after
+// dark-on-light ones.
+func Scan(img image.Image) (Result, error) {
+	b := binarize(img)
+	for pass := 0; pass < 2; pass++ {
+		if text, err := b.scanDataMatrix(); err == nil {
+			return Result{Format: FormatDataMatrix, Text: text}, nil
+		}
+		if res, err := b.scanLinear(); err == nil {
+			return res, nil
+		}
+		b.invert()
+	}
+	if text, err := qrscan.Scan(img); err == nil {
+		return Result{Format: FormatQR, Text: text}, nil
+	}
+	return Result{}, ErrNotFound
+}
+
+// binaryImage là ảnh đã phân ngưỡng; true là điểm tối.
+type binaryImage struct {
+	w, h int
+	dark []bool
+}
+
+func (b *binaryImage) get(x, y int) bool {
+	return b.dark[y*b.w+x]
+}
+
+func (b *binaryImage) invert() {
+	for i := range b.dark {
+		b.dark[i] = !b.dark[i]
+	}
+}
+
+// binarize chuyển ảnh sang độ sáng (điểm trong suốt coi như nằm trên nền
+// trắng) rồi phân ngưỡng bằng phương pháp Otsu, giống qrscan.
+func binarize(img image.Image) *binaryImage {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := make([]uint8, w*h)
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			white := 0xffff - a
+			l := (299*(r+white) + 587*(g+white) + 114*(bl+white)) / 1000 >> 8
+			lum[y*w+x] = uint8(l)
+			histogram[l]++
+		}
+	}
+
+	total := w * h
+	var sum float64
+	for i, n := range histogram {
+		sum += float64(i * n)
+	}
+	var sumDark, bestVariance float64
+	threshold, weightDark := 0, 0
+	for t := 0; t < 256; t++ {
+		weightDark += histogram[t]
+		if weightDark == 0 {
+			continue
+		}
+		weightLight := total - weightDark
+		if weightLight == 0 {
+			break
+		}
+		sumDark += float64(t * histogram[t])
+		meanDark := sumDark / float64(weightDark)
+		meanLight := (sum - sumDark) / float64(weightLight)
+		variance := float64(weightDark) * float64(weightLight) * (meanDark - meanLight) * (meanDark - meanLight)
+		if variance > bestVariance {
+			bestVariance, threshold = variance, t
+		}
+	}
+
+	b := &binaryImage{w: w, h: h, dark: make([]bool, w*h)}
+	for i, l := range lum {
+		b.dark[i] = int(l) <= threshold
+	}
+	return b
+}
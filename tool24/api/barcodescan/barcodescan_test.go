@@ -0,0 +1,159 @@
+package barcodescan
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"api/barcoderender"
+	"api/qrrender"
+)
+
+func render(t *testing.T, sym barcoderender.Symbology, data string, opts barcoderender.Options) image.Image {
+	t.Helper()
+	code, err := barcoderender.New(sym, data, opts)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return code.Image()
+}
+
+// rotate xoay ảnh 90° theo chiều kim đồng hồ.
+func rotate(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(b.Max.Y-1-y, x-b.Min.X, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func TestScanRoundTrip(t *testing.T) {
+	var ascii strings.Builder
+	for c := byte(32); c < 127; c++ {
+		ascii.WriteByte(c)
+	}
+	for _, tc := range []struct {
+		name string
+		sym  barcoderender.Symbology
+		data string
+		want string
+		opts barcoderender.Options
+	}{
+		{"code128 short code", barcoderender.Code128, "T000042", "", barcoderender.DefaultOptions(barcoderender.Code128)},
+		{"code128 digits", barcoderender.Code128, "42-20241224093000", "", barcoderender.Options{ModuleSize: 1, Height: 20, QuietZone: 10}},
+		{"code128 ascii", barcoderender.Code128, ascii.String()[:80], "", barcoderender.Options{ModuleSize: 1, Height: 10, QuietZone: 10}},
+		{"code128 tail", barcoderender.Code128, ascii.String()[80:], "", barcoderender.Options{ModuleSize: 3, Height: 10, QuietZone: 10}},
+		{"code128 control", barcoderender.Code128, "A\tB\nc", "", barcoderender.Options{ModuleSize: 2, Height: 10, QuietZone: 10}},
+		{"ean13", barcoderender.EAN13, "893452100001", "8934521000015", barcoderender.DefaultOptions(barcoderender.EAN13)},
+		{"ean13 odd parity", barcoderender.EAN13, "4006381333931", "", barcoderender.Options{ModuleSize: 1, Height: 30, QuietZone: 11}},
+		{"datamatrix", barcoderender.DataMatrix, "T000042", "", barcoderender.DefaultOptions(barcoderender.DataMatrix)},
+		{"datamatrix 2x2 regions", barcoderender.DataMatrix, "https://example.com/todo/3f2b8c1e-7d4a-4e9b-a1c2-5d6e7f8a9b0c", "",
+			barcoderender.Options{ModuleSize: 3, QuietZone: 2}},
+		{"datamatrix utf-8", barcoderender.DataMatrix, "Kiểm kê kho A", "", barcoderender.Options{ModuleSize: 2, QuietZone: 1}},
+		{"datamatrix blocks", barcoderender.DataMatrix, strings.Repeat("Warehouse 42, shelf B. ", 20), "",
+			barcoderender.Options{ModuleSize: 1, QuietZone: 1}},
+		{"datamatrix colors", barcoderender.DataMatrix, "0123456789", "", barcoderender.Options{ModuleSize: 5, QuietZone: 2,
+			Foreground: color.NRGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}, Background: color.NRGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0xff}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			want := tc.want
+			if want == "" {
+				want = tc.data
+			}
+			img := render(t, tc.sym, tc.data, tc.opts)
+			for turn := 0; turn < 4; turn++ {
+				res, err := Scan(img)
+				assert.NoError(t, err, "rotated %d°", 90*turn)
+				assert.Equal(t, tc.sym.String(), res.Format, "rotated %d°", 90*turn)
+				assert.Equal(t, want, res.Text, "rotated %d°", 90*turn)
+				img = rotate(img)
+			}
+		})
+	}
+}
+
+func TestScanQR(t *testing.T) {
+	code, err := qrrender.New("T000042", qrrender.DefaultOptions())
+	assert.NoError(t, err)
+	res, err := Scan(code.Image())
+	assert.NoError(t, err)
+	assert.Equal(t, Result{Format: FormatQR, Text: "T000042"}, res)
+}
+
+func TestScanInverted(t *testing.T) {
+	img := render(t, barcoderender.Code128, "INVERTED", barcoderender.Options{ModuleSize: 2, Height: 20, QuietZone: 10,
+		Foreground: color.White, Background: color.Black})
+	res, err := Scan(img)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVERTED", res.Text)
+}
+
+// Lật vài module dữ liệu vẫn đọc được nhờ Reed-Solomon.
+func TestScanDataMatrixCorrectsErrors(t *testing.T) {
+	code, err := barcoderender.New(barcoderender.DataMatrix, "https://example.com/todo/42", barcoderender.Options{ModuleSize: 2, QuietZone: 2})
+	assert.NoError(t, err)
+	img := code.Image().(*image.NRGBA)
+	for _, m := range [][2]int{{5, 5}, {9, 12}, {14, 7}} {
+		x, y := (m[0]+2)*2, (m[1]+2)*2
+		c := color.NRGBA{A: 0xff}
+		if code.Modules()[m[1]][m[0]] {
+			c = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+		}
+		for dy := 0; dy < 2; dy++ {
+			for dx := 0; dx < 2; dx++ {
+				img.SetNRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+	res, err := Scan(img)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/todo/42", res.Text)
+}
+
+func TestScanNotFound(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 64, 64))
+	_, err := Scan(blank)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// EAN-13 sai check digit không được nhận.
+	code, err := barcoderender.New(barcoderender.EAN13, "893452100001", barcoderender.DefaultOptions(barcoderender.EAN13))
+	assert.NoError(t, err)
+	img := code.Image().(*image.NRGBA)
+	bounds := img.Bounds()
+	for y := 0; y < bounds.Dy(); y++ {
+		// Xóa vạch cuối của guard phải làm hỏng mã.
+		for x := (11 + 94) * 2; x < (11+95)*2; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+		}
+	}
+	_, err = Scan(img)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDecodeCodewords(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"ascii digits": {[]byte{142, 164, 186, 129, 111}, "123456"},
+		"c40":          {[]byte{dmLatchC40, 91, 11, 91, 11, 91, 11, dmUnlatch, 'B' + 1}, "AIMAIMAIMB"},
+		"text":         {[]byte{dmLatchText, 91, 11}, "aim"},
+		"x12":          {[]byte{dmLatchX12, 87, 171, dmUnlatch}, "A*>"},
+		"edifact":      {[]byte{dmLatchEDI, 0x04, 0x20, 0xdf, 'D' + 1}, "ABCD"},
+		"base256":      {[]byte{dmLatchBase, 47, 9, 26, 149}, "Hé"},
+		"upper shift":  {[]byte{dmUpperShift, 0xe9 - 127}, "é"},
+		"macro 05":     {[]byte{dmMacro05, 'A' + 1}, "[)>\x1e05\x1dA\x1e\x04"},
+	} {
+		got, err := decodeCodewords(tc.data)
+		assert.NoError(t, err, name)
+		assert.Equal(t, tc.want, got, name)
+	}
+	_, err := decodeCodewords([]byte{233})
+	assert.Error(t, err)
+}
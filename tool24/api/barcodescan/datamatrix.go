@@ -0,0 +1,486 @@
+package barcodescan
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+var errNoDataMatrix = errors.New("no datamatrix found")
+
+// dmSize là một kích thước DataMatrix ECC200 vuông: cạnh tính bằng module (kể
+// cả finder), số vùng dữ liệu mỗi chiều, tổng số codeword sửa lỗi và số khối
+// Reed-Solomon.
+type dmSize struct {
+	side, regions, ecc, blocks int
+}
+
+var dmSizes = []dmSize{
+	{10, 1, 5, 1}, {12, 1, 7, 1}, {14, 1, 10, 1}, {16, 1, 12, 1}, {18, 1, 14, 1},
+	{20, 1, 18, 1}, {22, 1, 20, 1}, {24, 1, 24, 1}, {26, 1, 28, 1},
+	{32, 2, 36, 1}, {36, 2, 42, 1}, {40, 2, 48, 1}, {44, 2, 56, 1}, {48, 2, 68, 1},
+	{52, 2, 84, 2}, {64, 4, 112, 2}, {72, 4, 144, 4}, {80, 4, 192, 4}, {88, 4, 224, 4},
+	{96, 4, 272, 4}, {104, 4, 336, 6}, {120, 6, 408, 6}, {132, 6, 496, 8}, {144, 6, 620, 10},
+}
+
+// regionSide là cạnh một vùng dữ liệu, không tính finder và timing.
+func (s dmSize) regionSide() int {
+	return s.side/s.regions - 2
+}
+
+// matrixSide là cạnh ma trận dữ liệu sau khi bỏ finder và timing.
+func (s dmSize) matrixSide() int {
+	return s.regionSide() * s.regions
+}
+
+func (s dmSize) dataCodewords() int {
+	return s.matrixSide()*s.matrixSide()/8 - s.ecc
+}
+
+// blockData là số codeword dữ liệu của khối block; riêng mã 144×144 có 8
+// khối đầu dài hơn 2 khối cuối một codeword.
+func (s dmSize) blockData(block int) int {
+	if s.side == 144 {
+		if block < 8 {
+			return 156
+		}
+		return 155
+	}
+	return s.dataCodewords() / s.blocks
+}
+
+// scanDataMatrix định vị mã bằng khung bao các điểm tối, đếm module trên cạnh
+// timing rồi lấy mẫu tâm từng module.
+func (b *binaryImage) scanDataMatrix() (string, error) {
+	minX, minY, maxX, maxY := b.w, b.h, -1, -1
+	for y := 0; y < b.h; y++ {
+		for x := 0; x < b.w; x++ {
+			if b.get(x, y) {
+				minX, minY = min(minX, x), min(minY, y)
+				maxX, maxY = max(maxX, x), max(maxY, y)
+			}
+		}
+	}
+	w, h := maxX-minX+1, maxY-minY+1
+	if maxX < 0 || w < 10 || h < 10 || abs(w-h) > max(w, h)/10 {
+		return "", errNoDataMatrix
+	}
+
+	// Cạnh timing xen kẽ sáng tối từng module nên số đoạn bằng số module.
+	side := 0
+	for _, edge := range [][4]int{{minX, minY, 1, 0}, {minX, maxY, 1, 0}, {minX, minY, 0, 1}, {maxX, minY, 0, 1}} {
+		n := w
+		if edge[3] == 1 {
+			n = h
+		}
+		line := make([]bool, n)
+		for i := range line {
+			line[i] = b.get(edge[0]+i*edge[2], edge[1]+i*edge[3])
+		}
+		runs := lineRuns(line)
+		if runs[0] == 0 {
+			runs = runs[1:]
+		}
+		side = max(side, len(runs))
+	}
+	var size dmSize
+	for _, s := range dmSizes {
+		if s.side == side {
+			size = s
+		}
+	}
+	if size.side == 0 {
+		return "", errNoDataMatrix
+	}
+
+	grid := make([][]bool, side)
+	for r := range grid {
+		grid[r] = make([]bool, side)
+		for c := range grid[r] {
+			x := minX + int((float64(c)+0.5)*float64(w)/float64(side))
+			y := minY + int((float64(r)+0.5)*float64(h)/float64(side))
+			grid[r][c] = b.get(x, y)
+		}
+	}
+	// Xoay đến khi chữ L liền nằm ở cạnh trái và cạnh dưới.
+	for turn := 0; ; turn++ {
+		if turn == 4 {
+			return "", errNoDataMatrix
+		}
+		if solidL(grid) {
+			break
+		}
+		grid = rotateGrid(grid)
+	}
+	return decodeDataMatrix(grid, size)
+}
+
+func solidL(grid [][]bool) bool {
+	n := len(grid)
+	for i := 0; i < n; i++ {
+		if !grid[i][0] || !grid[n-1][i] {
+			return false
+		}
+	}
+	return true
+}
+
+// rotateGrid xoay lưới 90° theo chiều kim đồng hồ.
+func rotateGrid(grid [][]bool) [][]bool {
+	n := len(grid)
+	out := make([][]bool, n)
+	for r := range out {
+		out[r] = make([]bool, n)
+		for c := range out[r] {
+			out[r][c] = grid[n-1-c][r]
+		}
+	}
+	return out
+}
+
+// decodeDataMatrix đọc lưới module đã xoay đúng chiều: bỏ finder và timing
+// của từng vùng, đọc codeword theo thứ tự đặt bit, sửa lỗi rồi giải mã.
+func decodeDataMatrix(grid [][]bool, size dmSize) (string, error) {
+	rs, n := size.regionSide(), size.matrixSide()
+	matrix := make([]bool, n*n)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			matrix[r*n+c] = grid[r/rs*(rs+2)+r%rs+1][c/rs*(rs+2)+c%rs+1]
+		}
+	}
+
+	positions := placement(n, n)
+	raw := make([]byte, len(positions))
+	for i, bits := range positions {
+		for _, p := range bits {
+			raw[i] <<= 1
+			if matrix[p] {
+				raw[i] |= 1
+			}
+		}
+	}
+
+	// Codeword thứ i (dữ liệu rồi sửa lỗi) thuộc khối i % blocks.
+	dataTotal := size.dataCodewords()
+	eccPerBlock := size.ecc / size.blocks
+	data := make([]byte, dataTotal)
+	for block := 0; block < size.blocks; block++ {
+		codeword := make([]byte, 0, size.blockData(block)+eccPerBlock)
+		for i := block; i < dataTotal; i += size.blocks {
+			codeword = append(codeword, raw[i])
+		}
+		for i := block; i < size.ecc; i += size.blocks {
+			codeword = append(codeword, raw[dataTotal+i])
+		}
+		if _, err := rsCorrect(codeword, eccPerBlock); err != nil {
+			return "", err
+		}
+		for j, i := 0, block; i < dataTotal; j, i = j+1, i+size.blocks {
+			data[i] = codeword[j]
+		}
+	}
+	return decodeCodewords(data)
+}
+
+// placement trả về, cho mỗi codeword, vị trí (hàng*ncol+cột) của 8 bit từ
+// bit cao đến bit thấp theo thuật toán đặt bit của ISO/IEC 16022 phụ lục F.
+func placement(nrow, ncol int) [][8]int {
+	used := make([]bool, nrow*ncol)
+	var out [][8]int
+	module := func(cw *[8]int, bit, row, col int) {
+		if row < 0 {
+			row += nrow
+			col += 4 - (nrow+4)%8
+		}
+		if col < 0 {
+			col += ncol
+			row += 4 - (ncol+4)%8
+		}
+		cw[bit] = row*ncol + col
+		used[row*ncol+col] = true
+	}
+	place := func(cells [8][2]int) {
+		var cw [8]int
+		for bit, rc := range cells {
+			module(&cw, bit, rc[0], rc[1])
+		}
+		out = append(out, cw)
+	}
+	utah := func(r, c int) {
+		place([8][2]int{{r - 2, c - 2}, {r - 2, c - 1}, {r - 1, c - 2}, {r - 1, c - 1}, {r - 1, c}, {r, c - 2}, {r, c - 1}, {r, c}})
+	}
+
+	row, col := 4, 0
+	for row < nrow || col < ncol {
+		if row == nrow && col == 0 {
+			place([8][2]int{{nrow - 1, 0}, {nrow - 1, 1}, {nrow - 1, 2}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+		}
+		if row == nrow-2 && col == 0 && ncol%4 != 0 {
+			place([8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 4}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}})
+		}
+		if row == nrow-2 && col == 0 && ncol%8 == 4 {
+			place([8][2]int{{nrow - 3, 0}, {nrow - 2, 0}, {nrow - 1, 0}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 1}, {2, ncol - 1}, {3, ncol - 1}})
+		}
+		if row == nrow+4 && col == 2 && ncol%8 == 0 {
+			place([8][2]int{{nrow - 1, 0}, {nrow - 1, ncol - 1}, {0, ncol - 3}, {0, ncol - 2}, {0, ncol - 1}, {1, ncol - 3}, {1, ncol - 2}, {1, ncol - 1}})
+		}
+		for {
+			if row < nrow && col >= 0 && !used[row*ncol+col] {
+				utah(row, col)
+			}
+			row, col = row-2, col+2
+			if row < 0 || col >= ncol {
+				break
+			}
+		}
+		row, col = row+1, col+3
+		for {
+			if row >= 0 && col < ncol && !used[row*ncol+col] {
+				utah(row, col)
+			}
+			row, col = row+2, col-2
+			if row >= nrow || col < 0 {
+				break
+			}
+		}
+		row, col = row+3, col+1
+	}
+	return out
+}
+
+var errDataMatrixData = errors.New("invalid datamatrix data")
+
+// Bảng ký tự của chế độ C40/Text: bộ cơ bản cho giá trị 3–39 (0–2 là các shift), bộ Shift 2
+// cho dấu câu và bộ Shift 3 (chữ thường với C40, chữ hoa với Text).
+const (
+	c40Basic  = "    0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	textBasic = "    0123456789abcdefghijklmnopqrstuvwxyz"
+	c40Shift2 = "!\"#$%&'()*+,-./:;<=>?@[\\]^_"
+	c40Shift3 = "`abcdefghijklmnopqrstuvwxyz{|}~\x7f"
+	txtShift3 = "`ABCDEFGHIJKLMNOPQRSTUVWXYZ{|}~\x7f"
+	x12Chars  = "\r*> 0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+// Codeword chuyển chế độ của ASCII.
+const (
+	dmPad         = 129
+	dmLatchC40    = 230
+	dmLatchBase   = 231
+	dmFNC1        = 232
+	dmUpperShift  = 235
+	dmMacro05     = 236
+	dmMacro06     = 237
+	dmLatchX12    = 238
+	dmLatchText   = 239
+	dmLatchEDI    = 240
+	dmECI         = 241
+	dmUnlatch     = 254
+	dmEDIUnlatch  = 0x1f
+	macroTrailer  = "\x1e\x04"
+	macro05Header = "[)>\x1e05\x1d"
+	macro06Header = "[)>\x1e06\x1d"
+)
+
+// decodeCodewords giải mã các codeword dữ liệu: ASCII, C40, Text, X12,
+// EDIFACT và Base 256. Byte ngoài UTF-8 được hiểu là ISO-8859-1.
+func decodeCodewords(data []byte) (string, error) {
+	var out []byte
+	trailer := ""
+	upper := false
+	emit := func(c byte) {
+		if upper {
+			c += 128
+			upper = false
+		}
+		out = append(out, c)
+	}
+
+	for i := 0; i < len(data); {
+		c := data[i]
+		i++
+		switch {
+		case c == dmPad:
+			i = len(data)
+		case c >= 1 && c <= 128:
+			emit(c - 1)
+		case c >= 130 && c <= 229:
+			v := c - 130
+			out = append(out, '0'+v/10, '0'+v%10)
+		case c == dmLatchC40 || c == dmLatchText:
+			var err error
+			if i, err = decodeC40(data, i, c == dmLatchText, &out); err != nil {
+				return "", err
+			}
+		case c == dmLatchX12:
+			i = decodeX12(data, i, &out)
+		case c == dmLatchEDI:
+			i = decodeEDIFACT(data, i, &out)
+		case c == dmLatchBase:
+			var err error
+			if i, err = decodeBase256(data, i, &out); err != nil {
+				return "", err
+			}
+		case c == dmFNC1:
+			if i > 1 {
+				out = append(out, 0x1d)
+			}
+		case c == dmUpperShift:
+			upper = true
+		case c == dmMacro05 && i == 1:
+			out, trailer = append(out, macro05Header...), macroTrailer
+		case c == dmMacro06 && i == 1:
+			out, trailer = append(out, macro06Header...), macroTrailer
+		case c == dmECI:
+			// Bỏ qua chỉ định ECI; chỉ hỗ trợ ECI một codeword.
+			i++
+		case c == 234:
+			// Reader programming không mang dữ liệu.
+		default:
+			return "", fmt.Errorf("%w: unsupported codeword %d", errDataMatrixData, c)
+		}
+	}
+	out = append(out, trailer...)
+
+	if utf8.Valid(out) {
+		return string(out), nil
+	}
+	var s strings.Builder
+	for _, c := range out {
+		s.WriteRune(rune(c))
+	}
+	return s.String(), nil
+}
+
+// decodeC40 đọc các cặp codeword (mỗi cặp 3 giá trị 0–39) đến khi gặp
+// Unlatch hoặc hết dữ liệu, trả về vị trí tiếp theo.
+func decodeC40(data []byte, i int, text bool, out *[]byte) (int, error) {
+	basic, shift3 := c40Basic, c40Shift3
+	if text {
+		basic, shift3 = textBasic, txtShift3
+	}
+	shift := 0
+	upper := false
+	write := func(c byte) {
+		if upper {
+			c += 128
+			upper = false
+		}
+		*out = append(*out, c)
+		shift = 0
+	}
+	for i+1 < len(data) && data[i] != dmUnlatch {
+		v := int(data[i])*256 + int(data[i+1]) - 1
+		i += 2
+		for _, u := range []int{v / 1600, v / 40 % 40, v % 40} {
+			switch shift {
+			case 0:
+				if u < 3 {
+					shift = u + 1
+				} else {
+					write(basic[u])
+				}
+			case 1:
+				write(byte(u))
+			case 2:
+				switch {
+				case u < len(c40Shift2):
+					write(c40Shift2[u])
+				case u == 27:
+					write(0x1d)
+				case u == 30:
+					upper, shift = true, 0
+				default:
+					return i, fmt.Errorf("%w: invalid C40 shift 2 value %d", errDataMatrixData, u)
+				}
+			case 3:
+				if u >= len(shift3) {
+					return i, fmt.Errorf("%w: invalid C40 shift 3 value %d", errDataMatrixData, u)
+				}
+				write(shift3[u])
+			}
+		}
+	}
+	if i < len(data) && data[i] == dmUnlatch {
+		i++
+	}
+	return i, nil
+}
+
+func decodeX12(data []byte, i int, out *[]byte) int {
+	for i+1 < len(data) && data[i] != dmUnlatch {
+		v := int(data[i])*256 + int(data[i+1]) - 1
+		i += 2
+		for _, u := range []int{v / 1600, v / 40 % 40, v % 40} {
+			if u < len(x12Chars) {
+				*out = append(*out, x12Chars[u])
+			}
+		}
+	}
+	if i < len(data) && data[i] == dmUnlatch {
+		i++
+	}
+	return i
+}
+
+// decodeEDIFACT đọc 4 giá trị 6 bit trong mỗi 3 codeword; giá trị 0x1f trả về
+// ASCII, phần còn lại của codeword đó bị bỏ.
+func decodeEDIFACT(data []byte, i int, out *[]byte) int {
+	for i+2 < len(data) {
+		v := int(data[i])<<16 | int(data[i+1])<<8 | int(data[i+2])
+		i += 3
+		for shift := 18; shift >= 0; shift -= 6 {
+			u := byte(v>>shift) & 0x3f
+			if u == dmEDIUnlatch {
+				return i - 3 + (24-shift+7)/8
+			}
+			if u&0x20 == 0 {
+				u |= 0x40
+			}
+			*out = append(*out, u)
+		}
+	}
+	return i
+}
+
+// decodeBase256 đọc đoạn byte thô; độ dài và dữ liệu đều được xáo bằng thuật
+// toán 255 trạng thái theo vị trí codeword (tính từ 1).
+func decodeBase256(data []byte, i int, out *[]byte) (int, error) {
+	unrandomize := func(pos int) int {
+		v := int(data[pos]) - (149*(pos+1)%255 + 1)
+		if v < 0 {
+			v += 256
+		}
+		return v
+	}
+	if i >= len(data) {
+		return i, fmt.Errorf("%w: missing base 256 length", errDataMatrixData)
+	}
+	n := unrandomize(i)
+	i++
+	switch {
+	case n == 0:
+		n = len(data) - i
+	case n > 249:
+		if i >= len(data) {
+			return i, fmt.Errorf("%w: missing base 256 length", errDataMatrixData)
+		}
+		n = (n-249)*250 + unrandomize(i)
+		i++
+	}
+	if i+n > len(data) {
+		return i, fmt.Errorf("%w: base 256 length %d exceeds the symbol", errDataMatrixData, n)
+	}
+	for j := 0; j < n; j++ {
+		*out = append(*out, byte(unrandomize(i)))
+		i++
+	}
+	return i, nil
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
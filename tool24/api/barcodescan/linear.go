@@ -0,0 +1,301 @@
+package barcodescan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var errNoLinear = errors.New("no linear barcode found")
+
+// scanLines là số đường quét mỗi chiều; mã vạch thường chiếm một dải ngang
+// hẹp nên quét dày để chắc chắn có đường cắt qua.
+const scanLines = 24
+
+// scanLinear tìm Code128 hoặc EAN-13 trên các đường quét ngang rồi dọc (mã
+// xoay 90°), mỗi đường đọc theo cả hai chiều.
+func (b *binaryImage) scanLinear() (Result, error) {
+	for _, vertical := range []bool{false, true} {
+		n, length := b.h, b.w
+		if vertical {
+			n, length = b.w, b.h
+		}
+		line := make([]bool, length)
+		for i := 1; i <= scanLines; i++ {
+			pos := n * i / (scanLines + 1)
+			for j := range line {
+				if vertical {
+					line[j] = b.get(pos, j)
+				} else {
+					line[j] = b.get(j, pos)
+				}
+			}
+			runs := lineRuns(line)
+			for _, reversed := range []bool{false, true} {
+				if reversed {
+					runs = reverseRuns(runs)
+				}
+				if text, err := decodeCode128(runs); err == nil {
+					return Result{Format: FormatCode128, Text: text}, nil
+				}
+				if text, err := decodeEAN13(runs); err == nil {
+					return Result{Format: FormatEAN13, Text: text}, nil
+				}
+			}
+		}
+	}
+	return Result{}, errNoLinear
+}
+
+// lineRuns trả về độ dài các đoạn cùng màu liên tiếp; phần tử đầu luôn là
+// đoạn sáng (có thể dài 0) nên các vạch tối nằm ở chỉ số lẻ.
+func lineRuns(line []bool) []int {
+	runs := []int{0}
+	dark := false
+	for _, d := range line {
+		if d != dark {
+			runs = append(runs, 0)
+			dark = d
+		}
+		runs[len(runs)-1]++
+	}
+	return runs
+}
+
+// reverseRuns đảo chiều đọc mà vẫn giữ đoạn đầu là đoạn sáng.
+func reverseRuns(runs []int) []int {
+	out := make([]int, 0, len(runs)+1)
+	if len(runs)%2 == 0 {
+		// Đường kết thúc bằng đoạn tối: đảo xong phải chèn đoạn sáng rỗng.
+		out = append(out, 0)
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		out = append(out, runs[i])
+	}
+	return out
+}
+
+// patternDistance so độ rộng các đoạn (chuẩn hóa theo tổng modules module)
+// với mẫu, trả về tổng sai lệch tính bằng module.
+func patternDistance(runs []int, pattern string, modules int) float64 {
+	total := 0
+	for _, r := range runs {
+		total += r
+	}
+	unit := float64(total) / float64(modules)
+	var d float64
+	for i, r := range runs {
+		d += math.Abs(float64(r)/unit - float64(pattern[i]-'0'))
+	}
+	return d
+}
+
+// maxPatternDistance là tổng sai lệch tối đa để một ký tự được nhận.
+const maxPatternDistance = 1.5
+
+// bestPattern trả về chỉ số mẫu gần nhất với runs, hoặc -1.
+func bestPattern(runs []int, patterns []string, modules int) int {
+	best, bestDistance := -1, maxPatternDistance
+	for i, p := range patterns {
+		if d := patternDistance(runs, p, modules); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
+
+// code128Patterns là độ rộng vạch/khoảng của các ký tự Code128 0–106; ký tự
+// Stop (106) còn một vạch rộng 2 module phía sau.
+var code128Patterns = []string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "233111",
+}
+
+const (
+	code128StartA = 103
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+var errCode128 = errors.New("invalid code128")
+
+// decodeCode128 thử mọi vạch tối làm ký tự Start rồi đọc từng nhóm 6 đoạn đến
+// ký tự Stop.
+func decodeCode128(runs []int) (string, error) {
+	for s := 1; s+6 <= len(runs); s += 2 {
+		start := bestPattern(runs[s:s+6], code128Patterns, 11)
+		if start < code128StartA || start > code128StartC {
+			continue
+		}
+		values := []int{start}
+		for i := s + 6; i+7 <= len(runs); i += 6 {
+			v := bestPattern(runs[i:i+6], code128Patterns, 11)
+			if v < 0 || v >= code128StartA && v != code128Stop {
+				break
+			}
+			if v == code128Stop {
+				if text, err := code128Text(values); err == nil {
+					return text, nil
+				}
+				break
+			}
+			values = append(values, v)
+		}
+	}
+	return "", errCode128
+}
+
+// code128Text kiểm tra checksum (mod 103, trọng số là vị trí) rồi dịch các
+// giá trị theo bộ ký tự A, B hoặc C đang dùng.
+func code128Text(values []int) (string, error) {
+	if len(values) < 3 {
+		return "", errCode128
+	}
+	data, check := values[1:len(values)-1], values[len(values)-1]
+	sum := values[0]
+	for i, v := range data {
+		sum += (i + 1) * v
+	}
+	if sum%103 != check {
+		return "", fmt.Errorf("%w: checksum mismatch", errCode128)
+	}
+
+	const setA, setB, setC = 0, 1, 2
+	set := values[0] - code128StartA
+	shift := false
+	var out strings.Builder
+	for i, v := range data {
+		cur := set
+		if shift {
+			cur = setA + setB - set
+			shift = false
+		}
+		switch {
+		case v == 102:
+			// FNC1 ở đầu đánh dấu GS1-128, ở giữa là dấu phân cách GS.
+			if i > 0 {
+				out.WriteByte(0x1d)
+			}
+		case cur == setC && v < 100:
+			fmt.Fprintf(&out, "%02d", v)
+		case cur == setC:
+			set = map[int]int{100: setB, 101: setA}[v]
+		case cur == setA && v < 64:
+			out.WriteByte(byte(v + 32))
+		case cur == setA && v < 96:
+			out.WriteByte(byte(v - 64))
+		case cur == setB && v < 96:
+			out.WriteByte(byte(v + 32))
+		case v == 98:
+			shift = true
+		case v == 99:
+			set = setC
+		case cur == setA && v == 100, cur == setB && v == 101:
+			set = setA + setB - cur
+		}
+		// FNC2, FNC3, FNC4 không có ký tự tương ứng nên bỏ qua.
+	}
+	return out.String(), nil
+}
+
+// eanLPatterns là độ rộng khoảng/vạch của các chữ số EAN bộ L (và R, đọc
+// vạch trước); bộ G là bộ L đảo ngược.
+var eanLPatterns = []string{"3211", "2221", "2122", "1411", "1132", "1231", "1114", "1312", "1213", "3112"}
+
+var eanGPatterns = func() []string {
+	g := make([]string, len(eanLPatterns))
+	for i, p := range eanLPatterns {
+		g[i] = string([]byte{p[3], p[2], p[1], p[0]})
+	}
+	return g
+}()
+
+// eanParity là chữ số đầu mã hóa bằng thứ tự L/G (bit 1 là G) của 6 chữ số
+// bên trái.
+var eanParity = []int{0b000000, 0b001011, 0b001101, 0b001110, 0b010011, 0b011001, 0b011100, 0b010101, 0b010110, 0b011010}
+
+// eanRuns là số đoạn của một mã EAN-13: 3 guard + 6×4 + 5 guard + 6×4 + 3 guard.
+const eanRuns = 59
+
+var errEAN13 = errors.New("invalid ean13")
+
+func decodeEAN13(runs []int) (string, error) {
+	for s := 1; s+eanRuns <= len(runs); s += 2 {
+		if text, ok := readEAN13(runs[s : s+eanRuns]); ok {
+			return text, nil
+		}
+	}
+	return "", errEAN13
+}
+
+func readEAN13(runs []int) (string, bool) {
+	total := 0
+	for _, r := range runs {
+		total += r
+	}
+	unit := float64(total) / 95
+	for _, i := range []int{0, 1, 2, 27, 28, 29, 30, 31, 56, 57, 58} {
+		if w := float64(runs[i]) / unit; w < 0.5 || w > 1.5 {
+			return "", false
+		}
+	}
+
+	digits := make([]byte, 13)
+	parity := 0
+	for d := 0; d < 6; d++ {
+		group := runs[3+4*d : 7+4*d]
+		l, g := bestPattern(group, eanLPatterns, 7), bestPattern(group, eanGPatterns, 7)
+		switch {
+		case l >= 0 && (g < 0 || patternDistance(group, eanLPatterns[l], 7) <= patternDistance(group, eanGPatterns[g], 7)):
+			digits[1+d] = byte(l)
+		case g >= 0:
+			digits[1+d] = byte(g)
+			parity |= 1 << (5 - d)
+		default:
+			return "", false
+		}
+	}
+	for d := 0; d < 6; d++ {
+		r := bestPattern(runs[32+4*d:36+4*d], eanLPatterns, 7)
+		if r < 0 {
+			return "", false
+		}
+		digits[7+d] = byte(r)
+	}
+	first := -1
+	for i, p := range eanParity {
+		if p == parity {
+			first = i
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+	digits[0] = byte(first)
+
+	sum := 0
+	for i, d := range digits {
+		if i%2 == 1 {
+			sum += 3 * int(d)
+		} else {
+			sum += int(d)
+		}
+	}
+	if sum%10 != 0 {
+		return "", false
+	}
+	for i := range digits {
+		digits[i] += '0'
+	}
+	return string(digits), true
+}
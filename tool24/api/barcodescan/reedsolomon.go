@@ -0,0 +1,149 @@
+package barcodescan
+
+import "errors"
+
+var errTooManyErrors = errors.New("too many errors to correct")
+
+// Bảng log/antilog của GF(256) với đa thức nguyên thủy x^8+x^5+x^3+x^2+1
+// (0x12d) mà DataMatrix dùng; khác QR (0x11d) nên không dùng chung qrscan.
+var gfExp, gfLog = func() ([512]byte, [256]byte) {
+	var exp [512]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x12d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfPow trả về α^n.
+func gfPow(n int) byte {
+	return gfExp[((n%255)+255)%255]
+}
+
+// polyEval tính giá trị đa thức p (hệ số bậc thấp trước) tại x.
+func polyEval(p []byte, x byte) byte {
+	var y byte
+	for i := len(p) - 1; i >= 0; i-- {
+		y = gfMul(y, x) ^ p[i]
+	}
+	return y
+}
+
+// syndromes của codeword; codeword[0] là hệ số bậc cao nhất và đa thức sinh
+// của DataMatrix có gốc đầu tiên là α^1.
+func syndromes(codeword []byte, nsym int) ([]byte, bool) {
+	s := make([]byte, nsym)
+	clean := true
+	for j := range s {
+		x := gfPow(j + 1)
+		var y byte
+		for _, c := range codeword {
+			y = gfMul(y, x) ^ c
+		}
+		s[j] = y
+		if y != 0 {
+			clean = false
+		}
+	}
+	return s, clean
+}
+
+// rsCorrect sửa tại chỗ tối đa nsym/2 byte lỗi của codeword (dữ liệu nối với
+// nsym byte sửa lỗi) và trả về số byte đã sửa.
+func rsCorrect(codeword []byte, nsym int) (int, error) {
+	s, clean := syndromes(codeword, nsym)
+	if clean {
+		return 0, nil
+	}
+
+	// Berlekamp-Massey tìm đa thức định vị lỗi lambda (hệ số bậc thấp trước).
+	lambda := []byte{1}
+	prev := []byte{1}
+	l, m, b := 0, 1, byte(1)
+	for n := 0; n < nsym; n++ {
+		d := s[n]
+		for i := 1; i <= l && i < len(lambda); i++ {
+			d ^= gfMul(lambda[i], s[n-i])
+		}
+		if d == 0 {
+			m++
+			continue
+		}
+		next := make([]byte, max(len(lambda), len(prev)+m))
+		copy(next, lambda)
+		coef := gfDiv(d, b)
+		for i, p := range prev {
+			next[i+m] ^= gfMul(coef, p)
+		}
+		if 2*l <= n {
+			prev, l, b, m = lambda, n+1-l, d, 1
+		} else {
+			m++
+		}
+		lambda = next
+	}
+	if l == 0 || 2*l > nsym {
+		return 0, errTooManyErrors
+	}
+	lambda = lambda[:l+1]
+
+	// Chien search: byte ở vị trí k (lũy thừa p = n-1-k) lỗi khi lambda(α^-p) = 0.
+	n := len(codeword)
+	var positions []int
+	for p := 0; p < n; p++ {
+		if polyEval(lambda, gfPow(-p)) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	if len(positions) != l {
+		return 0, errTooManyErrors
+	}
+
+	// Forney với gốc đầu α^1: độ lớn lỗi tại X = α^p là omega(X^-1) / lambda'(X^-1).
+	omega := make([]byte, nsym)
+	for i := range omega {
+		for j := 0; j <= i && j < len(lambda); j++ {
+			omega[i] ^= gfMul(lambda[j], s[i-j])
+		}
+	}
+	derivative := make([]byte, len(lambda))
+	for i := 1; i < len(lambda); i += 2 {
+		derivative[i-1] = lambda[i]
+	}
+	for _, p := range positions {
+		xInv := gfPow(-p)
+		denominator := polyEval(derivative, xInv)
+		if denominator == 0 {
+			return 0, errTooManyErrors
+		}
+		codeword[n-1-p] ^= gfDiv(polyEval(omega, xInv), denominator)
+	}
+
+	if _, clean := syndromes(codeword, nsym); !clean {
+		return 0, errTooManyErrors
+	}
+	return l, nil
+}
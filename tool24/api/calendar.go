@@ -0,0 +1,548 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	ics "github.com/arran4/golang-ical"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+var ErrCalendarFeedNotFound = errors.New("calendar feed not found")
+
+// CalendarFeed là một URL bí mật /calendar.ics?token=... cho ứng dụng lịch.
+// Feed có ListID chứa mọi todo của danh sách đó; feed không có ListID chứa các
+// todo do Owner tạo.
+type CalendarFeed struct {
+	ID        string    `json:"id"`
+	Token     string    `json:"token"`
+	Owner     string    `json:"owner"`
+	ListID    string    `json:"list_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CalendarStore interface {
+	CreateCalendarFeedDB(ctx context.Context, feed CalendarFeed) (CalendarFeed, error)
+	ListCalendarFeedsDB(ctx context.Context, owner string) ([]CalendarFeed, error)
+	DeleteCalendarFeedDB(ctx context.Context, owner, id string) error
+	GetCalendarFeedByTokenDB(ctx context.Context, token string) (CalendarFeed, error)
+	ListCalendarTodosDB(ctx context.Context, feed CalendarFeed) ([]Todo, error)
+}
+
+const calendarFeedColumns = "id, token, owner, COALESCE(list_id, ''), created_at"
+
+func scanCalendarFeed(row pgx.Row, feed *CalendarFeed) error {
+	return row.Scan(&feed.ID, &feed.Token, &feed.Owner, &feed.ListID, &feed.CreatedAt)
+}
+
+func (db *Db) CreateCalendarFeedDB(ctx context.Context, feed CalendarFeed) (CalendarFeed, error) {
+	feed.ID = uuid.New().String()
+	err := scanCalendarFeed(db.Conn.QueryRow(ctx,
+		"INSERT INTO calendar_feed (id, token, owner, list_id) VALUES ($1, $2, $3, NULLIF($4, '')) RETURNING "+calendarFeedColumns,
+		feed.ID, feed.Token, feed.Owner, feed.ListID), &feed)
+	if err != nil {
+		return CalendarFeed{}, fmt.Errorf("failed to create calendar feed: %v", err)
+	}
+
+	return feed, nil
+}
+
+func (db *Db) ListCalendarFeedsDB(ctx context.Context, owner string) ([]CalendarFeed, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT "+calendarFeedColumns+" FROM calendar_feed WHERE owner = $1 ORDER BY created_at", owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar feeds: %v", err)
+	}
+	defer rows.Close()
+
+	feeds := []CalendarFeed{}
+	for rows.Next() {
+		var feed CalendarFeed
+		if err := scanCalendarFeed(rows, &feed); err != nil {
+			return nil, err
+		}
+		feeds = append(feeds, feed)
+	}
+
+	return feeds, rows.Err()
+}
+
+func (db *Db) DeleteCalendarFeedDB(ctx context.Context, owner, id string) error {
+	tag, err := db.Conn.Exec(ctx, "DELETE FROM calendar_feed WHERE id = $1 AND owner = $2", id, owner)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar feed: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("calendar feed %s: %w", id, ErrCalendarFeedNotFound)
+	}
+
+	return nil
+}
+
+func (db *Db) GetCalendarFeedByTokenDB(ctx context.Context, token string) (CalendarFeed, error) {
+	var feed CalendarFeed
+	err := scanCalendarFeed(db.Conn.QueryRow(ctx, "SELECT "+calendarFeedColumns+" FROM calendar_feed WHERE token = $1", token), &feed)
+	if err == pgx.ErrNoRows {
+		return CalendarFeed{}, ErrCalendarFeedNotFound
+	}
+	if err != nil {
+		return CalendarFeed{}, fmt.Errorf("failed to get calendar feed: %v", err)
+	}
+
+	return feed, nil
+}
+
+func (db *Db) ListCalendarTodosDB(ctx context.Context, feed CalendarFeed) ([]Todo, error) {
+	query, arg := "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL AND created_by = $1 ORDER BY created_at, id", feed.Owner
+	if feed.ListID != "" {
+		query, arg = "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL AND list_id = $1 ORDER BY created_at, id", feed.ListID
+	}
+	rows, err := db.Conn.Query(ctx, query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendar todos: %v", err)
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+// todoUID là UID của VTODO: ExternalID nếu todo được nhập từ nơi khác, để
+// ứng dụng lịch gốc nhận ra todo của nó, còn lại là ID của todo.
+func todoUID(todo Todo) string {
+	if todo.ExternalID != "" {
+		return todo.ExternalID
+	}
+	return todo.ID
+}
+
+// writeCalendar ghi các todo thành một VCALENDAR gồm các VTODO (RFC 5545).
+func writeCalendar(w http.ResponseWriter, name string, todos []Todo) error {
+	cal := ics.NewCalendarFor("todo-api")
+	cal.SetMethod(ics.MethodPublish)
+	cal.SetXWRCalName(name)
+	now := time.Now()
+
+	for _, todo := range todos {
+		vtodo := cal.AddTodo(todoUID(todo))
+		vtodo.SetDtStampTime(now)
+		vtodo.SetCreatedTime(todo.CreatedAt)
+		vtodo.SetSequence(todo.Version)
+		vtodo.SetSummary(todo.Title)
+		if todo.Desc != "" {
+			vtodo.SetDescription(todo.Desc)
+		}
+		if todo.Done {
+			vtodo.SetStatus(ics.ObjectStatusCompleted)
+			if todo.DoneAt != nil {
+				vtodo.SetCompletedAt(*todo.DoneAt)
+			}
+		} else {
+			vtodo.SetStatus(ics.ObjectStatusNeedsAction)
+		}
+		if todo.DueAt != nil {
+			vtodo.SetDueAt(*todo.DueAt)
+			// Nhắc việc tính ngược từ DUE, nên chỉ có nghĩa khi todo có hạn.
+			for _, offset := range todo.RemindAt {
+				d, err := time.ParseDuration(strings.TrimSpace(offset))
+				if err != nil {
+					continue
+				}
+				alarm := vtodo.AddAlarm()
+				alarm.SetAction(ics.ActionDisplay)
+				alarm.SetTrigger(formatTrigger(d), &ics.KeyValues{Key: string(ics.ParameterRelated), Value: []string{"END"}})
+				alarm.SetProperty(ics.ComponentPropertyDescription, todo.Title)
+			}
+		}
+		if todo.Recurrence != "" {
+			vtodo.AddRrule(todo.Recurrence)
+		}
+		for _, tag := range todo.Tags {
+			vtodo.AddCategory(tag)
+		}
+	}
+
+	return cal.SerializeTo(w)
+}
+
+// formatTrigger viết khoảng nhắc trước hạn thành DURATION âm, vd 90m -> -PT1H30M.
+func formatTrigger(d time.Duration) string {
+	var b strings.Builder
+	b.WriteString("-P")
+	if days := d / (24 * time.Hour); days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+		d -= days * 24 * time.Hour
+	}
+	if d == 0 {
+		if b.Len() == 2 {
+			return "PT0S"
+		}
+		return b.String()
+	}
+	b.WriteString("T")
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
+
+var icalDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration đọc một DURATION của RFC 5545, vd -PT15M hoặc P1W.
+func parseDuration(s string) (time.Duration, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	m := icalDuration.FindStringSubmatch(value)
+	if m == nil || strings.HasSuffix(value, "T") {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	found := false
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d += time.Duration(n) * unit
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+// formatOffset viết khoảng nhắc gọn như người dùng nhập, vd 1h30m thay vì 1h30m0s.
+func formatOffset(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// vtodoToTodo chuyển một VTODO thành Todo để nhập. UID được giữ làm ExternalID
+// để nhập lại thì cập nhật thay vì tạo bản trùng.
+func vtodoToTodo(vtodo *ics.VTodo) (Todo, error) {
+	todo := Todo{ExternalID: strings.TrimSpace(vtodo.Id())}
+	if todo.ExternalID == "" {
+		return todo, errors.New("UID is required")
+	}
+	if p := vtodo.GetProperty(ics.ComponentPropertySummary); p != nil {
+		todo.Title = strings.TrimSpace(p.Value)
+	}
+	if todo.Title == "" {
+		return todo, errors.New("SUMMARY is required")
+	}
+	if p := vtodo.GetProperty(ics.ComponentPropertyDescription); p != nil {
+		todo.Desc = p.Value
+	}
+	if p := vtodo.GetProperty(ics.ComponentPropertyStatus); p != nil {
+		todo.Done = strings.EqualFold(p.Value, string(ics.ObjectStatusCompleted))
+	}
+	if vtodo.HasProperty(ics.ComponentPropertyCompleted) {
+		todo.Done = true
+	}
+	if vtodo.HasProperty(ics.ComponentPropertyDue) {
+		due, err := vtodo.GetDueAt()
+		if err != nil {
+			return todo, fmt.Errorf("invalid DUE: %w", err)
+		}
+		due = due.UTC()
+		todo.DueAt = &due
+	}
+	if p := vtodo.GetProperty(ics.ComponentPropertyRrule); p != nil {
+		todo.Recurrence = p.Value
+	}
+	for _, p := range vtodo.GetProperties(ics.ComponentPropertyCategories) {
+		todo.Tags = append(todo.Tags, splitList(p.Value)...)
+	}
+	if todo.DueAt != nil {
+		for _, alarm := range vtodo.Alarms() {
+			if offset, ok := alarmOffset(alarm, *todo.DueAt); ok {
+				todo.RemindAt = append(todo.RemindAt, formatOffset(offset))
+			}
+		}
+	}
+	return todo, nil
+}
+
+// alarmOffset đổi TRIGGER của VALARM thành khoảng nhắc trước DUE. Chỉ đọc được
+// trigger tương đối với DUE (RELATED=END) và trigger thời điểm tuyệt đối; các
+// trigger khác (tương đối với DTSTART) bị bỏ qua.
+func alarmOffset(alarm *ics.VAlarm, due time.Time) (time.Duration, bool) {
+	p := alarm.GetProperty(ics.ComponentPropertyTrigger)
+	if p == nil {
+		return 0, false
+	}
+	if values := p.ICalParameters[string(ics.ParameterValue)]; len(values) > 0 && strings.EqualFold(values[0], string(ics.ValueDataTypeDateTime)) {
+		t, err := time.Parse("20060102T150405Z", p.Value)
+		if err != nil || t.After(due) {
+			return 0, false
+		}
+		return due.Sub(t), true
+	}
+	if related := p.ICalParameters[string(ics.ParameterRelated)]; len(related) == 0 || !strings.EqualFold(related[0], "END") {
+		return 0, false
+	}
+	d, err := parseDuration(p.Value)
+	if err != nil || d > 0 {
+		return 0, false
+	}
+	return -d, true
+}
+
+type CalendarHandler struct {
+	calendarStore CalendarStore
+	transferStore TransferStore
+}
+
+func NewCalendarHandler(calendarStore CalendarStore, transferStore TransferStore) *CalendarHandler {
+	return &CalendarHandler{calendarStore: calendarStore, transferStore: transferStore}
+}
+
+// @Summary Create a calendar feed
+// @Description Create a secret /calendar.ics?token=... URL for the caller. With list_id the feed contains every todo of that list, otherwise the todos created by the caller.
+// @Tags Calendar
+// @Accept json
+// @Produce json
+// @Param feed body CalendarFeed false "Feed scope"
+// @Success 201 {object} CalendarFeed "Created"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /calendar/feeds [post]
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	var feed CalendarFeed
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&feed); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+			return
+		}
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create calendar feed: " + err.Error()})
+		return
+	}
+	feed = CalendarFeed{Token: hex.EncodeToString(token), Owner: ActorFromContext(ctx), ListID: strings.TrimSpace(feed.ListID)}
+
+	created, err := h.calendarStore.CreateCalendarFeedDB(ctx, feed)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create calendar feed: " + err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary List calendar feeds
+// @Description Retrieve the calendar feeds created by the caller
+// @Tags Calendar
+// @Produce json
+// @Success 200 {array} CalendarFeed "OK"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /calendar/feeds [get]
+func (h *CalendarHandler) ListFeeds(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	feeds, err := h.calendarStore.ListCalendarFeedsDB(ctx, ActorFromContext(ctx))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list calendar feeds: " + err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(feeds)
+}
+
+// @Summary Revoke a calendar feed
+// @Tags Calendar
+// @Produce json
+// @Param id path string true "Feed ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /calendar/feeds/{id} [delete]
+func (h *CalendarHandler) DeleteFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	err := h.calendarStore.DeleteCalendarFeedDB(ctx, ActorFromContext(ctx), idStr)
+	if err != nil {
+		if errors.Is(err, ErrCalendarFeedNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Calendar feed not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete calendar feed: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary iCalendar feed
+// @Description Todos of a calendar feed as RFC 5545 VTODO components. The token is the only credential, so the URL can be given to calendar apps directly.
+// @Tags Calendar
+// @Produce text/calendar
+// @Param token query string true "Feed token"
+// @Success 200 {string} string "VCALENDAR"
+// @Failure 404 {object} ErrorResponse "Feed not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /calendar.ics [get]
+func (h *CalendarHandler) ServeFeed(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	fail := func(status int, message string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		fail(http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+	feed, err := h.calendarStore.GetCalendarFeedByTokenDB(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrCalendarFeedNotFound) {
+			fail(http.StatusNotFound, "Calendar feed not found")
+		} else {
+			fail(http.StatusInternalServerError, "Failed to load calendar feed: "+err.Error())
+		}
+		return
+	}
+
+	todos, err := h.calendarStore.ListCalendarTodosDB(ctx, feed)
+	if err != nil {
+		fail(http.StatusInternalServerError, "Failed to list todos: "+err.Error())
+		return
+	}
+
+	name := "Todos of " + feed.Owner
+	if feed.ListID != "" {
+		name = "Todos in " + feed.ListID
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	writeCalendar(w, name, todos)
+}
+
+// @Summary Import an iCalendar file
+// @Description Create or update todos from the VTODO components of an .ics file. The UID of each VTODO is kept as external_id, so importing the same file again updates the todos instead of duplicating them. VEVENTs and other components are ignored.
+// @Tags Calendar
+// @Accept text/calendar
+// @Produce json
+// @Param list_id query string false "List to put the imported todos in"
+// @Param dry_run query bool false "Validate and count without writing"
+// @Success 200 {object} ImportReport "OK"
+// @Failure 400 {object} ErrorResponse "Invalid calendar"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /calendar/import [post]
+func (h *CalendarHandler) ImportCalendar(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	badRequest := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+
+	q := r.URL.Query()
+	opts := ImportOptions{BatchSize: defaultImportBatchSize}
+	if v := q.Get("dry_run"); v != "" {
+		var err error
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			badRequest(fmt.Errorf("invalid dry_run %q", v))
+			return
+		}
+	}
+
+	cal, err := ics.ParseCalendar(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		badRequest(fmt.Errorf("invalid calendar: %w", err))
+		return
+	}
+
+	vtodos := cal.Todos()
+	var rows []ImportRow
+	var rowErrors []ImportRowError
+	for i, vtodo := range vtodos {
+		todo, err := vtodoToTodo(vtodo)
+		todo.ListID = q.Get("list_id")
+		if err == nil {
+			err = validateTodo(&todo)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: i + 1, ExternalID: todo.ExternalID, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, ImportRow{Row: i + 1, Todo: todo})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	report, err := h.transferStore.ImportTodosDB(ctx, rows, opts)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to import calendar: " + err.Error()})
+		return
+	}
+
+	report.Total = len(vtodos)
+	report.Failed += len(rowErrors)
+	report.Errors = append(report.Errors, rowErrors...)
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCalendarStore struct {
+	mock.Mock
+}
+
+func (m *MockCalendarStore) CreateCalendarFeedDB(ctx context.Context, feed CalendarFeed) (CalendarFeed, error) {
+	args := m.Called(feed)
+	return args.Get(0).(CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarStore) ListCalendarFeedsDB(ctx context.Context, owner string) ([]CalendarFeed, error) {
+	args := m.Called(owner)
+	return args.Get(0).([]CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarStore) DeleteCalendarFeedDB(ctx context.Context, owner, id string) error {
+	args := m.Called(owner, id)
+	return args.Error(0)
+}
+
+func (m *MockCalendarStore) GetCalendarFeedByTokenDB(ctx context.Context, token string) (CalendarFeed, error) {
+	args := m.Called(token)
+	return args.Get(0).(CalendarFeed), args.Error(1)
+}
+
+func (m *MockCalendarStore) ListCalendarTodosDB(ctx context.Context, feed CalendarFeed) ([]Todo, error) {
+	args := m.Called(feed)
+	return args.Get(0).([]Todo), args.Error(1)
+}
+
+func newCalendarRouter(store CalendarStore, transferStore TransferStore) *mux.Router {
+	h := NewCalendarHandler(store, transferStore)
+	router := mux.NewRouter()
+	router.Use(ActorMiddleware)
+	router.HandleFunc("/calendar.ics", h.ServeFeed).Methods("GET")
+	router.HandleFunc("/calendar/feeds", h.CreateFeed).Methods("POST")
+	router.HandleFunc("/calendar/feeds", h.ListFeeds).Methods("GET")
+	router.HandleFunc("/calendar/feeds/{id}", h.DeleteFeed).Methods("DELETE")
+	router.HandleFunc("/calendar/import", h.ImportCalendar).Methods("POST")
+	return router
+}
+
+func TestCreateCalendarFeed(t *testing.T) {
+	store := new(MockCalendarStore)
+	store.On("CreateCalendarFeedDB", mock.MatchedBy(func(feed CalendarFeed) bool {
+		return feed.Owner == "alice" && feed.ListID == "work" && len(feed.Token) == 64
+	})).Return(CalendarFeed{ID: "f1", Token: "secret", Owner: "alice", ListID: "work"}, nil)
+
+	req, _ := http.NewRequest("POST", "/calendar/feeds", strings.NewReader(`{"list_id":"work","owner":"mallory","token":"mine"}`))
+	req.Header.Set(ActorHeader, "alice")
+	rr := httptest.NewRecorder()
+	newCalendarRouter(store, new(MockTransferStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"token":"secret"`)
+	store.AssertExpectations(t)
+}
+
+func TestDeleteCalendarFeed_NotFound(t *testing.T) {
+	store := new(MockCalendarStore)
+	store.On("DeleteCalendarFeedDB", "bob", "f1").Return(ErrCalendarFeedNotFound)
+
+	req, _ := http.NewRequest("DELETE", "/calendar/feeds/f1", nil)
+	req.Header.Set(ActorHeader, "bob")
+	rr := httptest.NewRecorder()
+	newCalendarRouter(store, new(MockTransferStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	store.AssertExpectations(t)
+}
+
+func TestServeCalendarFeed(t *testing.T) {
+	created := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 12, 24, 17, 0, 0, 0, time.UTC)
+	doneAt := time.Date(2024, 12, 2, 9, 30, 0, 0, time.UTC)
+	feed := CalendarFeed{ID: "f1", Token: "secret", Owner: "alice"}
+
+	store := new(MockCalendarStore)
+	store.On("GetCalendarFeedByTokenDB", "secret").Return(feed, nil)
+	store.On("ListCalendarTodosDB", feed).Return([]Todo{
+		{ID: "1", Title: "Buy milk, eggs", Desc: "Line one\nline two", CreatedAt: created, Version: 3,
+			DueAt: &due, RemindAt: []string{"24h", "90m"}, Recurrence: "FREQ=WEEKLY", Tags: []string{"home", "shop"}},
+		{ID: "2", Title: "Call mom", Done: true, DoneAt: &doneAt, CreatedAt: created, ExternalID: "abc@example.com"},
+	}, nil)
+
+	req, _ := http.NewRequest("GET", "/calendar.ics?token=secret", nil)
+	rr := httptest.NewRecorder()
+	newCalendarRouter(store, new(MockTransferStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/calendar; charset=utf-8", rr.Header().Get("Content-Type"))
+	body := "\n" + strings.ReplaceAll(rr.Body.String(), "\r\n", "\n")
+	for _, line := range []string{
+		"BEGIN:VCALENDAR", "BEGIN:VTODO", "UID:1", "SUMMARY:Buy milk\\, eggs", "DESCRIPTION:Line one\\nline two",
+		"STATUS:NEEDS-ACTION", "CREATED:20241201T080000Z", "DUE:20241224T170000Z", "SEQUENCE:3",
+		"RRULE:FREQ=WEEKLY", "CATEGORIES:home", "CATEGORIES:shop",
+		"BEGIN:VALARM", "TRIGGER;RELATED=END:-P1D", "TRIGGER;RELATED=END:-PT1H30M",
+		"UID:abc@example.com", "STATUS:COMPLETED", "COMPLETED:20241202T093000Z",
+	} {
+		assert.Contains(t, body, "\n"+line+"\n")
+	}
+	store.AssertExpectations(t)
+}
+
+func TestServeCalendarFeed_UnknownToken(t *testing.T) {
+	store := new(MockCalendarStore)
+	store.On("GetCalendarFeedByTokenDB", "nope").Return(CalendarFeed{}, ErrCalendarFeedNotFound)
+
+	for _, url := range []string{"/calendar.ics", "/calendar.ics?token=nope"} {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		newCalendarRouter(store, new(MockTransferStore)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code, url)
+	}
+}
+
+const importedCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Tasks//EN\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-1@example.com\r\n" +
+	"DTSTAMP:20241210T120000Z\r\n" +
+	"SUMMARY:Write report\\, draft\r\n" +
+	"DESCRIPTION:Q4 numbers\\nand charts\r\n" +
+	"DUE:20241220T170000Z\r\n" +
+	"CATEGORIES:work,reports\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER;RELATED=END:-PT15M\r\n" +
+	"END:VALARM\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"TRIGGER;VALUE=DATE-TIME:20241219T170000Z\r\n" +
+	"END:VALARM\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:meeting@example.com\r\n" +
+	"DTSTAMP:20241210T120000Z\r\n" +
+	"SUMMARY:Not a todo\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-2@example.com\r\n" +
+	"DTSTAMP:20241210T120000Z\r\n" +
+	"SUMMARY:Pay rent\r\n" +
+	"STATUS:COMPLETED\r\n" +
+	"COMPLETED:20241201T080000Z\r\n" +
+	"END:VTODO\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:task-3@example.com\r\n" +
+	"DTSTAMP:20241210T120000Z\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportCalendar(t *testing.T) {
+	due := time.Date(2024, 12, 20, 17, 0, 0, 0, time.UTC)
+	transferStore := new(MockTransferStore)
+	transferStore.On("ImportTodosDB", mock.MatchedBy(func(rows []ImportRow) bool {
+		if len(rows) != 2 {
+			return false
+		}
+		first, second := rows[0].Todo, rows[1].Todo
+		return rows[0].Row == 1 && first.ExternalID == "task-1@example.com" && first.Title == "Write report, draft" &&
+			first.Desc == "Q4 numbers\nand charts" && first.DueAt != nil && first.DueAt.Equal(due) && !first.Done &&
+			assert.ObjectsAreEqual([]string{"work", "reports"}, first.Tags) &&
+			assert.ObjectsAreEqual([]string{"15m", "24h"}, first.RemindAt) && first.ListID == "inbox" &&
+			rows[1].Row == 2 && second.ExternalID == "task-2@example.com" && second.Done
+	}), ImportOptions{DryRun: true, BatchSize: defaultImportBatchSize}).
+		Return(ImportReport{DryRun: true, Total: 2, Created: 1, Updated: 1, Errors: []ImportRowError{}}, nil)
+
+	req, _ := http.NewRequest("POST", "/calendar/import?list_id=inbox&dry_run=true", bytes.NewBufferString(importedCalendar))
+	req.Header.Set("Content-Type", "text/calendar")
+	rr := httptest.NewRecorder()
+	newCalendarRouter(new(MockCalendarStore), transferStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report ImportReport
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, []ImportRowError{{Row: 3, ExternalID: "task-3@example.com", Error: "SUMMARY is required"}}, report.Errors)
+	transferStore.AssertExpectations(t)
+}
+
+func TestImportCalendar_Invalid(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/calendar/import", strings.NewReader("not a calendar"))
+	rr := httptest.NewRecorder()
+	newCalendarRouter(new(MockCalendarStore), new(MockTransferStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestTriggerDuration(t *testing.T) {
+	for _, tc := range []struct {
+		offset  time.Duration
+		trigger string
+	}{
+		{0, "PT0S"},
+		{15 * time.Minute, "-PT15M"},
+		{90 * time.Minute, "-PT1H30M"},
+		{24 * time.Hour, "-P1D"},
+		{26*time.Hour + 30*time.Second, "-P1DT2H30S"},
+	} {
+		assert.Equal(t, tc.trigger, formatTrigger(tc.offset))
+		d, err := parseDuration(tc.trigger)
+		assert.NoError(t, err)
+		assert.Equal(t, -tc.offset, d)
+	}
+
+	d, err := parseDuration("P1W")
+	assert.NoError(t, err)
+	assert.Equal(t, 7*24*time.Hour, d)
+	for _, bad := range []string{"", "P", "PT", "-P1DT", "15m"} {
+		_, err := parseDuration(bad)
+		assert.Error(t, err, bad)
+	}
+	assert.Equal(t, "24h", formatOffset(24*time.Hour))
+	assert.Equal(t, "1h30m", formatOffset(90*time.Minute))
+}
@@ -0,0 +1,257 @@
+// Package chartrender draws small line and stacked-area charts as standalone
+// SVG documents, light or dark, for embedding in wiki pages and dashboards
+// with a plain <img> tag. Like qrrender and barcoderender it is pure Go and
+// the output is deterministic.
+//
+//	chart := chartrender.Chart{Title: "Burndown", Labels: days, Series: series, Theme: chartrender.Dark}
+//	err := chart.WriteSVG(w)
+package chartrender
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidChart is wrapped by every validation error of WriteSVG.
+var ErrInvalidChart = errors.New("invalid chart")
+
+// Theme is the color scheme of a chart.
+type Theme struct {
+	Name       string
+	Background string
+	Text       string
+	// Muted is used for axis labels, Grid for the horizontal grid lines.
+	Muted string
+	Grid  string
+	// Palette colors the series in order, wrapping around.
+	Palette []string
+}
+
+// Light suits white pages, Dark suits dark wiki and dashboard themes. The
+// palettes keep the same hue order so a series has the same meaning in both.
+var (
+	Light = Theme{
+		Name: "light", Background: "#ffffff", Text: "#1f2328", Muted: "#59636e", Grid: "#d1d9e0",
+		Palette: []string{"#0969da", "#1a7f37", "#bf8700", "#cf222e", "#8250df"},
+	}
+	Dark = Theme{
+		Name: "dark", Background: "#0d1117", Text: "#f0f6fc", Muted: "#9198a1", Grid: "#3d444d",
+		Palette: []string{"#4493f8", "#3fb950", "#d29922", "#f85149", "#ab7df8"},
+	}
+)
+
+// ParseTheme reads "light" or "dark"; an empty string is Light.
+func ParseTheme(s string) (Theme, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "light":
+		return Light, nil
+	case "dark":
+		return Dark, nil
+	}
+	return Light, fmt.Errorf("%w: theme must be light or dark", ErrInvalidChart)
+}
+
+// Kind is how the series are drawn.
+type Kind int
+
+const (
+	// Lines draws each series as its own line; it is the default.
+	Lines Kind = iota
+	// StackedArea stacks the series on top of each other, the first at the
+	// bottom, as in a cumulative flow diagram.
+	StackedArea
+)
+
+// Series is one named row of values, one per label.
+type Series struct {
+	Name   string
+	Values []float64
+	// Dashed draws a Lines series dashed, e.g. for a target or ideal line.
+	Dashed bool
+}
+
+// Size limits of a chart in pixels.
+const (
+	MinWidth  = 320
+	MaxWidth  = 2400
+	MinHeight = 200
+	MaxHeight = 1600
+)
+
+// DefaultWidth and DefaultHeight fit a wiki column.
+const (
+	DefaultWidth  = 800
+	DefaultHeight = 400
+)
+
+// Chart is a chart ready to be drawn.
+type Chart struct {
+	Title string
+	Kind  Kind
+	// Labels are the x-axis labels, one per point; at most about ten are
+	// shown when there are many.
+	Labels []string
+	Series []Series
+	// Width and Height are in pixels; zero means the default.
+	Width  int
+	Height int
+	// Theme is Light when its Name is empty.
+	Theme Theme
+}
+
+// Lề của vùng vẽ, tính bằng pixel.
+const (
+	marginLeft   = 56
+	marginRight  = 24
+	marginTop    = 44
+	marginBottom = 64
+	maxXLabels   = 10
+	yTicks       = 5
+)
+
+// WriteSVG writes the chart as an SVG document.
+func (c Chart) WriteSVG(w io.Writer) error {
+	if c.Width == 0 {
+		c.Width = DefaultWidth
+	}
+	if c.Height == 0 {
+		c.Height = DefaultHeight
+	}
+	if c.Width < MinWidth || c.Width > MaxWidth || c.Height < MinHeight || c.Height > MaxHeight {
+		return fmt.Errorf("%w: size must be between %dx%d and %dx%d", ErrInvalidChart, MinWidth, MinHeight, MaxWidth, MaxHeight)
+	}
+	if c.Theme.Name == "" {
+		c.Theme = Light
+	}
+	if len(c.Labels) == 0 || len(c.Series) == 0 {
+		return fmt.Errorf("%w: a chart needs labels and series", ErrInvalidChart)
+	}
+	for _, s := range c.Series {
+		if len(s.Values) != len(c.Labels) {
+			return fmt.Errorf("%w: series %q has %d values for %d labels", ErrInvalidChart, s.Name, len(s.Values), len(c.Labels))
+		}
+	}
+
+	// tops[k][i] là đỉnh của series k tại điểm i; với biểu đồ chồng là tổng
+	// cộng dồn từ series đầu.
+	tops := make([][]float64, len(c.Series))
+	maxValue := 0.0
+	for k, s := range c.Series {
+		tops[k] = make([]float64, len(s.Values))
+		for i, v := range s.Values {
+			tops[k][i] = v
+			if c.Kind == StackedArea && k > 0 {
+				tops[k][i] += tops[k-1][i]
+			}
+			maxValue = math.Max(maxValue, tops[k][i])
+		}
+	}
+	step := niceStep(maxValue / yTicks)
+	yMax := math.Max(step, math.Ceil(maxValue/step)*step)
+
+	plotW := float64(c.Width - marginLeft - marginRight)
+	plotH := float64(c.Height - marginTop - marginBottom)
+	x := func(i int) float64 {
+		if len(c.Labels) == 1 {
+			return marginLeft + plotW/2
+		}
+		return marginLeft + plotW*float64(i)/float64(len(c.Labels)-1)
+	}
+	y := func(v float64) float64 {
+		return marginTop + plotH*(1-v/yMax)
+	}
+
+	var b strings.Builder
+	title := html.EscapeString(c.Title)
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%s" font-family="-apple-system, 'Segoe UI', Helvetica, Arial, sans-serif" font-size="12">`+"\n",
+		c.Width, c.Height, c.Width, c.Height, title)
+	fmt.Fprintf(&b, "<title>%s</title>\n", title)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", c.Width, c.Height, c.Theme.Background)
+	fmt.Fprintf(&b, `<text x="%d" y="26" fill="%s" font-size="16" font-weight="600">%s</text>`+"\n", marginLeft, c.Theme.Text, title)
+
+	// Lưới ngang và nhãn trục y.
+	for v := 0.0; v <= yMax+step/2; v += step {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%s" x2="%d" y2="%s" stroke="%s" stroke-width="1"/>`+"\n",
+			marginLeft, num(y(v)), c.Width-marginRight, num(y(v)), c.Theme.Grid)
+		fmt.Fprintf(&b, `<text x="%d" y="%s" fill="%s" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n",
+			marginLeft-8, num(y(v)), c.Theme.Muted, num(v))
+	}
+	// Nhãn trục x, thưa bớt khi nhiều điểm; điểm cuối luôn có nhãn.
+	every := (len(c.Labels) + maxXLabels - 1) / maxXLabels
+	last := len(c.Labels) - 1
+	for i, label := range c.Labels {
+		// Bỏ nhãn quá gần nhãn cuối để chữ không đè nhau.
+		if i != 0 && i != last && (i%every != 0 || last-i < every) {
+			continue
+		}
+		fmt.Fprintf(&b, `<text x="%s" y="%s" fill="%s" text-anchor="middle">%s</text>`+"\n",
+			num(x(i)), num(marginTop+plotH+18), c.Theme.Muted, html.EscapeString(label))
+	}
+
+	for k, s := range c.Series {
+		color := c.Theme.Palette[k%len(c.Theme.Palette)]
+		var points strings.Builder
+		for i := range s.Values {
+			fmt.Fprintf(&points, "%s,%s ", num(x(i)), num(y(tops[k][i])))
+		}
+		if c.Kind == StackedArea {
+			// Đáy của vùng là đỉnh của series bên dưới, đi ngược lại.
+			for i := len(s.Values) - 1; i >= 0; i-- {
+				bottom := 0.0
+				if k > 0 {
+					bottom = tops[k-1][i]
+				}
+				fmt.Fprintf(&points, "%s,%s ", num(x(i)), num(y(bottom)))
+			}
+			fmt.Fprintf(&b, `<polygon fill="%s" fill-opacity="0.8" stroke="%s" stroke-width="1" points="%s"/>`+"\n",
+				color, color, strings.TrimSpace(points.String()))
+			continue
+		}
+		dash := ""
+		if s.Dashed {
+			dash = ` stroke-dasharray="6 4"`
+		}
+		fmt.Fprintf(&b, `<polyline fill="none" stroke="%s" stroke-width="2"%s stroke-linejoin="round" points="%s"/>`+"\n",
+			color, dash, strings.TrimSpace(points.String()))
+	}
+
+	// Chú thích dưới trục x.
+	legendX := float64(marginLeft)
+	legendY := float64(c.Height - 18)
+	for k, s := range c.Series {
+		color := c.Theme.Palette[k%len(c.Theme.Palette)]
+		fmt.Fprintf(&b, `<rect x="%s" y="%s" width="12" height="12" rx="2" fill="%s"/>`+"\n", num(legendX), num(legendY-10), color)
+		fmt.Fprintf(&b, `<text x="%s" y="%s" fill="%s">%s</text>`+"\n", num(legendX+18), num(legendY), c.Theme.Text, html.EscapeString(s.Name))
+		// Ước lượng độ rộng chữ để xếp mục kế tiếp, 7px mỗi ký tự.
+		legendX += 18 + 7*float64(len([]rune(s.Name))) + 20
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// niceStep làm tròn khoảng chia trục lên 1, 2 hoặc 5 nhân lũy thừa của 10,
+// tối thiểu 1 vì số liệu là số todo.
+func niceStep(raw float64) float64 {
+	if raw <= 1 {
+		return 1
+	}
+	exp := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*exp {
+			return m * exp
+		}
+	}
+	return 10 * exp
+}
+
+// num in tọa độ gọn: tối đa một chữ số thập phân, bỏ ".0".
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
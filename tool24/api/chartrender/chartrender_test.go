@@ -0,0 +1,124 @@
+package chartrender
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "ghi lại các file golden trong testdata")
+
+func days(n int) []string {
+	labels := make([]string, n)
+	for i := range labels {
+		labels[i] = "12-" + string(rune('0'+(i+1)/10)) + string(rune('0'+(i+1)%10))
+	}
+	return labels
+}
+
+var goldenCases = []struct {
+	name  string
+	chart Chart
+}{
+	{"lines-light", Chart{
+		Title:  "Burndown · kho",
+		Labels: days(5),
+		Series: []Series{
+			{Name: "Remaining", Values: []float64{8, 7, 7, 4, 2}},
+			{Name: "Ideal", Values: []float64{8, 6, 4, 2, 0}, Dashed: true},
+		},
+	}},
+	{"stacked-dark", Chart{
+		Title:  "Cumulative flow <all>",
+		Kind:   StackedArea,
+		Labels: days(14),
+		Series: []Series{
+			{Name: "Done", Values: []float64{0, 1, 1, 2, 4, 4, 5, 7, 9, 10, 10, 12, 15, 18}},
+			{Name: "Open", Values: []float64{5, 6, 8, 8, 7, 9, 9, 8, 9, 9, 11, 10, 8, 6}},
+		},
+		Width:  640,
+		Height: 320,
+		Theme:  Dark,
+	}},
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err, "chạy go test ./chartrender -update để tạo file golden") {
+		assert.True(t, bytes.Equal(want, got), "%s khác file golden", name)
+	}
+}
+
+func TestGoldenSVG(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, tc.chart.WriteSVG(&buf))
+			checkGolden(t, tc.name+".svg", buf.Bytes())
+		})
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, goldenCases[1].chart.WriteSVG(&buf))
+	svg := buf.String()
+	assert.Contains(t, svg, `<title>Cumulative flow &lt;all&gt;</title>`)
+	assert.Contains(t, svg, `fill="#0d1117"`)
+	assert.Equal(t, 2, strings.Count(svg, "<polygon"))
+	// Trục y làm tròn lên 25 với bước 5: đỉnh chồng là 24.
+	assert.Contains(t, svg, `dominant-baseline="middle">25</text>`)
+	assert.NotContains(t, svg, `dominant-baseline="middle">30</text>`)
+	// 14 nhãn được thưa còn 12-01, 12-03, ..., 12-11 và 12-14.
+	assert.Contains(t, svg, ">12-11</text>")
+	assert.NotContains(t, svg, ">12-12</text>")
+	assert.NotContains(t, svg, ">12-13</text>")
+	assert.Contains(t, svg, ">12-14</text>")
+
+	buf.Reset()
+	assert.NoError(t, Chart{Title: "One", Labels: []string{"12-01"}, Series: []Series{{Name: "Open", Values: []float64{0}}}}.WriteSVG(&buf))
+	assert.Contains(t, buf.String(), `width="800" height="400"`)
+	assert.Contains(t, buf.String(), `fill="#ffffff"`)
+}
+
+func TestWriteSVG_Invalid(t *testing.T) {
+	valid := goldenCases[0].chart
+	for name, chart := range map[string]Chart{
+		"no labels": {Series: valid.Series},
+		"no series": {Labels: valid.Labels},
+		"mismatch":  {Labels: valid.Labels[:3], Series: valid.Series},
+		"too small": {Labels: valid.Labels, Series: valid.Series, Width: 100},
+		"too large": {Labels: valid.Labels, Series: valid.Series, Height: MaxHeight + 1},
+	} {
+		err := chart.WriteSVG(&bytes.Buffer{})
+		assert.True(t, errors.Is(err, ErrInvalidChart), "%s: %v", name, err)
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	for raw, want := range map[float64]float64{0: 1, 0.4: 1, 1.5: 2, 3: 5, 4.8: 5, 7: 10, 13: 20, 420: 500} {
+		assert.Equal(t, want, niceStep(raw), "%v", raw)
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	for in, want := range map[string]string{"": "light", "Light": "light", "DARK": "dark"} {
+		theme, err := ParseTheme(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, theme.Name, in)
+	}
+	_, err := ParseTheme("solarized")
+	assert.True(t, errors.Is(err, ErrInvalidChart))
+}
@@ -0,0 +1,262 @@
+// Package client is a typed Go client for the Todo API.
+//
+//	c, err := client.New("http://localhost:8080", client.WithAuth(client.Actor("alice")))
+//	todo, err := c.Create(ctx, client.Todo{Title: "Buy milk"})
+//	for todo, err := range c.All(ctx, client.ListOptions{Done: client.Bool(false)}) { ... }
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the Todo REST API. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient, e.g. to set timeouts or a proxy.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithAuth sets how each request is authenticated.
+func WithAuth(auth Authenticator) Option {
+	return func(c *Client) { c.auth = auth }
+}
+
+// WithRetry replaces DefaultRetryPolicy. Use RetryPolicy{MaxAttempts: 1} to
+// disable retries.
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New returns a Client for the API served at baseURL, e.g.
+// "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{baseURL: u, httpClient: http.DefaultClient, retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Authenticator adds credentials to an outgoing request.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// AuthFunc adapts a function to Authenticator.
+type AuthFunc func(req *http.Request) error
+
+func (f AuthFunc) Authenticate(req *http.Request) error { return f(req) }
+
+// BearerToken sends "Authorization: Bearer <token>".
+func BearerToken(token string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// BasicAuth sends HTTP basic credentials.
+func BasicAuth(username, password string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.SetBasicAuth(username, password)
+		return nil
+	})
+}
+
+// Actor sends the X-Actor header the server records in the audit log.
+func Actor(name string) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		req.Header.Set("X-Actor", name)
+		return nil
+	})
+}
+
+// Chain applies several authenticators in order, e.g. a token and an actor.
+func Chain(auths ...Authenticator) Authenticator {
+	return AuthFunc(func(req *http.Request) error {
+		for _, a := range auths {
+			if err := a.Authenticate(req); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RetryPolicy controls retries of idempotent requests (GET, PUT, DELETE) after
+// network errors and 429, 502, 503 or 504 responses. Create and ToggleStatus
+// are never retried because repeating them is not safe.
+type RetryPolicy struct {
+	// MaxAttempts counts the first try; values below 1 mean 1.
+	MaxAttempts int
+	// The wait before retry n is a random duration in [0, min(MaxDelay, BaseDelay*2^n)).
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+
+// backoff trả về thời gian chờ trước lần thử lại thứ attempt (bắt đầu từ 1),
+// dùng full jitter để các client không thử lại cùng lúc.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay << uint(attempt-1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// do gửi một request, thử lại nếu method là idempotent, và giải mã body JSON
+// vào out khi out khác nil. Trả về header của response cuối cùng.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	attempts := 1
+	if idempotent(method) && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		header, retry, err := c.attempt(ctx, method, path, query, payload, out)
+		if err == nil || !retry || attempt >= attempts {
+			return header, err
+		}
+
+		wait := c.retry.backoff(attempt)
+		if after := retryAfter(header); after > wait {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// newRequest tạo request tới path dưới baseURL và gắn thông tin xác thực.
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Request, error) {
+	u := *c.baseURL
+	u.Path += path
+	u.RawQuery = query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+func (c *Client) attempt(ctx context.Context, method, path string, query url.Values, payload []byte, out interface{}) (http.Header, bool, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Lỗi mạng có thể thử lại, trừ khi chính ctx đã hết hạn.
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return resp.Header, retryableStatus(resp.StatusCode), newAPIError(req, resp)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.Header, false, fmt.Errorf("decode %s %s: %w", method, req.URL.Path, err)
+		}
+	}
+	return resp.Header, false, nil
+}
+
+// stream gửi một request không thử lại và trả về response để người gọi đọc
+// body dạng luồng; người gọi phải đóng body.
+func (c *Client) stream(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		return nil, newAPIError(req, resp)
+	}
+	return resp, nil
+}
+
+func retryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
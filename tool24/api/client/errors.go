@@ -0,0 +1,48 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// APIError is returned when the server answers with a 4xx or 5xx status.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	// Message is the "error" field of the response body, or the raw body.
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+func newAPIError(req *http.Request, resp *http.Response) *APIError {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error string `json:"error"`
+	}
+	msg := string(raw)
+	if json.Unmarshal(raw, &body) == nil && body.Error != "" {
+		msg = body.Error
+	}
+	return &APIError{StatusCode: resp.StatusCode, Method: req.Method, Path: req.URL.Path, Message: msg}
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool { return hasStatus(err, http.StatusNotFound) }
+
+// IsConflict reports whether err is a 409, i.e. Todo.Version was stale.
+func IsConflict(err error) bool { return hasStatus(err, http.StatusConflict) }
+
+// IsBadRequest reports whether the server rejected the input with a 400.
+func IsBadRequest(err error) bool { return hasStatus(err, http.StatusBadRequest) }
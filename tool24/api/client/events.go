@@ -0,0 +1,104 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event types sent by Watch. EventReset means events were missed and the
+// caller should reload its todos.
+const (
+	EventCreate = "create"
+	EventUpdate = "update"
+	EventStatus = "status"
+	EventDelete = "delete"
+	EventReset  = "reset"
+)
+
+// Event is one change from GET /todos/events. Todo is the state after the
+// change; it is zero for permanent deletes, where only the ID is known to the
+// server's audit log.
+type Event struct {
+	ID    int64     `json:"id"`
+	Type  string    `json:"type"`
+	Actor string    `json:"actor"`
+	At    time.Time `json:"at"`
+	Todo  Todo      `json:"todo"`
+}
+
+// Watch streams changes from GET /todos/events, starting after lastEventID
+// (0 for only new changes). The sequence ends with an error when the
+// connection drops; reconnect with the ID of the last event received.
+func (c *Client) Watch(ctx context.Context, lastEventID int64) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		req, err := c.newRequest(ctx, http.MethodGet, "/todos/events", nil, nil)
+		if err != nil {
+			yield(Event{}, err)
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		if lastEventID > 0 {
+			req.Header.Set("Last-Event-ID", strconv.FormatInt(lastEventID, 10))
+		}
+
+		resp, err := c.stream(req)
+		if err != nil {
+			yield(Event{}, err)
+			return
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			yield(Event{}, fmt.Errorf("GET %s: unexpected content type %q", req.URL.Path, ct))
+			return
+		}
+
+		// Đọc theo định dạng SSE: các dòng "field: value", kết thúc sự kiện bằng dòng trống.
+		var eventType, data string
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if line != "" {
+				field, value, _ := strings.Cut(line, ":")
+				value = strings.TrimPrefix(value, " ")
+				switch field {
+				case "event":
+					eventType = value
+				case "data":
+					data += value
+				}
+				continue
+			}
+
+			switch {
+			case eventType == EventReset:
+				if !yield(Event{Type: EventReset}, nil) {
+					return
+				}
+			case eventType != "" && data != "":
+				var ev Event
+				if err := json.Unmarshal([]byte(data), &ev); err != nil {
+					yield(Event{}, fmt.Errorf("decode event: %w", err))
+					return
+				}
+				if !yield(ev, nil) {
+					return
+				}
+			}
+			eventType, data = "", ""
+		}
+
+		err = scanner.Err()
+		if err == nil {
+			err = fmt.Errorf("GET %s: stream closed", req.URL.Path)
+		}
+		yield(Event{}, err)
+	}
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Todo mirrors the JSON representation served by the API.
+type Todo struct {
+	ID          string     `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DoneAt      *time.Time `json:"done_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	// Version is the revision last seen. Update fails with a conflict
+	// (see IsConflict) when it is stale; 0 skips the check.
+	Version    int        `json:"version"`
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	SeriesID   string     `json:"series_id,omitempty"`
+	PreviousID string     `json:"previous_id,omitempty"`
+	RemindAt   []string   `json:"remind_at,omitempty"`
+	ListID     string     `json:"list_id,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	// ExternalID identifies the todo in the system it was imported from.
+	ExternalID string `json:"external_id,omitempty"`
+	// Payment is the amount to collect for the todo, if any. Update replaces
+	// the whole todo, so keep it from Get to leave the payment unchanged.
+	Payment *Payment `json:"payment,omitempty"`
+	// ShortCode is the scannable code the server assigns, e.g. T000042.
+	ShortCode string `json:"short_code,omitempty"`
+}
+
+// Payment is a bank transfer to collect, rendered by the server as a VietQR
+// code.
+type Payment struct {
+	// BankBIN is the 6-digit NAPAS BIN of the beneficiary bank.
+	BankBIN       string `json:"bank_bin"`
+	AccountNumber string `json:"account_number"`
+	// Amount in VND; 0 lets the payer enter the amount.
+	Amount int64 `json:"amount,omitempty"`
+	// Memo is the transfer note; the server defaults it from the todo ID.
+	Memo string `json:"memo,omitempty"`
+}
+
+// ListOptions filters and pages List. The zero value lists everything in one
+// page.
+type ListOptions struct {
+	Done      *bool
+	ListID    string
+	Tag       string
+	Search    string
+	DueBefore time.Time
+	// Limit is the page size, 1 to 100; 0 means no limit.
+	Limit int
+	// After is the NextCursor of the previous page.
+	After string
+}
+
+// Bool returns a pointer to b, for ListOptions.Done.
+func Bool(b bool) *bool { return &b }
+
+func (o ListOptions) values() url.Values {
+	q := url.Values{}
+	if o.Done != nil {
+		q.Set("done", strconv.FormatBool(*o.Done))
+	}
+	if o.ListID != "" {
+		q.Set("list", o.ListID)
+	}
+	if o.Tag != "" {
+		q.Set("tag", o.Tag)
+	}
+	if o.Search != "" {
+		q.Set("q", o.Search)
+	}
+	if !o.DueBefore.IsZero() {
+		q.Set("due_before", o.DueBefore.Format(time.RFC3339))
+	}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.After != "" {
+		q.Set("after", o.After)
+	}
+	return q
+}
+
+// Page is one page of List.
+type Page struct {
+	Todos []Todo
+	// Total is the number of todos matching the filter across all pages.
+	Total int
+	// NextCursor is empty on the last page.
+	NextCursor string
+}
+
+// List returns one page of todos (GET /todos).
+func (c *Client) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	var todos []Todo
+	header, err := c.do(ctx, http.MethodGet, "/todos", opts.values(), nil, &todos)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Todos: todos, Total: len(todos), NextCursor: header.Get("X-Next-Cursor")}
+	if total, err := strconv.Atoi(header.Get("X-Total-Count")); err == nil {
+		page.Total = total
+	}
+	return page, nil
+}
+
+// All iterates over every todo matching opts, fetching pages of opts.Limit
+// (default 50) as the loop advances. Iteration stops after the first error.
+func (c *Client) All(ctx context.Context, opts ListOptions) iter.Seq2[Todo, error] {
+	return func(yield func(Todo, error) bool) {
+		if opts.Limit == 0 {
+			opts.Limit = 50
+		}
+		for {
+			page, err := c.List(ctx, opts)
+			if err != nil {
+				yield(Todo{}, err)
+				return
+			}
+			for _, todo := range page.Todos {
+				if !yield(todo, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" {
+				return
+			}
+			opts.After = page.NextCursor
+		}
+	}
+}
+
+// Get returns the todo with id (GET /todo/{id}).
+func (c *Client) Get(ctx context.Context, id string) (Todo, error) {
+	var todo Todo
+	_, err := c.do(ctx, http.MethodGet, "/todo/"+url.PathEscape(id), nil, nil, &todo)
+	return todo, err
+}
+
+// Create adds a todo (POST /todo) and returns it as stored by the server.
+func (c *Client) Create(ctx context.Context, todo Todo) (Todo, error) {
+	var created Todo
+	_, err := c.do(ctx, http.MethodPost, "/todo", nil, todo, &created)
+	return created, err
+}
+
+// Update replaces the todo with id (PUT /todo/{id}).
+func (c *Client) Update(ctx context.Context, id string, todo Todo) (Todo, error) {
+	var updated Todo
+	_, err := c.do(ctx, http.MethodPut, "/todo/"+url.PathEscape(id), nil, todo, &updated)
+	return updated, err
+}
+
+// Delete moves the todo with id to the trash (DELETE /todo/{id}).
+func (c *Client) Delete(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/todo/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ToggleStatus flips Done on the todo with id (POST /todo/changeStatus/{id}).
+func (c *Client) ToggleStatus(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/todo/changeStatus/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
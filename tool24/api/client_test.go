@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"api/client"
+	"api/vietqr"
+)
+
+// routerStore cho phép chạy NewRouter với MockTodoStore; các store khác
+// không được dùng trong các test này.
+type routerStore struct {
+	*MockTodoStore
+	TrashStore
+	AuditStore
+	RevisionStore
+	RecurrenceStore
+	WebhookStore
+	GraphQLStore
+	TransferStore
+	CalendarStore
+	PaymentStore
+	PrintStore
+	ScanStore
+	StatsStore
+}
+
+func newTestClient(t *testing.T, store *MockTodoStore) *client.Client {
+	return newTestClientWithBroker(t, store, NewEventBroker(10))
+}
+
+func newTestClientWithBroker(t *testing.T, store *MockTodoStore, broker *EventBroker) *client.Client {
+	return newTestClientWithStore(t, routerStore{MockTodoStore: store}, broker)
+}
+
+func newTestClientWithStore(t *testing.T, store routerStore, broker *EventBroker) *client.Client {
+	srv := httptest.NewServer(NewRouter(store, broker, vietqr.NewRegistry(vietqr.DefaultDirectory()), "", nil, time.Minute, false))
+	t.Cleanup(srv.Close)
+
+	c, err := client.New(srv.URL, client.WithAuth(client.Actor("alice")))
+	assert.NoError(t, err)
+	return c
+}
+
+func TestClientCRUD(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("CreateTodoDB", mock.MatchedBy(func(todo Todo) bool { return todo.Title == "Buy milk" })).
+		Return(Todo{ID: "1", Title: "Buy milk", Version: 1}, nil)
+	mockStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "Buy milk", Version: 1}, nil)
+	mockStore.On("UpdateTodoDB", "1", mock.MatchedBy(func(todo Todo) bool { return todo.Title == "Buy oat milk" && todo.Version == 1 })).
+		Return(Todo{ID: "1", Title: "Buy oat milk", Version: 2}, nil)
+	mockStore.On("ChangeStatusDB", "1").Return(nil)
+	mockStore.On("DeleteTodoByIdDB", "1").Return(nil)
+	c := newTestClient(t, mockStore)
+	ctx := context.Background()
+
+	created, err := c.Create(ctx, client.Todo{Title: "Buy milk"})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", created.ID)
+
+	got, err := c.Get(ctx, "1")
+	assert.NoError(t, err)
+	got.Title = "Buy oat milk"
+	updated, err := c.Update(ctx, "1", got)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	assert.NoError(t, c.ToggleStatus(ctx, "1"))
+	assert.NoError(t, c.Delete(ctx, "1"))
+	mockStore.AssertExpectations(t)
+}
+
+// Update gửi lại cả todo nên payment đọc từ Get phải đi theo, nếu không PUT sẽ xóa nó.
+func TestClientUpdateKeepsPayment(t *testing.T) {
+	payment := &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "Thu tiền nhà", Version: 1, Payment: payment}, nil)
+	mockStore.On("UpdateTodoDB", "1", mock.MatchedBy(func(todo Todo) bool {
+		return todo.Title == "Thu tiền nhà tháng 12" && assert.ObjectsAreEqual(payment, todo.Payment)
+	})).Return(Todo{ID: "1", Title: "Thu tiền nhà tháng 12", Version: 2, Payment: payment}, nil)
+	c := newTestClient(t, mockStore)
+	ctx := context.Background()
+
+	got, err := c.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, &client.Payment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}, got.Payment)
+	got.Title = "Thu tiền nhà tháng 12"
+	updated, err := c.Update(ctx, "1", got)
+	assert.NoError(t, err)
+	assert.Equal(t, got.Payment, updated.Payment)
+	mockStore.AssertExpectations(t)
+}
+
+func TestClientTypedErrors(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetTodoByIdDB", "404").Return(Todo{}, ErrTodoNotFound)
+	mockStore.On("UpdateTodoDB", "1", mock.Anything).Return(Todo{}, ErrVersionConflict)
+	c := newTestClient(t, mockStore)
+
+	_, err := c.Get(context.Background(), "404")
+	assert.True(t, client.IsNotFound(err))
+
+	_, err = c.Update(context.Background(), "1", client.Todo{Title: "A", Version: 3})
+	assert.True(t, client.IsConflict(err))
+	assert.False(t, client.IsNotFound(err))
+}
+
+func TestClientAllFollowsCursors(t *testing.T) {
+	base := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	var todos []Todo
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		todos = append(todos, Todo{ID: id, Title: id, Done: i == 2, CreatedAt: base.Add(time.Duration(i) * time.Hour)})
+	}
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return(todos, nil)
+	c := newTestClient(t, mockStore)
+
+	var ids []string
+	for todo, err := range c.All(context.Background(), client.ListOptions{Done: client.Bool(false), Limit: 2}) {
+		assert.NoError(t, err)
+		ids = append(ids, todo.ID)
+	}
+
+	assert.Equal(t, []string{"a", "b", "d", "e"}, ids)
+	mockStore.AssertNumberOfCalls(t, "GetAllTodoDB", 2)
+}
+
+// Todo cuối trang bị xóa hoặc không còn khớp bộ lọc giữa hai trang: cursor
+// vẫn định vị được theo created_at nên All không dừng sớm.
+func TestClientAllSurvivesChangesBetweenPages(t *testing.T) {
+	base := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	var todos []Todo
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		todos = append(todos, Todo{ID: id, Title: id, CreatedAt: base.Add(time.Duration(i) * time.Hour)})
+	}
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return(append([]Todo(nil), todos...), nil).Once()
+	// Trước trang thứ hai, b bị xóa và c được đánh dấu xong.
+	changed := []Todo{todos[0], todos[2], todos[3], todos[4]}
+	changed[1].Done = true
+	mockStore.On("GetAllTodoDB").Return(changed, nil).Once()
+	mockStore.On("GetAllTodoDB").Return(changed, nil)
+	c := newTestClient(t, mockStore)
+
+	var ids []string
+	for todo, err := range c.All(context.Background(), client.ListOptions{Done: client.Bool(false), Limit: 2}) {
+		assert.NoError(t, err)
+		ids = append(ids, todo.ID)
+	}
+
+	assert.Equal(t, []string{"a", "b", "d", "e"}, ids)
+}
+
+func TestParseTodoQuery(t *testing.T) {
+	q, _ := url.ParseQuery("done=false&tag=home&due_before=2024-12-24T00:00:00Z&limit=10")
+	filter, after, limit, err := parseTodoQuery(q)
+	assert.NoError(t, err)
+	assert.Nil(t, after)
+	assert.Equal(t, 10, limit)
+	assert.Equal(t, time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC), *filter.DueBefore)
+
+	due := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	assert.True(t, filter.matches(Todo{Tags: []string{"home"}, DueAt: &due}))
+	assert.False(t, filter.matches(Todo{Tags: []string{"home"}}), "todos without a due date are not due before anything")
+
+	for _, query := range []string{"done=maybe", "due_before=tomorrow", "limit=0", "after=bogus"} {
+		q, _ := url.ParseQuery(query)
+		_, _, _, err := parseTodoQuery(q)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	todo := Todo{ID: "7f0c", CreatedAt: time.Date(2024, 12, 1, 9, 30, 0, 123456000, time.UTC)}
+	cursor, err := decodeCursor(encodeCursor(todo))
+	assert.NoError(t, err)
+	assert.True(t, todo.CreatedAt.Equal(cursor.CreatedAt))
+	assert.Equal(t, "7f0c", cursor.ID)
+
+	for _, invalid := range []string{"", "not base64!", "dG9kbzph" /* todo:a */} {
+		_, err := decodeCursor(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestClientListReportsTotal(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return([]Todo{{ID: "1", Tags: []string{"home"}}, {ID: "2"}, {ID: "3", Tags: []string{"home"}}}, nil)
+	c := newTestClient(t, mockStore)
+
+	page, err := c.List(context.Background(), client.ListOptions{Tag: "home", Limit: 1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, page.Total)
+	assert.Len(t, page.Todos, 1)
+	assert.NotEmpty(t, page.NextCursor)
+}
+
+func TestClientWatchResumesFromLastEventID(t *testing.T) {
+	broker := NewEventBroker(10)
+	for i := int64(1); i <= 3; i++ {
+		broker.Publish(TodoEvent{ID: i, Type: StreamUpdate, Actor: "bob", Todo: Todo{ID: fmt.Sprint(i), Title: "T", CreatedAt: time.Now()}})
+	}
+	c := newTestClientWithBroker(t, new(MockTodoStore), broker)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got []client.Event
+	for ev, err := range c.Watch(ctx, 1) {
+		assert.NoError(t, err)
+		got = append(got, ev)
+		if len(got) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, int64(2), got[0].ID)
+	assert.Equal(t, "3", got[1].Todo.ID)
+	assert.Equal(t, "bob", got[1].Actor)
+}
+
+func TestClientWatchReportsReset(t *testing.T) {
+	broker := NewEventBroker(10)
+	broker.Publish(TodoEvent{ID: 5, Type: StreamCreate, Todo: Todo{ID: "5"}})
+	c := newTestClientWithBroker(t, new(MockTodoStore), broker)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for ev, err := range c.Watch(ctx, 2) {
+		assert.NoError(t, err)
+		assert.Equal(t, client.EventReset, ev.Type)
+		break
+	}
+}
+
+func TestClientExportImport(t *testing.T) {
+	transferStore := new(MockTransferStore)
+	transferStore.On("ExportTodosDB").Return([]Todo{{ID: "1", Title: "A"}}, nil)
+	transferStore.On("ImportTodosDB", mock.MatchedBy(func(rows []ImportRow) bool {
+		return len(rows) == 1 && rows[0].Todo.Title == "Buy milk"
+	}), ImportOptions{DryRun: true, BatchSize: 10}).Return(ImportReport{DryRun: true, Created: 1, Errors: []ImportRowError{}}, nil)
+	c := newTestClientWithStore(t, routerStore{TransferStore: transferStore}, NewEventBroker(10))
+
+	var out strings.Builder
+	assert.NoError(t, c.Export(context.Background(), client.FormatNDJSON, &out))
+	assert.Contains(t, out.String(), `"title":"A"`)
+
+	report, err := c.Import(context.Background(), strings.NewReader("Task\nBuy milk\n"), client.ImportOptions{
+		Format: client.FormatCSV, DryRun: true, BatchSize: 10, Mapping: map[string]string{"Task": "title"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Total)
+	assert.Equal(t, 1, report.Created)
+	transferStore.AssertExpectations(t)
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"api/client"
+)
+
+// completeTodoIDs gợi ý ID todo cho shell completion, kèm tiêu đề làm mô tả.
+func (a *app) completeTodoIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if err := a.init(); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var ids []string
+	for todo, err := range a.client.All(cmd.Context(), client.ListOptions{}) {
+		if err != nil {
+			break
+		}
+		if strings.HasPrefix(todo.ID, toComplete) {
+			ids = append(ids, todo.ID+"\t"+todo.Title)
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// parseTime nhận RFC3339 hoặc ngày dạng 2006-01-02 (nửa đêm giờ địa phương).
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, use 2006-01-02 or RFC3339", s)
+	}
+	return t, nil
+}
+
+func splitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeTable(w io.Writer, todos []client.Todo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDONE\tTITLE\tDUE\tLIST\tTAGS")
+	for _, todo := range todos {
+		done := " "
+		if todo.Done {
+			done = "x"
+		}
+		due := ""
+		if todo.DueAt != nil {
+			due = todo.DueAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%s\t[%s]\t%s\t%s\t%s\t%s\n", todo.ID, done, todo.Title, due, todo.ListID, strings.Join(todo.Tags, ","))
+	}
+	return tw.Flush()
+}
+
+func writeTodo(w io.Writer, todo client.Todo) {
+	fmt.Fprintf(w, "ID:          %s\n", todo.ID)
+	fmt.Fprintf(w, "Title:       %s\n", todo.Title)
+	if todo.Description != "" {
+		fmt.Fprintf(w, "Description: %s\n", todo.Description)
+	}
+	fmt.Fprintf(w, "Done:        %t\n", todo.Done)
+	if todo.DueAt != nil {
+		fmt.Fprintf(w, "Due:         %s\n", todo.DueAt.Local().Format(time.RFC3339))
+	}
+	if todo.Recurrence != "" {
+		fmt.Fprintf(w, "Recurrence:  %s\n", todo.Recurrence)
+	}
+	if todo.ListID != "" {
+		fmt.Fprintf(w, "List:        %s\n", todo.ListID)
+	}
+	if len(todo.Tags) > 0 {
+		fmt.Fprintf(w, "Tags:        %s\n", strings.Join(todo.Tags, ", "))
+	}
+	fmt.Fprintf(w, "Created:     %s by %s\n", todo.CreatedAt.Local().Format(time.RFC3339), todo.CreatedBy)
+	fmt.Fprintf(w, "Version:     %d\n", todo.Version)
+}
+
+func newLsCmd(a *app) *cobra.Command {
+	var (
+		opts      client.ListOptions
+		done      bool
+		open      bool
+		dueBefore string
+		output    string
+	)
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Aliases: []string{"list"},
+		Short:   "List todos",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if done && open {
+				return fmt.Errorf("--done and --open are mutually exclusive")
+			}
+			if done || open {
+				opts.Done = client.Bool(done)
+			}
+			if dueBefore != "" {
+				t, err := parseTime(dueBefore)
+				if err != nil {
+					return err
+				}
+				opts.DueBefore = t
+			}
+
+			todos := []client.Todo{}
+			if opts.Limit > 0 {
+				page, err := a.client.List(cmd.Context(), opts)
+				if err != nil {
+					return err
+				}
+				todos = page.Todos
+			} else {
+				for todo, err := range a.client.All(cmd.Context(), opts) {
+					if err != nil {
+						return err
+					}
+					todos = append(todos, todo)
+				}
+			}
+
+			switch output {
+			case "json":
+				return writeJSON(cmd.OutOrStdout(), todos)
+			case "table":
+				return writeTable(cmd.OutOrStdout(), todos)
+			default:
+				return fmt.Errorf("unknown output %q, use table or json", output)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&done, "done", false, "only done todos")
+	cmd.Flags().BoolVar(&open, "open", false, "only open todos")
+	cmd.Flags().StringVar(&opts.ListID, "list", "", "only todos in this list")
+	cmd.Flags().StringVar(&opts.Tag, "tag", "", "only todos with this tag")
+	cmd.Flags().StringVarP(&opts.Search, "search", "q", "", "search title and description")
+	cmd.Flags().StringVar(&dueBefore, "due-before", "", "only todos due before this time")
+	cmd.Flags().IntVarP(&opts.Limit, "limit", "n", 0, "show at most n todos")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: table or json")
+	cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"table", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+func newShowCmd(a *app) *cobra.Command {
+	var output string
+	cmd := &cobra.Command{
+		Use:               "show <id>",
+		Short:             "Show one todo",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			todo, err := a.client.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if output == "json" {
+				return writeJSON(cmd.OutOrStdout(), todo)
+			}
+			writeTodo(cmd.OutOrStdout(), todo)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format: text or json")
+	cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{"text", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	return cmd
+}
+
+// todoFlags là các trường dùng chung cho add và edit.
+type todoFlags struct {
+	title      string
+	desc       string
+	due        string
+	recurrence string
+	list       string
+	tags       string
+	remind     []string
+}
+
+func (f *todoFlags) register(cmd *cobra.Command, withTitle bool) {
+	if withTitle {
+		cmd.Flags().StringVarP(&f.title, "title", "t", "", "title")
+	}
+	cmd.Flags().StringVarP(&f.desc, "desc", "d", "", "description")
+	cmd.Flags().StringVar(&f.due, "due", "", "due time, 2006-01-02 or RFC3339; empty string clears it in edit")
+	cmd.Flags().StringVar(&f.recurrence, "recurrence", "", "RRULE, e.g. FREQ=WEEKLY;BYDAY=MO")
+	cmd.Flags().StringVar(&f.list, "list", "", "list ID")
+	cmd.Flags().StringVar(&f.tags, "tags", "", "comma separated tags")
+	cmd.Flags().StringSliceVar(&f.remind, "remind", nil, "remind this long before due, e.g. 24h,15m")
+}
+
+// apply chép các cờ đã được đặt sang todo, để edit chỉ đổi những gì người dùng nêu.
+func (f *todoFlags) apply(cmd *cobra.Command, todo *client.Todo) error {
+	changed := cmd.Flags().Changed
+	if changed("title") {
+		todo.Title = f.title
+	}
+	if changed("desc") {
+		todo.Description = f.desc
+	}
+	if changed("due") {
+		if f.due == "" {
+			todo.DueAt = nil
+		} else {
+			t, err := parseTime(f.due)
+			if err != nil {
+				return err
+			}
+			todo.DueAt = &t
+		}
+	}
+	if changed("recurrence") {
+		todo.Recurrence = f.recurrence
+	}
+	if changed("list") {
+		todo.ListID = f.list
+	}
+	if changed("tags") {
+		todo.Tags = splitTags(f.tags)
+	}
+	if changed("remind") {
+		todo.RemindAt = f.remind
+	}
+	return nil
+}
+
+func newAddCmd(a *app) *cobra.Command {
+	var f todoFlags
+	cmd := &cobra.Command{
+		Use:   "add <title>",
+		Short: "Create a todo",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			todo := client.Todo{Title: strings.Join(args, " ")}
+			if err := f.apply(cmd, &todo); err != nil {
+				return err
+			}
+			created, err := a.client.Create(cmd.Context(), todo)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), created.ID)
+			return nil
+		},
+	}
+	f.register(cmd, false)
+	return cmd
+}
+
+func newEditCmd(a *app) *cobra.Command {
+	var f todoFlags
+	cmd := &cobra.Command{
+		Use:               "edit <id>",
+		Short:             "Change fields of a todo",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			todo, err := a.client.Get(cmd.Context(), args[0])
+			if err != nil {
+				return err
+			}
+			if err := f.apply(cmd, &todo); err != nil {
+				return err
+			}
+			// Gửi kèm Version vừa đọc để không ghi đè thay đổi của người khác.
+			updated, err := a.client.Update(cmd.Context(), todo.ID, todo)
+			if client.IsConflict(err) {
+				return fmt.Errorf("todo %s was changed by someone else, run the command again", todo.ID)
+			}
+			if err != nil {
+				return err
+			}
+			writeTodo(cmd.OutOrStdout(), updated)
+			return nil
+		},
+	}
+	f.register(cmd, true)
+	return cmd
+}
+
+// newDoneCmd tạo lệnh done (done=true) hoặc undone. ChangeStatus chỉ đảo trạng
+// thái nên lệnh đọc todo trước và bỏ qua những todo đã đúng trạng thái.
+func newDoneCmd(a *app, done bool) *cobra.Command {
+	use, short := "done", "Mark todos as done"
+	if !done {
+		use, short = "undone", "Mark todos as not done"
+	}
+	return &cobra.Command{
+		Use:               use + " <id>...",
+		Short:             short,
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, id := range args {
+				todo, err := a.client.Get(cmd.Context(), id)
+				if err != nil {
+					return err
+				}
+				if todo.Done == done {
+					continue
+				}
+				if err := a.client.ToggleStatus(cmd.Context(), id); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newRmCmd(a *app) *cobra.Command {
+	return &cobra.Command{
+		Use:               "rm <id>...",
+		Aliases:           []string{"delete"},
+		Short:             "Move todos to the trash",
+		Args:              cobra.MinimumNArgs(1),
+		ValidArgsFunction: a.completeTodoIDs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, id := range args {
+				if err := a.client.Delete(cmd.Context(), id); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func newConfigCmd(a *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manage server profiles",
+		// Không tạo client: profile hiện tại có thể chưa hợp lệ.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(a.configPath)
+			a.config = cfg
+			return err
+		},
+	}
+
+	var p Profile
+	set := &cobra.Command{
+		Use:   "set <name>",
+		Short: "Create or update a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			current := a.config.Profiles[args[0]]
+			if cmd.Flags().Changed("server") {
+				current.Server = p.Server
+			}
+			if cmd.Flags().Changed("token") {
+				current.Token = p.Token
+			}
+			if cmd.Flags().Changed("actor") {
+				current.Actor = p.Actor
+			}
+			a.config.Profiles[args[0]] = current
+			if a.config.Current == "" {
+				a.config.Current = args[0]
+			}
+			return a.config.save(a.configPath)
+		},
+	}
+	// --server của lệnh gốc dùng để ghi đè khi gọi API; ở đây là giá trị lưu vào profile.
+	set.Flags().StringVar(&p.Server, "server", "", "server URL")
+	set.Flags().StringVar(&p.Token, "token", "", "bearer token")
+	set.Flags().StringVar(&p.Actor, "actor", "", "name recorded as the author of changes")
+
+	use := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Switch the current profile",
+		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			cfg, err := loadConfig(a.configPath)
+			if err != nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return cfg.profileNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := a.config.Profiles[args[0]]; !ok {
+				return fmt.Errorf("profile %q not found", args[0])
+			}
+			a.config.Current = args[0]
+			return a.config.save(a.configPath)
+		},
+	}
+
+	ls := &cobra.Command{
+		Use:   "ls",
+		Short: "List profiles",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "\tNAME\tSERVER\tACTOR")
+			for _, name := range a.config.profileNames() {
+				mark := ""
+				if name == a.config.Current {
+					mark = "*"
+				}
+				p := a.config.Profiles[name]
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", mark, name, p.Server, p.Actor)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.AddCommand(set, use, ls)
+	return cmd
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Profile là một server cùng thông tin đăng nhập để gọi nó.
+type Profile struct {
+	Server string `json:"server"`
+	Token  string `json:"token,omitempty"`
+	Actor  string `json:"actor,omitempty"`
+}
+
+// Config là nội dung file cấu hình, mặc định ở $XDG_CONFIG_HOME/todo/config.json.
+type Config struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+const defaultServer = "http://localhost:8080"
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "todo.json"
+	}
+	return filepath.Join(dir, "todo", "config.json")
+}
+
+// loadConfig đọc file cấu hình; file chưa tồn tại được coi là cấu hình rỗng.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]Profile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	return cfg, nil
+}
+
+// save ghi cấu hình với quyền 0600 vì có thể chứa token.
+func (c *Config) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// profile trả về profile theo tên, hoặc profile hiện tại khi name rỗng. Khi
+// chưa cấu hình gì thì dùng server mặc định.
+func (c *Config) profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		name = "default"
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		if name == "default" {
+			return Profile{Server: defaultServer}, nil
+		}
+		return Profile{}, fmt.Errorf("profile %q not found", name)
+	}
+	if p.Server == "" {
+		p.Server = defaultServer
+	}
+	return p, nil
+}
+
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
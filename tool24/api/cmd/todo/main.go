@@ -0,0 +1,103 @@
+// Command todo is a terminal client for the Todo API.
+//
+//	todo config set default --server http://localhost:8080 --actor alice
+//	todo add "Buy milk" --due 2024-12-24 --tags home
+//	todo ls --open
+//	todo done <id>
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"api/client"
+)
+
+// app giữ trạng thái dùng chung của các lệnh, được điền trong PersistentPreRunE.
+type app struct {
+	configPath string
+	profile    string
+	server     string
+
+	config *Config
+	client *client.Client
+}
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCmd() *cobra.Command {
+	a := &app{}
+	root := &cobra.Command{
+		Use:           "todo",
+		Short:         "Manage todos from the terminal",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return a.init()
+		},
+	}
+	root.PersistentFlags().StringVar(&a.configPath, "config", defaultConfigPath(), "config file")
+	root.PersistentFlags().StringVarP(&a.profile, "profile", "p", os.Getenv("TODO_PROFILE"), "profile to use (default: current profile)")
+	root.PersistentFlags().StringVar(&a.server, "server", "", "server URL, overrides the profile")
+	root.RegisterFlagCompletionFunc("profile", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if err := a.init(); err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return a.config.profileNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	root.AddCommand(
+		newLsCmd(a),
+		newShowCmd(a),
+		newAddCmd(a),
+		newEditCmd(a),
+		newDoneCmd(a, true),
+		newDoneCmd(a, false),
+		newRmCmd(a),
+		newImportCmd(a),
+		newExportCmd(a),
+		newConfigCmd(a),
+		newTUICmd(a),
+	)
+	return root
+}
+
+// init đọc cấu hình và tạo client theo profile đang chọn.
+func (a *app) init() error {
+	if a.client != nil {
+		return nil
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	a.config = cfg
+
+	p, err := cfg.profile(a.profile)
+	if err != nil {
+		return err
+	}
+	if a.server != "" {
+		p.Server = a.server
+	}
+
+	var auths []client.Authenticator
+	if p.Token != "" {
+		auths = append(auths, client.BearerToken(p.Token))
+	}
+	if p.Actor != "" {
+		auths = append(auths, client.Actor(p.Actor))
+	}
+	c, err := client.New(p.Server, client.WithAuth(client.Chain(auths...)))
+	if err != nil {
+		return fmt.Errorf("profile %q: %w", a.profile, err)
+	}
+	a.client = c
+	return nil
+}
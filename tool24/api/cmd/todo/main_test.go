@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"api/client"
+)
+
+// fakeServer là API todo tối giản trong bộ nhớ, đủ cho các lệnh CLI, và ghi
+// lại các request để kiểm tra lệnh gọi đúng endpoint.
+type fakeServer struct {
+	mu       sync.Mutex
+	todos    map[string]*client.Todo
+	order    []string
+	requests []string
+	// lastImport là query của lần gọi /todos/import gần nhất.
+	lastImport url.Values
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	f := &fakeServer{todos: map[string]*client.Todo{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /todos", func(w http.ResponseWriter, r *http.Request) {
+		todos := []client.Todo{}
+		for _, id := range f.order {
+			if todo, ok := f.todos[id]; ok {
+				todos = append(todos, *todo)
+			}
+		}
+		json.NewEncoder(w).Encode(todos)
+	})
+	mux.HandleFunc("GET /todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if todo, ok := f.todos[r.PathValue("id")]; ok {
+			json.NewEncoder(w).Encode(todo)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "todo not found"})
+	})
+	mux.HandleFunc("POST /todo", func(w http.ResponseWriter, r *http.Request) {
+		var todo client.Todo
+		json.NewDecoder(r.Body).Decode(&todo)
+		todo.ID = fmt.Sprint(len(f.order) + 1)
+		todo.Done, todo.Version, todo.CreatedBy = false, 1, r.Header.Get("X-Actor")
+		f.todos[todo.ID] = &todo
+		f.order = append(f.order, todo.ID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(todo)
+	})
+	mux.HandleFunc("PUT /todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		var todo client.Todo
+		json.NewDecoder(r.Body).Decode(&todo)
+		current := f.todos[r.PathValue("id")]
+		if todo.Version != current.Version {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		todo.Version++
+		*current = todo
+		json.NewEncoder(w).Encode(current)
+	})
+	mux.HandleFunc("DELETE /todo/{id}", func(w http.ResponseWriter, r *http.Request) {
+		delete(f.todos, r.PathValue("id"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /todos/export", func(w http.ResponseWriter, r *http.Request) {
+		enc := json.NewEncoder(w)
+		for _, id := range f.order {
+			if todo, ok := f.todos[id]; ok {
+				enc.Encode(todo)
+			}
+		}
+	})
+	mux.HandleFunc("POST /todos/import", func(w http.ResponseWriter, r *http.Request) {
+		f.lastImport = r.URL.Query()
+		report := client.ImportReport{DryRun: r.URL.Query().Get("dry_run") == "true", Errors: []client.ImportRowError{}}
+		var todos []client.Todo
+		dec := json.NewDecoder(r.Body)
+		if r.URL.Query().Get("format") == "json" {
+			dec.Decode(&todos)
+		}
+		for dec.More() {
+			var todo client.Todo
+			if err := dec.Decode(&todo); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			todos = append(todos, todo)
+		}
+		for _, todo := range todos {
+			report.Total++
+			if todo.Title == "" {
+				report.Failed++
+				report.Errors = append(report.Errors, client.ImportRowError{Row: report.Total, Error: "title is required"})
+				continue
+			}
+			report.Created++
+			if !report.DryRun {
+				todo.ID = fmt.Sprint(len(f.order) + 1)
+				f.todos[todo.ID] = &todo
+				f.order = append(f.order, todo.ID)
+			}
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+	mux.HandleFunc("POST /todo/changeStatus/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.todos[r.PathValue("id")].Done = !f.todos[r.PathValue("id")].Done
+		w.Write([]byte(`{"status":"success"}`))
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return f, srv.URL
+}
+
+// run chạy CLI với một file cấu hình riêng cho test.
+func run(t *testing.T, configPath string, stdin string, args ...string) (string, error) {
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(append([]string{"--config", configPath}, args...))
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestCLIWorkflow(t *testing.T) {
+	f, url := newFakeServer(t)
+	config := filepath.Join(t.TempDir(), "config.json")
+
+	_, err := run(t, config, "", "config", "set", "local", "--server", url, "--actor", "alice")
+	assert.NoError(t, err)
+
+	out, err := run(t, config, "", "add", "Buy", "milk", "--tags", "home, shop", "--due", "2024-12-24")
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n", out)
+	assert.Equal(t, "alice", f.todos["1"].CreatedBy)
+	assert.Equal(t, []string{"home", "shop"}, f.todos["1"].Tags)
+
+	_, err = run(t, config, "", "done", "1")
+	assert.NoError(t, err)
+	_, err = run(t, config, "", "done", "1")
+	assert.NoError(t, err)
+	assert.True(t, f.todos["1"].Done)
+
+	// edit gửi lại cả todo nên không được làm mất payment đặt qua API.
+	payment := &client.Payment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000}
+	f.todos["1"].Payment = payment
+	out, err = run(t, config, "", "edit", "1", "--title", "Buy oat milk")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "Buy oat milk")
+	assert.Equal(t, 2, f.todos["1"].Version)
+	assert.Equal(t, payment, f.todos["1"].Payment)
+
+	out, err = run(t, config, "", "ls", "-o", "json")
+	assert.NoError(t, err)
+	var listed []client.Todo
+	assert.NoError(t, json.Unmarshal([]byte(out), &listed))
+	assert.Len(t, listed, 1)
+
+	_, err = run(t, config, "", "rm", "1")
+	assert.NoError(t, err)
+	_, err = run(t, config, "", "show", "1")
+	assert.True(t, client.IsNotFound(err))
+
+	// done gọi ChangeStatus đúng một lần vì lần thứ hai todo đã xong.
+	assert.Equal(t, []string{
+		"POST /todo",
+		"GET /todo/1", "POST /todo/changeStatus/1",
+		"GET /todo/1",
+		"GET /todo/1", "PUT /todo/1",
+		"GET /todos",
+		"DELETE /todo/1",
+		"GET /todo/1",
+	}, f.requests)
+}
+
+func TestCLIExportImportRoundTrip(t *testing.T) {
+	f, url := newFakeServer(t)
+	config := filepath.Join(t.TempDir(), "config.json")
+
+	input := `{"title":"A","tags":["x"]}
+{"title":"B","done":true}
+`
+	out, err := run(t, config, input, "--server", url, "import", "--map", "Task=title", "--batch-size", "50")
+	assert.NoError(t, err)
+	assert.Equal(t, "2 rows: 2 created, 0 updated, 0 unchanged, 0 failed\n", out)
+	assert.Len(t, f.todos, 2)
+	assert.Equal(t, "ndjson", f.lastImport.Get("format"))
+	assert.Equal(t, "Task:title", f.lastImport.Get("map"))
+	assert.Equal(t, "50", f.lastImport.Get("batch_size"))
+
+	out, err = run(t, config, "", "--server", url, "export", "-f", "ndjson")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(out, "\n"))
+	assert.Contains(t, out, `"tags":["x"]`)
+}
+
+func TestCLIImportReportsFailedRows(t *testing.T) {
+	f, url := newFakeServer(t)
+	config := filepath.Join(t.TempDir(), "config.json")
+
+	out, err := run(t, config, `[{"title":"A"},{"title":""}]`, "--server", url, "import", "--dry-run")
+
+	assert.EqualError(t, err, "1 of 2 rows failed")
+	assert.Contains(t, out, "dry run: 2 rows: 1 created")
+	assert.Contains(t, out, "row 2: title is required")
+	assert.Equal(t, "json", f.lastImport.Get("format"))
+	assert.Empty(t, f.todos)
+}
+
+func TestDetectFormat(t *testing.T) {
+	assert.Equal(t, "csv", detectFormat("todos.CSV", bufio.NewReader(strings.NewReader("[]"))))
+	assert.Equal(t, "ndjson", detectFormat("todos.jsonl", bufio.NewReader(strings.NewReader(""))))
+	assert.Equal(t, "json", detectFormat("", bufio.NewReader(strings.NewReader("\n  [{}]"))))
+	assert.Equal(t, "ndjson", detectFormat("", bufio.NewReader(strings.NewReader(`{"title":"A"}`))))
+	assert.Equal(t, "csv", detectFormat("", bufio.NewReader(strings.NewReader("title,done\n"))))
+}
+
+func TestCLIProfiles(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+
+	_, err := run(t, config, "", "config", "set", "work", "--server", "https://todo.example.com", "--token", "s3cret")
+	assert.NoError(t, err)
+	_, err = run(t, config, "", "config", "set", "home", "--server", "http://localhost:8080")
+	assert.NoError(t, err)
+	_, err = run(t, config, "", "config", "use", "home")
+	assert.NoError(t, err)
+
+	cfg, err := loadConfig(config)
+	assert.NoError(t, err)
+	assert.Equal(t, "home", cfg.Current)
+	assert.Equal(t, "s3cret", cfg.Profiles["work"].Token)
+
+	_, err = run(t, config, "", "config", "use", "missing")
+	assert.Error(t, err)
+	_, err = run(t, config, "", "--profile", "missing", "ls")
+	assert.Error(t, err)
+}
+
+func TestCLICompletion(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "config.json")
+	out, err := run(t, config, "", "completion", "bash")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "__start_todo")
+}
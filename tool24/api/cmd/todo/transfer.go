@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"api/client"
+)
+
+// detectFormat đoán định dạng theo phần mở rộng của file, nếu không được thì
+// theo ký tự đầu tiên của nội dung.
+func detectFormat(name string, in *bufio.Reader) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return client.FormatCSV
+	case ".ndjson", ".jsonl":
+		return client.FormatNDJSON
+	case ".json":
+		return client.FormatJSON
+	}
+
+	head, _ := in.Peek(512)
+	switch head = bytes.TrimLeft(head, " \t\r\n\ufeff"); {
+	case bytes.HasPrefix(head, []byte("[")):
+		return client.FormatJSON
+	case bytes.HasPrefix(head, []byte("{")):
+		return client.FormatNDJSON
+	default:
+		return client.FormatCSV
+	}
+}
+
+var formatCompletion = cobra.FixedCompletions([]string{client.FormatCSV, client.FormatJSON, client.FormatNDJSON}, cobra.ShellCompDirectiveNoFileComp)
+
+func newImportCmd(a *app) *cobra.Command {
+	var opts client.ImportOptions
+	cmd := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Create or update todos from a CSV, JSON or NDJSON file (default stdin)",
+		Long: "Import rows through POST /todos/import. Rows with an external_id that was imported before update that todo.\n" +
+			"Use --map to rename spreadsheet columns, e.g. --map Task=title,Notes=description.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in, name := cmd.InOrStdin(), ""
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in, name = f, args[0]
+			}
+
+			br := bufio.NewReader(in)
+			if opts.Format == "" {
+				opts.Format = detectFormat(name, br)
+			}
+			report, err := a.client.Import(cmd.Context(), br, opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			prefix := ""
+			if report.DryRun {
+				prefix = "dry run: "
+			}
+			fmt.Fprintf(out, "%s%d rows: %d created, %d updated, %d unchanged, %d failed\n",
+				prefix, report.Total, report.Created, report.Updated, report.Unchanged, report.Failed)
+			for _, rowErr := range report.Errors {
+				fmt.Fprintf(out, "  row %d: %s\n", rowErr.Row, rowErr.Error)
+			}
+			if report.Failed > 0 {
+				return fmt.Errorf("%d of %d rows failed", report.Failed, report.Total)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Format, "format", "f", "", "csv, json or ndjson (default: from the file name or content)")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "validate and report without writing")
+	cmd.Flags().IntVar(&opts.BatchSize, "batch-size", 0, "rows per transaction (default: server default)")
+	cmd.Flags().StringToStringVar(&opts.Mapping, "map", nil, "map source columns to fields, e.g. Task=title")
+	cmd.RegisterFlagCompletionFunc("format", formatCompletion)
+	return cmd
+}
+
+func newExportCmd(a *app) *cobra.Command {
+	var format, output string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write all todos as CSV, JSON or NDJSON",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var out io.Writer = cmd.OutOrStdout()
+			if output != "" && output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			return a.client.Export(cmd.Context(), format, out)
+		},
+	}
+	cmd.Flags().StringVarP(&format, "format", "f", client.FormatJSON, "csv, json or ndjson")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write (default stdout)")
+	cmd.RegisterFlagCompletionFunc("format", formatCompletion)
+	return cmd
+}
@@ -0,0 +1,548 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+
+	"api/client"
+)
+
+func newTUICmd(a *app) *cobra.Command {
+	var poll time.Duration
+	cmd := &cobra.Command{
+		Use:   "tui",
+		Short: "Browse and edit todos interactively",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, cancel := context.WithCancel(cmd.Context())
+			defer cancel()
+			m := newTUIModel(ctx, a.client, poll)
+			_, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+			return err
+		},
+	}
+	cmd.Flags().DurationVar(&poll, "poll", 5*time.Second, "refresh interval when the server has no change stream")
+	return cmd
+}
+
+type filterMode int
+
+const (
+	filterAll filterMode = iota
+	filterOpen
+	filterDone
+)
+
+var filterNames = [...]string{"All", "Open", "Done"}
+
+type inputMode int
+
+const (
+	inputNone inputMode = iota
+	inputSearch
+	inputTitle
+	inputDesc
+	inputAdd
+)
+
+// Các message nội bộ của TUI.
+type (
+	todosLoadedMsg struct {
+		todos []client.Todo
+		err   error
+	}
+	todoSavedMsg struct {
+		todo client.Todo
+		err  error
+	}
+	todoEventMsg   struct{ ev client.Event }
+	watchFailedMsg struct{ err error }
+	pollMsg        struct{}
+)
+
+var (
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	doneStyle     = lipgloss.NewStyle().Faint(true).Strikethrough(true)
+	tabStyle      = lipgloss.NewStyle().Padding(0, 1)
+	activeTab     = tabStyle.Reverse(true)
+	dimStyle      = lipgloss.NewStyle().Faint(true)
+	errorStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+)
+
+// tuiModel là trạng thái của màn hình. Danh sách được cập nhật từ luồng
+// /todos/events; nếu server không có luồng thì chuyển sang gọi lại GET /todos
+// theo chu kỳ poll.
+type tuiModel struct {
+	ctx    context.Context
+	client *client.Client
+	poll   time.Duration
+
+	todos   []client.Todo
+	visible []client.Todo
+	cursor  int
+	offset  int
+	filter  filterMode
+	search  string
+
+	mode  inputMode
+	input textinput.Model
+
+	width, height int
+	status        string
+	statusErr     bool
+
+	live        bool
+	lastEventID int64
+	events      chan tea.Msg
+}
+
+func newTUIModel(ctx context.Context, c *client.Client, poll time.Duration) *tuiModel {
+	input := textinput.New()
+	input.CharLimit = 500
+	return &tuiModel{
+		ctx:    ctx,
+		client: c,
+		poll:   poll,
+		input:  input,
+		events: make(chan tea.Msg, 16),
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	m.startWatch()
+	return tea.Batch(m.load(), m.waitEvent())
+}
+
+func (m *tuiModel) load() tea.Cmd {
+	return func() tea.Msg {
+		var todos []client.Todo
+		for todo, err := range m.client.All(m.ctx, client.ListOptions{}) {
+			if err != nil {
+				return todosLoadedMsg{err: err}
+			}
+			todos = append(todos, todo)
+		}
+		return todosLoadedMsg{todos: todos}
+	}
+}
+
+// startWatch mở luồng sự kiện trong một goroutine và chuyển mọi sự kiện vào
+// m.events. Lỗi kết nối được báo bằng watchFailedMsg.
+func (m *tuiModel) startWatch() {
+	m.live = true
+	lastID := m.lastEventID
+	go func() {
+		for ev, err := range m.client.Watch(m.ctx, lastID) {
+			msg := tea.Msg(todoEventMsg{ev})
+			if err != nil {
+				msg = watchFailedMsg{err}
+			}
+			select {
+			case m.events <- msg:
+			case <-m.ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (m *tuiModel) waitEvent() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-m.events:
+			return msg
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (m *tuiModel) selected() (client.Todo, bool) {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return client.Todo{}, false
+	}
+	return m.visible[m.cursor], true
+}
+
+// refilter tính lại danh sách hiển thị và giữ con trỏ ở todo đang chọn nếu còn.
+func (m *tuiModel) refilter() {
+	current, _ := m.selected()
+	q := strings.ToLower(m.search)
+
+	m.visible = m.visible[:0]
+	for _, todo := range m.todos {
+		if m.filter == filterOpen && todo.Done || m.filter == filterDone && !todo.Done {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(todo.Title), q) && !strings.Contains(strings.ToLower(todo.Description), q) {
+			continue
+		}
+		m.visible = append(m.visible, todo)
+	}
+
+	for i, todo := range m.visible {
+		if todo.ID == current.ID {
+			m.cursor = i
+		}
+	}
+	m.moveCursor(0)
+}
+
+func (m *tuiModel) listHeight() int {
+	if m.height <= 0 {
+		return 20
+	}
+	// Trừ dòng tab, dòng mô tả, dòng nhập và dòng trạng thái.
+	if h := m.height - 5; h > 1 {
+		return h
+	}
+	return 1
+}
+
+func (m *tuiModel) moveCursor(delta int) {
+	m.cursor += delta
+	if m.cursor >= len(m.visible) {
+		m.cursor = len(m.visible) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if h := m.listHeight(); m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+}
+
+func (m *tuiModel) upsert(todo client.Todo) {
+	for i := range m.todos {
+		if m.todos[i].ID == todo.ID {
+			m.todos[i] = todo
+			m.refilter()
+			return
+		}
+	}
+	m.todos = append(m.todos, todo)
+	m.sortTodos()
+	m.refilter()
+}
+
+func (m *tuiModel) remove(id string) {
+	for i := range m.todos {
+		if m.todos[i].ID == id {
+			m.todos = append(m.todos[:i], m.todos[i+1:]...)
+			break
+		}
+	}
+	m.refilter()
+}
+
+func (m *tuiModel) sortTodos() {
+	sort.SliceStable(m.todos, func(i, j int) bool {
+		if !m.todos[i].CreatedAt.Equal(m.todos[j].CreatedAt) {
+			return m.todos[i].CreatedAt.Before(m.todos[j].CreatedAt)
+		}
+		return m.todos[i].ID < m.todos[j].ID
+	})
+}
+
+func (m *tuiModel) setStatus(msg string, isErr bool) {
+	m.status, m.statusErr = msg, isErr
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.moveCursor(0)
+		return m, nil
+
+	case todosLoadedMsg:
+		if msg.err != nil {
+			m.setStatus(msg.err.Error(), true)
+			return m, nil
+		}
+		m.todos = msg.todos
+		m.sortTodos()
+		m.refilter()
+		return m, nil
+
+	case todoSavedMsg:
+		if client.IsConflict(msg.err) {
+			m.setStatus("changed by someone else, reloaded", true)
+			return m, m.load()
+		}
+		if msg.err != nil {
+			m.setStatus(msg.err.Error(), true)
+			return m, nil
+		}
+		m.setStatus("saved", false)
+		m.upsert(msg.todo)
+		return m, nil
+
+	case todoEventMsg:
+		m.applyEvent(msg.ev)
+		if msg.ev.Type == client.EventReset || msg.ev.Todo.ID == "" {
+			return m, tea.Batch(m.load(), m.waitEvent())
+		}
+		return m, m.waitEvent()
+
+	case watchFailedMsg:
+		if m.ctx.Err() != nil {
+			return m, nil
+		}
+		m.live = false
+		return m, tea.Batch(m.waitEvent(), tea.Tick(m.poll, func(time.Time) tea.Msg { return pollMsg{} }))
+
+	case pollMsg:
+		// Thử mở lại luồng sự kiện; nếu vẫn lỗi thì watchFailedMsg sẽ hẹn lần poll sau.
+		m.startWatch()
+		return m, m.load()
+
+	case tea.KeyMsg:
+		if m.mode != inputNone {
+			return m.updateInput(msg)
+		}
+		return m.updateList(msg)
+	}
+	return m, nil
+}
+
+// applyEvent cập nhật danh sách theo một sự kiện của luồng.
+func (m *tuiModel) applyEvent(ev client.Event) {
+	if ev.ID > m.lastEventID {
+		m.lastEventID = ev.ID
+	}
+	switch {
+	case ev.Type == client.EventReset || ev.Todo.ID == "":
+		// Cần tải lại toàn bộ; Update lo việc gọi load.
+	case ev.Type == client.EventDelete || ev.Todo.DeletedAt != nil:
+		m.remove(ev.Todo.ID)
+	default:
+		m.upsert(ev.Todo)
+	}
+}
+
+func (m *tuiModel) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "up", "k":
+		m.moveCursor(-1)
+	case "down", "j":
+		m.moveCursor(1)
+	case "pgup":
+		m.moveCursor(-m.listHeight())
+	case "pgdown":
+		m.moveCursor(m.listHeight())
+	case "home", "g":
+		m.moveCursor(-len(m.visible))
+	case "end", "G":
+		m.moveCursor(len(m.visible))
+	case "tab":
+		m.filter = (m.filter + 1) % filterMode(len(filterNames))
+		m.refilter()
+	case "shift+tab":
+		m.filter = (m.filter + filterMode(len(filterNames)) - 1) % filterMode(len(filterNames))
+		m.refilter()
+	case "1", "2", "3":
+		m.filter = filterMode(msg.String()[0] - '1')
+		m.refilter()
+	case "r":
+		return m, m.load()
+	case " ":
+		if todo, ok := m.selected(); ok {
+			return m, m.toggle(todo.ID)
+		}
+	case "/":
+		return m, m.startInput(inputSearch, m.search)
+	case "e":
+		if todo, ok := m.selected(); ok {
+			return m, m.startInput(inputTitle, todo.Title)
+		}
+	case "E":
+		if todo, ok := m.selected(); ok {
+			return m, m.startInput(inputDesc, todo.Description)
+		}
+	case "a":
+		return m, m.startInput(inputAdd, "")
+	}
+	return m, nil
+}
+
+func (m *tuiModel) startInput(mode inputMode, value string) tea.Cmd {
+	m.mode = mode
+	m.input.SetValue(value)
+	m.input.CursorEnd()
+	m.input.Prompt = map[inputMode]string{
+		inputSearch: "/",
+		inputTitle:  "title: ",
+		inputDesc:   "description: ",
+		inputAdd:    "new: ",
+	}[mode]
+	return m.input.Focus()
+}
+
+func (m *tuiModel) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		if m.mode == inputSearch {
+			m.search = ""
+			m.refilter()
+		}
+		m.mode = inputNone
+		m.input.Blur()
+		return m, nil
+
+	case tea.KeyEnter:
+		mode, value := m.mode, m.input.Value()
+		m.mode = inputNone
+		m.input.Blur()
+		switch mode {
+		case inputTitle, inputDesc:
+			if todo, ok := m.selected(); ok {
+				if mode == inputTitle {
+					todo.Title = value
+				} else {
+					todo.Description = value
+				}
+				return m, m.save(todo)
+			}
+		case inputAdd:
+			if strings.TrimSpace(value) != "" {
+				return m, m.create(client.Todo{Title: value})
+			}
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	if m.mode == inputSearch {
+		m.search = m.input.Value()
+		m.refilter()
+	}
+	return m, cmd
+}
+
+func (m *tuiModel) toggle(id string) tea.Cmd {
+	return func() tea.Msg {
+		if err := m.client.ToggleStatus(m.ctx, id); err != nil {
+			return todoSavedMsg{err: err}
+		}
+		todo, err := m.client.Get(m.ctx, id)
+		return todoSavedMsg{todo: todo, err: err}
+	}
+}
+
+// save gửi kèm Version đang hiển thị để không ghi đè thay đổi của người khác.
+func (m *tuiModel) save(todo client.Todo) tea.Cmd {
+	return func() tea.Msg {
+		updated, err := m.client.Update(m.ctx, todo.ID, todo)
+		return todoSavedMsg{todo: updated, err: err}
+	}
+}
+
+func (m *tuiModel) create(todo client.Todo) tea.Cmd {
+	return func() tea.Msg {
+		created, err := m.client.Create(m.ctx, todo)
+		return todoSavedMsg{todo: created, err: err}
+	}
+}
+
+func (m *tuiModel) View() string {
+	var b strings.Builder
+
+	for i, name := range filterNames {
+		style := tabStyle
+		if filterMode(i) == m.filter {
+			style = activeTab
+		}
+		b.WriteString(style.Render(fmt.Sprintf("%d %s", i+1, name)))
+	}
+	if m.live {
+		b.WriteString(dimStyle.Render("  ● live"))
+	} else {
+		b.WriteString(dimStyle.Render(fmt.Sprintf("  ○ polling every %s", m.poll)))
+	}
+	b.WriteString("\n")
+
+	h := m.listHeight()
+	for i := m.offset; i < m.offset+h; i++ {
+		if i >= len(m.visible) {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(m.renderRow(i))
+		b.WriteString("\n")
+	}
+
+	if todo, ok := m.selected(); ok && todo.Description != "" {
+		b.WriteString(dimStyle.Render(truncate(todo.Description, m.width)))
+	}
+	b.WriteString("\n")
+
+	if m.mode != inputNone {
+		b.WriteString(m.input.View())
+	} else if m.search != "" {
+		b.WriteString(dimStyle.Render("/" + m.search))
+	}
+	b.WriteString("\n")
+
+	if m.status != "" {
+		style := dimStyle
+		if m.statusErr {
+			style = errorStyle
+		}
+		b.WriteString(style.Render(m.status) + "  ")
+	}
+	b.WriteString(dimStyle.Render("space toggle · e title · E description · a add · / search · tab filter · r reload · q quit"))
+	return b.String()
+}
+
+func (m *tuiModel) renderRow(i int) string {
+	todo := m.visible[i]
+	check := "[ ]"
+	if todo.Done {
+		check = "[x]"
+	}
+	line := check + " " + todo.Title
+	if todo.DueAt != nil {
+		line += "  " + todo.DueAt.Local().Format("Jan 2 15:04")
+	}
+	for _, tag := range todo.Tags {
+		line += " #" + tag
+	}
+	line = truncate(line, m.width)
+
+	switch {
+	case i == m.cursor:
+		return selectedStyle.Render(line)
+	case todo.Done:
+		return doneStyle.Render(line)
+	}
+	return line
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	return string(r[:width-1]) + "…"
+}
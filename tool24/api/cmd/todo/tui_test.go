@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+
+	"api/client"
+)
+
+func newTestTUI(t *testing.T) (*fakeServer, *tuiModel) {
+	f, url := newFakeServer(t)
+	c, err := client.New(url)
+	assert.NoError(t, err)
+	for _, title := range []string{"Buy milk", "Write report", "Call mom"} {
+		_, err := c.Create(context.Background(), client.Todo{Title: title})
+		assert.NoError(t, err)
+	}
+	f.todos["2"].Done = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	m := newTUIModel(ctx, c, time.Hour)
+	m.Update(m.load()())
+	return f, m
+}
+
+// press gửi lần lượt các phím và chạy các lệnh trả về như bubbletea sẽ làm.
+// Lệnh không xong ngay (vd nhấp nháy con trỏ) được bỏ qua.
+func press(m *tuiModel, keys ...tea.KeyMsg) {
+	for _, key := range keys {
+		_, cmd := m.Update(key)
+		for cmd != nil {
+			msg := runCmd(cmd)
+			if msg == nil {
+				break
+			}
+			_, cmd = m.Update(msg)
+		}
+	}
+}
+
+func runCmd(cmd tea.Cmd) tea.Msg {
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+	select {
+	case msg := <-done:
+		switch msg.(type) {
+		case todosLoadedMsg, todoSavedMsg:
+			return msg
+		}
+	case <-time.After(100 * time.Millisecond):
+	}
+	return nil
+}
+
+func runes(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+func titles(todos []client.Todo) []string {
+	var out []string
+	for _, todo := range todos {
+		out = append(out, todo.Title)
+	}
+	return out
+}
+
+func TestTUIFilterAndSearch(t *testing.T) {
+	_, m := newTestTUI(t)
+	assert.Len(t, m.visible, 3)
+
+	press(m, tea.KeyMsg{Type: tea.KeyTab})
+	assert.Equal(t, []string{"Buy milk", "Call mom"}, titles(m.visible))
+
+	press(m, runes("3"))
+	assert.Equal(t, []string{"Write report"}, titles(m.visible))
+
+	press(m, runes("1"), runes("/"), runes("m"), runes("o"))
+	assert.Equal(t, []string{"Call mom"}, titles(m.visible))
+
+	press(m, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Len(t, m.visible, 3)
+}
+
+func TestTUIToggleAndEdit(t *testing.T) {
+	f, m := newTestTUI(t)
+
+	press(m, runes(" "))
+	assert.True(t, f.todos["1"].Done)
+	assert.True(t, m.visible[0].Done)
+
+	press(m, runes("j"), runes("j"), runes("e"), runes("!"), tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "Call mom!", f.todos["3"].Title)
+	assert.Equal(t, "Call mom!", m.visible[2].Title)
+
+	press(m, runes("E"), runes("weekly"), tea.KeyMsg{Type: tea.KeyEnter})
+	assert.Equal(t, "weekly", f.todos["3"].Description)
+}
+
+func TestTUIEditConflictReloads(t *testing.T) {
+	f, m := newTestTUI(t)
+	// Người khác sửa todo 1 sau khi TUI đã tải danh sách.
+	f.todos["1"].Title, f.todos["1"].Version = "Buy bread", 2
+
+	press(m, runes("e"), runes("!"), tea.KeyMsg{Type: tea.KeyEnter})
+
+	assert.True(t, m.statusErr)
+	assert.Equal(t, "Buy bread", m.visible[0].Title)
+}
+
+func TestTUIAppliesEvents(t *testing.T) {
+	_, m := newTestTUI(t)
+
+	m.Update(todoEventMsg{client.Event{ID: 7, Type: client.EventCreate, Todo: client.Todo{ID: "9", Title: "From web", CreatedAt: time.Now()}}})
+	assert.Equal(t, "From web", m.visible[3].Title)
+
+	m.Update(todoEventMsg{client.Event{ID: 8, Type: client.EventUpdate, Todo: client.Todo{ID: "1", Title: "Buy oat milk"}}})
+	assert.Equal(t, "Buy oat milk", m.visible[0].Title)
+
+	m.Update(todoEventMsg{client.Event{ID: 9, Type: client.EventDelete, Todo: client.Todo{ID: "2"}}})
+	assert.Equal(t, []string{"Buy oat milk", "Call mom", "From web"}, titles(m.visible))
+	assert.Equal(t, int64(9), m.lastEventID)
+}
+
+func TestTUIFallsBackToPolling(t *testing.T) {
+	_, m := newTestTUI(t)
+	m.startWatch()
+
+	// Server giả không có /todos/events nên luồng lỗi ngay.
+	msg := m.waitEvent()()
+	_, ok := msg.(watchFailedMsg)
+	assert.True(t, ok)
+
+	_, cmd := m.Update(msg)
+	assert.False(t, m.live)
+	assert.NotNil(t, cmd)
+	assert.Contains(t, m.View(), "polling")
+}
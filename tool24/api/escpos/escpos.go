@@ -0,0 +1,403 @@
+// Package escpos builds ESC/POS byte streams for 58mm and 80mm thermal
+// receipt printers: text with alignment and emphasis, native QR and Code128
+// commands, and the paper cut. The stream can be written to a raw TCP
+// printer (port 9100) or saved to a file.
+//
+//	b := escpos.New(escpos.Options{Paper: escpos.Paper58})
+//	b.SetBold(true)
+//	b.Paragraph("Kiểm kê kho")
+//	err := b.Barcode("T000042")
+//	b.Cut()
+//	_, err = b.WriteTo(conn)
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+
+	"api/barcoderender"
+)
+
+// ErrInvalidOptions is wrapped by every validation error of options.
+var ErrInvalidOptions = errors.New("invalid ESC/POS options")
+
+// ErrInvalidData is wrapped when a QR code or barcode cannot be printed, e.g.
+// too much data or a barcode wider than the paper.
+var ErrInvalidData = errors.New("invalid ESC/POS data")
+
+// Các byte điều khiển dùng trong lệnh ESC/POS.
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Paper is the roll width.
+type Paper int
+
+const (
+	// Paper80 is the 80mm roll; it is the default.
+	Paper80 Paper = iota
+	// Paper58 is the 58mm roll of small handheld and kitchen printers.
+	Paper58
+)
+
+// ParsePaper reads "58", "58mm", "80" or "80mm"; an empty string is Paper80.
+func ParsePaper(s string) (Paper, error) {
+	switch strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "mm") {
+	case "", "80":
+		return Paper80, nil
+	case "58":
+		return Paper58, nil
+	}
+	return Paper80, fmt.Errorf("%w: paper must be 58mm or 80mm", ErrInvalidOptions)
+}
+
+func (p Paper) String() string {
+	if p == Paper58 {
+		return "58mm"
+	}
+	return "80mm"
+}
+
+// Columns is the number of characters per line in the default font A
+// (12×24 dots).
+func (p Paper) Columns() int {
+	if p == Paper58 {
+		return 32
+	}
+	return 48
+}
+
+// Dots is the printable width in dots at 203 dpi.
+func (p Paper) Dots() int {
+	if p == Paper58 {
+		return 384
+	}
+	return 576
+}
+
+// Charset is how text is sent to the printer.
+type Charset int
+
+const (
+	// ASCII drops Vietnamese diacritics ("Kiểm kê" prints as "Kiem ke") and
+	// replaces other non-ASCII characters with '?'. Every printer reads it;
+	// it is the default.
+	ASCII Charset = iota
+	// CP1258 selects the Windows-1258 code page (ESC t 52), which prints
+	// Vietnamese on printers that have it.
+	CP1258
+)
+
+// ParseCharset reads "ascii" or "cp1258" (also "windows-1258"); an empty
+// string is ASCII.
+func ParseCharset(s string) (Charset, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "ascii":
+		return ASCII, nil
+	case "cp1258", "windows-1258", "windows1258":
+		return CP1258, nil
+	}
+	return ASCII, fmt.Errorf("%w: charset must be ascii or cp1258", ErrInvalidOptions)
+}
+
+func (c Charset) String() string {
+	if c == CP1258 {
+		return "cp1258"
+	}
+	return "ascii"
+}
+
+// codePage là tham số n của ESC t n theo bảng mã của Epson.
+func (c Charset) codePage() byte {
+	if c == CP1258 {
+		return 52
+	}
+	return 0
+}
+
+// Options controls the output of a Builder.
+type Options struct {
+	Paper   Paper
+	Charset Charset
+}
+
+// Align is the horizontal alignment of text, barcodes and QR codes.
+type Align byte
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Limits of QR and barcode content.
+const (
+	// MaxQRBytes is the byte capacity of a version 40 QR code at level M,
+	// the level Builder uses.
+	MaxQRBytes = 2331
+	// MaxBarcodeBytes is the data limit of GS k for Code128.
+	MaxBarcodeBytes = 253
+)
+
+// Builder accumulates an ESC/POS stream. The zero value is not usable; call
+// New.
+type Builder struct {
+	opts Options
+	buf  bytes.Buffer
+	// width là hệ số phóng ngang hiện tại, để Paragraph và Rule tính số cột.
+	width int
+}
+
+// New starts a stream: it resets the printer (ESC @) and selects the code
+// page of opts.Charset.
+func New(opts Options) *Builder {
+	b := &Builder{opts: opts, width: 1}
+	b.buf.Write([]byte{esc, '@', esc, 't', opts.Charset.codePage()})
+	return b
+}
+
+// Options returns the options the builder was created with.
+func (b *Builder) Options() Options {
+	return b.opts
+}
+
+// Columns is the number of characters that fit on a line at the current
+// character size.
+func (b *Builder) Columns() int {
+	return b.opts.Paper.Columns() / b.width
+}
+
+// SetAlign sets the alignment of the following lines (ESC a).
+func (b *Builder) SetAlign(a Align) {
+	b.buf.Write([]byte{esc, 'a', byte(a)})
+}
+
+// SetBold turns emphasized printing on or off (ESC E).
+func (b *Builder) SetBold(on bool) {
+	n := byte(0)
+	if on {
+		n = 1
+	}
+	b.buf.Write([]byte{esc, 'E', n})
+}
+
+// SetSize sets the character magnification, 1 to 8 in each direction
+// (GS !). Values out of range are clamped.
+func (b *Builder) SetSize(width, height int) {
+	width, height = min(max(width, 1), 8), min(max(height, 1), 8)
+	b.width = width
+	b.buf.Write([]byte{gs, '!', byte((width-1)<<4 | (height - 1))})
+}
+
+// Text writes s in the builder's charset without a line feed.
+func (b *Builder) Text(s string) {
+	b.buf.Write(b.encode(s))
+}
+
+// Line writes s followed by a line feed. s is not wrapped; see Paragraph.
+func (b *Builder) Line(s string) {
+	b.Text(s)
+	b.buf.WriteByte(lf)
+}
+
+// Paragraph writes s wrapped to the width of the paper at the current
+// character size. Line breaks in s are kept.
+func (b *Builder) Paragraph(s string) {
+	for _, line := range Wrap(s, b.Columns()) {
+		b.Line(line)
+	}
+}
+
+// Rule writes a full-width line of dashes.
+func (b *Builder) Rule() {
+	b.Line(strings.Repeat("-", b.Columns()))
+}
+
+// Feed prints the buffer and feeds n lines (ESC d).
+func (b *Builder) Feed(n int) {
+	b.buf.Write([]byte{esc, 'd', byte(min(max(n, 0), 255))})
+}
+
+// QR prints data as a native QR code at error-correction level M (GS ( k,
+// model 2). moduleSize is the size of one module in dots, 1 to 16.
+func (b *Builder) QR(data string, moduleSize int) error {
+	if data == "" || len(data) > MaxQRBytes {
+		return fmt.Errorf("%w: QR data must be 1 to %d bytes", ErrInvalidData, MaxQRBytes)
+	}
+	if moduleSize < 1 || moduleSize > 16 {
+		return fmt.Errorf("%w: QR module size must be between 1 and 16", ErrInvalidOptions)
+	}
+	// Chọn model 2, cỡ module, mức sửa lỗi M, nạp dữ liệu rồi in.
+	b.buf.Write([]byte{gs, '(', 'k', 4, 0, '1', 'A', '2', 0})
+	b.buf.Write([]byte{gs, '(', 'k', 3, 0, '1', 'C', byte(moduleSize)})
+	b.buf.Write([]byte{gs, '(', 'k', 3, 0, '1', 'E', '1'})
+	n := len(data) + 3
+	b.buf.Write([]byte{gs, '(', 'k', byte(n), byte(n >> 8), '1', 'P', '0'})
+	b.buf.WriteString(data)
+	b.buf.Write([]byte{gs, '(', 'k', 3, 0, '1', 'Q', '0'})
+	b.buf.WriteByte(lf)
+	return nil
+}
+
+// Barcode prints data as a native Code128 barcode, 60 dots high with the text
+// below (GS k 73). The module width is the widest of 2 or 3 dots that fits on
+// the paper; data that does not fit at 2 dots is rejected.
+func (b *Builder) Barcode(data string) error {
+	if len(data) > MaxBarcodeBytes {
+		return fmt.Errorf("%w: barcode data must be at most %d bytes", ErrInvalidData, MaxBarcodeBytes)
+	}
+	// Dùng barcoderender để kiểm tra dữ liệu và ước lượng độ rộng; máy in tự
+	// mã hóa theo bộ B nên có thể rộng hơn đôi chút, vẫn nằm trong lề.
+	code, err := barcoderender.New(barcoderender.Code128, data, barcoderender.DefaultOptions(barcoderender.Code128))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	modules := 11*(len(data)+2) + 13
+	modules = max(modules, len(code.Modules()[0]))
+	moduleWidth := min(b.opts.Paper.Dots()/modules, 3)
+	if moduleWidth < 2 {
+		return fmt.Errorf("%w: barcode %q is too wide for %s paper", ErrInvalidData, data, b.opts.Paper)
+	}
+
+	// "{B" chọn bộ B; ký tự '{' trong dữ liệu phải gửi thành "{{".
+	payload := "{B" + strings.ReplaceAll(data, "{", "{{")
+	if len(payload) > 255 {
+		return fmt.Errorf("%w: barcode data must be at most %d bytes", ErrInvalidData, MaxBarcodeBytes)
+	}
+	b.buf.Write([]byte{gs, 'h', 60, gs, 'w', byte(moduleWidth), gs, 'H', 2})
+	b.buf.Write([]byte{gs, 'k', 73, byte(len(payload))})
+	b.buf.WriteString(payload)
+	b.buf.WriteByte(lf)
+	return nil
+}
+
+// Cut feeds the paper to the cutter and makes a partial cut (GS V 66).
+func (b *Builder) Cut() {
+	b.buf.Write([]byte{gs, 'V', 66, 0})
+}
+
+// Bytes returns the stream built so far.
+func (b *Builder) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// Len is the length of the stream in bytes.
+func (b *Builder) Len() int {
+	return b.buf.Len()
+}
+
+// WriteTo writes the stream to w, e.g. a connection to the printer.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(b.buf.Bytes())
+	return int64(n), err
+}
+
+// Wrap breaks s into lines of at most width characters, at spaces when
+// possible. Line breaks in s are kept and words longer than width are split.
+func Wrap(s string, width int) []string {
+	width = max(width, 1)
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(norm.NFC.String(s), "\r\n", "\n"), "\n") {
+		var line []rune
+		for _, word := range strings.Fields(para) {
+			w := []rune(word)
+			if len(line) > 0 && len(line)+1+len(w) <= width {
+				line = append(append(line, ' '), w...)
+				continue
+			}
+			if len(line) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			for len(w) > width {
+				lines = append(lines, string(w[:width]))
+				w = w[width:]
+			}
+			line = w
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// encode chuyển s sang bảng mã của máy in; ký tự điều khiển trừ xuống dòng bị
+// bỏ để dữ liệu của người dùng không thành lệnh ESC/POS.
+func (b *Builder) encode(s string) []byte {
+	if b.opts.Charset == CP1258 {
+		return encodeCP1258(s)
+	}
+	var out []byte
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r == 'đ':
+			out = append(out, 'd')
+		case r == 'Đ':
+			out = append(out, 'D')
+		case r == '\n' || r == '\t':
+			out = append(out, byte(r))
+		case r < 0x20 || r == 0x7f:
+		case r < 0x80:
+			out = append(out, byte(r))
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// toneMarks là năm dấu thanh tiếng Việt; Windows-1258 chỉ có chúng ở dạng dấu
+// kết hợp, còn â, ê, ô, ơ, ư, ă, đ là ký tự dựng sẵn.
+var toneMarks = map[rune]bool{0x0300: true, 0x0301: true, 0x0303: true, 0x0309: true, 0x0323: true}
+
+// encodeCP1258 tách dấu thanh khỏi mỗi ký tự rồi mã hóa sang Windows-1258,
+// vd "ể" thành "ê" + dấu hỏi kết hợp.
+func encodeCP1258(s string) []byte {
+	var out []byte
+	var base []rune
+	var tones []rune
+	flush := func() {
+		for _, r := range []rune(norm.NFC.String(string(base))) {
+			out = appendCP1258(out, r)
+		}
+		for _, r := range tones {
+			out = appendCP1258(out, r)
+		}
+		base, tones = base[:0], tones[:0]
+	}
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case toneMarks[r]:
+			tones = append(tones, r)
+		case unicode.Is(unicode.Mn, r):
+			base = append(base, r)
+		default:
+			flush()
+			base = append(base, r)
+		}
+	}
+	flush()
+	return out
+}
+
+func appendCP1258(out []byte, r rune) []byte {
+	if r == '\n' || r == '\t' {
+		return append(out, byte(r))
+	}
+	if r < 0x20 || r == 0x7f {
+		return out
+	}
+	if c, ok := charmap.Windows1258.EncodeRune(r); ok {
+		return append(out, c)
+	}
+	return append(out, '?')
+}
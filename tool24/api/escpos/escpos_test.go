@@ -0,0 +1,123 @@
+package escpos
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	assert.Equal(t, []byte{0x1b, '@', 0x1b, 't', 0}, New(Options{}).Bytes())
+	assert.Equal(t, []byte{0x1b, '@', 0x1b, 't', 52}, New(Options{Charset: CP1258}).Bytes())
+}
+
+func TestText(t *testing.T) {
+	b := New(Options{})
+	start := b.Len()
+	b.SetBold(true)
+	b.Line("Kiểm kê kho Đà Nẵng €")
+	b.SetBold(false)
+	assert.Equal(t, "\x1bE\x01Kiem ke kho Da Nang ?\n\x1bE\x00", string(b.Bytes()[start:]))
+
+	// Dữ liệu người dùng không được chứa lệnh ESC/POS.
+	b = New(Options{})
+	start = b.Len()
+	b.Text("a\x1b@b\x1dV\x00c")
+	assert.Equal(t, "a@bVc", string(b.Bytes()[start:]))
+}
+
+func TestTextCP1258(t *testing.T) {
+	b := New(Options{Charset: CP1258})
+	start := b.Len()
+	b.Text("Kiểm Đà ă")
+	// ê = 0xea, dấu hỏi = 0xd2, Đ = 0xd0, dấu huyền = 0xcc, ă = 0xe3.
+	assert.Equal(t, []byte{'K', 'i', 0xea, 0xd2, 'm', ' ', 0xd0, 'a', 0xcc, ' ', 0xe3}, b.Bytes()[start:])
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, []string{"Đếm lại kệ A", "và B trước", "17h"}, Wrap("Đếm lại kệ A và B trước 17h", 12))
+	assert.Equal(t, []string{"abcde", "fghij", "k", "", "x y"}, Wrap("abcdefghijk\n\nx   y", 5))
+	assert.Equal(t, []string{""}, Wrap("", 10))
+}
+
+func TestParagraphAndRule(t *testing.T) {
+	b := New(Options{Paper: Paper58})
+	assert.Equal(t, 32, b.Columns())
+	b.SetSize(2, 2)
+	assert.Equal(t, 16, b.Columns())
+	start := b.Len()
+	b.Paragraph("Kiem ke kho hang thang muoi hai")
+	b.Rule()
+	assert.Equal(t, "Kiem ke kho hang\nthang muoi hai\n"+strings.Repeat("-", 16)+"\n", string(b.Bytes()[start:]))
+}
+
+func TestQR(t *testing.T) {
+	b := New(Options{})
+	start := b.Len()
+	assert.NoError(t, b.QR("T000042", 6))
+	want := []byte{
+		0x1d, '(', 'k', 4, 0, '1', 'A', '2', 0,
+		0x1d, '(', 'k', 3, 0, '1', 'C', 6,
+		0x1d, '(', 'k', 3, 0, '1', 'E', '1',
+		0x1d, '(', 'k', 10, 0, '1', 'P', '0', 'T', '0', '0', '0', '0', '4', '2',
+		0x1d, '(', 'k', 3, 0, '1', 'Q', '0', '\n',
+	}
+	assert.Equal(t, want, b.Bytes()[start:])
+
+	assert.True(t, errors.Is(b.QR("", 6), ErrInvalidData))
+	assert.True(t, errors.Is(b.QR(strings.Repeat("x", MaxQRBytes+1), 6), ErrInvalidData))
+	assert.True(t, errors.Is(b.QR("x", 17), ErrInvalidOptions))
+
+	// Dữ liệu dài hơn 255 byte dùng cả byte cao của độ dài.
+	b = New(Options{})
+	start = b.Len()
+	assert.NoError(t, b.QR(strings.Repeat("x", 300), 4))
+	assert.Contains(t, string(b.Bytes()[start:]), "\x1d(k\x2f\x011P0xxx")
+}
+
+func TestBarcode(t *testing.T) {
+	b := New(Options{Paper: Paper58})
+	start := b.Len()
+	assert.NoError(t, b.Barcode("T{42"))
+	assert.Equal(t, "\x1dh\x3c\x1dw\x03\x1dH\x02\x1dk\x49\x07{BT{{42\n", string(b.Bytes()[start:]))
+
+	// 20 ký tự bộ B rộng 255 module: khổ 58mm chỉ còn module 1 dot.
+	long := "42-20241224093000-AB"
+	assert.True(t, errors.Is(b.Barcode(long), ErrInvalidData))
+	b = New(Options{Paper: Paper80})
+	start = b.Len()
+	assert.NoError(t, b.Barcode(long))
+	assert.True(t, bytes.HasPrefix(b.Bytes()[start:], []byte("\x1dh\x3c\x1dw\x02")))
+
+	assert.True(t, errors.Is(b.Barcode("Kiểm"), ErrInvalidData))
+	assert.True(t, errors.Is(b.Barcode(""), ErrInvalidData))
+}
+
+func TestCut(t *testing.T) {
+	b := New(Options{})
+	start := b.Len()
+	b.Feed(2)
+	b.Cut()
+	assert.Equal(t, []byte{0x1b, 'd', 2, 0x1d, 'V', 66, 0}, b.Bytes()[start:])
+}
+
+func TestParse(t *testing.T) {
+	for in, want := range map[string]Paper{"": Paper80, "80mm": Paper80, "58": Paper58, " 58MM ": Paper58} {
+		got, err := ParsePaper(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err := ParsePaper("110mm")
+	assert.True(t, errors.Is(err, ErrInvalidOptions))
+
+	for in, want := range map[string]Charset{"": ASCII, "ASCII": ASCII, "cp1258": CP1258, "Windows-1258": CP1258} {
+		got, err := ParseCharset(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	_, err = ParseCharset("utf-8")
+	assert.True(t, errors.Is(err, ErrInvalidOptions))
+}
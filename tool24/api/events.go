@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// todoEventsChannel là kênh Postgres NOTIFY mà mọi replica cùng LISTEN.
+const todoEventsChannel = "todo_events"
+
+// Các loại sự kiện gửi qua GET /todos/events.
+const (
+	StreamCreate = "create"
+	StreamUpdate = "update"
+	StreamStatus = "status"
+	StreamDelete = "delete"
+)
+
+// TodoEvent is one change pushed to event stream clients. ID is the ID of the
+// audit record, so it can be used as Last-Event-ID.
+type TodoEvent struct {
+	ID    int64     `json:"id"`
+	Type  string    `json:"type"`
+	Actor string    `json:"actor"`
+	At    time.Time `json:"at"`
+	Todo  Todo      `json:"todo"`
+	// PreviousListID is set when the change moved the todo out of another
+	// list, so that list's subscribers can drop it; "" is the default list.
+	PreviousListID *string `json:"previous_list_id,omitempty"`
+}
+
+// streamEventType gom các thao tác audit về bốn loại sự kiện của luồng.
+func streamEventType(operation string) string {
+	switch operation {
+	case AuditCreate:
+		return StreamCreate
+	case AuditStatus:
+		return StreamStatus
+	case AuditDelete, AuditPurge:
+		return StreamDelete
+	default:
+		return StreamUpdate
+	}
+}
+
+// notifyChange báo ID bản ghi audit qua NOTIFY. Postgres chỉ gửi khi
+// transaction commit, nên client không bao giờ thấy thay đổi bị rollback.
+func notifyChange(ctx context.Context, tx pgx.Tx, auditID int64) error {
+	_, err := tx.Exec(ctx, "SELECT pg_notify($1, $2)", todoEventsChannel, strconv.FormatInt(auditID, 10))
+	if err != nil {
+		return fmt.Errorf("failed to notify change: %v", err)
+	}
+	return nil
+}
+
+func (db *Db) getTodoEventDB(ctx context.Context, auditID int64) (TodoEvent, error) {
+	var ev TodoEvent
+	var operation, todoID string
+	var changes, snapshot []byte
+	err := db.Conn.QueryRow(ctx,
+		"SELECT id, todo_id, actor, operation, created_at, changes, snapshot FROM todo_audit WHERE id = $1", auditID).
+		Scan(&ev.ID, &todoID, &ev.Actor, &operation, &ev.At, &changes, &snapshot)
+	if err != nil {
+		return TodoEvent{}, fmt.Errorf("failed to retrieve event %d: %v", auditID, err)
+	}
+	ev.Type = streamEventType(operation)
+	ev.Todo.ID = todoID
+	if err := decodeEventTodo(&ev, changes, snapshot); err != nil {
+		return TodoEvent{}, fmt.Errorf("failed to decode event %d: %v", auditID, err)
+	}
+
+	return ev, nil
+}
+
+// decodeEventTodo điền todo và danh sách cũ của sự kiện từ bản ghi audit. Xóa
+// vĩnh viễn không có ảnh chụp nên list của todo lấy từ diff, để client chỉ nhận
+// sự kiện của các danh sách mình theo dõi.
+func decodeEventTodo(ev *TodoEvent, changes, snapshot []byte) error {
+	previous, err := previousListID(changes)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		if previous != nil {
+			ev.Todo.ListID = *previous
+		}
+		return nil
+	}
+	ev.PreviousListID = previous
+	return json.Unmarshal(snapshot, &ev.Todo)
+}
+
+// previousListID đọc danh sách cũ từ diff của bản ghi audit. Tạo mới có
+// before là null nên không tính là chuyển danh sách.
+func previousListID(changes []byte) (*string, error) {
+	var diff map[string]FieldChange
+	if err := json.Unmarshal(changes, &diff); err != nil {
+		return nil, err
+	}
+	if before, ok := diff["list_id"].Before.(string); ok {
+		return &before, nil
+	}
+	return nil, nil
+}
+
+// ListenTodoEvents giữ một kết nối LISTEN riêng và đẩy mọi thay đổi (của bất
+// kỳ replica nào) vào broker. Tự kết nối lại khi mất kết nối.
+func (db *Db) ListenTodoEvents(ctx context.Context, broker *EventBroker) {
+	for {
+		err := db.listenTodoEvents(ctx, broker)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Mất kết nối LISTEN %s: %v", todoEventsChannel, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (db *Db) listenTodoEvents(ctx context.Context, broker *EventBroker) error {
+	conn, err := db.Conn.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+todoEventsChannel); err != nil {
+		return err
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		auditID, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			log.Printf("Bỏ qua thông báo không hợp lệ %q", n.Payload)
+			continue
+		}
+		ev, err := db.getTodoEventDB(ctx, auditID)
+		if err != nil {
+			log.Printf("Không thể đọc sự kiện: %v", err)
+			continue
+		}
+		broker.Publish(ev)
+	}
+}
+
+// EventBroker fans events out to stream subscribers in this process and keeps
+// the last few in a ring buffer so reconnecting clients can resume.
+type EventBroker struct {
+	mu          sync.Mutex
+	buffer      []TodoEvent
+	size        int
+	subscribers map[chan TodoEvent]struct{}
+}
+
+func NewEventBroker(size int) *EventBroker {
+	return &EventBroker{size: size, subscribers: map[chan TodoEvent]struct{}{}}
+}
+
+func (b *EventBroker) Publish(ev TodoEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buffer = append(b.buffer, ev)
+	if len(b.buffer) > b.size {
+		b.buffer = b.buffer[len(b.buffer)-b.size:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Client quá chậm: đóng luồng để nó kết nối lại với Last-Event-ID.
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered events after
+// lastID. complete is false when lastID is no longer in the buffer, in which
+// case the client has missed events and must reload.
+func (b *EventBroker) Subscribe(lastID int64) (replay []TodoEvent, complete bool, ch chan TodoEvent, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete = true
+	if lastID > 0 {
+		complete = false
+		for i, ev := range b.buffer {
+			if ev.ID == lastID {
+				replay = append(replay, b.buffer[i+1:]...)
+				complete = true
+				break
+			}
+		}
+	}
+
+	ch = make(chan TodoEvent, 64)
+	b.subscribers[ch] = struct{}{}
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return replay, complete, ch, cancel
+}
+
+type EventHandler struct {
+	broker    *EventBroker
+	heartbeat time.Duration
+}
+
+func NewEventHandler(broker *EventBroker) *EventHandler {
+	return &EventHandler{broker: broker, heartbeat: 15 * time.Second}
+}
+
+func writeEvent(w http.ResponseWriter, ev TodoEvent) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data)
+	return err
+}
+
+// @Summary Stream todo changes
+// @Description Server-Sent Events stream of create, update, status and delete events. Reconnect with Last-Event-ID to resume; a "reset" event means events were missed and the client should reload GET /todos.
+// @Tags Todos
+// @Produce text/event-stream
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Success 200 {string} string "Event stream"
+// @Router /todos/events [get]
+func (h *EventHandler) StreamTodoEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Streaming is not supported"})
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	lastID, _ := strconv.ParseInt(lastEventID, 10, 64)
+
+	replay, complete, events, cancel := h.broker.Subscribe(lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, ev := range replay {
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBroker_Replay(t *testing.T) {
+	broker := NewEventBroker(3)
+	for id := int64(1); id <= 5; id++ {
+		broker.Publish(TodoEvent{ID: id, Type: StreamUpdate})
+	}
+
+	replay, complete, _, cancel := broker.Subscribe(3)
+	defer cancel()
+	assert.True(t, complete)
+	assert.Len(t, replay, 2)
+	assert.Equal(t, int64(4), replay[0].ID)
+
+	replay, complete, _, cancel2 := broker.Subscribe(1)
+	defer cancel2()
+	assert.False(t, complete, "event 1 has fallen out of the buffer")
+	assert.Empty(t, replay)
+}
+
+func TestEventBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := NewEventBroker(10)
+	_, _, ch, cancel := broker.Subscribe(0)
+	defer cancel()
+
+	for id := int64(1); id <= 100; id++ {
+		broker.Publish(TodoEvent{ID: id})
+	}
+
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Equal(t, 64, received, "channel is closed once its buffer is full")
+}
+
+func TestStreamTodoEvents(t *testing.T) {
+	broker := NewEventBroker(10)
+	broker.Publish(TodoEvent{ID: 1, Type: StreamCreate, Todo: Todo{ID: "a"}})
+	broker.Publish(TodoEvent{ID: 2, Type: StreamStatus, Todo: Todo{ID: "a", Done: true}})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/todos/events", NewEventHandler(broker).StreamTodoEvents).Methods("GET")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL+"/todos/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(TodoEvent{ID: 3, Type: StreamDelete, Todo: Todo{ID: "a"}})
+	}()
+
+	var ids, types []string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(types) < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if v, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, v)
+		}
+		if v, ok := strings.CutPrefix(line, "event: "); ok {
+			types = append(types, v)
+		}
+	}
+
+	assert.Equal(t, []string{"2", "3"}, ids, "event 1 was already seen and must not be replayed")
+	assert.Equal(t, []string{StreamStatus, StreamDelete}, types)
+}
+
+func TestStreamEventType(t *testing.T) {
+	assert.Equal(t, StreamCreate, streamEventType(AuditCreate))
+	assert.Equal(t, StreamStatus, streamEventType(AuditStatus))
+	assert.Equal(t, StreamDelete, streamEventType(AuditPurge))
+	assert.Equal(t, StreamUpdate, streamEventType(AuditRestore))
+}
+
+func TestPreviousListID(t *testing.T) {
+	diff := func(before, after *Todo) []byte {
+		b, _ := json.Marshal(diffTodos(before, after))
+		return b
+	}
+	home, def := "home", ""
+
+	previous, err := previousListID(diff(&Todo{ListID: "home"}, &Todo{ListID: "work"}))
+	assert.NoError(t, err)
+	assert.Equal(t, &home, previous)
+
+	previous, err = previousListID(diff(&Todo{}, &Todo{ListID: "work"}))
+	assert.NoError(t, err)
+	assert.Equal(t, &def, previous, "moving out of the default list")
+
+	for name, changes := range map[string][]byte{
+		"create":    diff(nil, &Todo{ListID: "work"}),
+		"same list": diff(&Todo{ListID: "work", Title: "A"}, &Todo{ListID: "work", Title: "B"}),
+	} {
+		previous, err := previousListID(changes)
+		assert.NoError(t, err)
+		assert.Nil(t, previous, name)
+	}
+}
+
+func TestDecodeEventTodo_Purge(t *testing.T) {
+	changes, _ := json.Marshal(diffTodos(&Todo{ID: "a", ListID: "home", CreatedAt: time.Now()}, nil))
+
+	ev := TodoEvent{Type: StreamDelete, Todo: Todo{ID: "a"}}
+	assert.NoError(t, decodeEventTodo(&ev, changes, nil))
+	assert.Equal(t, "home", ev.Todo.ListID, "purges carry the list they were removed from")
+	assert.Nil(t, ev.PreviousListID)
+}
@@ -0,0 +1,757 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/dataloader"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+const graphqlSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+	subscription: Subscription
+}
+
+scalar Time
+
+type Query {
+	todo(id: ID!): Todo
+	todos(filter: TodoFilter, first: Int = 20, after: String): TodoConnection!
+	lists: [List!]!
+	list(id: String!): List!
+	tags: [TagCount!]!
+	counts(listId: String): Counts!
+}
+
+type Mutation {
+	createTodo(input: TodoInput!): Todo!
+	updateTodo(id: ID!, input: TodoInput!, version: Int): Todo!
+	toggleTodo(id: ID!, version: Int): Todo!
+	deleteTodo(id: ID!): Boolean!
+}
+
+type Subscription {
+	todoChanged(listId: String): TodoEvent!
+}
+
+input TodoFilter {
+	done: Boolean
+	listId: String
+	tag: String
+	search: String
+	dueBefore: Time
+}
+
+input TodoInput {
+	title: String!
+	description: String
+	done: Boolean
+	dueAt: Time
+	recurrence: String
+	remindAt: [String!]
+	listId: String
+	tags: [String!]
+}
+
+type Todo {
+	id: ID!
+	title: String!
+	description: String!
+	done: Boolean!
+	createdAt: Time!
+	doneAt: Time
+	dueAt: Time
+	createdBy: String!
+	version: Int!
+	recurrence: String
+	remindAt: [String!]!
+	tags: [String!]!
+	externalId: String
+	list: List!
+	previous: Todo
+}
+
+type List {
+	id: String!
+	todos(done: Boolean): [Todo!]!
+	counts: Counts!
+}
+
+type Counts {
+	total: Int!
+	done: Int!
+	open: Int!
+	overdue: Int!
+}
+
+type TagCount {
+	name: String!
+	count: Int!
+}
+
+type TodoConnection {
+	edges: [TodoEdge!]!
+	pageInfo: PageInfo!
+	totalCount: Int!
+}
+
+type TodoEdge {
+	cursor: String!
+	node: Todo!
+}
+
+type PageInfo {
+	hasNextPage: Boolean!
+	endCursor: String
+}
+
+type TodoEvent {
+	id: ID!
+	type: String!
+	actor: String!
+	at: Time!
+	todo: Todo!
+}
+`
+
+// GraphQLStore gom các truy vấn theo lô cho field lồng nhau, tránh N+1 query.
+type GraphQLStore interface {
+	ListIDsDB(ctx context.Context) ([]string, error)
+	GetTodosByListIDsDB(ctx context.Context, listIDs []string) (map[string][]Todo, error)
+	GetTodosByIDsDB(ctx context.Context, ids []string) (map[string]Todo, error)
+}
+
+func (db *Db) ListIDsDB(ctx context.Context) ([]string, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT DISTINCT COALESCE(list_id, '') AS list_id FROM todo WHERE deleted_at IS NULL ORDER BY list_id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lists: %v", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (db *Db) GetTodosByListIDsDB(ctx context.Context, listIDs []string) (map[string][]Todo, error) {
+	rows, err := db.Conn.Query(ctx,
+		"SELECT "+todoColumns+" FROM todo WHERE COALESCE(list_id, '') = ANY($1) AND deleted_at IS NULL ORDER BY created_at, id", listIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve todos by list: %v", err)
+	}
+	defer rows.Close()
+
+	byList := map[string][]Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, err
+		}
+		byList[todo.ListID] = append(byList[todo.ListID], todo)
+	}
+
+	return byList, rows.Err()
+}
+
+func (db *Db) GetTodosByIDsDB(ctx context.Context, ids []string) (map[string]Todo, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT "+todoColumns+" FROM todo WHERE id = ANY($1) AND deleted_at IS NULL", ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve todos: %v", err)
+	}
+	defer rows.Close()
+
+	byID := map[string]Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, err
+		}
+		byID[todo.ID] = todo
+	}
+
+	return byID, rows.Err()
+}
+
+// graphqlLoaders là các dataloader của một request (hoặc một subscription).
+type graphqlLoaders struct {
+	todosByList *dataloader.Loader
+	todoByID    *dataloader.Loader
+}
+
+type loadersKey struct{}
+
+func newGraphQLLoaders(store GraphQLStore, cached bool) *graphqlLoaders {
+	var opts []dataloader.Option
+	if !cached {
+		// Subscription sống lâu: không giữ kết quả cũ giữa các sự kiện.
+		opts = append(opts, dataloader.WithCache(&dataloader.NoCache{}))
+	}
+
+	return &graphqlLoaders{
+		todosByList: dataloader.NewBatchedLoader(func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+			byList, err := store.GetTodosByListIDsDB(ctx, keys.Keys())
+			results := make([]*dataloader.Result, len(keys))
+			for i, key := range keys {
+				results[i] = &dataloader.Result{Data: byList[key.String()], Error: err}
+			}
+			return results
+		}, opts...),
+		todoByID: dataloader.NewBatchedLoader(func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+			byID, err := store.GetTodosByIDsDB(ctx, keys.Keys())
+			results := make([]*dataloader.Result, len(keys))
+			for i, key := range keys {
+				if todo, ok := byID[key.String()]; ok {
+					results[i] = &dataloader.Result{Data: &todo, Error: err}
+				} else {
+					results[i] = &dataloader.Result{Data: (*Todo)(nil), Error: err}
+				}
+			}
+			return results
+		}, opts...),
+	}
+}
+
+func loadersFromContext(ctx context.Context) *graphqlLoaders {
+	return ctx.Value(loadersKey{}).(*graphqlLoaders)
+}
+
+func loadListTodos(ctx context.Context, listID string) ([]Todo, error) {
+	data, err := loadersFromContext(ctx).todosByList.Load(ctx, dataloader.StringKey(listID))()
+	if err != nil {
+		return nil, err
+	}
+	todos, _ := data.([]Todo)
+	return todos, nil
+}
+
+// gqlError gắn mã lỗi vào extensions.code, tương ứng với mã HTTP trong api.go.
+type gqlError struct {
+	err  error
+	code string
+}
+
+func (e gqlError) Error() string { return e.err.Error() }
+
+func (e gqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func graphqlError(err error) error {
+	switch {
+	case errors.Is(err, ErrTodoNotFound):
+		return gqlError{err, "NOT_FOUND"}
+	case errors.Is(err, ErrVersionConflict):
+		return gqlError{err, "CONFLICT"}
+	case errors.Is(err, ErrInvalidRecurrence), errors.Is(err, ErrInvalidReminder), errors.Is(err, ErrInvalidPayment):
+		return gqlError{err, "BAD_USER_INPUT"}
+	default:
+		return gqlError{err, "INTERNAL"}
+	}
+}
+
+// Resolver là root resolver; mọi thao tác trên todo đi qua TodoStore.
+type Resolver struct {
+	todoStore TodoStore
+	gqlStore  GraphQLStore
+	broker    *EventBroker
+}
+
+type todoFilterInput struct {
+	Done      *bool
+	ListID    *string
+	Tag       *string
+	Search    *string
+	DueBefore *graphql.Time
+}
+
+// todoFilter chuyển input GraphQL sang bộ lọc dùng chung với GET /todos.
+func (f *todoFilterInput) todoFilter() *todoFilter {
+	if f == nil {
+		return nil
+	}
+	filter := &todoFilter{Done: f.Done, ListID: f.ListID, Tag: f.Tag, Search: f.Search}
+	if f.DueBefore != nil {
+		filter.DueBefore = &f.DueBefore.Time
+	}
+	return filter
+}
+
+func (r *Resolver) Todo(ctx context.Context, args struct{ ID graphql.ID }) (*todoResolver, error) {
+	todo, err := r.todoStore.GetTodoByIdDB(ctx, string(args.ID))
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			return nil, nil
+		}
+		return nil, graphqlError(err)
+	}
+	return &todoResolver{todo}, nil
+}
+
+func (r *Resolver) Todos(ctx context.Context, args struct {
+	Filter *todoFilterInput
+	First  int32
+	After  *string
+}) (*todoConnectionResolver, error) {
+	todos, err := r.todoStore.GetAllTodoDB(ctx)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	var after *todoCursor
+	if args.After != nil {
+		var err error
+		if after, err = decodeCursor(*args.After); err != nil {
+			return nil, gqlError{err, "BAD_USER_INPUT"}
+		}
+	}
+
+	first := int(args.First)
+	if first < 0 || first > 100 {
+		return nil, gqlError{errors.New("first must be between 0 and 100"), "BAD_USER_INPUT"}
+	}
+
+	page, total, hasNext := pageTodos(todos, args.Filter.todoFilter(), after, first)
+	return &todoConnectionResolver{page: page, total: total, hasNext: hasNext}, nil
+}
+
+func (r *Resolver) Lists(ctx context.Context) ([]*listResolver, error) {
+	ids, err := r.gqlStore.ListIDsDB(ctx)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	lists := make([]*listResolver, 0, len(ids))
+	for _, id := range ids {
+		lists = append(lists, &listResolver{id})
+	}
+	return lists, nil
+}
+
+func (r *Resolver) List(args struct{ ID string }) *listResolver {
+	return &listResolver{args.ID}
+}
+
+func (r *Resolver) Tags(ctx context.Context) ([]*tagCountResolver, error) {
+	todos, err := r.todoStore.GetAllTodoDB(ctx)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	counts := map[string]int32{}
+	for _, todo := range todos {
+		for _, tag := range todo.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]*tagCountResolver, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &tagCountResolver{name, count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].name < tags[j].name })
+	return tags, nil
+}
+
+func (r *Resolver) Counts(ctx context.Context, args struct{ ListID *string }) (*countsResolver, error) {
+	if args.ListID != nil {
+		return (&listResolver{*args.ListID}).Counts(ctx)
+	}
+
+	todos, err := r.todoStore.GetAllTodoDB(ctx)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return countTodos(todos), nil
+}
+
+type todoInput struct {
+	Title       string
+	Description *string
+	Done        *bool
+	DueAt       *graphql.Time
+	Recurrence  *string
+	RemindAt    *[]string
+	ListID      *string
+	Tags        *[]string
+}
+
+// apply ghi các trường có trong input lên todo; trường bỏ trống giữ nguyên.
+func (in todoInput) apply(todo Todo) Todo {
+	todo.Title = in.Title
+	if in.Description != nil {
+		todo.Desc = *in.Description
+	}
+	if in.Done != nil {
+		todo.Done = *in.Done
+	}
+	if in.DueAt != nil {
+		due := in.DueAt.Time
+		todo.DueAt = &due
+	}
+	if in.Recurrence != nil {
+		todo.Recurrence = *in.Recurrence
+	}
+	if in.RemindAt != nil {
+		todo.RemindAt = *in.RemindAt
+	}
+	if in.ListID != nil {
+		todo.ListID = *in.ListID
+	}
+	if in.Tags != nil {
+		todo.Tags = *in.Tags
+	}
+	return todo
+}
+
+func (r *Resolver) CreateTodo(ctx context.Context, args struct{ Input todoInput }) (*todoResolver, error) {
+	todo, err := r.todoStore.CreateTodoDB(ctx, args.Input.apply(Todo{}))
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return &todoResolver{todo}, nil
+}
+
+func (r *Resolver) UpdateTodo(ctx context.Context, args struct {
+	ID      graphql.ID
+	Input   todoInput
+	Version *int32
+}) (*todoResolver, error) {
+	current, err := r.todoStore.GetTodoByIdDB(ctx, string(args.ID))
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	// current được đọc ngoài transaction của UpdateTodoDB nên luôn ghim version
+	// vừa đọc: không có version thì một thay đổi chen vào giữa thành xung đột
+	// thay vì bị ghi đè bằng các trường cũ.
+	todo := args.Input.apply(current)
+	if args.Version != nil {
+		todo.Version = int(*args.Version)
+	}
+	updated, err := r.todoStore.UpdateTodoDB(ctx, string(args.ID), todo)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return &todoResolver{updated}, nil
+}
+
+// ToggleTodo đi qua ChangeStatusDB như POST /todo/changeStatus/{id} để audit
+// và sự kiện có loại "status"; version được kiểm tra trong transaction đó, và
+// kết quả là todo mà transaction đó ghi.
+func (r *Resolver) ToggleTodo(ctx context.Context, args struct {
+	ID      graphql.ID
+	Version *int32
+}) (*todoResolver, error) {
+	version := 0
+	if args.Version != nil {
+		version = int(*args.Version)
+	}
+	opCtx, op := WithOperation(WithExpectedVersion(ctx, version))
+	if err := r.todoStore.ChangeStatusDB(opCtx, string(args.ID)); err != nil {
+		return nil, graphqlError(err)
+	}
+	return &todoResolver{*op.Todo}, nil
+}
+
+func (r *Resolver) DeleteTodo(ctx context.Context, args struct{ ID graphql.ID }) (bool, error) {
+	if err := r.todoStore.DeleteTodoByIdDB(ctx, string(args.ID)); err != nil {
+		return false, graphqlError(err)
+	}
+	return true, nil
+}
+
+func (r *Resolver) TodoChanged(ctx context.Context, args struct{ ListID *string }) (<-chan *todoEventResolver, error) {
+	_, _, events, cancel := r.broker.Subscribe(0)
+
+	out := make(chan *todoEventResolver)
+	go func() {
+		defer close(out)
+		defer cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				if args.ListID != nil && ev.Todo.ListID != *args.ListID {
+					continue
+				}
+				select {
+				case out <- &todoEventResolver{ev}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type todoResolver struct {
+	todo Todo
+}
+
+func timePtr(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
+
+func (t *todoResolver) ID() graphql.ID          { return graphql.ID(t.todo.ID) }
+func (t *todoResolver) Title() string           { return t.todo.Title }
+func (t *todoResolver) Description() string     { return t.todo.Desc }
+func (t *todoResolver) Done() bool              { return t.todo.Done }
+func (t *todoResolver) CreatedAt() graphql.Time { return graphql.Time{Time: t.todo.CreatedAt} }
+func (t *todoResolver) DoneAt() *graphql.Time   { return timePtr(t.todo.DoneAt) }
+func (t *todoResolver) DueAt() *graphql.Time    { return timePtr(t.todo.DueAt) }
+func (t *todoResolver) CreatedBy() string       { return t.todo.CreatedBy }
+func (t *todoResolver) Version() int32          { return int32(t.todo.Version) }
+func (t *todoResolver) RemindAt() []string      { return nonNil(t.todo.RemindAt) }
+func (t *todoResolver) Tags() []string          { return nonNil(t.todo.Tags) }
+func (t *todoResolver) List() *listResolver     { return &listResolver{t.todo.ListID} }
+func (t *todoResolver) ExternalID() *string {
+	if t.todo.ExternalID == "" {
+		return nil
+	}
+	return &t.todo.ExternalID
+}
+
+func (t *todoResolver) Recurrence() *string {
+	if t.todo.Recurrence == "" {
+		return nil
+	}
+	return &t.todo.Recurrence
+}
+
+func (t *todoResolver) Previous(ctx context.Context) (*todoResolver, error) {
+	if t.todo.PreviousID == "" {
+		return nil, nil
+	}
+	data, err := loadersFromContext(ctx).todoByID.Load(ctx, dataloader.StringKey(t.todo.PreviousID))()
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	if previous, _ := data.(*Todo); previous != nil {
+		return &todoResolver{*previous}, nil
+	}
+	return nil, nil
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+type listResolver struct {
+	id string
+}
+
+func (l *listResolver) ID() string { return l.id }
+
+func (l *listResolver) Todos(ctx context.Context, args struct{ Done *bool }) ([]*todoResolver, error) {
+	todos, err := loadListTodos(ctx, l.id)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+
+	resolvers := []*todoResolver{}
+	for _, todo := range todos {
+		if args.Done == nil || todo.Done == *args.Done {
+			resolvers = append(resolvers, &todoResolver{todo})
+		}
+	}
+	return resolvers, nil
+}
+
+func (l *listResolver) Counts(ctx context.Context) (*countsResolver, error) {
+	todos, err := loadListTodos(ctx, l.id)
+	if err != nil {
+		return nil, graphqlError(err)
+	}
+	return countTodos(todos), nil
+}
+
+type countsResolver struct {
+	total, done, overdue int32
+}
+
+func countTodos(todos []Todo) *countsResolver {
+	c := &countsResolver{}
+	now := time.Now()
+	for _, todo := range todos {
+		c.total++
+		if todo.Done {
+			c.done++
+		} else if todo.DueAt != nil && todo.DueAt.Before(now) {
+			c.overdue++
+		}
+	}
+	return c
+}
+
+func (c *countsResolver) Total() int32   { return c.total }
+func (c *countsResolver) Done() int32    { return c.done }
+func (c *countsResolver) Open() int32    { return c.total - c.done }
+func (c *countsResolver) Overdue() int32 { return c.overdue }
+
+type tagCountResolver struct {
+	name  string
+	count int32
+}
+
+func (t *tagCountResolver) Name() string { return t.name }
+func (t *tagCountResolver) Count() int32 { return t.count }
+
+type todoConnectionResolver struct {
+	page    []Todo
+	total   int
+	hasNext bool
+}
+
+func (c *todoConnectionResolver) Edges() []*todoEdgeResolver {
+	edges := make([]*todoEdgeResolver, 0, len(c.page))
+	for _, todo := range c.page {
+		edges = append(edges, &todoEdgeResolver{todo})
+	}
+	return edges
+}
+
+func (c *todoConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNext: c.hasNext}
+	if len(c.page) > 0 {
+		cursor := encodeCursor(c.page[len(c.page)-1])
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+func (c *todoConnectionResolver) TotalCount() int32 { return int32(c.total) }
+
+type todoEdgeResolver struct {
+	todo Todo
+}
+
+func (e *todoEdgeResolver) Cursor() string      { return encodeCursor(e.todo) }
+func (e *todoEdgeResolver) Node() *todoResolver { return &todoResolver{e.todo} }
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (p *pageInfoResolver) HasNextPage() bool  { return p.hasNext }
+func (p *pageInfoResolver) EndCursor() *string { return p.endCursor }
+
+type todoEventResolver struct {
+	ev TodoEvent
+}
+
+func (e *todoEventResolver) ID() graphql.ID      { return graphql.ID(fmt.Sprint(e.ev.ID)) }
+func (e *todoEventResolver) Type() string        { return e.ev.Type }
+func (e *todoEventResolver) Actor() string       { return e.ev.Actor }
+func (e *todoEventResolver) At() graphql.Time    { return graphql.Time{Time: e.ev.At} }
+func (e *todoEventResolver) Todo() *todoResolver { return &todoResolver{e.ev.Todo} }
+
+type GraphQLHandler struct {
+	schema     *graphql.Schema
+	gqlStore   GraphQLStore
+	playground bool
+}
+
+// NewGraphQLHandler phục vụ /graphql: POST cho query và mutation, WebSocket
+// (giao thức graphql-transport-ws) cho subscription, và GraphiQL khi playground bật.
+func NewGraphQLHandler(todoStore TodoStore, gqlStore GraphQLStore, broker *EventBroker, playground bool) *GraphQLHandler {
+	resolver := &Resolver{todoStore: todoStore, gqlStore: gqlStore, broker: broker}
+	schema := graphql.MustParseSchema(graphqlSchema, resolver, graphql.MaxDepth(10))
+	return &GraphQLHandler{schema: schema, gqlStore: gqlStore, playground: playground}
+}
+
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// @Summary GraphQL endpoint
+// @Description Queries and mutations over POST; subscriptions over WebSocket with the graphql-transport-ws protocol
+// @Tags GraphQL
+// @Accept json
+// @Produce json
+// @Router /graphql [post]
+func (h *GraphQLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		switch {
+		case strings.EqualFold(r.Header.Get("Upgrade"), "websocket"):
+			h.serveWS(w, r)
+		case h.playground:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(graphiqlPage))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Use POST for GraphQL queries"})
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Request body must contain a query"})
+		return
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, newGraphQLLoaders(h.gqlStore, true))
+	resp := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+const graphiqlPage = `<!DOCTYPE html>
+<html>
+<head>
+	<title>Todo GraphiQL</title>
+	<link rel="stylesheet" href="https://unpkg.com/graphiql@3/graphiql.min.css" />
+</head>
+<body style="margin: 0">
+	<div id="graphiql" style="height: 100vh"></div>
+	<script crossorigin src="https://unpkg.com/react@18/umd/react.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/react-dom@18/umd/react-dom.production.min.js"></script>
+	<script crossorigin src="https://unpkg.com/graphiql@3/graphiql.min.js"></script>
+	<script>
+		const url = new URL('/graphql', window.location.href);
+		const wsUrl = url.href.replace(/^http/, 'ws');
+		const fetcher = GraphiQL.createFetcher({ url: url.href, subscriptionUrl: wsUrl });
+		ReactDOM.createRoot(document.getElementById('graphiql')).render(React.createElement(GraphiQL, { fetcher }));
+	</script>
+</body>
+</html>
+`
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// fakeGraphQLStore đếm số lần gọi để kiểm tra các field lồng nhau được gom lô.
+type fakeGraphQLStore struct {
+	mu          sync.Mutex
+	todos       []Todo
+	listBatches [][]string
+	idBatches   [][]string
+}
+
+func (f *fakeGraphQLStore) ListIDsDB(ctx context.Context) ([]string, error) {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, todo := range f.todos {
+		if !seen[todo.ListID] {
+			seen[todo.ListID] = true
+			ids = append(ids, todo.ListID)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeGraphQLStore) GetTodosByListIDsDB(ctx context.Context, listIDs []string) (map[string][]Todo, error) {
+	f.mu.Lock()
+	f.listBatches = append(f.listBatches, listIDs)
+	f.mu.Unlock()
+
+	byList := map[string][]Todo{}
+	for _, todo := range f.todos {
+		if containsString(listIDs, todo.ListID) {
+			byList[todo.ListID] = append(byList[todo.ListID], todo)
+		}
+	}
+	return byList, nil
+}
+
+func (f *fakeGraphQLStore) GetTodosByIDsDB(ctx context.Context, ids []string) (map[string]Todo, error) {
+	f.mu.Lock()
+	f.idBatches = append(f.idBatches, ids)
+	f.mu.Unlock()
+
+	byID := map[string]Todo{}
+	for _, todo := range f.todos {
+		if containsString(ids, todo.ID) {
+			byID[todo.ID] = todo
+		}
+	}
+	return byID, nil
+}
+
+type graphqlResult struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func execGraphQL(t *testing.T, h *GraphQLHandler, query string, variables map[string]interface{}) graphqlResult {
+	body, _ := json.Marshal(graphqlRequest{Query: query, Variables: variables})
+	req, _ := http.NewRequest("POST", "/graphql", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var result graphqlResult
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &result))
+	return result
+}
+
+func sampleTodos() []Todo {
+	base := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)
+	return []Todo{
+		{ID: "a", Title: "Write report", ListID: "work", Tags: []string{"urgent"}, CreatedAt: base},
+		{ID: "b", Title: "Review PR", ListID: "work", Done: true, Tags: []string{"urgent", "code"}, CreatedAt: base.Add(time.Minute)},
+		{ID: "c", Title: "Buy milk", ListID: "home", CreatedAt: base.Add(2 * time.Minute), PreviousID: "a"},
+		{ID: "d", Title: "Call mom", ListID: "home", CreatedAt: base.Add(3 * time.Minute), PreviousID: "b"},
+	}
+}
+
+func TestGraphQL_ListsBatchNestedFields(t *testing.T) {
+	gqlStore := &fakeGraphQLStore{todos: sampleTodos()}
+	h := NewGraphQLHandler(new(MockTodoStore), gqlStore, NewEventBroker(10), false)
+
+	result := execGraphQL(t, h, `{ lists { id counts { total done open } todos { id previous { title } } } }`, nil)
+
+	assert.Empty(t, result.Errors)
+	lists := result.Data["lists"].([]interface{})
+	assert.Len(t, lists, 2)
+	work := lists[0].(map[string]interface{})
+	assert.Equal(t, "work", work["id"])
+	assert.Equal(t, map[string]interface{}{"total": float64(2), "done": float64(1), "open": float64(1)}, work["counts"])
+
+	assert.Len(t, gqlStore.listBatches, 1, "todos and counts of every list must be loaded in one query")
+	assert.ElementsMatch(t, []string{"work", "home"}, gqlStore.listBatches[0])
+	assert.Len(t, gqlStore.idBatches, 1, "previous todos must be loaded in one query")
+	assert.ElementsMatch(t, []string{"a", "b"}, gqlStore.idBatches[0])
+}
+
+func TestGraphQL_TodosFilterAndPagination(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return(sampleTodos(), nil)
+	h := NewGraphQLHandler(mockStore, &fakeGraphQLStore{}, NewEventBroker(10), false)
+
+	query := `query($after: String) {
+		todos(filter: {tag: "URGENT"}, first: 1, after: $after) {
+			totalCount
+			edges { node { id } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	result := execGraphQL(t, h, query, nil)
+	assert.Empty(t, result.Errors)
+	todos := result.Data["todos"].(map[string]interface{})
+	assert.Equal(t, float64(2), todos["totalCount"])
+	pageInfo := todos["pageInfo"].(map[string]interface{})
+	assert.Equal(t, true, pageInfo["hasNextPage"])
+
+	result = execGraphQL(t, h, query, map[string]interface{}{"after": pageInfo["endCursor"]})
+	todos = result.Data["todos"].(map[string]interface{})
+	edges := todos["edges"].([]interface{})
+	assert.Len(t, edges, 1)
+	assert.Equal(t, "b", edges[0].(map[string]interface{})["node"].(map[string]interface{})["id"])
+	assert.Equal(t, false, todos["pageInfo"].(map[string]interface{})["hasNextPage"])
+}
+
+func TestGraphQL_TagsAndCounts(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return(sampleTodos(), nil)
+	h := NewGraphQLHandler(mockStore, &fakeGraphQLStore{}, NewEventBroker(10), false)
+
+	result := execGraphQL(t, h, `{ tags { name count } counts { total done } }`, nil)
+
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "code", "count": float64(1)},
+		map[string]interface{}{"name": "urgent", "count": float64(2)},
+	}, result.Data["tags"])
+	assert.Equal(t, map[string]interface{}{"total": float64(4), "done": float64(1)}, result.Data["counts"])
+}
+
+func TestGraphQL_Mutations(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("CreateTodoDB", Todo{Title: "New", ListID: "work", Tags: []string{"x"}}).
+		Return(Todo{ID: "n", Title: "New", ListID: "work", Tags: []string{"x"}, Version: 1}, nil)
+	mockStore.On("GetTodoByIdDB", "a").Return(Todo{ID: "a", Title: "A", Version: 3}, nil)
+	// Không gửi version thì vẫn ghim version 3 vừa đọc.
+	mockStore.On("UpdateTodoDB", "a", mock.MatchedBy(func(todo Todo) bool { return todo.Title == "B" && todo.Version == 3 })).
+		Return(Todo{ID: "a", Title: "B", Version: 4}, nil)
+	mockStore.On("ChangeStatusDB", "a").Return(nil).Once()
+	mockStore.On("DeleteTodoByIdDB", "a").Return(nil)
+	store := versionedStore{MockTodoStore: mockStore, version: 3, written: Todo{ID: "a", Title: "B", Done: true, Version: 5}}
+	h := NewGraphQLHandler(store, &fakeGraphQLStore{}, NewEventBroker(10), false)
+
+	result := execGraphQL(t, h, `mutation { createTodo(input: {title: "New", listId: "work", tags: ["x"]}) { id version list { id } } }`, nil)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "n", result.Data["createTodo"].(map[string]interface{})["id"])
+
+	result = execGraphQL(t, h, `mutation { updateTodo(id: "a", input: {title: "B"}) { title version } }`, nil)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "B", result.Data["updateTodo"].(map[string]interface{})["title"])
+
+	result = execGraphQL(t, h, `mutation { toggleTodo(id: "a", version: 2) { id } }`, nil)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "CONFLICT", result.Errors[0].Extensions["code"])
+
+	// toggleTodo đi qua ChangeStatusDB như REST, không qua UpdateTodoDB.
+	result = execGraphQL(t, h, `mutation { toggleTodo(id: "a", version: 3) { id done version } }`, nil)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, map[string]interface{}{"id": "a", "done": true, "version": float64(5)}, result.Data["toggleTodo"])
+	mockStore.AssertNumberOfCalls(t, "UpdateTodoDB", 1)
+	// updateTodo đọc todo một lần; toggleTodo không đọc lại.
+	mockStore.AssertNumberOfCalls(t, "GetTodoByIdDB", 1)
+
+	result = execGraphQL(t, h, `mutation { deleteTodo(id: "a") }`, nil)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, true, result.Data["deleteTodo"])
+	mockStore.AssertExpectations(t)
+}
+
+func TestGraphQL_TodoNotFoundIsNull(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetTodoByIdDB", "zzz").Return(Todo{}, ErrTodoNotFound)
+	h := NewGraphQLHandler(mockStore, &fakeGraphQLStore{}, NewEventBroker(10), false)
+
+	result := execGraphQL(t, h, `{ todo(id: "zzz") { id } }`, nil)
+
+	assert.Empty(t, result.Errors)
+	assert.Nil(t, result.Data["todo"])
+}
+
+func TestGraphQL_Playground(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/graphql", nil)
+
+	rr := httptest.NewRecorder()
+	NewGraphQLHandler(new(MockTodoStore), &fakeGraphQLStore{}, NewEventBroker(10), true).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "GraphiQL")
+
+	rr = httptest.NewRecorder()
+	NewGraphQLHandler(new(MockTodoStore), &fakeGraphQLStore{}, NewEventBroker(10), false).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestGraphQL_Subscription(t *testing.T) {
+	broker := NewEventBroker(10)
+	router := mux.NewRouter()
+	router.Handle("/graphql", NewGraphQLHandler(new(MockTodoStore), &fakeGraphQLStore{}, broker, false)).Methods("GET", "POST")
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlWSProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg graphqlWSMessage
+	assert.NoError(t, conn.WriteJSON(graphqlWSMessage{Type: "connection_init"}))
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "connection_ack", msg.Type)
+
+	payload, _ := json.Marshal(graphqlRequest{Query: `subscription { todoChanged(listId: "work") { type todo { id title } } }`})
+	assert.NoError(t, conn.WriteJSON(graphqlWSMessage{ID: "1", Type: "subscribe", Payload: payload}))
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		now := time.Now()
+		broker.Publish(TodoEvent{ID: 1, Type: StreamCreate, Todo: Todo{ID: "h", Title: "Home", ListID: "home", CreatedAt: now}})
+		broker.Publish(TodoEvent{ID: 2, Type: StreamCreate, Todo: Todo{ID: "w", Title: "Work", ListID: "work", CreatedAt: now}})
+	}()
+
+	assert.NoError(t, conn.ReadJSON(&msg))
+	assert.Equal(t, "next", msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.JSONEq(t, `{"data":{"todoChanged":{"type":"create","todo":{"id":"w","title":"Work"}}}}`, string(msg.Payload))
+}
+
+// Todo trong thùng rác không lộ qua các field lồng nhau như previous.
+func TestGetTodosByIDsHidesTrashDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	kept, err := db.CreateTodoDB(ctx, Todo{Title: "Còn lại"})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	trashed, err := db.CreateTodoDB(ctx, Todo{Title: "Đã xóa"})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	if err := db.DeleteTodoByIdDB(ctx, trashed.ID); err != nil {
+		t.Fatalf("Failed to delete todo: %v", err)
+	}
+
+	byID, err := db.GetTodosByIDsDB(ctx, []string{kept.ID, trashed.ID})
+	assert.NoError(t, err)
+	assert.Contains(t, byID, kept.ID)
+	assert.NotContains(t, byID, trashed.ID)
+}
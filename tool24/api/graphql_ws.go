@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// graphqlWSProtocol là subprotocol WebSocket của GraphQL over WebSocket
+// (https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md).
+const graphqlWSProtocol = "graphql-transport-ws"
+
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+var graphqlUpgrader = websocket.Upgrader{Subprotocols: []string{graphqlWSProtocol}}
+
+// serveWS chạy subscription qua WebSocket. Mỗi kết nối phải gửi connection_init
+// trước, sau đó có thể mở nhiều subscription song song theo id.
+func (h *GraphQLHandler) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := graphqlUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != graphqlWSProtocol {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4406, "Subprotocol not acceptable"))
+		return
+	}
+
+	ctx, cancelAll := context.WithCancel(r.Context())
+	defer cancelAll()
+
+	var writeMu sync.Mutex
+	send := func(msg graphqlWSMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(msg)
+	}
+	closeWith := func(code int, reason string) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+	}
+
+	var mu sync.Mutex
+	subscriptions := map[string]context.CancelFunc{}
+	initialized := false
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var msg graphqlWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if initialized {
+				closeWith(4429, "Too many initialisation requests")
+				return
+			}
+			initialized = true
+			conn.SetReadDeadline(time.Time{})
+			send(graphqlWSMessage{Type: "connection_ack"})
+
+		case "ping":
+			send(graphqlWSMessage{Type: "pong"})
+
+		case "pong":
+
+		case "subscribe":
+			if !initialized {
+				closeWith(4401, "Unauthorized")
+				return
+			}
+			var req graphqlRequest
+			if err := json.Unmarshal(msg.Payload, &req); err != nil || msg.ID == "" {
+				closeWith(4400, "Invalid subscribe message")
+				return
+			}
+
+			mu.Lock()
+			if _, exists := subscriptions[msg.ID]; exists {
+				mu.Unlock()
+				closeWith(4409, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+			subCtx, cancel := context.WithCancel(ctx)
+			subscriptions[msg.ID] = cancel
+			mu.Unlock()
+
+			subCtx = context.WithValue(subCtx, loadersKey{}, newGraphQLLoaders(h.gqlStore, false))
+			responses, err := h.schema.Subscribe(subCtx, req.Query, req.OperationName, req.Variables)
+			if err != nil {
+				payload, _ := json.Marshal([]map[string]string{{"message": err.Error()}})
+				send(graphqlWSMessage{ID: msg.ID, Type: "error", Payload: payload})
+				mu.Lock()
+				delete(subscriptions, msg.ID)
+				mu.Unlock()
+				cancel()
+				continue
+			}
+
+			go func(id string, cancel context.CancelFunc) {
+				defer cancel()
+				for resp := range responses {
+					payload, err := json.Marshal(resp)
+					if err != nil {
+						continue
+					}
+					if send(graphqlWSMessage{ID: id, Type: "next", Payload: payload}) != nil {
+						return
+					}
+				}
+
+				mu.Lock()
+				_, active := subscriptions[id]
+				delete(subscriptions, id)
+				mu.Unlock()
+				// Client đã gửi complete thì không cần báo lại.
+				if active {
+					send(graphqlWSMessage{ID: id, Type: "complete"})
+				}
+			}(msg.ID, cancel)
+
+		case "complete":
+			mu.Lock()
+			if cancel, ok := subscriptions[msg.ID]; ok {
+				delete(subscriptions, msg.ID)
+				cancel()
+			}
+			mu.Unlock()
+
+		default:
+			closeWith(4400, "Unknown message type "+msg.Type)
+			return
+		}
+	}
+}
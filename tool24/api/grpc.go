@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"api/todopb"
+)
+
+// TodoGRPCServer phục vụ todopb.TodoService bằng cùng TodoStore với REST API.
+type TodoGRPCServer struct {
+	todopb.UnimplementedTodoServiceServer
+	todoStore TodoStore
+	broker    *EventBroker
+}
+
+func NewTodoGRPCServer(todoStore TodoStore, broker *EventBroker) *TodoGRPCServer {
+	return &TodoGRPCServer{todoStore: todoStore, broker: broker}
+}
+
+// NewGRPCServer tạo grpc.Server đã đăng ký TodoService, đọc actor từ metadata
+// "x-actor" giống ActorMiddleware.
+func NewGRPCServer(todoStore TodoStore, broker *EventBroker) *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			return handler(actorFromMetadata(ctx), req)
+		}),
+	)
+	todopb.RegisterTodoServiceServer(s, NewTodoGRPCServer(todoStore, broker))
+	return s
+}
+
+func actorFromMetadata(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if actors := md.Get(ActorHeader); len(actors) > 0 && actors[0] != "" {
+		return WithActor(ctx, actors[0])
+	}
+	return ctx
+}
+
+// grpcError ánh xạ lỗi của store sang mã gRPC, tương ứng với mã HTTP trong api.go.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, ErrTodoNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrVersionConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, ErrInvalidRecurrence), errors.Is(err, ErrInvalidReminder), errors.Is(err, ErrInvalidPayment):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func timeToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func todoToProto(todo Todo) *todopb.Todo {
+	return &todopb.Todo{
+		Id:          todo.ID,
+		Title:       todo.Title,
+		Description: todo.Desc,
+		Done:        todo.Done,
+		CreatedAt:   timestamppb.New(todo.CreatedAt),
+		DoneAt:      timeToProto(todo.DoneAt),
+		DeletedAt:   timeToProto(todo.DeletedAt),
+		CreatedBy:   todo.CreatedBy,
+		Version:     int32(todo.Version),
+		DueAt:       timeToProto(todo.DueAt),
+		Recurrence:  todo.Recurrence,
+		SeriesId:    todo.SeriesID,
+		PreviousId:  todo.PreviousID,
+		RemindAt:    todo.RemindAt,
+		ListId:      todo.ListID,
+		Tags:        todo.Tags,
+		ExternalId:  todo.ExternalID,
+		Payment:     paymentToProto(todo.Payment),
+		ShortCode:   todo.ShortCode,
+	}
+}
+
+func paymentToProto(p *TodoPayment) *todopb.Payment {
+	if p == nil {
+		return nil
+	}
+	return &todopb.Payment{BankBin: p.BankBIN, AccountNumber: p.AccountNumber, Amount: p.Amount, Memo: p.Memo}
+}
+
+func paymentFromProto(pb *todopb.Payment) *TodoPayment {
+	if pb == nil {
+		return nil
+	}
+	return &TodoPayment{BankBIN: pb.GetBankBin(), AccountNumber: pb.GetAccountNumber(), Amount: pb.GetAmount(), Memo: pb.GetMemo()}
+}
+
+// todoFromProto chỉ lấy các trường client được phép đặt.
+func todoFromProto(pb *todopb.Todo) Todo {
+	if pb == nil {
+		return Todo{}
+	}
+	return Todo{
+		Title:      pb.GetTitle(),
+		Desc:       pb.GetDescription(),
+		Done:       pb.GetDone(),
+		Version:    int(pb.GetVersion()),
+		DueAt:      timeFromProto(pb.GetDueAt()),
+		Recurrence: pb.GetRecurrence(),
+		RemindAt:   pb.GetRemindAt(),
+		ListID:     pb.GetListId(),
+		Tags:       pb.GetTags(),
+		ExternalID: pb.GetExternalId(),
+		Payment:    paymentFromProto(pb.GetPayment()),
+	}
+}
+
+func (s *TodoGRPCServer) ListTodos(ctx context.Context, _ *todopb.ListTodosRequest) (*todopb.ListTodosResponse, error) {
+	todos, err := s.todoStore.GetAllTodoDB(ctx)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &todopb.ListTodosResponse{Todos: make([]*todopb.Todo, 0, len(todos))}
+	for _, todo := range todos {
+		resp.Todos = append(resp.Todos, todoToProto(todo))
+	}
+	return resp, nil
+}
+
+func (s *TodoGRPCServer) GetTodo(ctx context.Context, req *todopb.GetTodoRequest) (*todopb.Todo, error) {
+	todo, err := s.todoStore.GetTodoByIdDB(ctx, req.GetId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *TodoGRPCServer) CreateTodo(ctx context.Context, req *todopb.CreateTodoRequest) (*todopb.Todo, error) {
+	todo, err := s.todoStore.CreateTodoDB(ctx, todoFromProto(req.GetTodo()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *TodoGRPCServer) UpdateTodo(ctx context.Context, req *todopb.UpdateTodoRequest) (*todopb.Todo, error) {
+	todo, err := s.todoStore.UpdateTodoDB(ctx, req.GetId(), todoFromProto(req.GetTodo()))
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return todoToProto(todo), nil
+}
+
+func (s *TodoGRPCServer) DeleteTodo(ctx context.Context, req *todopb.DeleteTodoRequest) (*todopb.DeleteTodoResponse, error) {
+	if err := s.todoStore.DeleteTodoByIdDB(ctx, req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &todopb.DeleteTodoResponse{}, nil
+}
+
+func (s *TodoGRPCServer) ChangeStatus(ctx context.Context, req *todopb.ChangeStatusRequest) (*todopb.ChangeStatusResponse, error) {
+	if err := s.todoStore.ChangeStatusDB(ctx, req.GetId()); err != nil {
+		return nil, grpcError(err)
+	}
+	return &todopb.ChangeStatusResponse{}, nil
+}
+
+func todoEventToProto(ev TodoEvent) *todopb.TodoEvent {
+	return &todopb.TodoEvent{
+		Id:    ev.ID,
+		Type:  ev.Type,
+		Actor: ev.Actor,
+		At:    timestamppb.New(ev.At),
+		Todo:  todoToProto(ev.Todo),
+	}
+}
+
+func (s *TodoGRPCServer) WatchTodos(req *todopb.WatchTodosRequest, stream grpc.ServerStreamingServer[todopb.TodoEvent]) error {
+	replay, complete, events, cancel := s.broker.Subscribe(req.GetLastEventId())
+	defer cancel()
+
+	if !complete {
+		// Giống sự kiện "reset" của SSE: client đã lỡ sự kiện và cần tải lại.
+		if err := stream.Send(&todopb.TodoEvent{Type: "reset"}); err != nil {
+			return err
+		}
+	}
+	for _, ev := range replay {
+		if err := stream.Send(todoEventToProto(ev)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client is too slow, reconnect with last_event_id")
+			}
+			if err := stream.Send(todoEventToProto(ev)); err != nil {
+				return err
+			}
+		}
+	}
+}
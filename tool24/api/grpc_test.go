@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+
+	"api/todopb"
+)
+
+func newGRPCClient(t *testing.T, store TodoStore, broker *EventBroker) todopb.TodoServiceClient {
+	lis := bufconn.Listen(1024 * 1024)
+	srv := NewGRPCServer(store, broker)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial bufnet: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return todopb.NewTodoServiceClient(conn)
+}
+
+func TestGRPC_GetTodo(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "Todo 1", CreatedAt: time.Now(), Version: 2, ShortCode: "T000001",
+		Payment: &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}}, nil)
+	mockStore.On("GetTodoByIdDB", "404").Return(Todo{}, ErrTodoNotFound)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	todo, err := client.GetTodo(context.Background(), &todopb.GetTodoRequest{Id: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Todo 1", todo.GetTitle())
+	assert.Equal(t, int32(2), todo.GetVersion())
+	assert.Equal(t, "T000001", todo.GetShortCode())
+	assert.Equal(t, int64(50000), todo.GetPayment().GetAmount())
+
+	_, err = client.GetTodo(context.Background(), &todopb.GetTodoRequest{Id: "404"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPC_ListTodos(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetAllTodoDB").Return([]Todo{{ID: "1"}, {ID: "2"}}, nil)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	resp, err := client.ListTodos(context.Background(), &todopb.ListTodosRequest{})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.GetTodos(), 2)
+}
+
+func TestGRPC_CreateTodo_UsesActorMetadata(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("CreateTodoDB", Todo{Title: "New", RemindAt: []string{"15m"}}).
+		Return(Todo{ID: "1", Title: "New", CreatedBy: "ci-bot"}, nil)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-actor", "ci-bot")
+	todo, err := client.CreateTodo(ctx, &todopb.CreateTodoRequest{Todo: &todopb.Todo{Title: "New", RemindAt: []string{"15m"}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1", todo.GetId())
+	mockStore.AssertExpectations(t)
+}
+
+func TestGRPC_UpdateTodo_ErrorMapping(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("UpdateTodoDB", "1", mock.Anything).Return(Todo{}, ErrVersionConflict).Once()
+	mockStore.On("UpdateTodoDB", "1", mock.Anything).Return(Todo{}, ErrInvalidRecurrence).Once()
+	mockStore.On("UpdateTodoDB", "2", mock.Anything).Return(Todo{}, ErrTodoNotFound)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	_, err := client.UpdateTodo(context.Background(), &todopb.UpdateTodoRequest{Id: "1", Todo: &todopb.Todo{Title: "x", Version: 1}})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = client.UpdateTodo(context.Background(), &todopb.UpdateTodoRequest{Id: "1", Todo: &todopb.Todo{Title: "x"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateTodo(context.Background(), &todopb.UpdateTodoRequest{Id: "2", Todo: &todopb.Todo{Title: "x"}})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// Payment đi cùng message Todo để UpdateTodo ghi trong cùng transaction,
+// không đọc lại payment bên ngoài rồi ghi đè thay đổi của request khác.
+func TestGRPC_UpdateTodo_Payment(t *testing.T) {
+	payment := &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}
+	mockStore := new(MockTodoStore)
+	mockStore.On("UpdateTodoDB", "1", Todo{Title: "Collect rent from Lan", Version: 3, Payment: payment}).
+		Return(Todo{ID: "1", Title: "Collect rent from Lan", Version: 4, Payment: payment}, nil)
+	mockStore.On("UpdateTodoDB", "2", Todo{Title: "Buy milk"}).Return(Todo{ID: "2", Title: "Buy milk"}, nil)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	pbPayment := &todopb.Payment{BankBin: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}
+	todo, err := client.UpdateTodo(context.Background(), &todopb.UpdateTodoRequest{Id: "1", Todo: &todopb.Todo{Title: "Collect rent from Lan", Version: 3, Payment: pbPayment}})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(pbPayment, todo.GetPayment()))
+
+	todo, err = client.UpdateTodo(context.Background(), &todopb.UpdateTodoRequest{Id: "2", Todo: &todopb.Todo{Title: "Buy milk"}})
+	assert.NoError(t, err)
+	assert.Nil(t, todo.GetPayment())
+	mockStore.AssertExpectations(t)
+}
+
+func TestGRPC_DeleteAndChangeStatus(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("DeleteTodoByIdDB", "1").Return(nil)
+	mockStore.On("ChangeStatusDB", "2").Return(ErrTodoNotFound)
+	client := newGRPCClient(t, mockStore, NewEventBroker(10))
+
+	_, err := client.DeleteTodo(context.Background(), &todopb.DeleteTodoRequest{Id: "1"})
+	assert.NoError(t, err)
+
+	_, err = client.ChangeStatus(context.Background(), &todopb.ChangeStatusRequest{Id: "2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestGRPC_WatchTodos(t *testing.T) {
+	broker := NewEventBroker(10)
+	broker.Publish(TodoEvent{ID: 1, Type: StreamCreate, Todo: Todo{ID: "a"}})
+	broker.Publish(TodoEvent{ID: 2, Type: StreamStatus, Todo: Todo{ID: "a", Done: true}})
+	client := newGRPCClient(t, new(MockTodoStore), broker)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	stream, err := client.WatchTodos(ctx, &todopb.WatchTodosRequest{LastEventId: 1})
+	assert.NoError(t, err)
+
+	ev, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), ev.GetId())
+	assert.True(t, ev.GetTodo().GetDone())
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		broker.Publish(TodoEvent{ID: 3, Type: StreamDelete, Todo: Todo{ID: "a"}})
+	}()
+	ev, err = stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, StreamDelete, ev.GetType())
+}
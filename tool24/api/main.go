@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
-
-	"github.com/gorilla/mux"
-	httpSwagger "github.com/swaggo/http-swagger"
+	"os"
+	"time"
+	// Nhúng dữ liệu múi giờ cho tham số tz của GET /stats khi image không có
+	// /usr/share/zoneinfo.
+	_ "time/tzdata"
 
 	_ "api/docs"
+	"api/vietqr"
 )
 
 // @title Todo API
@@ -19,23 +24,64 @@ func main() {
 	db, _ := NewDb()
 	defer db.Conn.Close()
 
-	h := NewTodoHandler(db)
-	router := mux.NewRouter()
+	broker := NewEventBroker(1000)
+	// Danh bạ ngân hàng đi kèm binary; BANK_DIRECTORY_FILE trỏ tới bản JSON mới
+	// hơn để cập nhật mà không cần build lại.
+	banks := vietqr.NewRegistry(vietqr.DefaultDirectory())
+	if path := os.Getenv("BANK_DIRECTORY_FILE"); path != "" {
+		go RunBankDirectoryReloader(context.Background(), banks, path, envDuration("BANK_DIRECTORY_INTERVAL", time.Minute))
+	}
+	// THERMAL_PRINTERS khai báo các máy in nhiệt mà POST /todos/print được gửi
+	// tới, vd "kho=192.168.1.50/58mm,quay=192.168.1.51:9100".
+	printers, err := ParseThermalPrinters(os.Getenv("THERMAL_PRINTERS"))
+	if err != nil {
+		log.Fatalf("Cấu hình máy in nhiệt không hợp lệ: %v", err)
+	}
+	// PAYMENT_CALLBACK_SECRET ký body của POST /payments/callback; để trống thì
+	// callback bị tắt.
+	router := NewRouter(db, broker, banks, os.Getenv("PAYMENT_CALLBACK_SECRET"), printers, envDuration("UNDO_TTL", 5*time.Minute), os.Getenv("APP_ENV") == "development")
 
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
-	router.HandleFunc("/swagger/swagger.json", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./docs/swagger.json")
-	})
+	go db.ListenTodoEvents(context.Background(), broker)
+	go RunTrashPurger(context.Background(), db, envDuration("TRASH_RETENTION", 30*24*time.Hour), time.Hour)
 
-	router.HandleFunc("/todos", h.GetAllTodos).Methods("GET")
-	router.HandleFunc("/todo/{id}", h.GetTodoByID).Methods("GET")
-	router.HandleFunc("/todo", h.CreateTodo).Methods("POST")
-	router.HandleFunc("/todo/{id}", h.UpdateTodo).Methods("PUT")
-	router.HandleFunc("/todo/{id}", h.DeleteTodoByID).Methods("DELETE")
-	router.HandleFunc("/todo/changeStatus/{id}", h.ChangeStatus).Methods("POST")
+	notifier, err := NewNotifierFromEnv(os.Getenv)
+	if err != nil {
+		log.Fatalf("Cấu hình nhắc việc không hợp lệ: %v", err)
+	}
+	go NewReminderScheduler(db, notifier, envDuration("REMINDER_INTERVAL", 30*time.Second)).Run(context.Background())
+	go NewWebhookDispatcher(db, envDuration("WEBHOOK_INTERVAL", 5*time.Second)).Run(context.Background())
+
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "9090"
+	}
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		log.Fatalf("Không thể mở cổng gRPC %s: %v", grpcPort, err)
+	}
+	go func() {
+		log.Printf("gRPC đang chạy trên cổng %s...", grpcPort)
+		if err := NewGRPCServer(db, broker).Serve(lis); err != nil {
+			log.Fatalf("Không thể khởi động gRPC: %v", err)
+		}
+	}()
 
 	log.Println("Server đang chạy trên cổng 8080...")
 	if err := http.ListenAndServe(":8080", router); err != nil {
 		log.Fatalf("Không thể khởi động server: %v", err)
 	}
 }
+
+// envDuration đọc một time.Duration (vd "720h") từ biến môi trường, dùng def nếu trống hoặc sai.
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Giá trị %s=%q không hợp lệ, dùng mặc định %s", key, v, def)
+		return def
+	}
+	return d
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS todo_audit;
+DROP FUNCTION IF EXISTS todo_audit_append_only();
+ALTER TABLE todo DROP COLUMN IF EXISTS created_by;
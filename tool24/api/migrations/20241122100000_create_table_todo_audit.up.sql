@@ -0,0 +1,27 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS created_by TEXT NULL;
+
+CREATE TABLE IF NOT EXISTS todo_audit (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    changes JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX IF NOT EXISTS todo_audit_todo_id_idx ON todo_audit (todo_id, id);
+CREATE INDEX IF NOT EXISTS todo_audit_created_at_idx ON todo_audit (created_at);
+
+-- todo_audit chỉ được phép thêm mới, không sửa hay xóa.
+CREATE OR REPLACE FUNCTION todo_audit_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'todo_audit is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER todo_audit_no_update_delete
+    BEFORE UPDATE OR DELETE ON todo_audit
+    FOR EACH ROW EXECUTE FUNCTION todo_audit_append_only();
+
+CREATE TRIGGER todo_audit_no_truncate
+    BEFORE TRUNCATE ON todo_audit
+    FOR EACH STATEMENT EXECUTE FUNCTION todo_audit_append_only();
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_audit_revision_idx;
+ALTER TABLE todo_audit DROP COLUMN IF EXISTS snapshot;
+ALTER TABLE todo_audit DROP COLUMN IF EXISTS revision;
+
+ALTER TABLE todo DROP COLUMN IF EXISTS version;
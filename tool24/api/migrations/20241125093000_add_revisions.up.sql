@@ -0,0 +1,5 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE todo_audit ADD COLUMN IF NOT EXISTS revision INT NOT NULL DEFAULT 0;
+ALTER TABLE todo_audit ADD COLUMN IF NOT EXISTS snapshot JSONB NULL;
+CREATE INDEX IF NOT EXISTS todo_audit_revision_idx ON todo_audit (todo_id, revision);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS todo_previous_id_idx;
+DROP INDEX IF EXISTS todo_series_id_idx;
+
+ALTER TABLE todo DROP COLUMN IF EXISTS previous_id;
+ALTER TABLE todo DROP COLUMN IF EXISTS series_id;
+ALTER TABLE todo DROP COLUMN IF EXISTS recurrence;
+ALTER TABLE todo DROP COLUMN IF EXISTS due_at;
@@ -0,0 +1,7 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS due_at TIMESTAMP NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS recurrence TEXT NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS series_id TEXT NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS previous_id TEXT NULL;
+
+CREATE INDEX IF NOT EXISTS todo_series_id_idx ON todo (series_id);
+CREATE UNIQUE INDEX IF NOT EXISTS todo_previous_id_idx ON todo (previous_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS reminder_delivery;
+DROP TABLE IF EXISTS reminder;
+ALTER TABLE todo DROP COLUMN IF EXISTS remind_at;
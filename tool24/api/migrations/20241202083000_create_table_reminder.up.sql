@@ -0,0 +1,25 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS remind_at TEXT[] NULL;
+
+CREATE TABLE IF NOT EXISTS reminder (
+    id BIGSERIAL PRIMARY KEY,
+    todo_id TEXT NOT NULL,
+    fire_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NULL,
+    sent_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (todo_id, fire_at)
+);
+CREATE INDEX IF NOT EXISTS reminder_due_idx ON reminder (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS reminder_delivery (
+    id BIGSERIAL PRIMARY KEY,
+    reminder_id BIGINT NOT NULL REFERENCES reminder (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    error TEXT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS reminder_delivery_reminder_id_idx ON reminder_delivery (reminder_id);
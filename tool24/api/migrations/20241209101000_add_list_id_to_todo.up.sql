@@ -0,0 +1,2 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS list_id TEXT NULL;
+CREATE INDEX IF NOT EXISTS todo_list_id_idx ON todo (list_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS todo_tags_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS tags;
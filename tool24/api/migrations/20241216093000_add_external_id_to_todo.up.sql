@@ -0,0 +1,2 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS external_id TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_external_id_idx ON todo (external_id) WHERE external_id IS NOT NULL;
@@ -0,0 +1,9 @@
+-- Mỗi feed là một URL bí mật /calendar.ics?token=... cho ứng dụng lịch.
+CREATE TABLE IF NOT EXISTS calendar_feed (
+    id TEXT PRIMARY KEY,
+    token TEXT NOT NULL UNIQUE,
+    owner TEXT NOT NULL,
+    list_id TEXT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS calendar_feed_owner_idx ON calendar_feed (owner);
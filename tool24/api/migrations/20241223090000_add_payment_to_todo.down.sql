@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_payment_pending_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS payment_memo;
+ALTER TABLE todo DROP COLUMN IF EXISTS payment_amount;
+ALTER TABLE todo DROP COLUMN IF EXISTS payment_account;
+ALTER TABLE todo DROP COLUMN IF EXISTS payment_bank_bin;
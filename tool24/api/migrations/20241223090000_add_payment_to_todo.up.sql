@@ -0,0 +1,5 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS payment_bank_bin TEXT NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS payment_account TEXT NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS payment_amount BIGINT NULL;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS payment_memo TEXT NULL;
+CREATE INDEX IF NOT EXISTS todo_payment_pending_idx ON todo (payment_memo) WHERE payment_memo IS NOT NULL AND NOT done AND deleted_at IS NULL;
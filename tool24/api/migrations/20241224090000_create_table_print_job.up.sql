@@ -0,0 +1,10 @@
+-- Mỗi lần in danh sách todo; barcode trên tờ in trỏ về đây để tra lại đúng
+-- các todo đã in, kể cả khi chúng đã bị sửa sau đó.
+CREATE TABLE IF NOT EXISTS print_job (
+    id BIGSERIAL PRIMARY KEY,
+    printed_at TIMESTAMP NOT NULL,
+    printed_by TEXT NOT NULL,
+    format TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    todos JSONB NOT NULL
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todo_short_code_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS short_code;
+DROP FUNCTION IF EXISTS next_todo_short_code();
+DROP SEQUENCE IF EXISTS todo_short_code_seq;
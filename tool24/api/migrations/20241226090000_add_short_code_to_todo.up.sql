@@ -0,0 +1,22 @@
+-- Mã ngắn để in thành barcode và quét (POST /scan): T + số thứ tự 6 chữ số,
+-- dài thêm khi vượt quá 999999.
+CREATE SEQUENCE IF NOT EXISTS todo_short_code_seq;
+
+CREATE OR REPLACE FUNCTION next_todo_short_code() RETURNS TEXT AS $$
+    SELECT 'T' || CASE WHEN n < 1000000 THEN lpad(n::text, 6, '0') ELSE n::text END
+    FROM nextval('todo_short_code_seq') AS n
+$$ LANGUAGE SQL VOLATILE;
+
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS short_code TEXT NULL;
+
+-- Cấp mã cho các todo có sẵn theo thứ tự tạo.
+UPDATE todo SET short_code = numbered.code
+FROM (
+    SELECT id, next_todo_short_code() AS code
+    FROM (SELECT id FROM todo WHERE short_code IS NULL ORDER BY created_at, id) AS ordered
+) AS numbered
+WHERE todo.id = numbered.id;
+
+ALTER TABLE todo ALTER COLUMN short_code SET DEFAULT next_todo_short_code();
+ALTER TABLE todo ALTER COLUMN short_code SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS todo_short_code_idx ON todo (short_code);
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notification is what a Notifier delivers when a reminder fires.
+type Notification struct {
+	ReminderID  int64      `json:"reminder_id"`
+	TodoID      string     `json:"todo_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	RemindAt    time.Time  `json:"remind_at"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// LogNotifier ghi nhắc việc ra log, dùng khi chưa cấu hình kênh nào khác.
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+func (l LogNotifier) Notify(ctx context.Context, n Notification) error {
+	logger := l.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	due := "không có hạn"
+	if n.DueAt != nil {
+		due = n.DueAt.Format(time.RFC3339)
+	}
+	logger.Printf("Nhắc việc: %q (todo %s, hạn %s)", n.Title, n.TodoID, due)
+	return nil
+}
+
+// WebhookNotifier POSTs the notification as JSON and treats any non-2xx
+// response as a failed delivery.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func (wh WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := wh.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier gửi nhắc việc qua email. Auth có thể nil với máy chủ nội bộ.
+type SMTPNotifier struct {
+	Addr string
+	From string
+	To   []string
+	Auth smtp.Auth
+}
+
+func (s SMTPNotifier) Notify(ctx context.Context, n Notification) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Nhắc việc: "+n.Title))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(n.Title + "\r\n")
+	if n.Description != "" {
+		msg.WriteString("\r\n" + n.Description + "\r\n")
+	}
+	if n.DueAt != nil {
+		msg.WriteString("\r\nHạn: " + n.DueAt.Format(time.RFC1123Z) + "\r\n")
+	}
+
+	if err := s.send(ctx, msg.Bytes()); err != nil {
+		return fmt.Errorf("smtp delivery failed: %v", err)
+	}
+	return nil
+}
+
+// send làm như smtp.SendMail nhưng theo ctx: smtp.SendMail không nhận
+// context nên một máy chủ treo sẽ giữ scheduler mãi mãi.
+func (s SMTPNotifier) send(ctx context.Context, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("server doesn't support AUTH")
+		}
+		if err := c.Auth(s.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// NewNotifierFromEnv chọn kênh nhắc việc theo REMINDER_NOTIFIER (log, webhook, smtp).
+func NewNotifierFromEnv(getenv func(string) string) (Notifier, error) {
+	switch kind := getenv("REMINDER_NOTIFIER"); kind {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "webhook":
+		url := getenv("REMINDER_WEBHOOK_URL")
+		if url == "" {
+			return nil, fmt.Errorf("REMINDER_WEBHOOK_URL must be set")
+		}
+		return WebhookNotifier{URL: url}, nil
+	case "smtp":
+		addr, from, to := getenv("SMTP_ADDR"), getenv("SMTP_FROM"), getenv("SMTP_TO")
+		if addr == "" || from == "" || to == "" {
+			return nil, fmt.Errorf("SMTP_ADDR, SMTP_FROM and SMTP_TO must be set")
+		}
+		notifier := SMTPNotifier{Addr: addr, From: from, To: strings.Split(to, ",")}
+		if user := getenv("SMTP_USER"); user != "" {
+			host, _, _ := net.SplitHostPort(addr)
+			notifier.Auth = smtp.PlainAuth("", user, getenv("SMTP_PASSWORD"), host)
+		}
+		return notifier, nil
+	default:
+		return nil, fmt.Errorf("unknown REMINDER_NOTIFIER %q", kind)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer nói vừa đủ giao thức SMTP để net/smtp gửi được một thư,
+// và trả nội dung DATA nhận được qua channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 fake.smtp ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 fake.smtp")
+			case strings.HasPrefix(cmd, "MAIL FROM"), strings.HasPrefix(cmd, "RCPT TO"):
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				messages <- data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return ln.Addr().String(), messages
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+	due := time.Date(2024, 12, 2, 9, 0, 0, 0, time.UTC)
+	notifier := SMTPNotifier{Addr: addr, From: "todo@example.com", To: []string{"me@example.com"}}
+
+	err := notifier.Notify(context.Background(), Notification{TodoID: "1", Title: "Nộp báo cáo", Description: "Quý 4", DueAt: &due})
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-messages:
+		assert.Contains(t, msg, "To: me@example.com")
+		assert.Contains(t, msg, "Subject: =?utf-8?q?")
+		assert.Contains(t, msg, "Nộp báo cáo")
+		assert.Contains(t, msg, "Quý 4")
+	case <-time.After(2 * time.Second):
+		t.Fatal("fake SMTP server received no message")
+	}
+}
+
+func TestSMTPNotifier_HonorsContextDeadline(t *testing.T) {
+	// Máy chủ nhận kết nối nhưng không bao giờ chào.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { conn.Close() })
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	notifier := SMTPNotifier{Addr: ln.Addr().String(), From: "todo@example.com", To: []string{"me@example.com"}}
+
+	done := make(chan error, 1)
+	go func() { done <- notifier.Notify(ctx, Notification{TodoID: "1", Title: "Treo"}) }()
+
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Notify did not return after the context deadline")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var got Notification
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := WebhookNotifier{URL: server.URL}.Notify(context.Background(), Notification{ReminderID: 3, TodoID: "1", Title: "Gọi điện"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), got.ReminderID)
+	assert.Equal(t, "Gọi điện", got.Title)
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	err := WebhookNotifier{URL: server.URL}.Notify(context.Background(), Notification{TodoID: "1"})
+	assert.Error(t, err)
+}
+
+func TestNewNotifierFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+
+	n, err := NewNotifierFromEnv(env(nil))
+	assert.NoError(t, err)
+	assert.IsType(t, LogNotifier{}, n)
+
+	n, err = NewNotifierFromEnv(env(map[string]string{"REMINDER_NOTIFIER": "smtp", "SMTP_ADDR": "localhost:25", "SMTP_FROM": "a@b", "SMTP_TO": "c@d,e@f"}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c@d", "e@f"}, n.(SMTPNotifier).To)
+
+	_, err = NewNotifierFromEnv(env(map[string]string{"REMINDER_NOTIFIER": "webhook"}))
+	assert.Error(t, err)
+	_, err = NewNotifierFromEnv(env(map[string]string{"REMINDER_NOTIFIER": "pigeon"}))
+	assert.Error(t, err)
+}
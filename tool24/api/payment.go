@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"api/qrrender"
+	"api/vietqr"
+)
+
+// TodoPayment is the transfer a "collect payment from X" todo is waiting for.
+type TodoPayment struct {
+	// BankBIN is the 6-digit NAPAS BIN of the beneficiary bank, see GET /banks.
+	BankBIN       string `json:"bank_bin"`
+	AccountNumber string `json:"account_number"`
+	// Amount in VND; 0 lets the payer enter the amount.
+	Amount int64 `json:"amount,omitempty"`
+	// Memo is the transfer note the payer's bank sends back in the payment
+	// confirmation. It defaults to the todo ID without dashes, cut to the 25
+	// characters a VietQR memo can hold.
+	Memo string `json:"memo,omitempty"`
+}
+
+// ErrInvalidPayment is wrapped by validation errors of Todo.Payment; it is the
+// same error vietqr returns for an invalid transfer.
+var ErrInvalidPayment = vietqr.ErrInvalidPayment
+
+// PaymentSignatureHeader mang chữ ký HMAC-SHA256 của body POST /payments/callback
+// với PAYMENT_CALLBACK_SECRET, cùng dạng "sha256=<hex>" như webhook gửi đi.
+const PaymentSignatureHeader = "X-Payment-Signature"
+
+// PaymentCallbackActor là actor ghi vào audit khi callback đánh dấu todo xong.
+const PaymentCallbackActor = "payment-callback"
+
+// minPaymentMemoLength là số chữ và số tối thiểu của memo; memo quá ngắn sẽ
+// khớp nhầm với nội dung chuyển khoản của todo khác.
+const minPaymentMemoLength = 6
+
+const maxPaymentCallbackBytes = 1 << 20
+
+// Trạng thái trả về cho bên gửi callback.
+const (
+	PaymentStatusDone      = "done"
+	PaymentStatusUnmatched = "unmatched"
+	PaymentStatusUnderpaid = "underpaid"
+	// PaymentStatusAlreadyHandled nghĩa là todo đã đổi từ lúc được tìm thấy, vd
+	// callback trùng trên replica khác đã đánh dấu xong trước.
+	PaymentStatusAlreadyHandled = "already_handled"
+)
+
+// PaymentCallback là xác nhận chuyển khoản mà cổng thanh toán gửi tới
+// POST /payments/callback.
+type PaymentCallback struct {
+	TransactionID string `json:"transaction_id"`
+	Amount        int64  `json:"amount"`
+	// Memo là nội dung chuyển khoản; ngân hàng thường thêm tiền tố hoặc bỏ dấu
+	// câu nên chỉ cần chứa memo của todo.
+	Memo string `json:"memo"`
+	// AccountNumber là tài khoản nhận tiền; khi có, chỉ todo thu về tài khoản
+	// này mới khớp.
+	AccountNumber string `json:"account_number,omitempty"`
+}
+
+// PaymentCallbackResult cho bên gửi callback biết giao dịch đã khớp todo nào.
+type PaymentCallbackResult struct {
+	Status string `json:"status"`
+	TodoID string `json:"todo_id,omitempty"`
+}
+
+type PaymentStore interface {
+	// FindPendingPaymentTodosDB trả về các todo chưa xong, chưa xóa có memo
+	// (đã chuẩn hóa bằng normalizePaymentMemo) nằm trong memo.
+	FindPendingPaymentTodosDB(ctx context.Context, memo string) ([]Todo, error)
+}
+
+func (db *Db) FindPendingPaymentTodosDB(ctx context.Context, memo string) ([]Todo, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT "+todoColumns+" FROM todo "+
+		"WHERE payment_memo IS NOT NULL AND NOT done AND deleted_at IS NULL "+
+		"AND strpos($1, upper(regexp_replace(payment_memo, '[^A-Za-z0-9]', '', 'g'))) > 0 ORDER BY created_at", memo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find payment todos: %v", err)
+	}
+	defer rows.Close()
+
+	var todos []Todo
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+	return todos, rows.Err()
+}
+
+// validatePayment chuẩn hóa và kiểm tra payment theo giới hạn của VietQR.
+// Memo rỗng được điền mặc định khi ghi, vì lúc này todo có thể chưa có ID.
+func validatePayment(p *TodoPayment) error {
+	if p == nil {
+		return nil
+	}
+	p.BankBIN = strings.TrimSpace(p.BankBIN)
+	p.AccountNumber = strings.TrimSpace(p.AccountNumber)
+	p.Memo = strings.TrimSpace(p.Memo)
+
+	if p.Memo != "" {
+		for _, r := range p.Memo {
+			if r < 0x20 || r > 0x7e {
+				return fmt.Errorf("%w: memo must be printable ASCII without diacritics", ErrInvalidPayment)
+			}
+		}
+		if len(normalizePaymentMemo(p.Memo)) < minPaymentMemoLength {
+			return fmt.Errorf("%w: memo must contain at least %d letters or digits", ErrInvalidPayment, minPaymentMemoLength)
+		}
+	}
+	_, err := p.vietQR("").Payload()
+	return err
+}
+
+// vietQR là giao dịch VietQR của payment; memo rỗng thay bằng memo mặc định của todo id.
+func (p TodoPayment) vietQR(id string) vietqr.Payment {
+	memo := p.Memo
+	if memo == "" {
+		memo = defaultPaymentMemo(id)
+	}
+	return vietqr.Payment{BankBIN: p.BankBIN, AccountNumber: p.AccountNumber, Amount: p.Amount, Purpose: memo}
+}
+
+// defaultPaymentMemo là ID todo bỏ dấu gạch, viết hoa và cắt còn 25 ký tự
+// (giới hạn memo của VietQR); 100 bit của UUID vẫn đủ để không trùng.
+func defaultPaymentMemo(id string) string {
+	memo := strings.ToUpper(strings.ReplaceAll(id, "-", ""))
+	if len(memo) > 25 {
+		memo = memo[:25]
+	}
+	return memo
+}
+
+// normalizePaymentMemo chỉ giữ chữ và số, viết hoa, giống cách ngân hàng
+// thường biến đổi nội dung chuyển khoản.
+func normalizePaymentMemo(memo string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(memo) {
+		if r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// paymentColumns là giá trị các cột payment_* khi ghi todo id; NULL khi không có payment.
+func paymentColumns(id string, p *TodoPayment) [4]interface{} {
+	if p == nil {
+		return [4]interface{}{}
+	}
+	memo := p.Memo
+	if memo == "" {
+		memo = defaultPaymentMemo(id)
+	}
+	return [4]interface{}{p.BankBIN, p.AccountNumber, p.Amount, memo}
+}
+
+// nextPayment là payment của lần lặp sau done; memo mặc định được sinh lại
+// theo ID mới để mỗi lần lặp khớp với khoản thanh toán của riêng nó.
+func nextPayment(done Todo) *TodoPayment {
+	if done.Payment == nil {
+		return nil
+	}
+	next := *done.Payment
+	if next.Memo == defaultPaymentMemo(done.ID) {
+		next.Memo = ""
+	}
+	return &next
+}
+
+type PaymentHandler struct {
+	todoStore    TodoStore
+	paymentStore PaymentStore
+	secret       string
+}
+
+func NewPaymentHandler(todoStore TodoStore, paymentStore PaymentStore, secret string) *PaymentHandler {
+	return &PaymentHandler{todoStore: todoStore, paymentStore: paymentStore, secret: secret}
+}
+
+// @Summary Payment QR code of a todo
+// @Description Render the VietQR code that pays the todo's payment. The transfer memo defaults to the todo ID without dashes, cut to 25 characters.
+// @Tags QR
+// @Produce png
+// @Param id path string true "Todo ID"
+// @Success 200 {file} file "QR code image"
+// @Failure 404 {object} ErrorResponse "Todo not found or has no payment"
+// @Router /todo/{id}/qr.png [get]
+func (h *PaymentHandler) GetTodoQR(w http.ResponseWriter, r *http.Request) {
+	todo, err := h.todoStore.GetTodoByIdDB(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, ErrTodoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Todo not found"})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	if todo.Payment == nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Todo has no payment"})
+		return
+	}
+
+	var buf bytes.Buffer
+	payload, err := todo.Payment.vietQR(todo.ID).Payload()
+	if err == nil {
+		var code *qrrender.Code
+		if code, err = qrrender.New(payload, qrrender.DefaultOptions()); err == nil {
+			err = code.WritePNG(&buf)
+		}
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to render QR code: " + err.Error()})
+		return
+	}
+
+	// Ảnh đổi theo todo nên client phải hỏi lại mỗi lần thay vì dùng bản cache.
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// @Summary Payment confirmation callback
+// @Description Called by the payment gateway when a transfer arrives. The body must be signed in the X-Payment-Signature header as sha256=<hex HMAC-SHA256 of the body> with PAYMENT_CALLBACK_SECRET. The pending todo whose payment memo appears in the transfer memo is marked done, unless the amount is less than the todo's amount. Unmatched transfers are acknowledged with status unmatched so the gateway does not retry them, and a todo that changed after it was matched, e.g. by a duplicate callback, with status already_handled.
+// @Tags QR
+// @Accept json
+// @Produce json
+// @Param callback body PaymentCallback true "Transfer confirmation"
+// @Success 200 {object} PaymentCallbackResult "OK"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 401 {object} ErrorResponse "Invalid signature"
+// @Failure 409 {object} ErrorResponse "Memo matches several todos"
+// @Failure 503 {object} ErrorResponse "Callback not configured"
+// @Router /payments/callback [post]
+func (h *PaymentHandler) PaymentCallback(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.secret == "" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Payment callback is not configured"})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPaymentCallbackBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+	if !hmac.Equal([]byte(r.Header.Get(PaymentSignatureHeader)), []byte(signPayload(h.secret, body))) {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid signature"})
+		return
+	}
+
+	var callback PaymentCallback
+	if err := json.Unmarshal(body, &callback); err != nil || normalizePaymentMemo(callback.Memo) == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	ctx := WithActor(r.Context(), PaymentCallbackActor)
+	todos, err := h.paymentStore.FindPendingPaymentTodosDB(ctx, normalizePaymentMemo(callback.Memo))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	var matched []Todo
+	for _, todo := range todos {
+		if callback.AccountNumber == "" || strings.TrimSpace(callback.AccountNumber) == todo.Payment.AccountNumber {
+			matched = append(matched, todo)
+		}
+	}
+
+	switch {
+	case len(matched) == 0:
+		log.Printf("Giao dịch %s (%d) không khớp todo nào: %q", callback.TransactionID, callback.Amount, callback.Memo)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(PaymentCallbackResult{Status: PaymentStatusUnmatched})
+		return
+	case len(matched) > 1:
+		ids := make([]string, len(matched))
+		for i, todo := range matched {
+			ids[i] = todo.ID
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Memo matches several todos: " + strings.Join(ids, ", ")})
+		return
+	}
+
+	todo := matched[0]
+	if todo.Payment.Amount > 0 && callback.Amount < todo.Payment.Amount {
+		log.Printf("Giao dịch %s trả %d cho todo %s, thiếu so với %d", callback.TransactionID, callback.Amount, todo.ID, todo.Payment.Amount)
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(PaymentCallbackResult{Status: PaymentStatusUnderpaid, TodoID: todo.ID})
+		return
+	}
+
+	// ChangeStatusDB đảo trạng thái nên phải ghim version vừa đọc: callback trùng
+	// hoặc người dùng đổi todo ở giữa thì không mở lại todo đã thanh toán.
+	if err := h.todoStore.ChangeStatusDB(WithExpectedVersion(ctx, todo.Version), todo.ID); err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			// Todo vừa bị xóa sau khi tìm thấy.
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(PaymentCallbackResult{Status: PaymentStatusUnmatched})
+			return
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			log.Printf("Giao dịch %s: todo %s đã đổi từ lúc tìm thấy, bỏ qua", callback.TransactionID, todo.ID)
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(PaymentCallbackResult{Status: PaymentStatusAlreadyHandled, TodoID: todo.ID})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	log.Printf("Giao dịch %s đã thanh toán todo %s", callback.TransactionID, todo.ID)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(PaymentCallbackResult{Status: PaymentStatusDone, TodoID: todo.ID})
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"api/qrscan"
+	"api/vietqr"
+)
+
+type MockPaymentStore struct {
+	mock.Mock
+}
+
+func (m *MockPaymentStore) FindPendingPaymentTodosDB(ctx context.Context, memo string) ([]Todo, error) {
+	args := m.Called(memo)
+	return args.Get(0).([]Todo), args.Error(1)
+}
+
+const testPaymentSecret = "s3cret"
+
+func newPaymentRouter(todoStore TodoStore, paymentStore PaymentStore, secret string) *mux.Router {
+	h := NewPaymentHandler(todoStore, paymentStore, secret)
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/{id}/qr.png", h.GetTodoQR).Methods("GET")
+	router.HandleFunc("/payments/callback", h.PaymentCallback).Methods("POST")
+	return router
+}
+
+func postPaymentCallback(router http.Handler, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/payments/callback", strings.NewReader(body))
+	req.Header.Set(PaymentSignatureHeader, signPayload(testPaymentSecret, []byte(body)))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+const paymentTodoID = "3f2b8c1e-7d4a-4e9b-a1c2-5d6e7f8a9b0c"
+
+func TestDefaultPaymentMemo(t *testing.T) {
+	assert.Equal(t, "3F2B8C1E7D4A4E9BA1C25D6E7", defaultPaymentMemo(paymentTodoID))
+	assert.Equal(t, "MBVCB12345RENT2024", normalizePaymentMemo("MBVCB.12345.rent-2024 "))
+}
+
+func TestValidatePayment(t *testing.T) {
+	p := &TodoPayment{BankBIN: " 970436", AccountNumber: "0011001234567 ", Amount: 50000, Memo: " RENT 2024 12 "}
+	assert.NoError(t, validatePayment(p))
+	assert.Equal(t, TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}, *p)
+	assert.NoError(t, validatePayment(nil))
+	assert.NoError(t, validatePayment(&TodoPayment{BankBIN: "970436", AccountNumber: "1"}))
+
+	for name, p := range map[string]TodoPayment{
+		"bad bin":        {BankBIN: "VCB", AccountNumber: "1"},
+		"no account":     {BankBIN: "970436"},
+		"negative":       {BankBIN: "970436", AccountNumber: "1", Amount: -1},
+		"diacritics":     {BankBIN: "970436", AccountNumber: "1", Memo: "Tiền nhà tháng 12"},
+		"short memo":     {BankBIN: "970436", AccountNumber: "1", Memo: "A-1"},
+		"memo too long":  {BankBIN: "970436", AccountNumber: "1", Memo: strings.Repeat("A", 26)},
+		"account spaces": {BankBIN: "970436", AccountNumber: "0011 0012"},
+	} {
+		err := validateTodo(&Todo{Title: "x", Payment: &p})
+		assert.True(t, errors.Is(err, ErrInvalidPayment), name)
+	}
+}
+
+func TestNextPayment(t *testing.T) {
+	assert.Nil(t, nextPayment(Todo{ID: paymentTodoID}))
+
+	done := Todo{ID: paymentTodoID, Payment: &TodoPayment{BankBIN: "970436", AccountNumber: "1", Memo: defaultPaymentMemo(paymentTodoID)}}
+	assert.Equal(t, &TodoPayment{BankBIN: "970436", AccountNumber: "1"}, nextPayment(done))
+
+	done.Payment.Memo = "RENT MONTHLY"
+	assert.Equal(t, "RENT MONTHLY", nextPayment(done).Memo)
+}
+
+func TestGetTodoQR(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetTodoByIdDB", paymentTodoID).Return(Todo{ID: paymentTodoID, Title: "Collect from Lan",
+		Payment: &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000}}, nil)
+
+	req, _ := http.NewRequest("GET", "/todo/"+paymentTodoID+"/qr.png", nil)
+	rr := httptest.NewRecorder()
+	newPaymentRouter(todoStore, new(MockPaymentStore), "").ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	img, err := png.Decode(rr.Body)
+	assert.NoError(t, err)
+	payload, err := qrscan.Scan(img)
+	assert.NoError(t, err)
+
+	d := vietqr.Decode(payload)
+	assert.True(t, d.Valid, "%v", d.Violations)
+	assert.Equal(t, "970436", d.MerchantAccounts[0].BankBIN)
+	assert.Equal(t, "50000", d.Amount)
+	assert.Equal(t, "3F2B8C1E7D4A4E9BA1C25D6E7", d.AdditionalData.Purpose)
+}
+
+func TestGetTodoQR_NotFound(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "No payment"}, nil)
+	todoStore.On("GetTodoByIdDB", "2").Return(Todo{}, ErrTodoNotFound)
+	router := newPaymentRouter(todoStore, new(MockPaymentStore), "")
+
+	for id, want := range map[string]string{"1": "Todo has no payment", "2": "Todo not found"} {
+		req, _ := http.NewRequest("GET", "/todo/"+id+"/qr.png", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusNotFound, rr.Code, id)
+		assert.JSONEq(t, `{"error":"`+want+`"}`, rr.Body.String(), id)
+	}
+}
+
+func TestPaymentCallback_MarksTodoDone(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	paymentStore := new(MockPaymentStore)
+	paymentStore.On("FindPendingPaymentTodosDB", "MBVCB123453F2B8C1E7D4A4E9BA1C25D6E7CTTU0123").Return([]Todo{
+		{ID: paymentTodoID, Payment: &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: defaultPaymentMemo(paymentTodoID)}},
+	}, nil)
+	todoStore.On("ChangeStatusDB", paymentTodoID).Return(nil).Once()
+
+	rr := postPaymentCallback(newPaymentRouter(todoStore, paymentStore, testPaymentSecret),
+		`{"transaction_id":"FT1","amount":50000,"account_number":"0011001234567","memo":"MBVCB.12345.3F2B8C1E7D4A4E9BA1C25D6E7.CT tu 0123"}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"done","todo_id":"`+paymentTodoID+`"}`, rr.Body.String())
+	todoStore.AssertExpectations(t)
+}
+
+func TestPaymentCallback_AlreadyHandled(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	paymentStore := new(MockPaymentStore)
+	// Callback trùng trên replica khác đã đánh dấu xong, todo lên version 3.
+	paymentStore.On("FindPendingPaymentTodosDB", "RENT202412").Return([]Todo{
+		{ID: "1", Version: 2, Payment: &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}},
+	}, nil)
+
+	rr := postPaymentCallback(newPaymentRouter(versionedStore{MockTodoStore: todoStore, version: 3}, paymentStore, testPaymentSecret),
+		`{"transaction_id":"FT1","amount":50000,"memo":"RENT 2024 12"}`)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"already_handled","todo_id":"1"}`, rr.Body.String())
+	todoStore.AssertNotCalled(t, "ChangeStatusDB", mock.Anything)
+}
+
+func TestPaymentCallback_NotMarked(t *testing.T) {
+	pending := func(id, account string, amount int64) Todo {
+		return Todo{ID: id, Payment: &TodoPayment{BankBIN: "970436", AccountNumber: account, Amount: amount, Memo: "RENT 2024 12"}}
+	}
+	for _, tc := range []struct {
+		name  string
+		todos []Todo
+		body  string
+		code  int
+		want  string
+	}{
+		{"unmatched", []Todo{}, `{"amount":50000,"memo":"RENT 2024 12"}`, http.StatusOK, `{"status":"unmatched"}`},
+		{"other account", []Todo{pending("1", "999", 0)}, `{"amount":50000,"memo":"RENT 2024 12","account_number":"0011001234567"}`,
+			http.StatusOK, `{"status":"unmatched"}`},
+		{"underpaid", []Todo{pending("1", "0011001234567", 50000)}, `{"amount":20000,"memo":"RENT 2024 12"}`,
+			http.StatusOK, `{"status":"underpaid","todo_id":"1"}`},
+		{"ambiguous", []Todo{pending("1", "0011001234567", 0), pending("2", "0011001234567", 0)}, `{"amount":50000,"memo":"RENT 2024 12"}`,
+			http.StatusConflict, `{"error":"Memo matches several todos: 1, 2"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			todoStore := new(MockTodoStore)
+			paymentStore := new(MockPaymentStore)
+			paymentStore.On("FindPendingPaymentTodosDB", "RENT202412").Return(tc.todos, nil)
+
+			rr := postPaymentCallback(newPaymentRouter(todoStore, paymentStore, testPaymentSecret), tc.body)
+
+			assert.Equal(t, tc.code, rr.Code)
+			assert.JSONEq(t, tc.want, rr.Body.String())
+			todoStore.AssertNotCalled(t, "ChangeStatusDB", mock.Anything)
+		})
+	}
+}
+
+func TestPaymentCallback_Rejected(t *testing.T) {
+	body := `{"amount":50000,"memo":"RENT 2024 12"}`
+
+	req, _ := http.NewRequest("POST", "/payments/callback", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	newPaymentRouter(new(MockTodoStore), new(MockPaymentStore), "").ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	req, _ = http.NewRequest("POST", "/payments/callback", strings.NewReader(body))
+	req.Header.Set(PaymentSignatureHeader, signPayload("wrong", []byte(body)))
+	rr = httptest.NewRecorder()
+	newPaymentRouter(new(MockTodoStore), new(MockPaymentStore), testPaymentSecret).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	for _, body := range []string{`not json`, `{"amount":1,"memo":" .- "}`} {
+		rr = postPaymentCallback(newPaymentRouter(new(MockTodoStore), new(MockPaymentStore), testPaymentSecret), body)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
@@ -0,0 +1,595 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/jung-kurt/gofpdf"
+
+	"api/barcoderender"
+	"api/escpos"
+)
+
+var ErrPrintJobNotFound = errors.New("print job not found")
+
+// PrintJob là một lần in danh sách todo. Todos là bản chụp lúc in nên vẫn
+// đúng với tờ giấy dù todo đã bị sửa hay xóa sau đó.
+type PrintJob struct {
+	ID        int64     `json:"id"`
+	Code      string    `json:"code"`
+	PrintedAt time.Time `json:"printed_at"`
+	PrintedBy string    `json:"printed_by"`
+	Format    string    `json:"format"`
+	// Query là bộ lọc của GET /todos/print, vd "done=false&tag=kho".
+	Query string `json:"query,omitempty"`
+	Todos []Todo `json:"todos"`
+}
+
+// printCodeTimeLayout là dạng thời điểm in trong barcode: chỉ gồm chữ số để
+// Code128 mã hóa gọn (bộ C).
+const printCodeTimeLayout = "20060102150405"
+
+// printCode là nội dung barcode của job: "<id>-<thời điểm in UTC>".
+func printCode(id int64, printedAt time.Time) string {
+	return strconv.FormatInt(id, 10) + "-" + printedAt.UTC().Format(printCodeTimeLayout)
+}
+
+// parsePrintCode đọc ID job từ ID trần hoặc từ nội dung barcode đã quét; với
+// nội dung barcode, stamp là phần thời điểm in để đối chiếu với job.
+func parsePrintCode(s string) (id int64, stamp string, err error) {
+	idPart, stamp, _ := strings.Cut(strings.TrimSpace(s), "-")
+	id, err = strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id < 1 {
+		return 0, "", fmt.Errorf("invalid print job %q", s)
+	}
+	return id, stamp, nil
+}
+
+type PrintStore interface {
+	CreatePrintJobDB(ctx context.Context, job PrintJob) (PrintJob, error)
+	GetPrintJobDB(ctx context.Context, id int64) (PrintJob, error)
+}
+
+func (db *Db) CreatePrintJobDB(ctx context.Context, job PrintJob) (PrintJob, error) {
+	todos, err := json.Marshal(job.Todos)
+	if err != nil {
+		return PrintJob{}, err
+	}
+	err = db.Conn.QueryRow(ctx, "INSERT INTO print_job (printed_at, printed_by, format, query, todos) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		job.PrintedAt, job.PrintedBy, job.Format, job.Query, todos).Scan(&job.ID)
+	if err != nil {
+		return PrintJob{}, fmt.Errorf("failed to create print job: %v", err)
+	}
+	job.Code = printCode(job.ID, job.PrintedAt)
+
+	return job, nil
+}
+
+func (db *Db) GetPrintJobDB(ctx context.Context, id int64) (PrintJob, error) {
+	job := PrintJob{ID: id}
+	var todos []byte
+	err := db.Conn.QueryRow(ctx, "SELECT printed_at, printed_by, format, query, todos FROM print_job WHERE id = $1", id).
+		Scan(&job.PrintedAt, &job.PrintedBy, &job.Format, &job.Query, &todos)
+	if err == pgx.ErrNoRows {
+		return PrintJob{}, fmt.Errorf("print job %d: %w", id, ErrPrintJobNotFound)
+	}
+	if err != nil {
+		return PrintJob{}, fmt.Errorf("failed to get print job: %v", err)
+	}
+	if err := json.Unmarshal(todos, &job.Todos); err != nil {
+		return PrintJob{}, fmt.Errorf("failed to read printed todos: %v", err)
+	}
+	job.Code = printCode(job.ID, job.PrintedAt)
+
+	return job, nil
+}
+
+type PrintHandler struct {
+	todoStore  TodoStore
+	printStore PrintStore
+	// printers là các máy in nhiệt được phép gửi tới, theo tên; để trống thì
+	// chỉ tải file ESC/POS về được.
+	printers map[string]ThermalPrinter
+}
+
+func NewPrintHandler(todoStore TodoStore, printStore PrintStore, printers map[string]ThermalPrinter) *PrintHandler {
+	return &PrintHandler{todoStore: todoStore, printStore: printStore, printers: printers}
+}
+
+// printRequest là các tham số in chung của /todos/print và /todo/{id}/print.
+type printRequest struct {
+	format string
+	escpos escpos.Options
+	// printer là máy in nhiệt nhận bản in; nil thì trả file về.
+	printer *ThermalPrinter
+}
+
+// parsePrintRequest đọc format, paper, charset và printer rồi xóa chúng khỏi q
+// để phần còn lại là bộ lọc. POST gửi ESC/POS tới máy in nên cần printer.
+func (h *PrintHandler) parsePrintRequest(r *http.Request, q url.Values) (printRequest, error) {
+	req := printRequest{format: strings.ToLower(q.Get("format"))}
+	name, paper, charset := q.Get("printer"), q.Get("paper"), q.Get("charset")
+	for _, key := range []string{"format", "paper", "charset", "printer"} {
+		q.Del(key)
+	}
+
+	if r.Method == http.MethodPost {
+		if req.format == "" {
+			req.format = "escpos"
+		}
+		if req.format != "escpos" {
+			return req, errors.New("only escpos can be sent to a printer")
+		}
+		if name == "" {
+			return req, errors.New("printer is required")
+		}
+		printer, ok := h.printers[name]
+		if !ok {
+			return req, fmt.Errorf("unknown printer %q", name)
+		}
+		req.printer = &printer
+		req.escpos = printer.Options
+	} else if name != "" {
+		return req, errors.New("use POST to send to a printer")
+	}
+	if req.format == "" {
+		req.format = "pdf"
+	}
+	if req.format != "pdf" && req.format != "html" && req.format != "escpos" {
+		return req, errors.New("format must be pdf, html or escpos")
+	}
+
+	var err error
+	if paper != "" {
+		if req.escpos.Paper, err = escpos.ParsePaper(paper); err != nil {
+			return req, err
+		}
+	}
+	if charset != "" {
+		if req.escpos.Charset, err = escpos.ParseCharset(charset); err != nil {
+			return req, err
+		}
+	}
+	return req, nil
+}
+
+// @Summary Print the todo list
+// @Description Render the todo list, filtered like GET /todos, as a printable PDF or HTML page, or as an ESC/POS stream for a thermal printer. Every sheet carries a barcode of "<job id>-<print time, UTC yyyyMMddHHmmss>"; the print job is recorded so GET /prints/{id} shows the todos that were printed. A POST sends the ESC/POS stream over raw TCP to a printer configured in THERMAL_PRINTERS and returns a ThermalPrintResponse.
+// @Tags Print
+// @Produce application/pdf
+// @Produce html
+// @Produce application/octet-stream
+// @Produce json
+// @Param format query string false "pdf (default), html or escpos; POST only accepts escpos"
+// @Param paper query string false "ESC/POS paper width: 80mm (default, or the printer's) or 58mm"
+// @Param charset query string false "ESC/POS charset: ascii (default, drops diacritics) or cp1258"
+// @Param printer query string false "Printer name from THERMAL_PRINTERS, required for POST"
+// @Param done query bool false "Done status"
+// @Param list query string false "List ID"
+// @Param tag query string false "Tag"
+// @Param q query string false "Search in title and description"
+// @Param due_before query string false "RFC3339 time"
+// @Success 200 {file} file "Printable todo list"
+// @Header 200 {integer} X-Print-Job "ID of the recorded print job"
+// @Failure 400 {object} ErrorResponse "Invalid query or unknown printer"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Printer unreachable"
+// @Router /todos/print [get]
+// @Router /todos/print [post]
+func (h *PrintHandler) PrintTodos(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req, err := h.parsePrintRequest(r, q)
+	if err != nil {
+		printError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter, after, limit, err := parseTodoQuery(q)
+	if err != nil {
+		printError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	todos, err := h.todoStore.GetAllTodoDB(r.Context())
+	if err != nil {
+		printError(w, http.StatusInternalServerError, "Failed to get todos: "+err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = len(todos)
+	}
+	todos, _, _ = pageTodos(todos, filter, after, limit)
+
+	h.print(w, r, req, false, q.Encode(), todos)
+}
+
+// @Summary Print one todo
+// @Description Render a single todo as a slip, in the same formats as GET /todos/print. A POST sends the ESC/POS slip to a thermal printer configured in THERMAL_PRINTERS instead, like POST /todos/print.
+// @Tags Print
+// @Produce application/pdf
+// @Produce html
+// @Produce application/octet-stream
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param format query string false "pdf (default), html or escpos; POST only accepts escpos"
+// @Param paper query string false "ESC/POS paper width: 80mm (default) or 58mm"
+// @Param charset query string false "ESC/POS charset: ascii (default) or cp1258"
+// @Param printer query string false "Printer name from THERMAL_PRINTERS, required for POST"
+// @Success 200 {file} file "Printable slip, or ThermalPrintResponse for POST"
+// @Header 200 {integer} X-Print-Job "ID of the recorded print job"
+// @Failure 400 {object} ErrorResponse "Invalid query or unknown printer"
+// @Failure 404 {object} ErrorResponse "Todo not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 502 {object} ErrorResponse "Printer unreachable"
+// @Router /todo/{id}/print [get]
+// @Router /todo/{id}/print [post]
+func (h *PrintHandler) PrintTodo(w http.ResponseWriter, r *http.Request) {
+	req, err := h.parsePrintRequest(r, r.URL.Query())
+	if err != nil {
+		printError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	todo, err := h.todoStore.GetTodoByIdDB(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			printError(w, http.StatusNotFound, "Todo not found")
+		} else {
+			printError(w, http.StatusInternalServerError, "Failed to get todo: "+err.Error())
+		}
+		return
+	}
+
+	h.print(w, r, req, true, url.Values{"id": {id}}.Encode(), []Todo{todo})
+}
+
+// print ghi lại job, vẽ theo req.format rồi trả file về hoặc gửi tới máy in;
+// single là phiếu của một todo thay vì cả danh sách.
+func (h *PrintHandler) print(w http.ResponseWriter, r *http.Request, req printRequest, single bool, query string, todos []Todo) {
+	job, err := h.printStore.CreatePrintJobDB(r.Context(), PrintJob{
+		// Barcode chỉ ghi tới giây nên thời điểm lưu cũng làm tròn theo.
+		PrintedAt: time.Now().UTC().Truncate(time.Second),
+		PrintedBy: ActorFromContext(r.Context()),
+		Format:    req.format,
+		Query:     query,
+		Todos:     todos,
+	})
+	if err != nil {
+		printError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType, disposition, ext := "text/html; charset=utf-8", "inline", req.format
+	switch req.format {
+	case "pdf":
+		contentType = "application/pdf"
+		err = writePrintPDF(&buf, job)
+	case "escpos":
+		// File ESC/POS không xem được trên trình duyệt nên luôn là tải về.
+		contentType, disposition, ext = "application/octet-stream", "attachment", "bin"
+		err = writePrintESCPOS(&buf, job, single, req.escpos)
+	default:
+		err = writePrintHTML(&buf, job)
+	}
+	if err != nil {
+		printError(w, http.StatusInternalServerError, "Failed to render print: "+err.Error())
+		return
+	}
+
+	w.Header().Set("X-Print-Job", strconv.FormatInt(job.ID, 10))
+	if req.printer != nil {
+		if err := sendToPrinter(r.Context(), req.printer.Addr, buf.Bytes()); err != nil {
+			printError(w, http.StatusBadGateway, fmt.Sprintf("Failed to send to printer %s: %v", req.printer.Name, err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ThermalPrintResponse{Job: job, Printer: req.printer.Name, Bytes: buf.Len()})
+		return
+	}
+
+	name := "todos"
+	if single {
+		name = "todo"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s-%d.%s"`, disposition, name, job.ID, ext))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// @Summary Look up a print job
+// @Description Show which todos were on a printed sheet. id is the job ID or the scanned barcode text; with the barcode text the print time must match too.
+// @Tags Print
+// @Produce json
+// @Param id path string true "Job ID or barcode text, e.g. 42-20241224093000"
+// @Success 200 {object} PrintJob "OK"
+// @Failure 400 {object} ErrorResponse "Invalid ID"
+// @Failure 404 {object} ErrorResponse "Print job not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /prints/{id} [get]
+func (h *PrintHandler) GetPrintJob(w http.ResponseWriter, r *http.Request) {
+	id, stamp, err := parsePrintCode(mux.Vars(r)["id"])
+	if err != nil {
+		printError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job, err := h.printStore.GetPrintJobDB(r.Context(), id)
+	if err == nil && stamp != "" && stamp != job.PrintedAt.UTC().Format(printCodeTimeLayout) {
+		err = ErrPrintJobNotFound
+	}
+	if err != nil {
+		if errors.Is(err, ErrPrintJobNotFound) {
+			printError(w, http.StatusNotFound, "Print job not found")
+		} else {
+			printError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(job)
+}
+
+func printError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// printBars mã hóa nội dung barcode thành dãy module Code128, true là vạch đen.
+func printBars(code string) ([]bool, error) {
+	bc, err := barcoderender.New(barcoderender.Code128, code, barcoderender.DefaultOptions(barcoderender.Code128))
+	if err != nil {
+		return nil, err
+	}
+	return bc.Modules()[0], nil
+}
+
+// barRuns gọi fn cho mỗi vạch đen liền nhau: vị trí module đầu và độ rộng.
+func barRuns(bars []bool, fn func(start, width int)) {
+	for x := 0; x < len(bars); {
+		if !bars[x] {
+			x++
+			continue
+		}
+		start := x
+		for x < len(bars) && bars[x] {
+			x++
+		}
+		fn(start, x-start)
+	}
+}
+
+// Kích thước barcode trên giấy: module 0.33 mm là cỡ máy quét cầm tay đọc tốt.
+// Mỗi todo có short_code được in kèm barcode thấp hơn để quét qua POST /scan.
+const (
+	printModuleMM       = 0.33
+	printBarHeightMM    = 10
+	printRowBarHeightMM = 7
+)
+
+func printDue(todo Todo) string {
+	if todo.DueAt == nil {
+		return ""
+	}
+	return todo.DueAt.UTC().Format("2006-01-02 15:04")
+}
+
+func printTags(todo Todo) string {
+	tags := make([]string, len(todo.Tags))
+	for i, tag := range todo.Tags {
+		tags[i] = "#" + tag
+	}
+	return strings.Join(tags, " ")
+}
+
+//go:embed fonts/DejaVuSans.ttf
+var dejaVuSans []byte
+
+//go:embed fonts/DejaVuSans-Bold.ttf
+var dejaVuSansBold []byte
+
+// writePrintPDF vẽ job ra PDF khổ A4. Font DejaVu được nhúng vì font chuẩn
+// của PDF không có dấu tiếng Việt.
+func writePrintPDF(w *bytes.Buffer, job PrintJob) error {
+	bars, err := printBars(job.Code)
+	if err != nil {
+		return err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetCreationDate(job.PrintedAt)
+	pdf.SetTitle(fmt.Sprintf("Todo list #%d", job.ID), true)
+	pdf.AddUTF8FontFromBytes("DejaVu", "", dejaVuSans)
+	pdf.AddUTF8FontFromBytes("DejaVu", "B", dejaVuSansBold)
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 15)
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	left, _, right, bottom := pdf.GetMargins()
+	// Mỗi trang đều có barcode để quét được từ bất kỳ tờ nào.
+	pdf.SetHeaderFunc(func() {
+		pdf.SetFont("DejaVu", "B", 16)
+		pdf.CellFormat(0, 8, "Todo list", "", 1, "L", false, 0, "")
+		pdf.SetFont("DejaVu", "", 9)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Printed %s UTC by %s", job.PrintedAt.UTC().Format("2006-01-02 15:04:05"), job.PrintedBy), "", 1, "L", false, 0, "")
+		if job.Query != "" {
+			pdf.CellFormat(0, 5, "Filter: "+job.Query, "", 1, "L", false, 0, "")
+		}
+
+		x := pageWidth - right - float64(len(bars))*printModuleMM
+		barRuns(bars, func(start, width int) {
+			pdf.Rect(x+float64(start)*printModuleMM, 15, float64(width)*printModuleMM, printBarHeightMM, "F")
+		})
+		pdf.SetXY(x, 15+printBarHeightMM)
+		pdf.CellFormat(float64(len(bars))*printModuleMM, 4, job.Code, "", 0, "C", false, 0, "")
+		pdf.SetXY(left, 15+printBarHeightMM+8)
+		pdf.Line(left, pdf.GetY(), pageWidth-right, pdf.GetY())
+		pdf.Ln(3)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("DejaVu", "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Job #%d · %d todos · page %d", job.ID, len(job.Todos), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	if len(job.Todos) == 0 {
+		pdf.SetFont("DejaVu", "", 11)
+		pdf.CellFormat(0, 8, "No todos match this filter.", "", 1, "L", false, 0, "")
+	}
+	const box, indent, gap = 4.0, 7.0, 4.0
+	for _, todo := range job.Todos {
+		var code []bool
+		textWidth := pageWidth - left - right - indent
+		if todo.ShortCode != "" {
+			if code, err = printBars(todo.ShortCode); err != nil {
+				return err
+			}
+			textWidth -= float64(len(code))*printModuleMM + gap
+		}
+		// Giữ ô đánh dấu, tiêu đề và barcode trên cùng một trang.
+		if pdf.GetY()+printRowBarHeightMM+5 > pageHeight-bottom {
+			pdf.AddPage()
+		}
+		y := pdf.GetY()
+		pdf.Rect(left, y+1, box, box, "D")
+		if todo.Done {
+			pdf.Line(left+0.8, y+3, left+1.8, y+4.2)
+			pdf.Line(left+1.8, y+4.2, left+3.4, y+1.6)
+		}
+		pdf.SetX(left + indent)
+		pdf.SetFont("DejaVu", "B", 11)
+		pdf.MultiCell(textWidth, 6, todo.Title, "", "L", false)
+
+		pdf.SetFont("DejaVu", "", 9)
+		if todo.Desc != "" {
+			pdf.SetX(left + indent)
+			pdf.MultiCell(textWidth, 4.5, todo.Desc, "", "L", false)
+		}
+		var meta []string
+		if due := printDue(todo); due != "" {
+			meta = append(meta, "Due "+due)
+		}
+		if tags := printTags(todo); tags != "" {
+			meta = append(meta, tags)
+		}
+		if len(meta) > 0 {
+			pdf.SetX(left + indent)
+			pdf.SetTextColor(90, 90, 90)
+			pdf.MultiCell(textWidth, 4.5, strings.Join(meta, "  ·  "), "", "L", false)
+			pdf.SetTextColor(0, 0, 0)
+		}
+
+		if code != nil {
+			end := pdf.GetY()
+			width := float64(len(code)) * printModuleMM
+			x := pageWidth - right - width
+			barRuns(code, func(start, n int) {
+				pdf.Rect(x+float64(start)*printModuleMM, y+1, float64(n)*printModuleMM, printRowBarHeightMM, "F")
+			})
+			pdf.SetXY(x, y+1+printRowBarHeightMM)
+			pdf.SetFont("DejaVu", "", 7)
+			pdf.CellFormat(width, 3, todo.ShortCode, "", 0, "C", false, 0, "")
+			pdf.SetXY(left, max(end, y+1+printRowBarHeightMM+3))
+		}
+		pdf.Ln(2)
+	}
+
+	return pdf.Output(w)
+}
+
+var printTemplate = template.Must(template.New("print").Funcs(template.FuncMap{
+	"due":     printDue,
+	"tags":    printTags,
+	"barcode": printBarcodeSVG,
+	"utc":     func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html lang="vi">
+<head>
+<meta charset="utf-8">
+<title>Todo list #{{.Job.ID}}</title>
+<style>
+  @page { size: A4; margin: 15mm; }
+  body { font-family: "DejaVu Sans", Arial, sans-serif; font-size: 10pt; color: #000; margin: 0; }
+  header { display: flex; justify-content: space-between; align-items: flex-start; border-bottom: 1px solid #000; padding-bottom: 3mm; margin-bottom: 3mm; }
+  h1 { font-size: 16pt; margin: 0 0 1mm; }
+  .meta, .code { font-size: 9pt; }
+  .code { text-align: center; }
+  .barcode { display: block; }
+  table { width: 100%; border-collapse: collapse; }
+  td { padding: 1.5mm 1mm; vertical-align: top; border-bottom: 0.2mm solid #ccc; }
+  tr { page-break-inside: avoid; }
+  .box { width: 6mm; font-size: 12pt; }
+  td.code { width: 1%; font-size: 7pt; }
+  .title { font-weight: bold; }
+  .desc { white-space: pre-wrap; }
+  .extra { color: #555; font-size: 9pt; }
+</style>
+</head>
+<body>
+<header>
+  <div>
+    <h1>Todo list</h1>
+    <div class="meta">Printed {{utc .Job.PrintedAt}} UTC by {{.Job.PrintedBy}} · Job #{{.Job.ID}} · {{len .Job.Todos}} todos</div>
+    {{with .Job.Query}}<div class="meta">Filter: {{.}}</div>{{end}}
+  </div>
+  <div class="code">
+    {{barcode .Job.Code 10}}
+    {{.Job.Code}}
+  </div>
+</header>
+{{if .Job.Todos}}
+<table>
+{{range .Job.Todos}}  <tr>
+    <td class="box">{{if .Done}}☑{{else}}☐{{end}}</td>
+    <td>
+      <div class="title">{{.Title}}</div>
+      {{with .Desc}}<div class="desc">{{.}}</div>{{end}}
+      {{with due .}}<span class="extra">Due {{.}}</span>{{end}} {{with tags .}}<span class="extra">{{.}}</span>{{end}}
+    </td>
+    <td class="code">{{with .ShortCode}}{{barcode . 7}}{{.}}{{end}}</td>
+  </tr>
+{{end}}</table>
+{{else}}
+<p>No todos match this filter.</p>
+{{end}}
+</body>
+</html>
+`))
+
+func writePrintHTML(w *bytes.Buffer, job PrintJob) error {
+	return printTemplate.Execute(w, struct{ Job PrintJob }{job})
+}
+
+// printBarcodeSVG vẽ Code128 của code thành thẻ svg nhúng, mỗi module
+// printModuleMM và cao height mm.
+func printBarcodeSVG(code string, height float64) (template.HTML, error) {
+	bars, err := printBars(code)
+	if err != nil {
+		return "", err
+	}
+	var path strings.Builder
+	barRuns(bars, func(start, width int) {
+		fmt.Fprintf(&path, "M%d 0h%dv1h-%dz", start, width, width)
+	})
+	return template.HTML(fmt.Sprintf(`<svg class="barcode" style="width: %smm; height: %smm" xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d 1" preserveAspectRatio="none" shape-rendering="crispEdges"><path d="%s"/></svg>`,
+		strconv.FormatFloat(float64(len(bars))*printModuleMM, 'f', -1, 64), strconv.FormatFloat(height, 'f', -1, 64), len(bars), path.String())), nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPrintStore struct {
+	mock.Mock
+}
+
+func (m *MockPrintStore) CreatePrintJobDB(ctx context.Context, job PrintJob) (PrintJob, error) {
+	args := m.Called(job)
+	return args.Get(0).(PrintJob), args.Error(1)
+}
+
+func (m *MockPrintStore) GetPrintJobDB(ctx context.Context, id int64) (PrintJob, error) {
+	args := m.Called(id)
+	return args.Get(0).(PrintJob), args.Error(1)
+}
+
+func newPrintRouter(todoStore TodoStore, printStore PrintStore) *mux.Router {
+	return newPrintRouterWithPrinters(todoStore, printStore, nil)
+}
+
+func newPrintRouterWithPrinters(todoStore TodoStore, printStore PrintStore, printers map[string]ThermalPrinter) *mux.Router {
+	h := NewPrintHandler(todoStore, printStore, printers)
+	router := mux.NewRouter()
+	router.Use(ActorMiddleware)
+	router.HandleFunc("/todos/print", h.PrintTodos).Methods("GET", "POST")
+	router.HandleFunc("/todo/{id}/print", h.PrintTodo).Methods("GET", "POST")
+	router.HandleFunc("/prints/{id}", h.GetPrintJob).Methods("GET")
+	return router
+}
+
+func printTestTodos() []Todo {
+	due := time.Date(2024, 12, 24, 17, 0, 0, 0, time.UTC)
+	return []Todo{
+		{ID: "1", Title: "Kiểm kê kho", Desc: "Đếm lại kệ A & B", CreatedAt: time.Date(2024, 12, 20, 8, 0, 0, 0, time.UTC), DueAt: &due, Tags: []string{"kho"}, ShortCode: "T000001"},
+		{ID: "2", Title: "Đã xong", Done: true, CreatedAt: time.Date(2024, 12, 21, 8, 0, 0, 0, time.UTC)},
+	}
+}
+
+func printedJob(todos []Todo) PrintJob {
+	job := PrintJob{ID: 42, PrintedAt: time.Date(2024, 12, 24, 9, 30, 0, 0, time.UTC), PrintedBy: "kho-1", Format: "pdf", Todos: todos}
+	job.Code = printCode(job.ID, job.PrintedAt)
+	return job
+}
+
+func TestPrintTodos_HTML(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(printTestTodos(), nil)
+	printStore := new(MockPrintStore)
+	printStore.On("CreatePrintJobDB", mock.MatchedBy(func(job PrintJob) bool {
+		return job.Format == "html" && job.Query == "done=false" && job.PrintedBy == "kho-1" && len(job.Todos) == 1 && job.Todos[0].ID == "1"
+	})).Return(printedJob(printTestTodos()[:1]), nil)
+
+	req, _ := http.NewRequest("GET", "/todos/print?format=html&done=false", nil)
+	req.Header.Set(ActorHeader, "kho-1")
+	rr := httptest.NewRecorder()
+	newPrintRouter(todoStore, printStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "42", rr.Header().Get("X-Print-Job"))
+	body := rr.Body.String()
+	assert.Contains(t, body, "Kiểm kê kho")
+	assert.Contains(t, body, "Đếm lại kệ A &amp; B")
+	assert.Contains(t, body, "Due 2024-12-24 17:00")
+	assert.Contains(t, body, "#kho")
+	assert.NotContains(t, body, "Đã xong")
+	assert.Contains(t, body, "42-20241224093000")
+	assert.Contains(t, body, "<path d=\"M0 0h2v1h-2z")
+	// Barcode riêng của todo để quét qua POST /scan.
+	assert.Equal(t, 2, strings.Count(body, "<svg class=\"barcode\""))
+	assert.Contains(t, body, "T000001</td>")
+	printStore.AssertExpectations(t)
+}
+
+func TestPrintTodos_PDF(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(printTestTodos(), nil)
+	printStore := new(MockPrintStore)
+	var created PrintJob
+	printStore.On("CreatePrintJobDB", mock.Anything).Return(printedJob(printTestTodos()), nil).
+		Run(func(args mock.Arguments) { created = args.Get(0).(PrintJob) })
+
+	req, _ := http.NewRequest("GET", "/todos/print", nil)
+	rr := httptest.NewRecorder()
+	newPrintRouter(todoStore, printStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/pdf", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `inline; filename="todos-42.pdf"`, rr.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")))
+	assert.Len(t, created.Todos, 2)
+	assert.Equal(t, "pdf", created.Format)
+	assert.WithinDuration(t, time.Now(), created.PrintedAt, 5*time.Second)
+	assert.Equal(t, created.PrintedAt, created.PrintedAt.Truncate(time.Second))
+}
+
+func TestPrintTodos_InvalidQuery(t *testing.T) {
+	for _, query := range []string{"format=docx", "done=maybe"} {
+		req, _ := http.NewRequest("GET", "/todos/print?"+query, nil)
+		rr := httptest.NewRecorder()
+		newPrintRouter(new(MockTodoStore), new(MockPrintStore)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestPrintBars(t *testing.T) {
+	bars, err := printBars("42-20241224093000")
+	assert.NoError(t, err)
+
+	var s strings.Builder
+	for _, dark := range bars {
+		if dark {
+			s.WriteByte('1')
+		} else {
+			s.WriteByte('0')
+		}
+	}
+	// Start B rồi chuyển sang bộ C cho chuỗi số dài, kết thúc bằng Stop.
+	assert.True(t, strings.HasPrefix(s.String(), "11010010000"), s.String())
+	assert.True(t, strings.HasSuffix(s.String(), "1100011101011"), s.String())
+
+	var runs [][2]int
+	barRuns([]bool{true, true, false, true, false, false, true}, func(start, width int) { runs = append(runs, [2]int{start, width}) })
+	assert.Equal(t, [][2]int{{0, 2}, {3, 1}, {6, 1}}, runs)
+}
+
+func TestGetPrintJob(t *testing.T) {
+	printStore := new(MockPrintStore)
+	printStore.On("GetPrintJobDB", int64(42)).Return(printedJob(printTestTodos()[:1]), nil)
+	printStore.On("GetPrintJobDB", int64(7)).Return(PrintJob{}, ErrPrintJobNotFound)
+	router := newPrintRouter(new(MockTodoStore), printStore)
+
+	for _, id := range []string{"42", "42-20241224093000"} {
+		req, _ := http.NewRequest("GET", "/prints/"+id, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, id)
+		var got PrintJob
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+		assert.Equal(t, "42-20241224093000", got.Code)
+		assert.Equal(t, "Kiểm kê kho", got.Todos[0].Title)
+	}
+
+	for id, code := range map[string]int{"42-20241224093001": http.StatusNotFound, "7": http.StatusNotFound, "abc": http.StatusBadRequest, "0": http.StatusBadRequest} {
+		req, _ := http.NewRequest("GET", "/prints/"+id, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, id)
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"api/qrrender"
+	"api/qrscan"
+	"api/vietqr"
+)
+
+// QRPaymentResponse là payload VietQR để frontend hoặc dịch vụ khác vẽ thành mã QR.
+type QRPaymentResponse struct {
+	Payload string `json:"payload"`
+}
+
+type QRHandler struct {
+	banks *vietqr.Registry
+}
+
+func NewQRHandler(banks *vietqr.Registry) *QRHandler {
+	return &QRHandler{banks: banks}
+}
+
+// @Summary Build a VietQR payment payload
+// @Description Build the EMVCo payload (NAPAS VietQR profile) that banking apps scan to prefill a transfer. The bank is given as bank_bin or as bank (a BIN, code or name, resolved through GET /banks). Without amount the code is static and the payer enters the amount.
+// @Tags QR
+// @Accept json
+// @Produce json
+// @Param payment body vietqr.Payment true "Beneficiary and transfer details"
+// @Success 200 {object} QRPaymentResponse "OK"
+// @Failure 400 {object} ErrorResponse "Invalid payment"
+// @Router /qr/payment [post]
+func (h *QRHandler) CreatePaymentPayload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var payment vietqr.Payment
+	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body"})
+		return
+	}
+
+	payment, err := payment.Resolve(h.banks.Directory())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	payload, err := payment.Payload()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(QRPaymentResponse{Payload: payload})
+}
+
+// QRRenderRequest là body của POST /qr/render. GET /qr/render nhận các trường
+// này (trừ logo) làm tham số query.
+type QRRenderRequest struct {
+	Data string `json:"data"`
+	// Format là png (mặc định) hoặc svg.
+	Format string `json:"format,omitempty"`
+	// ECC là mức sửa lỗi L, M, Q hoặc H; mặc định M, hoặc H khi có logo.
+	ECC        string `json:"ecc,omitempty"`
+	ModuleSize *int   `json:"module_size,omitempty"`
+	QuietZone  *int   `json:"quiet_zone,omitempty"`
+	Foreground string `json:"foreground,omitempty"`
+	Background string `json:"background,omitempty"`
+	// Logo là ảnh PNG, JPEG hoặc GIF mã hóa base64, vẽ ở giữa mã.
+	Logo string `json:"logo,omitempty"`
+}
+
+const maxQRRenderBytes = 2 << 20
+
+// @Summary Render a QR code
+// @Description Render any payload (text, URL or a /qr/payment payload) as PNG or SVG. GET takes the same fields as query parameters, except logo.
+// @Tags QR
+// @Produce png
+// @Produce image/svg+xml
+// @Param data query string true "Payload to encode"
+// @Param format query string false "png (default) or svg"
+// @Param ecc query string false "Error correction level L, M, Q or H"
+// @Param module_size query int false "Module size in pixels (default 8)"
+// @Param quiet_zone query int false "Quiet zone in modules (default 4)"
+// @Param foreground query string false "Foreground color, e.g. #000000"
+// @Param background query string false "Background color, e.g. #ffffff or #ffffff00 for transparent"
+// @Success 200 {file} file "QR code image"
+// @Failure 400 {object} ErrorResponse "Invalid options"
+// @Router /qr/render [get]
+// @Router /qr/render [post]
+func (h *QRHandler) RenderQR(w http.ResponseWriter, r *http.Request) {
+	var req QRRenderRequest
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxQRRenderBytes)).Decode(&req); err != nil {
+			qrBadRequest(w, "Invalid request body")
+			return
+		}
+	} else {
+		q := r.URL.Query()
+		req = QRRenderRequest{Data: q.Get("data"), Format: q.Get("format"), ECC: q.Get("ecc"),
+			Foreground: q.Get("foreground"), Background: q.Get("background")}
+		for name, dst := range map[string]**int{"module_size": &req.ModuleSize, "quiet_zone": &req.QuietZone} {
+			if v := q.Get(name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					qrBadRequest(w, "invalid "+name+" "+strconv.Quote(v))
+					return
+				}
+				*dst = &n
+			}
+		}
+	}
+
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		qrBadRequest(w, "format must be png or svg")
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		qrBadRequest(w, err.Error())
+		return
+	}
+	code, err := qrrender.New(req.Data, opts)
+	if err != nil {
+		qrBadRequest(w, err.Error())
+		return
+	}
+
+	// Vẽ vào buffer trước để lỗi (nếu có) vẫn trả được 500 thay vì ảnh hỏng.
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf)
+	} else {
+		err = code.WritePNG(&buf)
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to render QR code: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func (req QRRenderRequest) options() (qrrender.Options, error) {
+	opts := qrrender.DefaultOptions()
+	var err error
+	if opts.Level, err = qrrender.ParseLevel(req.ECC); err != nil {
+		return opts, err
+	}
+	if req.ModuleSize != nil {
+		opts.ModuleSize = *req.ModuleSize
+		if opts.ModuleSize == 0 {
+			return opts, fmt.Errorf("%w: module size must be between 1 and %d", qrrender.ErrInvalidOptions, qrrender.MaxModuleSize)
+		}
+	}
+	if req.QuietZone != nil {
+		opts.QuietZone = *req.QuietZone
+	}
+	if req.Foreground != "" {
+		if opts.Foreground, err = qrrender.ParseColor(req.Foreground); err != nil {
+			return opts, err
+		}
+	}
+	if req.Background != "" {
+		if opts.Background, err = qrrender.ParseColor(req.Background); err != nil {
+			return opts, err
+		}
+	}
+	if req.Logo != "" {
+		raw, err := base64.StdEncoding.DecodeString(req.Logo)
+		if err != nil {
+			return opts, fmt.Errorf("%w: logo must be base64", qrrender.ErrInvalidOptions)
+		}
+		if opts.Logo, _, err = image.Decode(bytes.NewReader(raw)); err != nil {
+			return opts, fmt.Errorf("%w: logo must be a PNG, JPEG or GIF image", qrrender.ErrInvalidOptions)
+		}
+	}
+	return opts, nil
+}
+
+// QRDecodeRequest là body JSON của POST /qr/decode.
+type QRDecodeRequest struct {
+	Payload string `json:"payload"`
+}
+
+// QRDecodeResponse là payload đã đọc (gửi thẳng hoặc quét từ ảnh) cùng cây
+// EMVCo và các vi phạm tìm được.
+type QRDecodeResponse struct {
+	Payload string `json:"payload"`
+	*vietqr.Decoded
+}
+
+// Giới hạn của ảnh tải lên /qr/decode; ảnh lớn hơn cần nhiều bộ nhớ để phân
+// ngưỡng mà không giúp đọc mã tốt hơn.
+const (
+	maxQRDecodeBytes  = 10 << 20
+	maxQRDecodePixels = 4096 * 4096
+)
+
+var errNoQRImage = errors.New("multipart body must have an image file or a payload field")
+
+// @Summary Decode and validate an EMVCo QR payload
+// @Description Parse a payment payload, sent as JSON or scanned from an uploaded image, into its EMVCo data objects (merchant account, BIN, account, amount, additional data) and report every violation of the CRC, mandatory data objects, lengths and formats. A payload with violations still returns 200 with valid set to false.
+// @Tags QR
+// @Accept json
+// @Accept mpfd
+// @Accept png
+// @Accept jpeg
+// @Produce json
+// @Param request body QRDecodeRequest false "Payload to decode"
+// @Param image formData file false "PNG, JPEG or GIF image of the QR code"
+// @Success 200 {object} QRDecodeResponse "OK"
+// @Failure 400 {object} ErrorResponse "Invalid request body or image"
+// @Failure 422 {object} ErrorResponse "No QR code found in the image"
+// @Router /qr/decode [post]
+func (h *QRHandler) DecodeQR(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxQRDecodeBytes)
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var payload string
+	switch {
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(maxQRDecodeBytes); err != nil {
+			qrBadRequest(w, "Invalid multipart body")
+			return
+		}
+		file, _, err := r.FormFile("image")
+		if errors.Is(err, http.ErrMissingFile) {
+			if payload = r.FormValue("payload"); payload == "" {
+				qrBadRequest(w, errNoQRImage.Error())
+				return
+			}
+			break
+		}
+		if err != nil {
+			qrBadRequest(w, "Invalid multipart body")
+			return
+		}
+		defer file.Close()
+		var ok bool
+		if payload, ok = scanQRImage(w, file); !ok {
+			return
+		}
+	case strings.HasPrefix(mediaType, "image/"):
+		var ok bool
+		if payload, ok = scanQRImage(w, r.Body); !ok {
+			return
+		}
+	default:
+		var req QRDecodeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			qrBadRequest(w, "Invalid request body")
+			return
+		}
+		payload = req.Payload
+	}
+
+	// Khoảng trắng thừa khi copy-paste không thể là một phần của payload hợp
+	// lệ, vì payload luôn bắt đầu bằng "00" và kết thúc bằng CRC.
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		qrBadRequest(w, "payload is required")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(QRDecodeResponse{Payload: payload, Decoded: vietqr.Decode(payload)})
+}
+
+// scanQRImage đọc mã QR trong ảnh; khi thất bại nó đã ghi response lỗi và trả
+// về false.
+func scanQRImage(w http.ResponseWriter, r io.Reader) (string, bool) {
+	img, ok := readUploadedImage(w, r)
+	if !ok {
+		return "", false
+	}
+
+	payload, err := qrscan.Scan(img)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]string{"error": "No QR code found in the image"})
+		return "", false
+	}
+	return payload, true
+}
+
+// readUploadedImage đọc ảnh tải lên trong giới hạn của /qr/decode; khi thất
+// bại nó đã ghi response 400 và trả về false.
+func readUploadedImage(w http.ResponseWriter, r io.Reader) (image.Image, bool) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		qrBadRequest(w, fmt.Sprintf("image must be at most %d bytes", maxQRDecodeBytes))
+		return nil, false
+	}
+	// Kiểm tra kích thước trước khi giải nén cả ảnh.
+	config, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		qrBadRequest(w, "image must be a PNG, JPEG or GIF image")
+		return nil, false
+	}
+	if config.Width*config.Height > maxQRDecodePixels {
+		qrBadRequest(w, fmt.Sprintf("image must be at most %d pixels", maxQRDecodePixels))
+		return nil, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		qrBadRequest(w, "image must be a PNG, JPEG or GIF image")
+		return nil, false
+	}
+	return img, true
+}
+
+func qrBadRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
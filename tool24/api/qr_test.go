@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"api/qrrender"
+	"api/vietqr"
+)
+
+func newQRRouter() *mux.Router {
+	h := NewQRHandler(vietqr.NewRegistry(vietqr.DefaultDirectory()))
+	router := mux.NewRouter()
+	router.HandleFunc("/qr/payment", h.CreatePaymentPayload).Methods("POST")
+	router.HandleFunc("/qr/render", h.RenderQR).Methods("GET", "POST")
+	router.HandleFunc("/qr/decode", h.DecodeQR).Methods("POST")
+	return router
+}
+
+func TestCreatePaymentPayload(t *testing.T) {
+	body := `{"bank_bin":"970436","account_number":"0011001234567","amount":50000,"currency":"704","purpose":"Order 42"}`
+	req, _ := http.NewRequest("POST", "/qr/payment", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"payload":"00020101021238570010A00000072701270006970436011300110012345670208QRIBFTTA53037045405500005802VN62120808Order 42630430BA"}`, rr.Body.String())
+}
+
+func TestCreatePaymentPayload_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`not json`,
+		`{"bank_bin":"VCB","account_number":"0011001234567"}`,
+		`{"bank_bin":"970436","account_number":"0011001234567","purpose":"Thanh toán"}`,
+		`{"bank_bin":"999999","account_number":"0011001234567"}`,
+		`{"bank":"Công thương","account_number":"0011001234567"}`,
+		`{"account_number":"0011001234567"}`,
+	} {
+		req, _ := http.NewRequest("POST", "/qr/payment", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		newQRRouter().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestRenderQR_PNG(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/qr/render?data=hello&module_size=2&quiet_zone=1&foreground=%23ff0000", nil)
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get("Content-Type"))
+	img, err := png.Decode(rr.Body)
+	assert.NoError(t, err)
+	// Version 1 có 21 module, cộng quiet zone 1 module mỗi bên, mỗi module 2px.
+	assert.Equal(t, image.Rect(0, 0, 46, 46), img.Bounds())
+	assert.Equal(t, color.NRGBA{R: 0xff, A: 0xff}, color.NRGBAModel.Convert(img.At(2, 2)))
+	assert.Equal(t, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}, color.NRGBAModel.Convert(img.At(0, 0)))
+}
+
+func TestRenderQR_SVGWithLogo(t *testing.T) {
+	logo := image.NewNRGBA(image.Rect(0, 0, 8, 8))
+	var buf bytes.Buffer
+	png.Encode(&buf, logo)
+	body, _ := json.Marshal(QRRenderRequest{Data: "https://example.com", Format: "svg", Logo: base64.StdEncoding.EncodeToString(buf.Bytes())})
+
+	req, _ := http.NewRequest("POST", "/qr/render", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(rr.Body.String(), "<svg "))
+	assert.Contains(t, rr.Body.String(), `href="data:image/png;base64,`)
+}
+
+func TestRenderQR_Invalid(t *testing.T) {
+	for _, url := range []string{
+		"/qr/render",
+		"/qr/render?data=x&format=gif",
+		"/qr/render?data=x&ecc=Z",
+		"/qr/render?data=x&module_size=abc",
+		"/qr/render?data=x&module_size=0",
+		"/qr/render?data=x&quiet_zone=100",
+		"/qr/render?data=x&background=blue",
+	} {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		newQRRouter().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, url)
+	}
+
+	for _, body := range []string{`{"data":"x","logo":"not base64!"}`, `{"data":"x","logo":"aGVsbG8="}`} {
+		req, _ := http.NewRequest("POST", "/qr/render", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		newQRRouter().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestDecodeQR_Payload(t *testing.T) {
+	body := `{"payload":" 00020101021238570010A00000072701270006970436011300110012345670208QRIBFTTA53037045405500005802VN62120808Order 42630430BA\n"}`
+	req, _ := http.NewRequest("POST", "/qr/decode", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp struct {
+		Valid            bool                     `json:"valid"`
+		MerchantAccounts []vietqr.MerchantAccount `json:"merchant_accounts"`
+		Amount           string                   `json:"amount"`
+		AdditionalData   vietqr.AdditionalData    `json:"additional_data"`
+		Violations       []vietqr.Violation       `json:"violations"`
+	}
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.True(t, resp.Valid)
+	assert.Equal(t, "970436", resp.MerchantAccounts[0].BankBIN)
+	assert.Equal(t, "0011001234567", resp.MerchantAccounts[0].AccountNumber)
+	assert.Equal(t, "50000", resp.Amount)
+	assert.Equal(t, "Order 42", resp.AdditionalData.Purpose)
+	assert.Empty(t, resp.Violations)
+}
+
+func TestDecodeQR_Violations(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/qr/decode", strings.NewReader(`{"payload":"000201010211"}`))
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp QRDecodeResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.False(t, resp.Valid)
+	assert.Contains(t, resp.Violations, vietqr.Violation{Path: "63", Offset: 0, Code: vietqr.ViolationMissing, Message: "CRC (63) is required"})
+}
+
+func TestDecodeQR_Image(t *testing.T) {
+	const payload = "00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB"
+	code, err := qrrender.New(payload, qrrender.DefaultOptions())
+	assert.NoError(t, err)
+	var img bytes.Buffer
+	assert.NoError(t, code.WritePNG(&img))
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("image", "qr.png")
+	part.Write(img.Bytes())
+	form.Close()
+
+	req, _ := http.NewRequest("POST", "/qr/decode", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp QRDecodeResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, payload, resp.Payload)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, "970436", resp.MerchantAccounts[0].BankBIN)
+}
+
+func TestDecodeQR_NoCode(t *testing.T) {
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 100, 100)))
+
+	req, _ := http.NewRequest("POST", "/qr/decode", &img)
+	req.Header.Set("Content-Type", "image/png")
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestDecodeQR_Invalid(t *testing.T) {
+	for _, tc := range []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", `not json`},
+		{"application/json", `{"payload":"  "}`},
+		{"image/png", "not an image"},
+		{"multipart/form-data; boundary=x", "--x\r\nContent-Disposition: form-data; name=\"other\"\r\n\r\nv\r\n--x--\r\n"},
+	} {
+		req, _ := http.NewRequest("POST", "/qr/decode", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rr := httptest.NewRecorder()
+		newQRRouter().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, tc.body)
+	}
+}
+
+func TestCreatePaymentPayload_BankName(t *testing.T) {
+	body := `{"bank":"Ngoại thương","account_number":"0011001234567"}`
+	req, _ := http.NewRequest("POST", "/qr/payment", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	newQRRouter().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"payload":"00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB"}`, rr.Body.String())
+}
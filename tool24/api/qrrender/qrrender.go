@@ -0,0 +1,352 @@
+// Package qrrender draws QR codes as PNG or SVG. Encoding is pure Go, so it
+// works offline and needs no system libraries.
+//
+//	code, err := qrrender.New("https://example.com", qrrender.Options{Level: qrrender.LevelQ})
+//	err = code.WritePNG(w)
+package qrrender
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// ErrInvalidOptions is wrapped by every validation error of New.
+var ErrInvalidOptions = errors.New("invalid QR options")
+
+// Level is the error correction level. Higher levels survive more damage,
+// and leave room for a logo, at the cost of a denser symbol.
+type Level int
+
+const (
+	// LevelDefault is LevelM, or LevelH when a logo is drawn.
+	LevelDefault Level = iota
+	LevelL             // ~7% recovery
+	LevelM             // ~15% recovery
+	LevelQ             // ~25% recovery
+	LevelH             // ~30% recovery
+)
+
+// ParseLevel reads "L", "M", "Q" or "H"; an empty string is LevelDefault.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "":
+		return LevelDefault, nil
+	case "L":
+		return LevelL, nil
+	case "M":
+		return LevelM, nil
+	case "Q":
+		return LevelQ, nil
+	case "H":
+		return LevelH, nil
+	}
+	return LevelDefault, fmt.Errorf("%w: level must be L, M, Q or H", ErrInvalidOptions)
+}
+
+func (l Level) String() string {
+	return [...]string{"", "L", "M", "Q", "H"}[l]
+}
+
+// Giới hạn diện tích logo (tính cả viền trống) theo tỷ lệ số module của mã,
+// thấp hơn hẳn khả năng sửa lỗi danh nghĩa để vẫn còn dư cho vết bẩn, lóa...
+var logoBudget = map[Level]float64{LevelL: 0.03, LevelM: 0.08, LevelQ: 0.14, LevelH: 0.2}
+
+var recoveryLevels = map[Level]qrcode.RecoveryLevel{
+	LevelL: qrcode.Low, LevelM: qrcode.Medium, LevelQ: qrcode.High, LevelH: qrcode.Highest,
+}
+
+// Limits of Options.
+const (
+	DefaultModuleSize = 8
+	MaxModuleSize     = 64
+	DefaultQuietZone  = 4
+	MaxQuietZone      = 16
+	MaxImageSize      = 4096
+)
+
+// Options controls how a code is drawn. The zero value draws black modules of
+// DefaultModuleSize pixels on white with no quiet zone; use DefaultOptions
+// for the 4-module quiet zone the QR specification asks for.
+type Options struct {
+	Level Level
+	// ModuleSize is the width of one module in pixels; 0 means DefaultModuleSize.
+	ModuleSize int
+	// QuietZone is the blank border in modules.
+	QuietZone int
+	// Foreground and Background default to black and white.
+	Foreground color.Color
+	Background color.Color
+	// Logo is drawn in the center, scaled to the largest box the error
+	// correction level can afford to lose.
+	Logo image.Image
+}
+
+// DefaultOptions returns the options used when a caller sets nothing.
+func DefaultOptions() Options {
+	return Options{ModuleSize: DefaultModuleSize, QuietZone: DefaultQuietZone}
+}
+
+// Code is an encoded QR symbol ready to be drawn.
+type Code struct {
+	opts    Options
+	modules [][]bool
+	// logo là số module mỗi cạnh của ô vuông giữa mã dành cho logo, 0 nếu không có.
+	logo int
+}
+
+// New encodes data and validates opts.
+func New(data string, opts Options) (*Code, error) {
+	if data == "" {
+		return nil, fmt.Errorf("%w: data is required", ErrInvalidOptions)
+	}
+	if opts.Level == LevelDefault {
+		opts.Level = LevelM
+		if opts.Logo != nil {
+			opts.Level = LevelH
+		}
+	}
+	if opts.Level < LevelL || opts.Level > LevelH {
+		return nil, fmt.Errorf("%w: unknown level %d", ErrInvalidOptions, opts.Level)
+	}
+	if opts.ModuleSize == 0 {
+		opts.ModuleSize = DefaultModuleSize
+	}
+	if opts.ModuleSize < 1 || opts.ModuleSize > MaxModuleSize {
+		return nil, fmt.Errorf("%w: module size must be between 1 and %d", ErrInvalidOptions, MaxModuleSize)
+	}
+	if opts.QuietZone < 0 || opts.QuietZone > MaxQuietZone {
+		return nil, fmt.Errorf("%w: quiet zone must be between 0 and %d", ErrInvalidOptions, MaxQuietZone)
+	}
+	if opts.Foreground == nil {
+		opts.Foreground = color.Black
+	}
+	if opts.Background == nil {
+		opts.Background = color.White
+	}
+
+	q, err := qrcode.New(data, recoveryLevels[opts.Level])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+	}
+	logo := 0
+	if opts.Logo != nil {
+		// Mã nhỏ không đủ chỗ cho logo thì dùng version lớn hơn, cùng dữ liệu.
+		logo = logoModules(17+4*q.VersionNumber, logoBudget[opts.Level])
+		for logo < minLogoModules && q.VersionNumber < 40 {
+			if q, err = qrcode.NewWithForcedVersion(data, q.VersionNumber+1, recoveryLevels[opts.Level]); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidOptions, err)
+			}
+			logo = logoModules(17+4*q.VersionNumber, logoBudget[opts.Level])
+		}
+		if logo < minLogoModules {
+			return nil, fmt.Errorf("%w: level %s leaves no room for a logo, use Q or H", ErrInvalidOptions, opts.Level)
+		}
+	}
+	q.DisableBorder = true
+	code := &Code{opts: opts, modules: q.Bitmap(), logo: logo}
+
+	if code.Size()*opts.ModuleSize > MaxImageSize {
+		return nil, fmt.Errorf("%w: image would be larger than %dpx, use a smaller module size", ErrInvalidOptions, MaxImageSize)
+	}
+	return code, nil
+}
+
+// minLogoModules là cạnh nhỏ nhất của ô logo; nhỏ hơn thì logo không còn nhìn ra.
+const minLogoModules = 5
+
+// logoModules trả về cạnh lớn nhất (tính bằng module) của ô logo mà diện tích
+// không vượt budget, cùng tính chẵn lẻ với n để ô nằm chính giữa lưới module.
+// Ô không được chạm vào finder pattern, separator và thông tin format (9 module
+// tính từ mỗi góc), nếu không thì mã không quét được dù còn dư sửa lỗi.
+func logoModules(n int, budget float64) int {
+	side := int(math.Sqrt(budget) * float64(n))
+	if (n-side)%2 != 0 {
+		side--
+	}
+	return min(side, n-18)
+}
+
+// Size is the width of the drawing in modules, including the quiet zone.
+func (c *Code) Size() int {
+	return len(c.modules) + 2*c.opts.QuietZone
+}
+
+// Level is the error correction level the code was encoded with.
+func (c *Code) Level() Level {
+	return c.opts.Level
+}
+
+// dark cho biết module (x, y) của mã (không tính quiet zone) có được tô không;
+// các module trong ô logo luôn để trống.
+func (c *Code) dark(x, y int) bool {
+	if c.logo > 0 {
+		start := (len(c.modules) - c.logo) / 2
+		if x >= start && x < start+c.logo && y >= start && y < start+c.logo {
+			return false
+		}
+	}
+	return c.modules[y][x]
+}
+
+// logoRect là vùng vẽ logo tính bằng pixel: ô logo thu vào nửa module mỗi
+// cạnh để logo không dính vào các module xung quanh.
+func (c *Code) logoRect() image.Rectangle {
+	m := c.opts.ModuleSize
+	start := (c.opts.QuietZone + (len(c.modules)-c.logo)/2) * m
+	inset := m / 2
+	return image.Rect(start+inset, start+inset, start+c.logo*m-inset, start+c.logo*m-inset)
+}
+
+// Image draws the code.
+func (c *Code) Image() image.Image {
+	m := c.opts.ModuleSize
+	size := c.Size() * m
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c.opts.Background), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(c.opts.Foreground)
+	offset := c.opts.QuietZone * m
+	for y := range c.modules {
+		for x := range c.modules[y] {
+			if c.dark(x, y) {
+				r := image.Rect(offset+x*m, offset+y*m, offset+(x+1)*m, offset+(y+1)*m)
+				draw.Draw(img, r, fg, image.Point{}, draw.Over)
+			}
+		}
+	}
+
+	if c.logo > 0 {
+		r := fitRect(c.opts.Logo.Bounds(), c.logoRect())
+		draw.Draw(img, r, scale(c.opts.Logo, r.Dx(), r.Dy()), image.Point{}, draw.Over)
+	}
+	return img
+}
+
+// WritePNG writes the code as a PNG image.
+func (c *Code) WritePNG(w io.Writer) error {
+	return png.Encode(w, c.Image())
+}
+
+// WriteSVG writes the code as an SVG document. Modules are merged into one
+// path per color, so the output stays small and scales without blurring.
+func (c *Code) WriteSVG(w io.Writer) error {
+	var b strings.Builder
+	n, m := c.Size(), c.opts.ModuleSize
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", n*m, n*m, n, n)
+	if _, _, _, a := c.opts.Background.RGBA(); a > 0 {
+		fmt.Fprintf(&b, `<rect width="%d" height="%d" %s/>`+"\n", n, n, svgFill(c.opts.Background))
+	}
+
+	fmt.Fprintf(&b, `<path %s d="`, svgFill(c.opts.Foreground))
+	q := c.opts.QuietZone
+	for y := range c.modules {
+		// Gộp các module tối liên tiếp trên một hàng thành một hình chữ nhật.
+		for x := 0; x < len(c.modules); x++ {
+			if !c.dark(x, y) {
+				continue
+			}
+			start := x
+			for x < len(c.modules) && c.dark(x, y) {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start+q, y+q, x-start, x-start)
+		}
+	}
+	b.WriteString("\"/>\n")
+
+	if c.logo > 0 {
+		var logo bytes.Buffer
+		if err := png.Encode(&logo, c.opts.Logo); err != nil {
+			return err
+		}
+		r := c.logoRect()
+		fmt.Fprintf(&b, `<image x="%s" y="%s" width="%s" height="%s" href="data:image/png;base64,%s"/>`+"\n",
+			svgUnits(r.Min.X, m), svgUnits(r.Min.Y, m), svgUnits(r.Dx(), m), svgUnits(r.Dy(), m),
+			base64.StdEncoding.EncodeToString(logo.Bytes()))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// svgUnits đổi pixel sang đơn vị module của viewBox.
+func svgUnits(px, moduleSize int) string {
+	return strconv.FormatFloat(float64(px)/float64(moduleSize), 'f', -1, 64)
+}
+
+func svgFill(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill := fmt.Sprintf(`fill="#%02x%02x%02x"`, n.R, n.G, n.B)
+	if n.A < 0xff {
+		fill += fmt.Sprintf(` fill-opacity="%s"`, strconv.FormatFloat(float64(n.A)/0xff, 'f', 3, 64))
+	}
+	return fill
+}
+
+// fitRect trả về hình chữ nhật lớn nhất có tỷ lệ của src nằm giữa box.
+func fitRect(src, box image.Rectangle) image.Rectangle {
+	w, h := box.Dx(), box.Dy()
+	if src.Dx()*h > src.Dy()*w {
+		h = max(1, src.Dy()*w/src.Dx())
+	} else {
+		w = max(1, src.Dx()*h/src.Dy())
+	}
+	x, y := box.Min.X+(box.Dx()-w)/2, box.Min.Y+(box.Dy()-h)/2
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// scale thu phóng src về w×h bằng cách lấy trung bình các pixel nguồn rơi vào
+// mỗi pixel đích (hoặc pixel gần nhất khi phóng to).
+func scale(src image.Image, w, h int) image.Image {
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	b := src.Bounds()
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// RGBA trả về giá trị đã nhân alpha nên cộng trung bình trực tiếp được.
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / count), uint16(g / count), uint16(bl / count), uint16(a / count)})
+		}
+	}
+	return dst
+}
+
+// ParseColor reads a CSS-style hex color: #rgb, #rrggbb or #rrggbbaa. The
+// leading # is optional.
+func ParseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("%w: color %q must be #rgb, #rrggbb or #rrggbbaa", ErrInvalidOptions, s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
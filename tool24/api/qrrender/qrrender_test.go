@@ -0,0 +1,164 @@
+package qrrender
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qrcode "github.com/skip2/go-qrcode"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "ghi lại các file golden trong testdata")
+
+// testLogo là logo 40x20 màu đỏ có sọc xanh, đủ để thấy tỷ lệ được giữ nguyên.
+func testLogo() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			c := color.NRGBA{R: 0xd0, A: 0xff}
+			if y >= 8 && y < 12 {
+				c = color.NRGBA{B: 0xd0, A: 0xff}
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+var goldenCases = []struct {
+	name string
+	data string
+	opts Options
+}{
+	{"default", "https://example.com", DefaultOptions()},
+	{"colors", "HELLO WORLD", Options{Level: LevelL, ModuleSize: 4, QuietZone: 2,
+		Foreground: color.NRGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}, Background: color.NRGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0x80}}},
+	{"logo", "00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB",
+		Options{ModuleSize: 6, QuietZone: 4, Logo: testLogo()}},
+}
+
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		assert.NoError(t, os.WriteFile(path, got, 0o644))
+		return
+	}
+	want, err := os.ReadFile(path)
+	if assert.NoError(t, err, "chạy go test ./qrrender -update để tạo file golden") {
+		assert.True(t, bytes.Equal(want, got), "%s khác file golden", name)
+	}
+}
+
+func TestGoldenPNG(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := New(tc.data, tc.opts)
+			assert.NoError(t, err)
+			var buf bytes.Buffer
+			assert.NoError(t, code.WritePNG(&buf))
+			checkGolden(t, tc.name+".png", buf.Bytes())
+		})
+	}
+}
+
+func TestGoldenSVG(t *testing.T) {
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := New(tc.data, tc.opts)
+			assert.NoError(t, err)
+			var buf bytes.Buffer
+			assert.NoError(t, code.WriteSVG(&buf))
+			checkGolden(t, tc.name+".svg", buf.Bytes())
+		})
+	}
+}
+
+func TestImageMatchesEncoder(t *testing.T) {
+	code, err := New("https://example.com", Options{Level: LevelQ, ModuleSize: 3, QuietZone: 4})
+	assert.NoError(t, err)
+	q, _ := qrcode.New("https://example.com", qrcode.High)
+	bitmap := q.Bitmap() // đã gồm quiet zone 4 module
+
+	img := code.Image()
+	assert.Equal(t, len(bitmap)*3, img.Bounds().Dx())
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			r, _, _, _ := img.At(x*3+1, y*3+1).RGBA()
+			assert.Equal(t, bitmap[y][x], r == 0, "module (%d, %d)", x, y)
+		}
+	}
+}
+
+func TestLogoStaysWithinBudget(t *testing.T) {
+	for _, level := range []Level{LevelM, LevelQ, LevelH} {
+		code, err := New("https://example.com/a/fairly/long/path?with=query", Options{Level: level, Logo: testLogo()})
+		if !assert.NoError(t, err, level.String()) {
+			continue
+		}
+		n := len(code.modules)
+		assert.GreaterOrEqual(t, code.logo, minLogoModules)
+		assert.LessOrEqual(t, float64(code.logo*code.logo), logoBudget[level]*float64(n*n), level.String())
+		// Ô logo không được chạm vùng finder pattern và thông tin format.
+		start := (n - code.logo) / 2
+		assert.GreaterOrEqual(t, start, 9, level.String())
+		assert.Equal(t, n-start-code.logo, start, "logo phải nằm chính giữa")
+	}
+}
+
+func TestLogoBumpsVersion(t *testing.T) {
+	plain, err := New("hi", Options{Level: LevelH})
+	assert.NoError(t, err)
+	withLogo, err := New("hi", Options{Level: LevelH, Logo: testLogo()})
+	assert.NoError(t, err)
+	assert.Greater(t, len(withLogo.modules), len(plain.modules))
+	assert.Equal(t, LevelH, withLogo.Level())
+}
+
+func TestNewInvalid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		data string
+		opts Options
+	}{
+		"empty data":    {"", Options{}},
+		"module size":   {"x", Options{ModuleSize: MaxModuleSize + 1}},
+		"quiet zone":    {"x", Options{QuietZone: -1}},
+		"too large":     {string(make([]byte, 2000)), Options{ModuleSize: 64}},
+		"too much data": {string(make([]byte, 4000)), Options{}},
+		"unknown level": {"x", Options{Level: Level(9)}},
+	} {
+		_, err := New(tc.data, tc.opts)
+		assert.True(t, errors.Is(err, ErrInvalidOptions), name)
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	for in, want := range map[string]color.NRGBA{
+		"#000":     {A: 0xff},
+		"#1a237e":  {R: 0x1a, G: 0x23, B: 0x7e, A: 0xff},
+		"FFFFFF00": {R: 0xff, G: 0xff, B: 0xff},
+		" #abc ":   {R: 0xaa, G: 0xbb, B: 0xcc, A: 0xff},
+	} {
+		got, err := ParseColor(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"", "red", "#12345", "#gggggg"} {
+		_, err := ParseColor(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("q")
+	assert.NoError(t, err)
+	assert.Equal(t, LevelQ, level)
+	_, err = ParseLevel("X")
+	assert.Error(t, err)
+}
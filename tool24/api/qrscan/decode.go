@@ -0,0 +1,440 @@
+package qrscan
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+var (
+	errFormatInfo  = errors.New("unreadable format information")
+	errVersionInfo = errors.New("version information does not match the symbol size")
+)
+
+// bitMatrix là lưới module đã lấy mẫu; true là module tối.
+type bitMatrix struct {
+	dim  int
+	bits []bool
+}
+
+func newBitMatrix(dim int) *bitMatrix {
+	return &bitMatrix{dim: dim, bits: make([]bool, dim*dim)}
+}
+
+func (m *bitMatrix) get(x, y int) bool {
+	return m.bits[y*m.dim+x]
+}
+
+func (m *bitMatrix) set(x, y int, v bool) {
+	m.bits[y*m.dim+x] = v
+}
+
+// setRegion đánh dấu hình chữ nhật w x h bắt đầu tại (x, y).
+func (m *bitMatrix) setRegion(x, y, w, h int) {
+	for j := y; j < y+h; j++ {
+		for i := x; i < x+w; i++ {
+			m.set(i, j, true)
+		}
+	}
+}
+
+// bch trả về phần dư của value<<(degree của poly) chia cho poly trên GF(2),
+// dùng cho mã BCH của format và version information.
+func bch(value, poly int) int {
+	degree := bits.Len(uint(poly)) - 1
+	value <<= degree
+	for bits.Len(uint(value)) > degree {
+		value ^= poly << (bits.Len(uint(value)) - 1 - degree)
+	}
+	return value
+}
+
+// levelIndex ánh xạ 2 bit mức sửa lỗi trong format information sang chỉ số
+// L, M, Q, H của blockLayouts.
+var levelIndex = [4]int{1: 0, 0: 1, 3: 2, 2: 3}
+
+// readFormat đọc cả hai bản format information và chọn giá trị hợp lệ gần
+// nhất; mã BCH(15,5) sửa được tới 3 bit sai.
+func readFormat(m *bitMatrix) (level, mask int, err error) {
+	var first, second int
+	for x := 0; x <= 5; x++ {
+		first = first<<1 | bit(m.get(x, 8))
+	}
+	first = first<<1 | bit(m.get(7, 8))
+	first = first<<1 | bit(m.get(8, 8))
+	first = first<<1 | bit(m.get(8, 7))
+	for y := 5; y >= 0; y-- {
+		first = first<<1 | bit(m.get(8, y))
+	}
+	for y := m.dim - 1; y >= m.dim-7; y-- {
+		second = second<<1 | bit(m.get(8, y))
+	}
+	for x := m.dim - 8; x < m.dim; x++ {
+		second = second<<1 | bit(m.get(x, 8))
+	}
+
+	best, bestDistance := -1, 4
+	for data := 0; data < 32; data++ {
+		code := (data<<10 | bch(data, 0x537)) ^ 0x5412
+		for _, read := range []int{first, second} {
+			if d := bits.OnesCount(uint(code ^ read)); d < bestDistance {
+				best, bestDistance = data, d
+			}
+		}
+	}
+	if best < 0 {
+		return 0, 0, errFormatInfo
+	}
+	return levelIndex[best>>3], best & 7, nil
+}
+
+// readVersion đọc version information (chỉ có từ version 7) ở cả hai góc.
+func readVersion(m *bitMatrix) (int, bool) {
+	var first, second int
+	for y := 5; y >= 0; y-- {
+		for x := m.dim - 9; x >= m.dim-11; x-- {
+			first = first<<1 | bit(m.get(x, y))
+		}
+	}
+	for x := 5; x >= 0; x-- {
+		for y := m.dim - 9; y >= m.dim-11; y-- {
+			second = second<<1 | bit(m.get(x, y))
+		}
+	}
+
+	best, bestDistance := 0, 4
+	for version := 7; version <= 40; version++ {
+		code := version<<12 | bch(version, 0x1f25)
+		for _, read := range []int{first, second} {
+			if d := bits.OnesCount(uint(code ^ read)); d < bestDistance {
+				best, bestDistance = version, d
+			}
+		}
+	}
+	return best, best > 0
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// functionPatterns đánh dấu các module không chứa dữ liệu: finder, separator,
+// format/version information, timing và alignment pattern.
+func functionPatterns(version int) *bitMatrix {
+	dim := 17 + 4*version
+	m := newBitMatrix(dim)
+	m.setRegion(0, 0, 9, 9)
+	m.setRegion(dim-8, 0, 8, 9)
+	m.setRegion(0, dim-8, 9, 8)
+
+	positions := alignmentPositions[version]
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			// Bỏ các vị trí trùng với finder pattern.
+			if (i == 0 && (j == 0 || j == last)) || (i == last && j == 0) {
+				continue
+			}
+			m.setRegion(x-2, y-2, 5, 5)
+		}
+	}
+
+	m.setRegion(6, 9, 1, dim-17)
+	m.setRegion(9, 6, dim-17, 1)
+	if version >= 7 {
+		m.setRegion(dim-11, 0, 3, 6)
+		m.setRegion(0, dim-11, 6, 3)
+	}
+	return m
+}
+
+// masked cho biết module ở hàng i, cột j có bị đảo bởi mask pattern không.
+func masked(mask, i, j int) bool {
+	switch mask {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// readCodewords đọc các byte theo đường zigzag từ góc dưới phải, mỗi lần hai
+// cột, bỏ qua cột timing số 6.
+func readCodewords(m *bitMatrix, version, mask int) []byte {
+	function := functionPatterns(version)
+	var codewords []byte
+	var current byte
+	n := 0
+	up := true
+	for x := m.dim - 1; x > 0; x -= 2 {
+		if x == 6 {
+			x--
+		}
+		for count := 0; count < m.dim; count++ {
+			y := count
+			if up {
+				y = m.dim - 1 - count
+			}
+			for col := 0; col < 2; col++ {
+				if function.get(x-col, y) {
+					continue
+				}
+				current <<= 1
+				if m.get(x-col, y) != masked(mask, y, x-col) {
+					current |= 1
+				}
+				if n++; n%8 == 0 {
+					codewords = append(codewords, current)
+					current = 0
+				}
+			}
+		}
+		up = !up
+	}
+	return codewords
+}
+
+// correct tách codeword thành các khối Reed-Solomon, sửa lỗi từng khối và nối
+// phần dữ liệu lại.
+func correct(raw []byte, layout blockLayout) ([]byte, error) {
+	if len(raw) < layout.totalCodewords() {
+		return nil, fmt.Errorf("expected %d codewords, read %d", layout.totalCodewords(), len(raw))
+	}
+	count := layout.blocks1 + layout.blocks2
+	blocks := make([][]byte, count)
+	dataLen := func(b int) int {
+		if b < layout.blocks1 {
+			return layout.data1
+		}
+		return layout.data2
+	}
+	for b := range blocks {
+		blocks[b] = make([]byte, dataLen(b)+layout.ecc)
+	}
+
+	offset := 0
+	for i := 0; i < layout.data1; i++ {
+		for b := range blocks {
+			blocks[b][i] = raw[offset]
+			offset++
+		}
+	}
+	for b := layout.blocks1; b < count; b++ {
+		blocks[b][layout.data1] = raw[offset]
+		offset++
+	}
+	for i := 0; i < layout.ecc; i++ {
+		for b := range blocks {
+			blocks[b][dataLen(b)+i] = raw[offset]
+			offset++
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		if _, err := rsCorrect(block, layout.ecc); err != nil {
+			return nil, fmt.Errorf("block %d: %w", b, err)
+		}
+		data = append(data, block[:dataLen(b)]...)
+	}
+	return data, nil
+}
+
+// bitReader đọc lần lượt các bit của dòng dữ liệu, bit cao trước.
+type bitReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *bitReader) available() int {
+	return len(r.data)*8 - r.pos
+}
+
+func (r *bitReader) read(n int) (int, error) {
+	if n > r.available() {
+		return 0, errors.New("data ends in the middle of a segment")
+	}
+	v := 0
+	for i := 0; i < n; i++ {
+		b := r.data[r.pos/8] >> (7 - r.pos%8) & 1
+		v = v<<1 | int(b)
+		r.pos++
+	}
+	return v, nil
+}
+
+// Mode indicator của các segment.
+const (
+	modeTerminator   = 0
+	modeNumeric      = 1
+	modeAlphanumeric = 2
+	modeStructured   = 3
+	modeByte         = 4
+	modeFNC1First    = 5
+	modeECI          = 7
+	modeKanji        = 8
+	modeFNC1Second   = 9
+)
+
+// alphanumericCharset là bảng 45 ký tự của alphanumeric mode.
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// countBits trả về độ dài trường character count theo mode và nhóm version.
+func countBits(mode, version int) int {
+	group := 0
+	switch {
+	case version >= 27:
+		group = 2
+	case version >= 10:
+		group = 1
+	}
+	switch mode {
+	case modeNumeric:
+		return [3]int{10, 12, 14}[group]
+	case modeAlphanumeric:
+		return [3]int{9, 11, 13}[group]
+	default:
+		return [3]int{8, 16, 16}[group]
+	}
+}
+
+// decodeSegments giải các segment của dòng dữ liệu. Byte mode được trả nguyên
+// byte, tức là UTF-8 với mọi payload mà bộ sinh mã của chúng ta tạo ra.
+func decodeSegments(data []byte, version int) (string, error) {
+	r := &bitReader{data: data}
+	var out strings.Builder
+	for r.available() >= 4 {
+		mode, _ := r.read(4)
+		switch mode {
+		case modeTerminator:
+			return out.String(), nil
+		case modeFNC1First:
+		case modeFNC1Second:
+			if _, err := r.read(8); err != nil {
+				return "", err
+			}
+		case modeStructured:
+			if _, err := r.read(16); err != nil {
+				return "", err
+			}
+		case modeECI:
+			// Designator dài 1, 2 hoặc 3 byte tùy các bit đầu.
+			first, err := r.read(8)
+			if err != nil {
+				return "", err
+			}
+			switch {
+			case first&0xc0 == 0x80:
+				_, err = r.read(8)
+			case first&0xe0 == 0xc0:
+				_, err = r.read(16)
+			}
+			if err != nil {
+				return "", err
+			}
+		case modeNumeric, modeAlphanumeric, modeByte:
+			count, err := r.read(countBits(mode, version))
+			if err != nil {
+				return "", err
+			}
+			if err := decodeSegment(r, mode, count, &out); err != nil {
+				return "", err
+			}
+		case modeKanji:
+			return "", errors.New("kanji mode is not supported")
+		default:
+			return "", fmt.Errorf("unknown mode %d", mode)
+		}
+	}
+	return out.String(), nil
+}
+
+func decodeSegment(r *bitReader, mode, count int, out *strings.Builder) error {
+	switch mode {
+	case modeNumeric:
+		for count > 0 {
+			digits := min(count, 3)
+			v, err := r.read([4]int{0, 4, 7, 10}[digits])
+			if err != nil {
+				return err
+			}
+			s := fmt.Sprintf("%0*d", digits, v)
+			if len(s) != digits {
+				return errors.New("invalid numeric segment")
+			}
+			out.WriteString(s)
+			count -= digits
+		}
+	case modeAlphanumeric:
+		for count > 0 {
+			if count == 1 {
+				v, err := r.read(6)
+				if err != nil {
+					return err
+				}
+				if v >= len(alphanumericCharset) {
+					return errors.New("invalid alphanumeric segment")
+				}
+				out.WriteByte(alphanumericCharset[v])
+				break
+			}
+			v, err := r.read(11)
+			if err != nil {
+				return err
+			}
+			if v >= 45*45 {
+				return errors.New("invalid alphanumeric segment")
+			}
+			out.WriteByte(alphanumericCharset[v/45])
+			out.WriteByte(alphanumericCharset[v%45])
+			count -= 2
+		}
+	case modeByte:
+		for ; count > 0; count-- {
+			v, err := r.read(8)
+			if err != nil {
+				return err
+			}
+			out.WriteByte(byte(v))
+		}
+	}
+	return nil
+}
+
+// decodeMatrix giải một lưới module đã lấy mẫu thành nội dung của mã.
+func decodeMatrix(m *bitMatrix) (string, error) {
+	version := (m.dim - 17) / 4
+	if version < 1 || version > 40 || 17+4*version != m.dim {
+		return "", fmt.Errorf("invalid symbol size %d", m.dim)
+	}
+	if version >= 7 {
+		if read, ok := readVersion(m); !ok || read != version {
+			return "", errVersionInfo
+		}
+	}
+	level, mask, err := readFormat(m)
+	if err != nil {
+		return "", err
+	}
+	data, err := correct(readCodewords(m, version, mask), blockLayouts[version][level])
+	if err != nil {
+		return "", err
+	}
+	return decodeSegments(data, version)
+}
@@ -0,0 +1,356 @@
+// Package qrscan reads QR codes from clean digital images: screenshots,
+// exported PNGs and scans that are upright or rotated by a multiple of 90°.
+// It does not correct perspective, so camera photos taken at an angle may not
+// decode.
+//
+//	text, err := qrscan.Scan(img)
+package qrscan
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sort"
+)
+
+// ErrNotFound is returned when the image contains no readable QR code.
+var ErrNotFound = errors.New("no QR code found")
+
+// Scan decodes the first QR code it can read in img. Light-on-dark codes are
+// tried after dark-on-light ones.
+func Scan(img image.Image) (string, error) {
+	b := binarize(img)
+	if text, err := b.scan(); err == nil {
+		return text, nil
+	}
+	b.invert()
+	if text, err := b.scan(); err == nil {
+		return text, nil
+	}
+	return "", ErrNotFound
+}
+
+// binaryImage là ảnh đã phân ngưỡng; true là điểm tối.
+type binaryImage struct {
+	w, h int
+	dark []bool
+}
+
+func (b *binaryImage) get(x, y int) bool {
+	return b.dark[y*b.w+x]
+}
+
+func (b *binaryImage) invert() {
+	for i := range b.dark {
+		b.dark[i] = !b.dark[i]
+	}
+}
+
+// binarize chuyển ảnh sang độ sáng (điểm trong suốt coi như nằm trên nền
+// trắng) rồi phân ngưỡng bằng phương pháp Otsu.
+func binarize(img image.Image) *binaryImage {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	lum := make([]uint8, w*h)
+	var histogram [256]int
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			white := 0xffff - a
+			l := (299*(r+white) + 587*(g+white) + 114*(bl+white)) / 1000 >> 8
+			lum[y*w+x] = uint8(l)
+			histogram[l]++
+		}
+	}
+
+	// Otsu: chọn ngưỡng làm cực đại phương sai giữa hai lớp.
+	total := w * h
+	var sum float64
+	for i, n := range histogram {
+		sum += float64(i * n)
+	}
+	var sumDark float64
+	var bestVariance float64
+	threshold, weightDark := 0, 0
+	for t := 0; t < 256; t++ {
+		weightDark += histogram[t]
+		if weightDark == 0 {
+			continue
+		}
+		weightLight := total - weightDark
+		if weightLight == 0 {
+			break
+		}
+		sumDark += float64(t * histogram[t])
+		meanDark := sumDark / float64(weightDark)
+		meanLight := (sum - sumDark) / float64(weightLight)
+		variance := float64(weightDark) * float64(weightLight) * (meanDark - meanLight) * (meanDark - meanLight)
+		if variance > bestVariance {
+			bestVariance, threshold = variance, t
+		}
+	}
+
+	b := &binaryImage{w: w, h: h, dark: make([]bool, w*h)}
+	for i, l := range lum {
+		b.dark[i] = int(l) <= threshold
+	}
+	return b
+}
+
+// finder là tâm một finder pattern tìm được cùng kích thước module ước lượng;
+// count là số lần quét trúng nó.
+type finder struct {
+	x, y   float64
+	module float64
+	count  int
+}
+
+func (b *binaryImage) scan() (string, error) {
+	finders := b.findFinders()
+	for _, triple := range bestTriples(finders) {
+		tl, tr, bl := orderTriple(triple)
+		module := (tl.module + tr.module + bl.module) / 3
+		side := (distance(tl, tr) + distance(tl, bl)) / 2 / module
+		estimate := int(math.Round((side + 7 - 17) / 4))
+		for _, version := range []int{estimate, estimate - 1, estimate + 1} {
+			if version < 1 || version > 40 {
+				continue
+			}
+			if text, err := decodeMatrix(b.sample(tl, tr, bl, 17+4*version)); err == nil {
+				return text, nil
+			}
+		}
+	}
+	return "", ErrNotFound
+}
+
+// sample lấy mẫu lưới dim x dim bằng phép biến đổi affine xác định bởi tâm ba
+// finder pattern; tâm finder là tâm module thứ 3 tính từ góc.
+func (b *binaryImage) sample(tl, tr, bl finder, dim int) *bitMatrix {
+	ux, uy := (tr.x-tl.x)/float64(dim-7), (tr.y-tl.y)/float64(dim-7)
+	vx, vy := (bl.x-tl.x)/float64(dim-7), (bl.y-tl.y)/float64(dim-7)
+	m := newBitMatrix(dim)
+	for my := 0; my < dim; my++ {
+		for mx := 0; mx < dim; mx++ {
+			px := tl.x + float64(mx-3)*ux + float64(my-3)*vx
+			py := tl.y + float64(mx-3)*uy + float64(my-3)*vy
+			x, y := int(math.Floor(px)), int(math.Floor(py))
+			if x >= 0 && y >= 0 && x < b.w && y < b.h {
+				m.set(mx, my, b.get(x, y))
+			}
+		}
+	}
+	return m
+}
+
+// ratioMatches kiểm tra 5 đoạn tối-sáng-tối-sáng-tối có tỷ lệ 1:1:3:1:1.
+func ratioMatches(runs [5]int) bool {
+	total := 0
+	for _, r := range runs {
+		if r == 0 {
+			return false
+		}
+		total += r
+	}
+	if total < 7 {
+		return false
+	}
+	module := float64(total) / 7
+	tolerance := module / 2
+	return math.Abs(module-float64(runs[0])) < tolerance &&
+		math.Abs(module-float64(runs[1])) < tolerance &&
+		math.Abs(3*module-float64(runs[2])) < 3*tolerance &&
+		math.Abs(module-float64(runs[3])) < tolerance &&
+		math.Abs(module-float64(runs[4])) < tolerance
+}
+
+// crossCheck đo lại pattern theo hướng (dx, dy) đi qua điểm (x, y) nằm trong
+// ô vuông giữa, trả về tọa độ tâm dọc theo hướng đó và tổng độ dài 5 đoạn.
+func (b *binaryImage) crossCheck(x, y, dx, dy, maxRun int) (float64, int, bool) {
+	inside := func(x, y int) bool { return x >= 0 && y >= 0 && x < b.w && y < b.h }
+	var runs [5]int
+
+	// Đi lùi: đoạn giữa, đoạn sáng, đoạn tối ngoài cùng.
+	bx, by := x, y
+	for inside(bx, by) && b.get(bx, by) {
+		runs[2]++
+		bx, by = bx-dx, by-dy
+	}
+	for inside(bx, by) && !b.get(bx, by) && runs[1] <= maxRun {
+		runs[1]++
+		bx, by = bx-dx, by-dy
+	}
+	if !inside(bx, by) || runs[1] > maxRun {
+		return 0, 0, false
+	}
+	for inside(bx, by) && b.get(bx, by) && runs[0] <= maxRun {
+		runs[0]++
+		bx, by = bx-dx, by-dy
+	}
+	start := x*dx + y*dy - runs[2] + 1
+
+	fx, fy := x+dx, y+dy
+	for inside(fx, fy) && b.get(fx, fy) {
+		runs[2]++
+		fx, fy = fx+dx, fy+dy
+	}
+	for inside(fx, fy) && !b.get(fx, fy) && runs[3] <= maxRun {
+		runs[3]++
+		fx, fy = fx+dx, fy+dy
+	}
+	if !inside(fx, fy) || runs[3] > maxRun {
+		return 0, 0, false
+	}
+	for inside(fx, fy) && b.get(fx, fy) && runs[4] <= maxRun {
+		runs[4]++
+		fx, fy = fx+dx, fy+dy
+	}
+
+	if !ratioMatches(runs) {
+		return 0, 0, false
+	}
+	total := runs[0] + runs[1] + runs[2] + runs[3] + runs[4]
+	return float64(start) + float64(runs[2])/2, total, true
+}
+
+// findFinders quét từng hàng tìm chuỗi 1:1:3:1:1, kiểm tra lại theo chiều
+// dọc rồi chiều ngang và gộp các lần trúng cùng một pattern.
+func (b *binaryImage) findFinders() []finder {
+	var found []finder
+	var starts []int
+	var runs []int
+	for y := 0; y < b.h; y++ {
+		starts, runs = starts[:0], runs[:0]
+		for x := 0; x < b.w; x++ {
+			if x == 0 || b.get(x, y) != b.get(x-1, y) {
+				starts = append(starts, x)
+				runs = append(runs, 0)
+			}
+			runs[len(runs)-1]++
+		}
+		for i := 0; i+5 <= len(runs); i++ {
+			if !b.get(starts[i], y) {
+				continue
+			}
+			window := [5]int{runs[i], runs[i+1], runs[i+2], runs[i+3], runs[i+4]}
+			if !ratioMatches(window) {
+				continue
+			}
+			total := window[0] + window[1] + window[2] + window[3] + window[4]
+			cx := float64(starts[i+2]) + float64(runs[i+2])/2
+			cy, vertical, ok := b.crossCheck(int(cx), y, 0, 1, total)
+			if !ok || 5*abs(vertical-total) >= 2*total {
+				continue
+			}
+			cx, horizontal, ok := b.crossCheck(int(cx), int(cy), 1, 0, total)
+			if !ok || 5*abs(horizontal-total) >= 2*total {
+				continue
+			}
+			found = addFinder(found, finder{x: cx, y: cy, module: float64(vertical+horizontal) / 14, count: 1})
+		}
+	}
+	return found
+}
+
+func addFinder(found []finder, f finder) []finder {
+	for i, g := range found {
+		if math.Abs(g.x-f.x) <= g.module && math.Abs(g.y-f.y) <= g.module &&
+			math.Abs(g.module-f.module) <= math.Max(1, g.module/2) {
+			n := float64(g.count)
+			found[i] = finder{
+				x:      (g.x*n + f.x) / (n + 1),
+				y:      (g.y*n + f.y) / (n + 1),
+				module: (g.module*n + f.module) / (n + 1),
+				count:  g.count + 1,
+			}
+			return found
+		}
+	}
+	return append(found, f)
+}
+
+// maxCandidates giới hạn số pattern đem ghép bộ ba, lấy những pattern được
+// quét trúng nhiều nhất.
+const maxCandidates = 12
+
+// bestTriples trả về các bộ ba có thể là ba góc của một mã, bộ giống tam giác
+// vuông cân nhất trước.
+func bestTriples(finders []finder) [][3]finder {
+	sort.SliceStable(finders, func(i, j int) bool { return finders[i].count > finders[j].count })
+	if len(finders) > maxCandidates {
+		finders = finders[:maxCandidates]
+	}
+
+	type scored struct {
+		triple [3]finder
+		score  float64
+	}
+	var candidates []scored
+	for i := 0; i < len(finders); i++ {
+		for j := i + 1; j < len(finders); j++ {
+			for k := j + 1; k < len(finders); k++ {
+				triple := [3]finder{finders[i], finders[j], finders[k]}
+				if score, ok := triangleScore(triple); ok {
+					candidates = append(candidates, scored{triple, score})
+				}
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score < candidates[j].score })
+
+	triples := make([][3]finder, 0, min(len(candidates), 3))
+	for i := 0; i < len(candidates) && i < 3; i++ {
+		triples = append(triples, candidates[i].triple)
+	}
+	return triples
+}
+
+// triangleScore đo độ lệch của bộ ba so với tam giác vuông cân có các finder
+// cùng cỡ; điểm càng nhỏ càng giống.
+func triangleScore(t [3]finder) (float64, bool) {
+	minModule := math.Min(t[0].module, math.Min(t[1].module, t[2].module))
+	maxModule := math.Max(t[0].module, math.Max(t[1].module, t[2].module))
+	if maxModule > 1.5*minModule {
+		return 0, false
+	}
+	sides := []float64{distance(t[0], t[1]), distance(t[1], t[2]), distance(t[0], t[2])}
+	sort.Float64s(sides)
+	a, c, hyp := sides[0], sides[1], sides[2]
+	if a < 14*minModule {
+		return 0, false
+	}
+	legs := (c - a) / c
+	right := math.Abs(hyp-math.Hypot(a, c)) / hyp
+	if legs > 0.2 || right > 0.1 {
+		return 0, false
+	}
+	return legs + right + (maxModule-minModule)/maxModule, true
+}
+
+// orderTriple xác định góc trên trái (đối diện cạnh dài nhất), rồi đặt góc
+// trên phải và dưới trái theo chiều quay để ảnh xoay vẫn giải đúng.
+func orderTriple(t [3]finder) (tl, tr, bl finder) {
+	switch {
+	case distance(t[1], t[2]) >= distance(t[0], t[1]) && distance(t[1], t[2]) >= distance(t[0], t[2]):
+		tl, tr, bl = t[0], t[1], t[2]
+	case distance(t[0], t[2]) >= distance(t[0], t[1]):
+		tl, tr, bl = t[1], t[0], t[2]
+	default:
+		tl, tr, bl = t[2], t[0], t[1]
+	}
+	if (tr.x-tl.x)*(bl.y-tl.y)-(tr.y-tl.y)*(bl.x-tl.x) < 0 {
+		tr, bl = bl, tr
+	}
+	return tl, tr, bl
+}
+
+func distance(a, b finder) float64 {
+	return math.Hypot(a.x-b.x, a.y-b.y)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package qrscan
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"api/qrrender"
+)
+
+const vietQRPayload = "00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB"
+
+func render(t *testing.T, data string, opts qrrender.Options) image.Image {
+	t.Helper()
+	code, err := qrrender.New(data, opts)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return code.Image()
+}
+
+// rotate xoay ảnh 90° theo chiều kim đồng hồ.
+func rotate(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(b.Max.Y-1-y, x-b.Min.X, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func testLogo() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: 0xd0, A: 0xff})
+		}
+	}
+	return img
+}
+
+func TestScanRoundTrip(t *testing.T) {
+	long := strings.Repeat("Thanh toán đơn hàng 42 - ", 20)
+	for _, tc := range []struct {
+		name string
+		data string
+		opts qrrender.Options
+	}{
+		{"vietqr", vietQRPayload, qrrender.DefaultOptions()},
+		{"numeric", "0123456789012345678901234567890", qrrender.Options{Level: qrrender.LevelH, ModuleSize: 3, QuietZone: 4}},
+		{"alphanumeric", "HELLO WORLD $%*+-./:", qrrender.Options{Level: qrrender.LevelQ, ModuleSize: 5, QuietZone: 4}},
+		{"utf-8 version 7+", long, qrrender.Options{Level: qrrender.LevelM, ModuleSize: 2, QuietZone: 4}},
+		{"no quiet zone", "https://example.com", qrrender.Options{ModuleSize: 4}},
+		{"single pixel modules", "https://example.com/todos/42", qrrender.Options{Level: qrrender.LevelL, ModuleSize: 1, QuietZone: 4}},
+		{"colors", vietQRPayload, qrrender.Options{Level: qrrender.LevelL, ModuleSize: 4, QuietZone: 2,
+			Foreground: color.NRGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}, Background: color.NRGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0x80}}},
+		// Logo xóa các module ở giữa nên chỉ giải được nhờ sửa lỗi Reed-Solomon.
+		{"logo", vietQRPayload, qrrender.Options{ModuleSize: 6, QuietZone: 4, Logo: testLogo()}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			img := render(t, tc.data, tc.opts)
+			for turn := 0; turn < 4; turn++ {
+				text, err := Scan(img)
+				assert.NoError(t, err, "rotated %d°", 90*turn)
+				assert.Equal(t, tc.data, text, "rotated %d°", 90*turn)
+				img = rotate(img)
+			}
+		})
+	}
+}
+
+func TestScanInverted(t *testing.T) {
+	img := render(t, "INVERTED", qrrender.Options{ModuleSize: 4, QuietZone: 4, Foreground: color.White, Background: color.Black})
+	text, err := Scan(img)
+	assert.NoError(t, err)
+	assert.Equal(t, "INVERTED", text)
+}
+
+func TestScanNotFound(t *testing.T) {
+	blank := image.NewGray(image.Rect(0, 0, 64, 64))
+	_, err := Scan(blank)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRSCorrect(t *testing.T) {
+	// Khối version 1-M của "01234567" (ISO/IEC 18004 phụ lục I).
+	block := []byte{0x10, 0x20, 0x0c, 0x56, 0x61, 0x80, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11, 0xec, 0x11,
+		0xa5, 0x24, 0xd4, 0xc1, 0xed, 0x36, 0xc7, 0x87, 0x2c, 0x55}
+	want := append([]byte(nil), block...)
+
+	n, err := rsCorrect(block, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	for _, i := range []int{0, 3, 9, 17, 25} {
+		block[i] ^= 0x5a
+	}
+	n, err = rsCorrect(block, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.Equal(t, want, block)
+
+	for _, i := range []int{0, 1, 2, 3, 4, 5} {
+		block[i] ^= 0xff
+	}
+	_, err = rsCorrect(block, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+type RecurrenceStore interface {
+	GetOccurrencesDB(ctx context.Context, id string) ([]Todo, error)
+}
+
+// spawnNextOccurrence tạo lần lặp kế tiếp khi một todo lặp lại được đánh dấu xong.
+// Không tạo gì nếu todo không lặp, quy tắc đã hết, hoặc lần kế tiếp đã tồn tại
+// (ví dụ khi todo bị bỏ đánh dấu rồi đánh dấu lại).
+func spawnNextOccurrence(ctx context.Context, tx pgx.Tx, done Todo) error {
+	if done.Recurrence == "" {
+		return nil
+	}
+	rule, err := ParseRRule(done.Recurrence)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM todo WHERE previous_id = $1)", done.ID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check next occurrence: %v", err)
+	}
+	if exists {
+		return nil
+	}
+
+	// Lần lặp đã bị xóa (kể cả còn trong thùng rác) không tính vào COUNT.
+	var occurrences int
+	err = tx.QueryRow(ctx, "SELECT COUNT(*) FROM todo WHERE series_id = $1 AND deleted_at IS NULL", done.SeriesID).Scan(&occurrences)
+	if err != nil {
+		return fmt.Errorf("failed to count occurrences: %v", err)
+	}
+
+	current := done.CreatedAt
+	if done.DueAt != nil {
+		current = *done.DueAt
+	}
+	nextDue, ok := rule.Next(current, occurrences)
+	if !ok {
+		return nil
+	}
+
+	_, err = insertTodo(ctx, tx, Todo{
+		ID:         uuid.New().String(),
+		Title:      done.Title,
+		Desc:       done.Desc,
+		CreatedAt:  time.Now(),
+		CreatedBy:  ActorFromContext(ctx),
+		DueAt:      &nextDue,
+		Recurrence: done.Recurrence,
+		SeriesID:   done.SeriesID,
+		PreviousID: done.ID,
+		RemindAt:   done.RemindAt,
+		ListID:     done.ListID,
+		Tags:       done.Tags,
+		Payment:    nextPayment(done),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create next occurrence: %v", err)
+	}
+
+	return nil
+}
+
+func (db *Db) GetOccurrencesDB(ctx context.Context, id string) ([]Todo, error) {
+	todo, err := db.GetTodoByIdDB(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if todo.SeriesID == "" {
+		return []Todo{todo}, nil
+	}
+
+	rows, err := db.Conn.Query(ctx,
+		"SELECT "+todoColumns+" FROM todo WHERE series_id = $1 AND deleted_at IS NULL ORDER BY due_at NULLS FIRST, created_at",
+		todo.SeriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve occurrences: %v", err)
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var occurrence Todo
+		if err := scanTodo(rows, &occurrence); err != nil {
+			return nil, err
+		}
+		todos = append(todos, occurrence)
+	}
+
+	return todos, rows.Err()
+}
+
+type RecurrenceHandler struct {
+	recurrenceStore RecurrenceStore
+}
+
+func NewRecurrenceHandler(recurrenceStore RecurrenceStore) *RecurrenceHandler {
+	return &RecurrenceHandler{recurrenceStore: recurrenceStore}
+}
+
+// @Summary List the occurrences of a recurring todo
+// @Description Retrieve every occurrence in the series the todo belongs to, ordered by due date
+// @Tags Todos
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {array} Todo "OK"
+// @Failure 404 {object} ErrorResponse "Todo not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /todo/{id}/occurrences [get]
+func (h *RecurrenceHandler) GetOccurrences(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	todos, err := h.recurrenceStore.GetOccurrencesDB(ctx, idStr)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Todo not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get occurrences: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(todos)
+}
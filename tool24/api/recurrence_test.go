@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRecurrenceStore struct {
+	mock.Mock
+}
+
+func (m *MockRecurrenceStore) GetOccurrencesDB(ctx context.Context, id string) ([]Todo, error) {
+	args := m.Called(id)
+	return args.Get(0).([]Todo), args.Error(1)
+}
+
+func TestGetOccurrences(t *testing.T) {
+	mockRecurrence := new(MockRecurrenceStore)
+	mockRecurrence.On("GetOccurrencesDB", "2").Return([]Todo{
+		{ID: "1", Title: "Đổ rác", Done: true, SeriesID: "1", Recurrence: "FREQ=WEEKLY;BYDAY=MO"},
+		{ID: "2", Title: "Đổ rác", SeriesID: "1", PreviousID: "1", Recurrence: "FREQ=WEEKLY;BYDAY=MO"},
+	}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/{id}/occurrences", NewRecurrenceHandler(mockRecurrence).GetOccurrences).Methods("GET")
+	req, _ := http.NewRequest("GET", "/todo/2/occurrences", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	assert.Contains(t, rr.Body.String(), `"previous_id":"1"`)
+	mockRecurrence.AssertExpectations(t)
+}
+
+func TestCreateTodo_InvalidRecurrence(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("CreateTodoDB", mock.Anything).Return(Todo{}, fmt.Errorf("%w: unsupported FREQ", ErrInvalidRecurrence))
+
+	handler := NewTodoHandler(mockStore)
+	req, _ := http.NewRequest("POST", "/todo", bytes.NewBufferString(`{"title":"Đổ rác","recurrence":"FREQ=YEARLY"}`))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status code 400")
+	mockStore.AssertExpectations(t)
+}
+
+// Lần lặp bị chuyển vào thùng rác không tính vào COUNT của RRULE.
+func TestRecurrenceCountSkipsTrashDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	due := time.Now().Add(time.Hour)
+	first, err := db.CreateTodoDB(ctx, Todo{Title: "Tưới cây", Recurrence: "FREQ=DAILY;COUNT=2", DueAt: &due})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	if err := db.ChangeStatusDB(ctx, first.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+	occurrences, err := db.GetOccurrencesDB(ctx, first.ID)
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 2)
+
+	if err := db.DeleteTodoByIdDB(ctx, first.ID); err != nil {
+		t.Fatalf("Failed to delete todo: %v", err)
+	}
+	second := occurrences[1]
+	if err := db.ChangeStatusDB(ctx, second.ID); err != nil {
+		t.Fatalf("Failed to complete todo: %v", err)
+	}
+	occurrences, err = db.GetOccurrencesDB(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.Len(t, occurrences, 2, "the trashed first occurrence must not use up COUNT=2")
+}
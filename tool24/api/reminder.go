@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+var ErrInvalidReminder = errors.New("invalid reminder offset")
+
+// validateRemindAt kiểm tra các khoảng nhắc trước hạn, vd "15m" hoặc "24h".
+func validateRemindAt(offsets []string) error {
+	for _, offset := range offsets {
+		d, err := time.ParseDuration(strings.TrimSpace(offset))
+		if err != nil || d < 0 {
+			return fmt.Errorf("%w: %q must be a non-negative duration such as 15m or 24h", ErrInvalidReminder, offset)
+		}
+	}
+	return nil
+}
+
+// syncReminders thay các nhắc việc đang chờ của todo bằng lịch mới tính từ
+// DueAt và RemindAt. Todo đã xong, đã xóa hoặc không có hạn thì không được nhắc.
+func syncReminders(ctx context.Context, tx pgx.Tx, before, after *Todo) error {
+	todoID := ""
+	if after != nil {
+		todoID = after.ID
+	} else if before != nil {
+		todoID = before.ID
+	}
+
+	_, err := tx.Exec(ctx, "DELETE FROM reminder WHERE todo_id = $1 AND status = 'pending'", todoID)
+	if err != nil {
+		return fmt.Errorf("failed to clear reminders: %v", err)
+	}
+
+	if after == nil || after.Done || after.DeletedAt != nil || after.DueAt == nil {
+		return nil
+	}
+
+	now := time.Now()
+	for _, offset := range after.RemindAt {
+		d, err := time.ParseDuration(strings.TrimSpace(offset))
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalidReminder, offset)
+		}
+		fireAt := after.DueAt.Add(-d)
+		if fireAt.Before(now) {
+			continue
+		}
+
+		_, err = tx.Exec(ctx,
+			"INSERT INTO reminder (todo_id, fire_at, next_attempt_at) VALUES ($1, $2, $2) ON CONFLICT (todo_id, fire_at) DO NOTHING",
+			todoID, fireAt)
+		if err != nil {
+			return fmt.Errorf("failed to schedule reminder: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Backoff tính thời gian chờ trước lần thử lại thứ attempt: Base, 2*Base, 4*Base...
+// tối đa Max, và bỏ cuộc sau MaxAttempts lần.
+type Backoff struct {
+	Base        time.Duration
+	Max         time.Duration
+	MaxAttempts int
+}
+
+var DefaultBackoff = Backoff{Base: 30 * time.Second, Max: time.Hour, MaxAttempts: 8}
+
+func (b Backoff) Delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// ReminderJob is a due reminder claimed by ProcessDueRemindersDB.
+type ReminderJob struct {
+	Notification
+	Attempts int
+}
+
+type ReminderStore interface {
+	// ProcessDueRemindersDB claims up to limit due reminders and commits the
+	// claim, then calls deliver for each outside any transaction and records
+	// the outcome.
+	ProcessDueRemindersDB(ctx context.Context, limit int, backoff Backoff, deliver func(ReminderJob) error) (int, error)
+}
+
+// reminderNotifyTimeout giới hạn thời gian gửi một nhắc việc.
+const reminderNotifyTimeout = 15 * time.Second
+
+func (db *Db) ProcessDueRemindersDB(ctx context.Context, limit int, backoff Backoff, deliver func(ReminderJob) error) (int, error) {
+	jobs, err := db.claimDueReminders(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, job := range jobs {
+		deliveryErr := deliver(job)
+		if err := db.recordReminderDelivery(ctx, job, backoff, deliveryErr); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// claimDueReminders khóa các nhắc việc đến hạn rồi đẩy next_attempt_at qua
+// hết thời gian gửi cả lô trước khi commit, để replica khác bỏ qua chúng mà
+// không phải giữ khóa hàng trong lúc gửi. Nếu tiến trình chết giữa chừng thì
+// nhắc việc lại đến hạn khi hết hạn claim.
+func (db *Db) claimDueReminders(ctx context.Context, limit int) ([]ReminderJob, error) {
+	var jobs []ReminderJob
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		// SKIP LOCKED để nhiều replica cùng chạy scheduler mà không gửi trùng.
+		rows, err := tx.Query(ctx,
+			"SELECT r.id, r.todo_id, t.title, t.description, t.due_at, r.fire_at, r.attempts "+
+				"FROM reminder r JOIN todo t ON t.id = r.todo_id "+
+				"WHERE r.status = 'pending' AND r.next_attempt_at <= now() "+
+				"ORDER BY r.next_attempt_at LIMIT $1 FOR UPDATE OF r SKIP LOCKED", limit)
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for rows.Next() {
+			var job ReminderJob
+			if err := rows.Scan(&job.ReminderID, &job.TodoID, &job.Title, &job.Description, &job.DueAt, &job.RemindAt, &job.Attempts); err != nil {
+				rows.Close()
+				return err
+			}
+			jobs = append(jobs, job)
+			ids = append(ids, job.ReminderID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		lease := time.Duration(len(ids))*reminderNotifyTimeout + time.Minute
+		_, err = tx.Exec(ctx, "UPDATE reminder SET next_attempt_at = $1 WHERE id = ANY($2)", time.Now().Add(lease), ids)
+		if err != nil {
+			return fmt.Errorf("failed to claim reminders: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// recordReminderDelivery ghi kết quả một lần gửi và lên lịch lần thử lại nếu cần.
+func (db *Db) recordReminderDelivery(ctx context.Context, job ReminderJob, backoff Backoff, deliveryErr error) error {
+	attempt := job.Attempts + 1
+	errText := ""
+	if deliveryErr != nil {
+		errText = deliveryErr.Error()
+	}
+
+	return db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var query string
+		var args []interface{}
+		switch {
+		case deliveryErr == nil:
+			query = "UPDATE reminder SET status = 'sent', attempts = $1, sent_at = now(), last_error = NULL WHERE id = $2 AND status = 'pending'"
+			args = []interface{}{attempt, job.ReminderID}
+		case attempt >= backoff.MaxAttempts:
+			query = "UPDATE reminder SET status = 'failed', attempts = $1, last_error = $2 WHERE id = $3 AND status = 'pending'"
+			args = []interface{}{attempt, errText, job.ReminderID}
+		default:
+			query = "UPDATE reminder SET attempts = $1, last_error = $2, next_attempt_at = $3 WHERE id = $4 AND status = 'pending'"
+			args = []interface{}{attempt, errText, time.Now().Add(backoff.Delay(attempt)), job.ReminderID}
+		}
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to update reminder: %v", err)
+		}
+		// Todo đổi hạn trong lúc gửi thì nhắc việc cũ đã bị xóa, không còn gì để ghi.
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx,
+			"INSERT INTO reminder_delivery (reminder_id, attempt, succeeded, error) VALUES ($1, $2, $3, NULLIF($4, ''))",
+			job.ReminderID, attempt, deliveryErr == nil, errText)
+		if err != nil {
+			return fmt.Errorf("failed to record delivery: %v", err)
+		}
+		return nil
+	})
+}
+
+// ReminderScheduler periodically fires due reminders through a Notifier.
+type ReminderScheduler struct {
+	store    ReminderStore
+	notifier Notifier
+	interval time.Duration
+	batch    int
+	backoff  Backoff
+}
+
+func NewReminderScheduler(store ReminderStore, notifier Notifier, interval time.Duration) *ReminderScheduler {
+	return &ReminderScheduler{store: store, notifier: notifier, interval: interval, batch: 50, backoff: DefaultBackoff}
+}
+
+// RunOnce gửi một lượt các nhắc việc đến hạn và trả về số nhắc việc đã xử lý.
+func (s *ReminderScheduler) RunOnce(ctx context.Context) (int, error) {
+	return s.store.ProcessDueRemindersDB(ctx, s.batch, s.backoff, func(job ReminderJob) error {
+		notifyCtx, cancel := context.WithTimeout(ctx, reminderNotifyTimeout)
+		defer cancel()
+		return s.notifier.Notify(notifyCtx, job.Notification)
+	})
+}
+
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			log.Printf("Không thể gửi nhắc việc: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeReminderStore struct {
+	jobs    []ReminderJob
+	results map[int64]error
+}
+
+func (f *fakeReminderStore) ProcessDueRemindersDB(ctx context.Context, limit int, backoff Backoff, deliver func(ReminderJob) error) (int, error) {
+	f.results = map[int64]error{}
+	for _, job := range f.jobs {
+		f.results[job.ReminderID] = deliver(job)
+	}
+	return len(f.jobs), nil
+}
+
+type recordingNotifier struct {
+	sent []Notification
+	fail map[string]bool
+}
+
+func (r *recordingNotifier) Notify(ctx context.Context, n Notification) error {
+	if r.fail[n.TodoID] {
+		return errors.New("unreachable")
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestReminderScheduler_RunOnce(t *testing.T) {
+	store := &fakeReminderStore{jobs: []ReminderJob{
+		{Notification: Notification{ReminderID: 1, TodoID: "a", Title: "A"}},
+		{Notification: Notification{ReminderID: 2, TodoID: "b", Title: "B"}, Attempts: 2},
+	}}
+	notifier := &recordingNotifier{fail: map[string]bool{"b": true}}
+
+	n, err := NewReminderScheduler(store, notifier, time.Minute).RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, notifier.sent, 1)
+	assert.Equal(t, "A", notifier.sent[0].Title)
+	assert.NoError(t, store.results[1])
+	assert.Error(t, store.results[2], "failed delivery must be reported back to the store for retry")
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 10 * time.Second, MaxAttempts: 5}
+
+	assert.Equal(t, time.Second, b.Delay(1))
+	assert.Equal(t, 2*time.Second, b.Delay(2))
+	assert.Equal(t, 8*time.Second, b.Delay(4))
+	assert.Equal(t, 10*time.Second, b.Delay(5))
+	assert.Equal(t, 10*time.Second, b.Delay(50))
+}
+
+func TestValidateRemindAt(t *testing.T) {
+	assert.NoError(t, validateRemindAt([]string{"15m", "24h", "0s"}))
+	assert.True(t, errors.Is(validateRemindAt([]string{"tomorrow"}), ErrInvalidReminder))
+	assert.True(t, errors.Is(validateRemindAt([]string{"-1h"}), ErrInvalidReminder))
+}
+
+// Lúc gửi nhắc việc, claim đã được commit.
+func TestRemindersDeliveredAfterClaimDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	due := time.Now().Add(time.Hour)
+	createdTodo, err := db.CreateTodoDB(ctx, Todo{Title: "Họp nhóm", DueAt: &due, RemindAt: []string{"30m"}})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	_, err = db.Conn.Exec(ctx, "UPDATE reminder SET next_attempt_at = now() - interval '1 second' WHERE todo_id = $1", createdTodo.ID)
+	assert.NoError(t, err)
+
+	delivered := 0
+	_, err = db.ProcessDueRemindersDB(ctx, 100, DefaultBackoff, func(job ReminderJob) error {
+		if job.TodoID != createdTodo.ID {
+			return nil
+		}
+		delivered++
+		// Lúc gửi, claim đã commit: không còn khóa hàng và replica khác không lấy lại.
+		err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "SELECT id FROM reminder WHERE id = $1 FOR UPDATE NOWAIT", job.ReminderID)
+			return err
+		})
+		assert.NoError(t, err)
+		_, err = db.ProcessDueRemindersDB(ctx, 100, DefaultBackoff, func(other ReminderJob) error {
+			assert.NotEqual(t, job.ReminderID, other.ReminderID)
+			return nil
+		})
+		assert.NoError(t, err)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	var status string
+	var deliveries int
+	err = db.Conn.QueryRow(ctx,
+		"SELECT r.status, (SELECT COUNT(*) FROM reminder_delivery d WHERE d.reminder_id = r.id) FROM reminder r WHERE r.todo_id = $1",
+		createdTodo.ID).Scan(&status, &deliveries)
+	assert.NoError(t, err)
+	assert.Equal(t, "sent", status)
+	assert.Equal(t, 1, deliveries)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"api/chartrender"
+)
+
+// ReportRange is the scope of a report, as given to GET /reports/*.
+type ReportRange struct {
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	TimeZone string    `json:"time_zone"`
+	ListID   *string   `json:"list_id,omitempty"`
+	Tag      string    `json:"tag,omitempty"`
+}
+
+// BurndownPoint is the state at the end of one day of the range.
+type BurndownPoint struct {
+	Date string `json:"date"`
+	// Remaining is the number of todos created and not yet done.
+	Remaining int `json:"remaining"`
+	// Ideal falls in a straight line from the todos open at the start of the
+	// range to zero at its end.
+	Ideal float64 `json:"ideal"`
+	// Added and Completed count the todos created and done during the day.
+	Added     int `json:"added"`
+	Completed int `json:"completed"`
+}
+
+type BurndownReport struct {
+	ReportRange
+	Points []BurndownPoint `json:"points"`
+}
+
+// CFDPoint is the cumulative flow at the end of one day of the range: every
+// todo created by then is either open or done.
+type CFDPoint struct {
+	Date string `json:"date"`
+	Open int    `json:"open"`
+	Done int    `json:"done"`
+}
+
+type CFDReport struct {
+	ReportRange
+	Points []CFDPoint `json:"points"`
+}
+
+// reportDay là một ngày của báo cáo: [Start, End) đã cắt theo Since/Until.
+type reportDay struct {
+	Date       string
+	Start, End time.Time
+}
+
+// reportDays chia khoảng của q thành các ngày theo q.Location, như per_day
+// của GET /stats.
+func reportDays(q StatsQuery) []reportDay {
+	var days []reportDay
+	last := startOfDay(q.Until.Add(-time.Nanosecond), q.Location)
+	for day := startOfDay(q.Since, q.Location); !day.After(last); day = day.AddDate(0, 0, 1) {
+		d := reportDay{Date: day.Format(time.DateOnly), Start: day, End: day.AddDate(0, 0, 1)}
+		if d.Start.Before(q.Since) {
+			d.Start = q.Since
+		}
+		if d.End.After(q.Until) {
+			d.End = q.Until
+		}
+		days = append(days, d)
+	}
+	return days
+}
+
+func newReportRange(q StatsQuery) ReportRange {
+	return ReportRange{Since: q.Since.In(q.Location), Until: q.Until.In(q.Location), TimeZone: q.Location.String(), ListID: q.ListID, Tag: q.Tag}
+}
+
+// doneBy cho biết todo đã xong trước t; todo mở lại sau khi xong không còn
+// done_at nên được tính là chưa xong.
+func doneBy(todo Todo, t time.Time) bool {
+	return todo.Done && todo.DoneAt != nil && todo.DoneAt.Before(t)
+}
+
+func openAt(todo Todo, t time.Time) bool {
+	return todo.CreatedAt.Before(t) && !doneBy(todo, t)
+}
+
+// buildBurndown dựng burndown từ created_at/done_at của các todo trong phạm vi q.
+func buildBurndown(todos []Todo, q StatsQuery) BurndownReport {
+	report := BurndownReport{ReportRange: newReportRange(q), Points: []BurndownPoint{}}
+	start := 0
+	for _, todo := range todos {
+		if q.matches(todo) && openAt(todo, q.Since) {
+			start++
+		}
+	}
+	span := q.Until.Sub(q.Since).Seconds()
+
+	for _, day := range reportDays(q) {
+		p := BurndownPoint{Date: day.Date}
+		for _, todo := range todos {
+			if !q.matches(todo) {
+				continue
+			}
+			if openAt(todo, day.End) {
+				p.Remaining++
+			}
+			if !todo.CreatedAt.Before(day.Start) && todo.CreatedAt.Before(day.End) {
+				p.Added++
+			}
+			if doneBy(todo, day.End) && !todo.DoneAt.Before(day.Start) {
+				p.Completed++
+			}
+		}
+		ideal := float64(start) * (1 - day.End.Sub(q.Since).Seconds()/span)
+		p.Ideal = math.Round(ideal*100) / 100
+		report.Points = append(report.Points, p)
+	}
+	return report
+}
+
+// buildCFD dựng cumulative flow: cuối mỗi ngày có bao nhiêu todo đang mở và
+// đã xong trong số các todo đã tạo.
+func buildCFD(todos []Todo, q StatsQuery) CFDReport {
+	report := CFDReport{ReportRange: newReportRange(q), Points: []CFDPoint{}}
+	for _, day := range reportDays(q) {
+		p := CFDPoint{Date: day.Date}
+		for _, todo := range todos {
+			if !q.matches(todo) {
+				continue
+			}
+			if doneBy(todo, day.End) {
+				p.Done++
+			} else if openAt(todo, day.End) {
+				p.Open++
+			}
+		}
+		report.Points = append(report.Points, p)
+	}
+	return report
+}
+
+// reportTitle là tiêu đề biểu đồ: tên, phạm vi và khoảng ngày.
+func reportTitle(name string, r ReportRange) string {
+	parts := []string{name}
+	if r.ListID != nil {
+		if *r.ListID == "" {
+			parts = append(parts, "default list")
+		} else {
+			parts = append(parts, "list "+*r.ListID)
+		}
+	}
+	if r.Tag != "" {
+		parts = append(parts, "#"+r.Tag)
+	}
+	last := r.Until.Add(-time.Nanosecond)
+	parts = append(parts, r.Since.Format(time.DateOnly)+" – "+last.Format(time.DateOnly))
+	return strings.Join(parts, " · ")
+}
+
+// reportLabel rút ngày YYYY-MM-DD thành MM-DD cho trục x.
+func reportLabel(date string) string {
+	return date[len("2006-"):]
+}
+
+type ReportHandler struct {
+	todoStore TodoStore
+}
+
+func NewReportHandler(todoStore TodoStore) *ReportHandler {
+	return &ReportHandler{todoStore: todoStore}
+}
+
+// @Summary Burndown and cumulative flow charts
+// @Description Render a burndown or cumulative flow diagram (CFD) of a list or tag over a date range from created_at and done_at, as an SVG that can be embedded with an <img> tag, or the underlying daily series as JSON. The burndown shows the todos remaining at the end of each day against an ideal line to zero; the CFD stacks done under open todos. Days are cut in tz like GET /stats.
+// @Tags Reports
+// @Produce image/svg+xml
+// @Produce json
+// @Param since query string false "Start of the range, RFC 3339 or YYYY-MM-DD (default 30 days before until)"
+// @Param until query string false "End of the range, exclusive, RFC 3339 or YYYY-MM-DD (inclusive day); default now"
+// @Param tz query string false "IANA time zone for days, e.g. Asia/Ho_Chi_Minh (default UTC)"
+// @Param list query string false "Only this list; empty for the default list"
+// @Param tag query string false "Only todos with this tag"
+// @Param theme query string false "SVG theme: light (default) or dark" Enums(light, dark)
+// @Param width query int false "SVG width in pixels, 320 to 2400 (default 800)"
+// @Param height query int false "SVG height in pixels, 200 to 1600 (default 400)"
+// @Success 200 {object} BurndownReport "Chart, or the series for .json"
+// @Failure 400 {object} ErrorResponse "Invalid query"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /reports/burndown.svg [get]
+// @Router /reports/burndown.json [get]
+// @Router /reports/cfd.svg [get]
+// @Router /reports/cfd.json [get]
+func (h *ReportHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	q := r.URL.Query()
+	query, err := parseStatsQuery(q, time.Now())
+	if err != nil {
+		reportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	chart := chartrender.Chart{}
+	if chart.Theme, err = chartrender.ParseTheme(q.Get("theme")); err != nil {
+		reportError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for name, dst := range map[string]*int{"width": &chart.Width, "height": &chart.Height} {
+		if v := q.Get(name); v != "" {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				reportError(w, http.StatusBadRequest, "invalid "+name)
+				return
+			}
+		}
+	}
+
+	todos, err := h.todoStore.GetAllTodoDB(r.Context())
+	if err != nil {
+		reportError(w, http.StatusInternalServerError, "Failed to get todos: "+err.Error())
+		return
+	}
+
+	var series interface{}
+	if vars["chart"] == "cfd" {
+		report := buildCFD(todos, query)
+		series = report
+		chart.Title = reportTitle("Cumulative flow", report.ReportRange)
+		chart.Kind = chartrender.StackedArea
+		open, done := make([]float64, len(report.Points)), make([]float64, len(report.Points))
+		for i, p := range report.Points {
+			chart.Labels = append(chart.Labels, reportLabel(p.Date))
+			open[i], done[i] = float64(p.Open), float64(p.Done)
+		}
+		chart.Series = []chartrender.Series{{Name: "Done", Values: done}, {Name: "Open", Values: open}}
+	} else {
+		report := buildBurndown(todos, query)
+		series = report
+		chart.Title = reportTitle("Burndown", report.ReportRange)
+		remaining, ideal := make([]float64, len(report.Points)), make([]float64, len(report.Points))
+		for i, p := range report.Points {
+			chart.Labels = append(chart.Labels, reportLabel(p.Date))
+			remaining[i], ideal[i] = float64(p.Remaining), p.Ideal
+		}
+		chart.Series = []chartrender.Series{{Name: "Remaining", Values: remaining}, {Name: "Ideal", Values: ideal, Dashed: true}}
+	}
+
+	if vars["ext"] == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(series)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := chart.WriteSVG(&buf); err != nil {
+		if errors.Is(err, chartrender.ErrInvalidChart) {
+			reportError(w, http.StatusBadRequest, err.Error())
+		} else {
+			reportError(w, http.StatusInternalServerError, "Failed to render chart: "+err.Error())
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	// Trang wiki nhúng ảnh và tải lại thường xuyên; số liệu theo ngày nên cache
+	// vài phút là đủ mới.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func reportError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
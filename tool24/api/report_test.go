@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReportRouter(todoStore TodoStore) *mux.Router {
+	h := NewReportHandler(todoStore)
+	router := mux.NewRouter()
+	router.HandleFunc("/reports/{chart:burndown|cfd}.{ext:svg|json}", h.GetReport).Methods("GET")
+	return router
+}
+
+func TestBuildBurndown(t *testing.T) {
+	report := buildBurndown(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh"))
+
+	assert.Equal(t, "Asia/Ho_Chi_Minh", report.TimeZone)
+	assert.Len(t, report.Points, 14)
+	// Chỉ todo 3 còn mở lúc bắt đầu nên đường lý tưởng đi từ 1 về 0.
+	assert.Equal(t, BurndownPoint{Date: "2024-12-01", Remaining: 1, Ideal: 0.93}, report.Points[0])
+	assert.Equal(t, BurndownPoint{Date: "2024-12-02", Remaining: 2, Ideal: 0.86, Added: 1}, report.Points[1])
+	assert.Equal(t, BurndownPoint{Date: "2024-12-03", Remaining: 1, Ideal: 0.79, Completed: 1}, report.Points[2])
+	assert.Equal(t, BurndownPoint{Date: "2024-12-10", Remaining: 0, Ideal: 0.29, Completed: 2}, report.Points[9])
+	assert.Equal(t, BurndownPoint{Date: "2024-12-14", Remaining: 2, Ideal: 0, Added: 1}, report.Points[13])
+
+	report = buildBurndown(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh&list=kho"))
+	assert.Equal(t, []int{0, 1, 0, 0, 1, 1, 1, 1, 1, 0, 0, 0, 0, 1}, burndownRemaining(report))
+}
+
+func burndownRemaining(report BurndownReport) []int {
+	remaining := make([]int, len(report.Points))
+	for i, p := range report.Points {
+		remaining[i] = p.Remaining
+	}
+	return remaining
+}
+
+func TestBuildCFD(t *testing.T) {
+	report := buildCFD(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh"))
+
+	assert.Len(t, report.Points, 14)
+	assert.Equal(t, CFDPoint{Date: "2024-12-01", Open: 1, Done: 1}, report.Points[0])
+	assert.Equal(t, CFDPoint{Date: "2024-12-02", Open: 2, Done: 1}, report.Points[1])
+	assert.Equal(t, CFDPoint{Date: "2024-12-10", Open: 0, Done: 4}, report.Points[9])
+	assert.Equal(t, CFDPoint{Date: "2024-12-14", Open: 2, Done: 4}, report.Points[13])
+
+	report = buildCFD(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tag=gap"))
+	assert.Equal(t, CFDPoint{Date: "2024-12-14", Open: 1, Done: 1}, report.Points[13])
+}
+
+func TestGetReport_SVG(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(statsTestTodos(), nil)
+	router := newReportRouter(todoStore)
+
+	req, _ := http.NewRequest("GET", "/reports/burndown.svg?since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh&list=kho", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=300", rr.Header().Get("Cache-Control"))
+	svg := rr.Body.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg "))
+	assert.Contains(t, svg, "<title>Burndown · list kho · 2024-12-01 – 2024-12-14</title>")
+	assert.Contains(t, svg, `fill="#ffffff"`)
+	assert.Equal(t, 2, strings.Count(svg, "<polyline"))
+	assert.Contains(t, svg, ">12-14</text>")
+
+	req, _ = http.NewRequest("GET", "/reports/cfd.svg?since=2024-12-01&until=2024-12-14&tag=kho&theme=dark&width=600&height=300", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	svg = rr.Body.String()
+	assert.Contains(t, svg, `width="600" height="300"`)
+	assert.Contains(t, svg, "<title>Cumulative flow · #kho · 2024-12-01 – 2024-12-14</title>")
+	assert.Contains(t, svg, `fill="#0d1117"`)
+	assert.Equal(t, 2, strings.Count(svg, "<polygon"))
+}
+
+func TestGetReport_JSON(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(statsTestTodos(), nil)
+	router := newReportRouter(todoStore)
+
+	req, _ := http.NewRequest("GET", "/reports/cfd.json?since=2024-12-13&until=2024-12-14&tz=Asia/Ho_Chi_Minh&list=", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"since": "2024-12-13T00:00:00+07:00",
+		"until": "2024-12-15T00:00:00+07:00",
+		"time_zone": "Asia/Ho_Chi_Minh",
+		"list_id": "",
+		"points": [
+			{"date": "2024-12-13", "open": 1, "done": 2},
+			{"date": "2024-12-14", "open": 1, "done": 2}
+		]
+	}`, rr.Body.String())
+
+	req, _ = http.NewRequest("GET", "/reports/burndown.json?since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report BurndownReport
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+	assert.Len(t, report.Points, 14)
+	assert.Equal(t, 2, report.Points[13].Remaining)
+}
+
+func TestGetReport_Errors(t *testing.T) {
+	for target, code := range map[string]int{
+		"/reports/burndown.svg?theme=solarized": http.StatusBadRequest,
+		"/reports/burndown.svg?width=wide":      http.StatusBadRequest,
+		"/reports/burndown.svg?width=100":       http.StatusBadRequest,
+		"/reports/cfd.json?since=soon":          http.StatusBadRequest,
+		"/reports/cfd.svg":                      http.StatusInternalServerError,
+		"/reports/velocity.svg":                 http.StatusNotFound,
+		"/reports/cfd.png":                      http.StatusNotFound,
+	} {
+		todoStore := new(MockTodoStore)
+		if code == http.StatusInternalServerError {
+			todoStore.On("GetAllTodoDB").Return([]Todo(nil), errors.New("connection refused"))
+		} else {
+			// Kích thước sai chỉ phát hiện khi vẽ, sau khi đã lấy todo.
+			todoStore.On("GetAllTodoDB").Return(statsTestTodos(), nil).Maybe()
+		}
+		req, _ := http.NewRequest("GET", target, nil)
+		rr := httptest.NewRecorder()
+		newReportRouter(todoStore).ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, target)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrUndoExpired      = errors.New("operation can no longer be undone")
+	ErrUndoConflict     = errors.New("todo has changed since the operation")
+	ErrUndoUnsupported  = errors.New("operation cannot be undone")
+)
+
+// OperationHeader carries the ID of the audit record written by a mutation,
+// which can be passed to POST /undo/{operationId}.
+const OperationHeader = "X-Operation-Id"
+
+// Operation receives the audit ID of the change made with a context returned
+// by WithOperation, and the todo as that change wrote it. When a change writes
+// several audit records, such as completing a recurring todo, it is the first
+// one: the change the client asked for.
+type Operation struct {
+	ID   int64
+	Todo *Todo
+}
+
+type operationKey struct{}
+
+func WithOperation(ctx context.Context) (context.Context, *Operation) {
+	op := &Operation{}
+	return context.WithValue(ctx, operationKey{}, op), op
+}
+
+func operationFromContext(ctx context.Context) *Operation {
+	op, _ := ctx.Value(operationKey{}).(*Operation)
+	return op
+}
+
+// setOperationHeader trả ID thao tác cho client nếu store đã ghi audit.
+func setOperationHeader(w http.ResponseWriter, op *Operation) {
+	if op.ID != 0 {
+		w.Header().Set(OperationHeader, strconv.FormatInt(op.ID, 10))
+	}
+}
+
+type RevisionStore interface {
+	RevertTodoDB(ctx context.Context, id string, revision int) (Todo, error)
+	UndoOperationDB(ctx context.Context, operationID int64, ttl time.Duration) (Todo, error)
+}
+
+// applySnapshot ghi đè các trường của todo bằng ảnh chụp, tạo một revision mới.
+func applySnapshot(ctx context.Context, tx pgx.Tx, id string, snapshot Todo) (Todo, error) {
+	payment := paymentColumns(id, snapshot.Payment)
+	var todo Todo
+	err := scanTodo(tx.QueryRow(ctx,
+		"UPDATE todo SET title=$1, description=$2, done=$3, done_at=$4, deleted_at=$5, due_at=$6, recurrence=NULLIF($7, ''), "+
+			"remind_at=$8, list_id=NULLIF($9, ''), tags=$10, payment_bank_bin=$11, payment_account=$12, payment_amount=$13, payment_memo=$14, "+
+			"version=version+1 WHERE id=$15 RETURNING "+todoColumns,
+		snapshot.Title, snapshot.Desc, snapshot.Done, snapshot.DoneAt, snapshot.DeletedAt, snapshot.DueAt, snapshot.Recurrence,
+		snapshot.RemindAt, snapshot.ListID, snapshot.Tags, payment[0], payment[1], payment[2], payment[3], id), &todo)
+	if err != nil {
+		return Todo{}, fmt.Errorf("failed to apply revision: %v", err)
+	}
+
+	return todo, nil
+}
+
+// lockTodo khóa một todo kể cả khi nó đang nằm trong thùng rác.
+func lockTodo(ctx context.Context, tx pgx.Tx, id string) (Todo, error) {
+	var todo Todo
+	err := scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM todo WHERE id = $1 FOR UPDATE", id), &todo)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Todo{}, fmt.Errorf("todo not found with ID %s: %w", id, ErrTodoNotFound)
+		}
+		return Todo{}, fmt.Errorf("failed to retrieve todo: %v", err)
+	}
+
+	return todo, nil
+}
+
+func getSnapshot(ctx context.Context, tx pgx.Tx, id string, revision int) (Todo, error) {
+	var snapshot []byte
+	err := tx.QueryRow(ctx,
+		"SELECT snapshot FROM todo_audit WHERE todo_id = $1 AND revision = $2 AND snapshot IS NOT NULL ORDER BY id DESC LIMIT 1",
+		id, revision).Scan(&snapshot)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return Todo{}, fmt.Errorf("revision %d of todo %s: %w", revision, id, ErrRevisionNotFound)
+		}
+		return Todo{}, fmt.Errorf("failed to retrieve revision: %v", err)
+	}
+
+	var todo Todo
+	if err := json.Unmarshal(snapshot, &todo); err != nil {
+		return Todo{}, fmt.Errorf("failed to decode revision: %v", err)
+	}
+
+	return todo, nil
+}
+
+func (db *Db) RevertTodoDB(ctx context.Context, id string, revision int) (Todo, error) {
+	var reverted Todo
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		current, err := lockTodo(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+
+		snapshot, err := getSnapshot(ctx, tx, id, revision)
+		if err != nil {
+			return err
+		}
+
+		reverted, err = applySnapshot(ctx, tx, id, snapshot)
+		if err != nil {
+			return err
+		}
+
+		return recordChange(ctx, tx, AuditRevert, &current, &reverted)
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return reverted, nil
+}
+
+// UndoOperationDB reverses the create, update, status change or delete
+// recorded as audit entry operationID, provided it is younger than ttl and
+// is still the latest change to its todo.
+func (db *Db) UndoOperationDB(ctx context.Context, operationID int64, ttl time.Duration) (Todo, error) {
+	var undone Todo
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var todoID, operation string
+		var revision int
+		var fresh bool
+		// So TTL bằng đồng hồ của DB: created_at là TIMESTAMP theo giờ của phiên
+		// nên so với time.Now() sẽ lệch theo múi giờ của phiên.
+		err := tx.QueryRow(ctx,
+			"SELECT todo_id, operation, revision, created_at > now() - $2::interval FROM todo_audit WHERE id = $1", operationID, ttl).
+			Scan(&todoID, &operation, &revision, &fresh)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("operation %d: %w", operationID, ErrRevisionNotFound)
+			}
+			return fmt.Errorf("failed to retrieve operation: %v", err)
+		}
+
+		if !fresh {
+			return fmt.Errorf("operation %d: %w", operationID, ErrUndoExpired)
+		}
+
+		current, err := lockTodo(ctx, tx, todoID)
+		if err != nil {
+			return err
+		}
+		if current.Version != revision {
+			return fmt.Errorf("operation %d: %w", operationID, ErrUndoConflict)
+		}
+
+		switch operation {
+		case AuditCreate:
+			// Hoàn tác tạo mới: chuyển todo vào thùng rác.
+			err = scanTodo(tx.QueryRow(ctx,
+				"UPDATE todo SET deleted_at = now(), version = version + 1 WHERE id = $1 RETURNING "+todoColumns, todoID), &undone)
+			if err != nil {
+				return fmt.Errorf("failed to undo create: %v", err)
+			}
+		case AuditUpdate, AuditStatus, AuditDelete:
+			previous, err := getSnapshot(ctx, tx, todoID, revision-1)
+			if err != nil {
+				return err
+			}
+			undone, err = applySnapshot(ctx, tx, todoID, previous)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("operation %d (%s): %w", operationID, operation, ErrUndoUnsupported)
+		}
+
+		return recordChange(ctx, tx, AuditUndo, &current, &undone)
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return undone, nil
+}
+
+type RevisionHandler struct {
+	revisionStore RevisionStore
+	undoTTL       time.Duration
+}
+
+func NewRevisionHandler(revisionStore RevisionStore, undoTTL time.Duration) *RevisionHandler {
+	return &RevisionHandler{revisionStore: revisionStore, undoTTL: undoTTL}
+}
+
+// @Summary Revert a todo to a previous revision
+// @Description Restore the fields of a todo from a past revision, recorded as a new revision
+// @Tags Revisions
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param revision query int true "Revision to restore"
+// @Success 200 {object} Todo "Reverted"
+// @Failure 400 {object} ErrorResponse "Invalid revision"
+// @Failure 404 {object} ErrorResponse "Todo or revision not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /todo/{id}/revert [post]
+func (h *RevisionHandler) RevertTodo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	revision, err := strconv.Atoi(r.URL.Query().Get("revision"))
+	if err != nil || revision <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "revision must be a positive integer"})
+		return
+	}
+
+	ctx, op := WithOperation(ctx)
+	todo, err := h.revisionStore.RevertTodoDB(ctx, idStr, revision)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) || errors.Is(err, ErrRevisionNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to revert todo: " + err.Error()})
+		}
+		return
+	}
+
+	setOperationHeader(w, op)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(todo)
+}
+
+// @Summary Undo a recent change
+// @Description Reverse the create, update, status change or delete identified by the X-Operation-Id of its response
+// @Tags Revisions
+// @Produce json
+// @Param operationId path int true "Operation ID"
+// @Success 200 {object} Todo "Undone"
+// @Failure 400 {object} ErrorResponse "Operation cannot be undone"
+// @Failure 404 {object} ErrorResponse "Operation not found"
+// @Failure 409 {object} ErrorResponse "Todo changed since the operation"
+// @Failure 410 {object} ErrorResponse "Undo window has passed"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /undo/{operationId} [post]
+func (h *RevisionHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	operationID, err := strconv.ParseInt(mux.Vars(r)["operationId"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "operation not found"})
+		return
+	}
+
+	ctx, op := WithOperation(ctx)
+	todo, err := h.revisionStore.UndoOperationDB(ctx, operationID, h.undoTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTodoNotFound), errors.Is(err, ErrRevisionNotFound):
+			w.WriteHeader(http.StatusNotFound)
+		case errors.Is(err, ErrUndoConflict):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, ErrUndoExpired):
+			w.WriteHeader(http.StatusGone)
+		case errors.Is(err, ErrUndoUnsupported):
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	setOperationHeader(w, op)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(todo)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRevisionStore struct {
+	mock.Mock
+}
+
+func (m *MockRevisionStore) RevertTodoDB(ctx context.Context, id string, revision int) (Todo, error) {
+	args := m.Called(ctx, id, revision)
+	return args.Get(0).(Todo), args.Error(1)
+}
+
+func (m *MockRevisionStore) UndoOperationDB(ctx context.Context, operationID int64, ttl time.Duration) (Todo, error) {
+	args := m.Called(operationID, ttl)
+	return args.Get(0).(Todo), args.Error(1)
+}
+
+func newRevisionRouter(revisionStore RevisionStore) *mux.Router {
+	rh := NewRevisionHandler(revisionStore, 5*time.Minute)
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/{id}/revert", rh.RevertTodo).Methods("POST")
+	router.HandleFunc("/undo/{operationId}", rh.Undo).Methods("POST")
+	return router
+}
+
+func TestRevertTodo(t *testing.T) {
+	mockRevisions := new(MockRevisionStore)
+	mockRevisions.On("RevertTodoDB", mock.Anything, "1", 2).Return(Todo{ID: "1", Title: "Old title", Version: 5}, nil).
+		Run(func(args mock.Arguments) {
+			operationFromContext(args.Get(0).(context.Context)).ID = 42
+		})
+
+	req, _ := http.NewRequest("POST", "/todo/1/revert?revision=2", nil)
+	rr := httptest.NewRecorder()
+	newRevisionRouter(mockRevisions).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	assert.Equal(t, "42", rr.Header().Get(OperationHeader))
+	mockRevisions.AssertExpectations(t)
+}
+
+func TestRevertTodo_BadRevision(t *testing.T) {
+	mockRevisions := new(MockRevisionStore)
+
+	req, _ := http.NewRequest("POST", "/todo/1/revert?revision=abc", nil)
+	rr := httptest.NewRecorder()
+	newRevisionRouter(mockRevisions).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Expected status code 400")
+	mockRevisions.AssertNotCalled(t, "RevertTodoDB", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRevertTodo_RevisionNotFound(t *testing.T) {
+	mockRevisions := new(MockRevisionStore)
+	mockRevisions.On("RevertTodoDB", mock.Anything, "1", 9).Return(Todo{}, ErrRevisionNotFound)
+
+	req, _ := http.NewRequest("POST", "/todo/1/revert?revision=9", nil)
+	rr := httptest.NewRecorder()
+	newRevisionRouter(mockRevisions).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status code 404")
+	mockRevisions.AssertExpectations(t)
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"success", nil, http.StatusOK},
+		{"expired", ErrUndoExpired, http.StatusGone},
+		{"conflict", ErrUndoConflict, http.StatusConflict},
+		{"unsupported", ErrUndoUnsupported, http.StatusBadRequest},
+		{"not found", ErrRevisionNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRevisions := new(MockRevisionStore)
+			mockRevisions.On("UndoOperationDB", int64(7), 5*time.Minute).Return(Todo{ID: "1"}, tt.err)
+
+			req, _ := http.NewRequest("POST", "/undo/7", nil)
+			rr := httptest.NewRecorder()
+			newRevisionRouter(mockRevisions).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.code, rr.Code)
+			mockRevisions.AssertExpectations(t)
+		})
+	}
+}
+
+// Hoàn tác việc hoàn thành todo lặp lại phải mở lại todo, không phải xóa lần
+// lặp kế tiếp vừa được tạo.
+func TestUndoCompleteRecurringDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	complete := map[string]func(ctx context.Context, todo Todo) error{
+		"status": func(ctx context.Context, todo Todo) error {
+			return db.ChangeStatusDB(ctx, todo.ID)
+		},
+		"update": func(ctx context.Context, todo Todo) error {
+			todo.Done = true
+			_, err := db.UpdateTodoDB(ctx, todo.ID, todo)
+			return err
+		},
+	}
+	for operation, fn := range complete {
+		due := time.Now().Add(time.Hour)
+		createdTodo, err := db.CreateTodoDB(context.Background(), Todo{Title: "Đổ rác", Recurrence: "FREQ=DAILY", DueAt: &due})
+		if err != nil {
+			t.Fatalf("Failed to create todo: %v", err)
+		}
+
+		opCtx, op := WithOperation(context.Background())
+		if err := fn(opCtx, createdTodo); err != nil {
+			t.Fatalf("Failed to complete todo: %v", err)
+		}
+		occurrences, err := db.GetOccurrencesDB(context.Background(), createdTodo.ID)
+		assert.NoError(t, err)
+		assert.Len(t, occurrences, 2, operation)
+
+		history, err := db.GetTodoHistoryDB(context.Background(), createdTodo.ID)
+		assert.NoError(t, err)
+		last := history[len(history)-1]
+		assert.Equal(t, last.ID, op.ID, operation)
+		assert.Equal(t, operation, last.Operation)
+
+		undone, err := db.UndoOperationDB(context.Background(), op.ID, time.Minute)
+		if err != nil {
+			t.Fatalf("Failed to undo %s: %v", operation, err)
+		}
+		assert.Equal(t, createdTodo.ID, undone.ID, operation)
+		assert.False(t, undone.Done, operation)
+		assert.Nil(t, undone.DeletedAt, operation)
+	}
+}
+
+// Revert và undo khôi phục cả payment trong ảnh chụp.
+func TestRevertRestoresPaymentDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	payment := &TodoPayment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Memo: "RENT 2024 12"}
+	createdTodo, err := db.CreateTodoDB(ctx, Todo{Title: "Thu tiền nhà", Payment: payment})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+
+	opCtx, op := WithOperation(ctx)
+	updated, err := db.UpdateTodoDB(opCtx, createdTodo.ID, Todo{Title: "Thu tiền nhà"})
+	if err != nil {
+		t.Fatalf("Failed to update todo: %v", err)
+	}
+	assert.Nil(t, updated.Payment)
+
+	undone, err := db.UndoOperationDB(ctx, op.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to undo update: %v", err)
+	}
+	assert.Equal(t, payment, undone.Payment)
+
+	if _, err := db.UpdateTodoDB(ctx, createdTodo.ID, Todo{Title: "Thu tiền nhà"}); err != nil {
+		t.Fatalf("Failed to update todo: %v", err)
+	}
+	reverted, err := db.RevertTodoDB(ctx, createdTodo.ID, createdTodo.Version)
+	if err != nil {
+		t.Fatalf("Failed to revert todo: %v", err)
+	}
+	assert.Equal(t, payment, reverted.Payment)
+}
+
+// Cửa sổ undo tính bằng đồng hồ của DB, không lệch theo múi giờ của phiên.
+func TestUndoOperationTTLDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	createdTodo, err := db.CreateTodoDB(ctx, Todo{Title: "Hoàn tác trong hạn"})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	opCtx, op := WithOperation(ctx)
+	if _, err := db.UpdateTodoDB(opCtx, createdTodo.ID, Todo{Title: "Đã sửa"}); err != nil {
+		t.Fatalf("Failed to update todo: %v", err)
+	}
+
+	_, err = db.UndoOperationDB(ctx, op.ID, time.Nanosecond)
+	assert.ErrorIs(t, err, ErrUndoExpired)
+
+	undone, err := db.UndoOperationDB(ctx, op.ID, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, "Hoàn tác trong hạn", undone.Title)
+}
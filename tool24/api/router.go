@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	httpSwagger "github.com/swaggo/http-swagger"
+
+	"api/vietqr"
+)
+
+// Store gom tất cả các store mà router cần; *Db thỏa mãn toàn bộ.
+type Store interface {
+	TodoStore
+	TrashStore
+	AuditStore
+	RevisionStore
+	RecurrenceStore
+	WebhookStore
+	GraphQLStore
+	TransferStore
+	CalendarStore
+	PaymentStore
+	PrintStore
+	ScanStore
+	StatsStore
+}
+
+// NewRouter đăng ký toàn bộ route HTTP của API. main và các test end-to-end
+// (vd của package client) dùng chung hàm này để không lệch nhau.
+func NewRouter(store Store, broker *EventBroker, banks *vietqr.Registry, paymentSecret string, printers map[string]ThermalPrinter, undoTTL time.Duration, playground bool) *mux.Router {
+	h := NewTodoHandler(store)
+	th := NewTrashHandler(store)
+	ah := NewAuditHandler(store)
+	rh := NewRevisionHandler(store, undoTTL)
+	rch := NewRecurrenceHandler(store)
+	wh := NewWebhookHandler(store)
+	eh := NewEventHandler(broker)
+	wsh := NewWSHandler(store, broker)
+	gh := NewGraphQLHandler(store, store, broker, playground)
+	tfh := NewTransferHandler(store)
+	ch := NewCalendarHandler(store, store)
+	qh := NewQRHandler(banks)
+	bh := NewBankHandler(banks)
+	pmh := NewPaymentHandler(store, store, paymentSecret)
+	prh := NewPrintHandler(store, store, printers)
+	bch := NewBarcodeHandler(store, store)
+	sh := NewStatsHandler(store)
+	rph := NewReportHandler(store)
+	router := mux.NewRouter()
+	router.Use(ActorMiddleware)
+
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	router.HandleFunc("/swagger/swagger.json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./docs/swagger.json")
+	})
+
+	router.HandleFunc("/todos", h.GetAllTodos).Methods("GET")
+	router.HandleFunc("/todos/events", eh.StreamTodoEvents).Methods("GET")
+	router.HandleFunc("/todos/export", tfh.ExportTodos).Methods("GET")
+	router.HandleFunc("/todos/import", tfh.ImportTodos).Methods("POST")
+	router.HandleFunc("/todos/print", prh.PrintTodos).Methods("GET", "POST")
+	router.HandleFunc("/ws", wsh.ServeWS).Methods("GET")
+	router.Handle("/graphql", gh).Methods("GET", "POST")
+	router.HandleFunc("/todo/{id}", h.GetTodoByID).Methods("GET")
+	router.HandleFunc("/todo", h.CreateTodo).Methods("POST")
+	router.HandleFunc("/todo/{id}", h.UpdateTodo).Methods("PUT")
+	router.HandleFunc("/todo/{id}", th.PurgeTodo).Methods("DELETE").Queries("permanent", "true")
+	router.HandleFunc("/todo/{id}", h.DeleteTodoByID).Methods("DELETE")
+	router.HandleFunc("/todo/changeStatus/{id}", h.ChangeStatus).Methods("POST")
+	router.HandleFunc("/trash", th.GetTrash).Methods("GET")
+	router.HandleFunc("/todo/{id}/restore", th.RestoreTodo).Methods("POST")
+	router.HandleFunc("/todo/{id}/history", ah.GetTodoHistory).Methods("GET")
+	router.HandleFunc("/audit", ah.ListAudit).Methods("GET")
+	router.HandleFunc("/todo/{id}/revert", rh.RevertTodo).Methods("POST")
+	router.HandleFunc("/undo/{operationId}", rh.Undo).Methods("POST")
+	router.HandleFunc("/todo/{id}/occurrences", rch.GetOccurrences).Methods("GET")
+	router.HandleFunc("/todo/{id}/qr.png", pmh.GetTodoQR).Methods("GET")
+	router.HandleFunc("/todo/{id}/print", prh.PrintTodo).Methods("GET", "POST")
+	router.HandleFunc("/webhooks", wh.CreateWebhook).Methods("POST")
+	router.HandleFunc("/webhooks", wh.ListWebhooks).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", wh.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/webhooks/{id}/deliveries", wh.ListDeliveries).Methods("GET")
+	router.HandleFunc("/calendar.ics", ch.ServeFeed).Methods("GET")
+	router.HandleFunc("/calendar/feeds", ch.CreateFeed).Methods("POST")
+	router.HandleFunc("/calendar/feeds", ch.ListFeeds).Methods("GET")
+	router.HandleFunc("/calendar/feeds/{id}", ch.DeleteFeed).Methods("DELETE")
+	router.HandleFunc("/calendar/import", ch.ImportCalendar).Methods("POST")
+	router.HandleFunc("/qr/payment", qh.CreatePaymentPayload).Methods("POST")
+	router.HandleFunc("/qr/render", qh.RenderQR).Methods("GET", "POST")
+	router.HandleFunc("/qr/decode", qh.DecodeQR).Methods("POST")
+	router.HandleFunc("/banks", bh.ListBanks).Methods("GET")
+	router.HandleFunc("/banks/{bin}", bh.GetBank).Methods("GET")
+	router.HandleFunc("/payments/callback", pmh.PaymentCallback).Methods("POST")
+	router.HandleFunc("/prints/{id}", prh.GetPrintJob).Methods("GET")
+	router.HandleFunc("/barcode", bch.RenderBarcode).Methods("GET", "POST")
+	router.HandleFunc("/barcode/decode", bch.DecodeBarcode).Methods("POST")
+	router.HandleFunc("/scan", bch.Scan).Methods("POST")
+	router.HandleFunc("/stats", sh.GetStats).Methods("GET")
+	router.HandleFunc("/reports/{chart:burndown|cfd}.{ext:svg|json}", rph.GetReport).Methods("GET")
+
+	return router
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+// RRule is the subset of an RFC 5545 recurrence rule that todos support:
+// FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, BYDAY (weekly), BYMONTHDAY
+// (monthly) and either UNTIL or COUNT.
+type RRule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Until      *time.Time
+	Count      int
+}
+
+var rruleWeekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// ParseRRule parses a rule such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// An optional "RRULE:" prefix is accepted.
+func ParseRRule(s string) (RRule, error) {
+	rule := RRule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return RRule{}, fmt.Errorf("%w: empty rule", ErrInvalidRecurrence)
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return RRule{}, fmt.Errorf("%w: malformed part %q", ErrInvalidRecurrence, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(value)
+			if rule.Freq != "DAILY" && rule.Freq != "WEEKLY" && rule.Freq != "MONTHLY" {
+				return RRule{}, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRecurrence, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return RRule{}, fmt.Errorf("%w: INTERVAL must be a positive integer", ErrInvalidRecurrence)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return RRule{}, fmt.Errorf("%w: COUNT must be a positive integer", ErrInvalidRecurrence)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return RRule{}, fmt.Errorf("%w: UNTIL %q", ErrInvalidRecurrence, value)
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(value), ",") {
+				wd, ok := rruleWeekdays[d]
+				if !ok {
+					return RRule{}, fmt.Errorf("%w: unsupported BYDAY %q", ErrInvalidRecurrence, d)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return RRule{}, fmt.Errorf("%w: BYMONTHDAY %q", ErrInvalidRecurrence, d)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return RRule{}, fmt.Errorf("%w: unsupported part %q", ErrInvalidRecurrence, key)
+		}
+	}
+
+	switch {
+	case rule.Freq == "":
+		return RRule{}, fmt.Errorf("%w: FREQ is required", ErrInvalidRecurrence)
+	case rule.Until != nil && rule.Count > 0:
+		return RRule{}, fmt.Errorf("%w: UNTIL and COUNT are mutually exclusive", ErrInvalidRecurrence)
+	case len(rule.ByDay) > 0 && rule.Freq != "WEEKLY":
+		return RRule{}, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalidRecurrence)
+	case len(rule.ByMonthDay) > 0 && rule.Freq != "MONTHLY":
+		return RRule{}, fmt.Errorf("%w: BYMONTHDAY is only supported with FREQ=MONTHLY", ErrInvalidRecurrence)
+	}
+
+	return rule, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// UNTIL theo ngày bao gồm cả ngày đó.
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+func (r RRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, wd := range r.ByDay {
+			days = append(days, strings.ToUpper(wd.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, d := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(d))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence that follows current, which must itself be an
+// occurrence of the rule. occurrences is how many occurrences exist so far
+// and is checked against COUNT. The second result is false once the rule is
+// exhausted.
+func (r RRule) Next(current time.Time, occurrences int) (time.Time, bool) {
+	if r.Count > 0 && occurrences >= r.Count {
+		return time.Time{}, false
+	}
+
+	var next time.Time
+	switch r.Freq {
+	case "DAILY":
+		next = current.AddDate(0, 0, r.Interval)
+	case "WEEKLY":
+		next = r.nextWeekly(current)
+	case "MONTHLY":
+		var ok bool
+		if next, ok = r.nextMonthly(current); !ok {
+			return time.Time{}, false
+		}
+	default:
+		return time.Time{}, false
+	}
+
+	if r.Until != nil && next.After(*r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+func (r RRule) nextWeekly(current time.Time) time.Time {
+	if len(r.ByDay) == 0 {
+		return current.AddDate(0, 0, 7*r.Interval)
+	}
+
+	// Tuần bắt đầu từ thứ Hai như WKST mặc định của RFC 5545.
+	offsets := make([]int, 0, len(r.ByDay))
+	for _, wd := range r.ByDay {
+		offsets = append(offsets, (int(wd)+6)%7)
+	}
+	sort.Ints(offsets)
+
+	today := (int(current.Weekday()) + 6) % 7
+	for _, off := range offsets {
+		if off > today {
+			return current.AddDate(0, 0, off-today)
+		}
+	}
+	weekStart := current.AddDate(0, 0, -today)
+	return weekStart.AddDate(0, 0, 7*r.Interval+offsets[0])
+}
+
+func (r RRule) nextMonthly(current time.Time) (time.Time, bool) {
+	days := r.ByMonthDay
+	if len(days) == 0 {
+		days = []int{current.Day()}
+	}
+
+	year, month, _ := current.Date()
+	// Bỏ qua các tháng không có ngày hợp lệ (vd ngày 31), tối đa vài năm.
+	for step := 0; step < 12*8; step++ {
+		first := time.Date(year, month+time.Month(step*r.Interval), 1,
+			current.Hour(), current.Minute(), current.Second(), current.Nanosecond(), current.Location())
+		length := first.AddDate(0, 1, -1).Day()
+
+		var candidates []int
+		for _, d := range days {
+			if d < 0 {
+				d = length + d + 1
+			}
+			if d >= 1 && d <= length {
+				candidates = append(candidates, d)
+			}
+		}
+		sort.Ints(candidates)
+
+		for _, d := range candidates {
+			t := first.AddDate(0, 0, d-1)
+			if t.After(current) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRRule(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5")
+	assert.NoError(t, err)
+	assert.Equal(t, "WEEKLY", rule.Freq)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Thursday}, rule.ByDay)
+	assert.Equal(t, 5, rule.Count)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=5", rule.String())
+
+	for _, bad := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;COUNT=3;UNTIL=20241231",
+		"FREQ=DAILY;BYSETPOS=1",
+	} {
+		_, err := ParseRRule(bad)
+		assert.True(t, errors.Is(err, ErrInvalidRecurrence), "expected %q to be rejected", bad)
+	}
+}
+
+func TestRRuleNext(t *testing.T) {
+	// Thứ Tư, 6/11/2024 lúc 9h.
+	wed := time.Date(2024, 11, 6, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		rule    string
+		current time.Time
+		want    time.Time
+	}{
+		{"FREQ=DAILY", wed, time.Date(2024, 11, 7, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=DAILY;INTERVAL=3", wed, time.Date(2024, 11, 9, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY", wed, time.Date(2024, 11, 13, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;BYDAY=MO,FR", wed, time.Date(2024, 11, 8, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;BYDAY=MO,WE", wed, time.Date(2024, 11, 11, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", wed, time.Date(2024, 11, 18, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=WEEKLY;BYDAY=SU", wed, time.Date(2024, 11, 10, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY", wed, time.Date(2024, 12, 6, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,15", wed, time.Date(2024, 11, 15, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", wed, time.Date(2024, 11, 30, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;BYMONTHDAY=31", time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC), time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC)},
+		{"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=5", wed, time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseRRule(tt.rule)
+		assert.NoError(t, err)
+		next, ok := rule.Next(tt.current, 1)
+		assert.True(t, ok, tt.rule)
+		assert.Equal(t, tt.want, next, tt.rule)
+	}
+}
+
+func TestRRuleNext_Exhausted(t *testing.T) {
+	wed := time.Date(2024, 11, 6, 9, 0, 0, 0, time.UTC)
+
+	rule, _ := ParseRRule("FREQ=DAILY;COUNT=3")
+	_, ok := rule.Next(wed, 2)
+	assert.True(t, ok, "third occurrence is still allowed")
+	_, ok = rule.Next(wed, 3)
+	assert.False(t, ok, "COUNT=3 allows no fourth occurrence")
+
+	rule, _ = ParseRRule("FREQ=WEEKLY;UNTIL=20241112")
+	_, ok = rule.Next(wed, 1)
+	assert.False(t, ok, "next week is past UNTIL")
+	rule, _ = ParseRRule("FREQ=WEEKLY;UNTIL=20241113")
+	_, ok = rule.Next(wed, 1)
+	assert.True(t, ok, "UNTIL as a date includes that whole day")
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultStatsRange là khoảng thời gian khi không có since.
+const defaultStatsRange = 30 * 24 * time.Hour
+
+// maxStatsDays giới hạn số ngày của khoảng thống kê để per_day không quá dài.
+const maxStatsDays = 366
+
+// StatsQuery chọn phạm vi thống kê. Since/Until giới hạn các số liệu theo
+// thời gian (created, completed, median, per_day, per_week); Open, Done,
+// Overdue là hiện trạng tại Now.
+type StatsQuery struct {
+	Since time.Time
+	Until time.Time
+	Now   time.Time
+	// Location dùng để chia ngày và tuần (tuần bắt đầu thứ Hai).
+	Location *time.Location
+	// ListID nil là mọi danh sách; "" là danh sách mặc định.
+	ListID *string
+	// Tag rỗng là mọi tag.
+	Tag string
+}
+
+// matches cho biết todo có thuộc phạm vi list/tag của q không.
+func (q StatsQuery) matches(todo Todo) bool {
+	if q.ListID != nil && todo.ListID != *q.ListID {
+		return false
+	}
+	return q.Tag == "" || containsString(todo.Tags, q.Tag)
+}
+
+// StatsCounts are the numbers reported for the whole scope and for each list
+// and tag.
+type StatsCounts struct {
+	// Open, Done and Overdue describe the todos as they are now.
+	Open    int `json:"open"`
+	Done    int `json:"done"`
+	Overdue int `json:"overdue"`
+	// Created and Completed count todos created and marked done in the range.
+	Created   int `json:"created"`
+	Completed int `json:"completed"`
+	// MedianSecondsToDone is the median of done_at - created_at over the todos
+	// completed in the range; null when none was.
+	MedianSecondsToDone *float64 `json:"median_seconds_to_done"`
+}
+
+// StatsGroup is the breakdown of one list or tag.
+type StatsGroup struct {
+	Key string `json:"key"`
+	StatsCounts
+}
+
+// StatsBucket counts the todos created and completed in one day or week.
+type StatsBucket struct {
+	// Start is the first day of the bucket, YYYY-MM-DD in the requested time
+	// zone; weeks start on Monday.
+	Start     string `json:"start"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+// Stats is the response of GET /stats.
+type Stats struct {
+	Since    time.Time `json:"since"`
+	Until    time.Time `json:"until"`
+	TimeZone string    `json:"time_zone"`
+	StatsCounts
+	PerDay  []StatsBucket `json:"per_day"`
+	PerWeek []StatsBucket `json:"per_week"`
+	// Lists has one entry per list, "" being the default list; Tags one per
+	// tag. Both are sorted by key.
+	Lists []StatsGroup `json:"lists"`
+	Tags  []StatsGroup `json:"tags"`
+}
+
+// StatsStore tính số liệu thống kê todo.
+type StatsStore interface {
+	GetStatsDB(ctx context.Context, q StatsQuery) (Stats, error)
+}
+
+// statsScope chọn các todo trong phạm vi cùng các cờ mà mọi truy vấn thống kê
+// dùng chung. Thời gian lưu dạng TIMESTAMP theo giờ UTC nên tham số cũng phải
+// là giờ UTC.
+const statsScope = `WITH t AS (
+	SELECT done, created_at, done_at, COALESCE(list_id, '') AS list_id, COALESCE(tags, '{}') AS tags,
+		NOT done AND due_at < $3::timestamp AS overdue,
+		created_at >= $1::timestamp AND created_at < $2::timestamp AS created,
+		done AND done_at >= $1::timestamp AND done_at < $2::timestamp AS completed,
+		EXTRACT(EPOCH FROM done_at - created_at)::float8 AS seconds_to_done
+	FROM todo
+	WHERE deleted_at IS NULL AND ($4::text IS NULL OR COALESCE(list_id, '') = $4) AND ($5 = '' OR $5 = ANY(tags))
+) `
+
+// statsAggregates là các cột của StatsCounts, theo thứ tự scanStatsCounts đọc.
+const statsAggregates = `count(*) FILTER (WHERE NOT done), count(*) FILTER (WHERE done), count(*) FILTER (WHERE overdue),
+	count(*) FILTER (WHERE created), count(*) FILTER (WHERE completed),
+	percentile_cont(0.5) WITHIN GROUP (ORDER BY seconds_to_done) FILTER (WHERE completed)`
+
+func (db *Db) GetStatsDB(ctx context.Context, q StatsQuery) (Stats, error) {
+	stats := newStats(q)
+	args := []interface{}{q.Since.UTC(), q.Until.UTC(), q.Now.UTC(), q.ListID, q.Tag}
+
+	var total []StatsGroup
+	for _, query := range []struct {
+		dst *[]StatsGroup
+		sql string
+	}{
+		{&total, statsScope + "SELECT '', " + statsAggregates + " FROM t"},
+		{&stats.Lists, statsScope + "SELECT list_id, " + statsAggregates + " FROM t GROUP BY list_id ORDER BY list_id"},
+		{&stats.Tags, statsScope + "SELECT tag, " + statsAggregates + " FROM t, unnest(t.tags) AS tag GROUP BY tag ORDER BY tag"},
+	} {
+		rows, err := db.Conn.Query(ctx, query.sql, args...)
+		if err != nil {
+			return Stats{}, fmt.Errorf("failed to get stats: %v", err)
+		}
+		for rows.Next() {
+			var g StatsGroup
+			c := &g.StatsCounts
+			if err := rows.Scan(&g.Key, &c.Open, &c.Done, &c.Overdue, &c.Created, &c.Completed, &c.MedianSecondsToDone); err != nil {
+				rows.Close()
+				return Stats{}, fmt.Errorf("failed to read stats: %v", err)
+			}
+			*query.dst = append(*query.dst, g)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Stats{}, fmt.Errorf("failed to get stats: %v", err)
+		}
+	}
+	if len(total) == 1 {
+		stats.StatsCounts = total[0].StatsCounts
+	}
+
+	// Đổi giờ UTC đã lưu sang giờ địa phương rồi mới cắt theo ngày.
+	rows, err := db.Conn.Query(ctx, statsScope+`SELECT to_char((ts AT TIME ZONE 'UTC') AT TIME ZONE $6, 'YYYY-MM-DD'), kind, count(*) FROM (
+		SELECT created_at AS ts, 'created' AS kind FROM t WHERE created
+		UNION ALL
+		SELECT done_at, 'completed' FROM t WHERE completed
+	) e GROUP BY 1, 2`, append(args, q.Location.String())...)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to get stats per day: %v", err)
+	}
+	defer rows.Close()
+	days := map[string]*StatsBucket{}
+	for rows.Next() {
+		var day, kind string
+		var n int
+		if err := rows.Scan(&day, &kind, &n); err != nil {
+			return Stats{}, fmt.Errorf("failed to read stats per day: %v", err)
+		}
+		bucket := statsDay(days, day)
+		if kind == "created" {
+			bucket.Created = n
+		} else {
+			bucket.Completed = n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Stats{}, fmt.Errorf("failed to get stats per day: %v", err)
+	}
+	fillStatsBuckets(&stats, days)
+
+	return stats, nil
+}
+
+// MemoryStatsStore computes the same statistics as the Postgres store in Go,
+// from GetAllTodoDB. It needs no SQL, so it serves stores that only implement
+// TodoStore and is the reference the SQL aggregation is checked against.
+type MemoryStatsStore struct {
+	TodoStore TodoStore
+}
+
+func (s MemoryStatsStore) GetStatsDB(ctx context.Context, q StatsQuery) (Stats, error) {
+	todos, err := s.TodoStore.GetAllTodoDB(ctx)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to get todos: %v", err)
+	}
+	return computeStats(todos, q), nil
+}
+
+// statsAccumulator cộng dồn StatsCounts cùng thời gian hoàn thành để tính trung vị.
+type statsAccumulator struct {
+	counts  StatsCounts
+	seconds []float64
+}
+
+func (a *statsAccumulator) add(todo Todo, q StatsQuery) {
+	if todo.Done {
+		a.counts.Done++
+	} else {
+		a.counts.Open++
+		if todo.DueAt != nil && todo.DueAt.Before(q.Now) {
+			a.counts.Overdue++
+		}
+	}
+	if inStatsRange(todo.CreatedAt, q) {
+		a.counts.Created++
+	}
+	if statsCompleted(todo, q) {
+		a.counts.Completed++
+		a.seconds = append(a.seconds, todo.DoneAt.Sub(todo.CreatedAt).Seconds())
+	}
+}
+
+func (a *statsAccumulator) result() StatsCounts {
+	counts := a.counts
+	counts.MedianSecondsToDone = median(a.seconds)
+	return counts
+}
+
+func inStatsRange(t time.Time, q StatsQuery) bool {
+	return !t.Before(q.Since) && t.Before(q.Until)
+}
+
+func statsCompleted(todo Todo, q StatsQuery) bool {
+	return todo.Done && todo.DoneAt != nil && inStatsRange(*todo.DoneAt, q)
+}
+
+// median trả về trung vị, trung bình hai phần tử giữa khi số phần tử chẵn như
+// percentile_cont(0.5) của Postgres; nil khi không có phần tử nào.
+func median(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	m := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		m = (sorted[len(sorted)/2-1] + m) / 2
+	}
+	return &m
+}
+
+// computeStats là bản tính trong bộ nhớ của GetStatsDB.
+func computeStats(todos []Todo, q StatsQuery) Stats {
+	stats := newStats(q)
+	var total statsAccumulator
+	lists := map[string]*statsAccumulator{}
+	tags := map[string]*statsAccumulator{}
+	days := map[string]*StatsBucket{}
+	group := func(groups map[string]*statsAccumulator, key string) *statsAccumulator {
+		if groups[key] == nil {
+			groups[key] = &statsAccumulator{}
+		}
+		return groups[key]
+	}
+
+	for _, todo := range todos {
+		if !q.matches(todo) {
+			continue
+		}
+		total.add(todo, q)
+		group(lists, todo.ListID).add(todo, q)
+		for _, tag := range todo.Tags {
+			group(tags, tag).add(todo, q)
+		}
+		if inStatsRange(todo.CreatedAt, q) {
+			statsDay(days, todo.CreatedAt.In(q.Location).Format(time.DateOnly)).Created++
+		}
+		if statsCompleted(todo, q) {
+			statsDay(days, todo.DoneAt.In(q.Location).Format(time.DateOnly)).Completed++
+		}
+	}
+
+	stats.StatsCounts = total.result()
+	stats.Lists = statsGroups(lists)
+	stats.Tags = statsGroups(tags)
+	fillStatsBuckets(&stats, days)
+	return stats
+}
+
+func statsGroups(groups map[string]*statsAccumulator) []StatsGroup {
+	result := make([]StatsGroup, 0, len(groups))
+	for key, acc := range groups {
+		result = append(result, StatsGroup{Key: key, StatsCounts: acc.result()})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
+
+// newStats tạo Stats rỗng với phạm vi của q; hai bản cài đặt dùng chung để
+// kết quả giống hệt nhau kể cả khi không có todo nào.
+func newStats(q StatsQuery) Stats {
+	return Stats{
+		Since:    q.Since.In(q.Location),
+		Until:    q.Until.In(q.Location),
+		TimeZone: q.Location.String(),
+		Lists:    []StatsGroup{},
+		Tags:     []StatsGroup{},
+	}
+}
+
+func statsDay(days map[string]*StatsBucket, day string) *StatsBucket {
+	if days[day] == nil {
+		days[day] = &StatsBucket{Start: day}
+	}
+	return days[day]
+}
+
+// fillStatsBuckets điền PerDay cho mọi ngày trong khoảng, kể cả ngày không có
+// gì, rồi cộng các ngày thành PerWeek theo tuần bắt đầu thứ Hai.
+func fillStatsBuckets(stats *Stats, days map[string]*StatsBucket) {
+	loc := stats.Since.Location()
+	first := startOfDay(stats.Since, loc)
+	last := startOfDay(stats.Until.Add(-time.Nanosecond), loc)
+
+	stats.PerDay = []StatsBucket{}
+	stats.PerWeek = []StatsBucket{}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		bucket := StatsBucket{Start: day.Format(time.DateOnly)}
+		if d := days[bucket.Start]; d != nil {
+			bucket.Created, bucket.Completed = d.Created, d.Completed
+		}
+		stats.PerDay = append(stats.PerDay, bucket)
+
+		week := day.AddDate(0, 0, -(int(day.Weekday())+6)%7).Format(time.DateOnly)
+		if n := len(stats.PerWeek); n == 0 || stats.PerWeek[n-1].Start != week {
+			stats.PerWeek = append(stats.PerWeek, StatsBucket{Start: week})
+		}
+		w := &stats.PerWeek[len(stats.PerWeek)-1]
+		w.Created += bucket.Created
+		w.Completed += bucket.Completed
+	}
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// parseStatsQuery đọc tz, since, until, list và tag. since/until nhận RFC 3339
+// hoặc một ngày YYYY-MM-DD theo tz; until dạng ngày được tính trọn ngày đó.
+func parseStatsQuery(q url.Values, now time.Time) (StatsQuery, error) {
+	query := StatsQuery{Now: now, Location: time.UTC, Tag: strings.ToLower(strings.TrimSpace(q.Get("tag")))}
+	if v := q.Get("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return StatsQuery{}, fmt.Errorf("unknown time zone %q", v)
+		}
+		query.Location = loc
+	}
+	if q.Has("list") {
+		list := q.Get("list")
+		query.ListID = &list
+	}
+
+	query.Until = now
+	if v := q.Get("until"); v != "" {
+		t, dateOnly, err := parseStatsTime(v, query.Location)
+		if err != nil {
+			return StatsQuery{}, fmt.Errorf("invalid until: %v", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		query.Until = t
+	}
+	query.Since = query.Until.Add(-defaultStatsRange)
+	if v := q.Get("since"); v != "" {
+		t, _, err := parseStatsTime(v, query.Location)
+		if err != nil {
+			return StatsQuery{}, fmt.Errorf("invalid since: %v", err)
+		}
+		query.Since = t
+	}
+
+	if !query.Since.Before(query.Until) {
+		return StatsQuery{}, errors.New("since must be before until")
+	}
+	if query.Until.Sub(query.Since) > maxStatsDays*24*time.Hour {
+		return StatsQuery{}, fmt.Errorf("range must be at most %d days", maxStatsDays)
+	}
+	return query, nil
+}
+
+func parseStatsTime(v string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("must be RFC 3339 or YYYY-MM-DD")
+	}
+	return t, false, nil
+}
+
+// statsCSVColumns là các cột của GET /stats?format=csv. Mỗi dòng là một phần
+// (total, list, tag, day, week) và khóa của nó; ô không áp dụng để trống.
+var statsCSVColumns = []string{"section", "key", "open", "done", "overdue", "created", "completed", "median_seconds_to_done"}
+
+func writeStatsCSV(w io.Writer, stats Stats) error {
+	cw := csv.NewWriter(w)
+	cw.Write(statsCSVColumns)
+	counts := func(section, key string, c StatsCounts) {
+		median := ""
+		if c.MedianSecondsToDone != nil {
+			median = strconv.FormatFloat(*c.MedianSecondsToDone, 'f', -1, 64)
+		}
+		cw.Write([]string{section, key, strconv.Itoa(c.Open), strconv.Itoa(c.Done), strconv.Itoa(c.Overdue),
+			strconv.Itoa(c.Created), strconv.Itoa(c.Completed), median})
+	}
+	buckets := func(section string, buckets []StatsBucket) {
+		for _, b := range buckets {
+			cw.Write([]string{section, b.Start, "", "", "", strconv.Itoa(b.Created), strconv.Itoa(b.Completed), ""})
+		}
+	}
+
+	counts("total", "", stats.StatsCounts)
+	for _, g := range stats.Lists {
+		counts("list", g.Key, g.StatsCounts)
+	}
+	for _, g := range stats.Tags {
+		counts("tag", g.Key, g.StatsCounts)
+	}
+	buckets("day", stats.PerDay)
+	buckets("week", stats.PerWeek)
+	cw.Flush()
+	return cw.Error()
+}
+
+type StatsHandler struct {
+	statsStore StatsStore
+}
+
+func NewStatsHandler(statsStore StatsStore) *StatsHandler {
+	return &StatsHandler{statsStore: statsStore}
+}
+
+// @Summary Todo statistics
+// @Description Throughput numbers over a date range: open, done and overdue todos now; todos created and completed in the range, with the median time from created_at to done_at; completions per day and per ISO week; and the same counts per list and per tag. list and tag narrow every number to one list or tag.
+// @Tags Stats
+// @Produce json
+// @Produce text/csv
+// @Param since query string false "Start of the range, RFC 3339 or YYYY-MM-DD (default 30 days before until)"
+// @Param until query string false "End of the range, exclusive, RFC 3339 or YYYY-MM-DD (inclusive day); default now"
+// @Param tz query string false "IANA time zone for days and weeks, e.g. Asia/Ho_Chi_Minh (default UTC)"
+// @Param list query string false "Only this list; empty for the default list"
+// @Param tag query string false "Only todos with this tag"
+// @Param format query string false "json (default) or csv; the Accept header is used when absent" Enums(json, csv)
+// @Success 200 {object} Stats "OK"
+// @Failure 400 {object} ErrorResponse "Invalid query"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /stats [get]
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = FormatJSON
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Accept")); err == nil && mediaType == formatContentTypes[FormatCSV] {
+			format = FormatCSV
+		}
+	}
+	if format != FormatJSON && format != FormatCSV {
+		statsError(w, http.StatusBadRequest, "format must be json or csv")
+		return
+	}
+	query, err := parseStatsQuery(q, time.Now())
+	if err != nil {
+		statsError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := h.statsStore.GetStatsDB(r.Context(), query)
+	if err != nil {
+		statsError(w, http.StatusInternalServerError, "Failed to get stats: "+err.Error())
+		return
+	}
+
+	if format == FormatCSV {
+		w.Header().Set("Content-Type", formatContentTypes[FormatCSV])
+		w.Header().Set("Content-Disposition", `attachment; filename="stats.csv"`)
+		w.WriteHeader(http.StatusOK)
+		writeStatsCSV(w, stats)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+func statsError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStatsRouter(statsStore StatsStore) *mux.Router {
+	h := NewStatsHandler(statsStore)
+	router := mux.NewRouter()
+	router.HandleFunc("/stats", h.GetStats).Methods("GET")
+	return router
+}
+
+func statsTime(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func statsTimePtr(s string) *time.Time {
+	t := statsTime(s)
+	return &t
+}
+
+func statsTestTodos() []Todo {
+	return []Todo{
+		{ID: "1", ListID: "kho", Tags: []string{"gap", "kho"}, Done: true, CreatedAt: statsTime("2024-12-02T02:00:00Z"), DoneAt: statsTimePtr("2024-12-03T02:00:00Z")},
+		// Xong lúc 18h UTC là 1h sáng hôm sau ở Việt Nam.
+		{ID: "2", ListID: "kho", Tags: []string{"kho"}, Done: true, CreatedAt: statsTime("2024-12-05T00:00:00Z"), DoneAt: statsTimePtr("2024-12-09T18:00:00Z")},
+		// Tạo trước khoảng thống kê nhưng xong trong khoảng.
+		{ID: "3", Done: true, CreatedAt: statsTime("2024-11-20T00:00:00Z"), DoneAt: statsTimePtr("2024-12-10T00:00:00Z")},
+		{ID: "4", Tags: []string{"gap"}, CreatedAt: statsTime("2024-12-12T10:00:00Z"), DueAt: statsTimePtr("2024-12-13T00:00:00Z")},
+		{ID: "5", ListID: "kho", CreatedAt: statsTime("2024-12-14T01:00:00Z"), DueAt: statsTimePtr("2024-12-20T00:00:00Z")},
+		// Xong trước khoảng thống kê: chỉ tính vào done.
+		{ID: "6", Done: true, CreatedAt: statsTime("2024-11-01T00:00:00Z"), DoneAt: statsTimePtr("2024-11-25T00:00:00Z")},
+	}
+}
+
+func statsTestQuery(t *testing.T, query string) StatsQuery {
+	t.Helper()
+	values, _ := url.ParseQuery(query)
+	q, err := parseStatsQuery(values, statsTime("2024-12-14T12:00:00Z"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func seconds(s float64) *float64 {
+	return &s
+}
+
+func TestComputeStats(t *testing.T) {
+	stats := computeStats(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh"))
+
+	assert.Equal(t, "Asia/Ho_Chi_Minh", stats.TimeZone)
+	assert.Equal(t, "2024-12-01T00:00:00+07:00", stats.Since.Format(time.RFC3339))
+	assert.Equal(t, "2024-12-15T00:00:00+07:00", stats.Until.Format(time.RFC3339))
+	assert.Equal(t, StatsCounts{Open: 2, Done: 4, Overdue: 1, Created: 4, Completed: 3, MedianSecondsToDone: seconds(410400)}, stats.StatsCounts)
+
+	assert.Equal(t, []StatsGroup{
+		{Key: "", StatsCounts: StatsCounts{Open: 1, Done: 2, Overdue: 1, Created: 1, Completed: 1, MedianSecondsToDone: seconds(1728000)}},
+		{Key: "kho", StatsCounts: StatsCounts{Open: 1, Done: 2, Created: 3, Completed: 2, MedianSecondsToDone: seconds(248400)}},
+	}, stats.Lists)
+	assert.Equal(t, []StatsGroup{
+		{Key: "gap", StatsCounts: StatsCounts{Open: 1, Done: 1, Overdue: 1, Created: 2, Completed: 1, MedianSecondsToDone: seconds(86400)}},
+		{Key: "kho", StatsCounts: StatsCounts{Done: 2, Created: 2, Completed: 2, MedianSecondsToDone: seconds(248400)}},
+	}, stats.Tags)
+
+	assert.Len(t, stats.PerDay, 14)
+	assert.Equal(t, StatsBucket{Start: "2024-12-01"}, stats.PerDay[0])
+	assert.Equal(t, StatsBucket{Start: "2024-12-02", Created: 1}, stats.PerDay[1])
+	assert.Equal(t, StatsBucket{Start: "2024-12-03", Completed: 1}, stats.PerDay[2])
+	assert.Equal(t, StatsBucket{Start: "2024-12-10", Completed: 2}, stats.PerDay[9])
+	assert.Equal(t, StatsBucket{Start: "2024-12-14", Created: 1}, stats.PerDay[13])
+	assert.Equal(t, []StatsBucket{
+		{Start: "2024-11-25"},
+		{Start: "2024-12-02", Created: 2, Completed: 1},
+		{Start: "2024-12-09", Created: 2, Completed: 2},
+	}, stats.PerWeek)
+}
+
+func TestComputeStats_Scope(t *testing.T) {
+	stats := computeStats(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&list=kho"))
+	assert.Equal(t, StatsCounts{Open: 1, Done: 2, Created: 3, Completed: 2, MedianSecondsToDone: seconds(248400)}, stats.StatsCounts)
+	assert.Len(t, stats.Lists, 1)
+
+	// list rỗng là danh sách mặc định, không phải bỏ lọc.
+	stats = computeStats(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&list="))
+	assert.Equal(t, 3, stats.Open+stats.Done)
+
+	stats = computeStats(statsTestTodos(), statsTestQuery(t, "since=2024-12-01&until=2024-12-14&tag=GAP"))
+	assert.Equal(t, StatsCounts{Open: 1, Done: 1, Overdue: 1, Created: 2, Completed: 1, MedianSecondsToDone: seconds(86400)}, stats.StatsCounts)
+
+	// Không có todo nào vẫn trả đủ ngày với số 0 và danh sách rỗng.
+	stats = computeStats(nil, statsTestQuery(t, "since=2024-12-09T00:00:00Z&until=2024-12-11T00:00:00Z"))
+	assert.Nil(t, stats.MedianSecondsToDone)
+	assert.Equal(t, []StatsBucket{{Start: "2024-12-09"}, {Start: "2024-12-10"}}, stats.PerDay)
+	assert.Equal(t, []StatsBucket{{Start: "2024-12-09"}}, stats.PerWeek)
+	assert.Equal(t, []StatsGroup{}, stats.Tags)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Nil(t, median(nil))
+	assert.Equal(t, 2.0, *median([]float64{3, 1, 2}))
+	assert.Equal(t, 2.5, *median([]float64{4, 1, 3, 2}))
+}
+
+func TestParseStatsQuery(t *testing.T) {
+	now := statsTime("2024-12-14T12:00:00Z")
+	q, err := parseStatsQuery(url.Values{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, now, q.Until)
+	assert.Equal(t, now.Add(-30*24*time.Hour), q.Since)
+	assert.Equal(t, time.UTC, q.Location)
+	assert.Nil(t, q.ListID)
+
+	for _, query := range []string{
+		"tz=Mars/Olympus",
+		"since=yesterday",
+		"until=2024-13-01",
+		"since=2024-12-10&until=2024-12-01",
+		"since=2023-01-01&until=2024-12-01",
+	} {
+		values, _ := url.ParseQuery(query)
+		_, err := parseStatsQuery(values, now)
+		assert.Error(t, err, query)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(statsTestTodos(), nil)
+	router := newStatsRouter(MemoryStatsStore{TodoStore: todoStore})
+
+	req, _ := http.NewRequest("GET", "/stats?since=2024-12-01&until=2024-12-14&tz=Asia/Ho_Chi_Minh", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	var stats Stats
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&stats))
+	assert.Equal(t, 4, stats.Created)
+	assert.Equal(t, 3, stats.Completed)
+	assert.Equal(t, 410400.0, *stats.MedianSecondsToDone)
+	assert.Len(t, stats.PerDay, 14)
+	assert.Len(t, stats.Lists, 2)
+}
+
+func TestGetStats_CSV(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(statsTestTodos(), nil)
+	router := newStatsRouter(MemoryStatsStore{TodoStore: todoStore})
+
+	for _, accept := range []string{"", "text/csv"} {
+		target := "/stats?since=2024-12-09&until=2024-12-10&tz=Asia/Ho_Chi_Minh"
+		if accept == "" {
+			target += "&format=csv"
+		}
+		req, _ := http.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			statsCSVColumns,
+			{"total", "", "2", "4", "2", "0", "2", "1069200"},
+			{"list", "", "1", "2", "1", "0", "1", "1728000"},
+			{"list", "kho", "1", "2", "1", "0", "1", "410400"},
+			{"tag", "gap", "1", "1", "1", "0", "0", ""},
+			{"tag", "kho", "0", "2", "0", "0", "1", "410400"},
+			{"day", "2024-12-09", "", "", "", "0", "0", ""},
+			{"day", "2024-12-10", "", "", "", "0", "2", ""},
+			{"week", "2024-12-09", "", "", "", "0", "2", ""},
+		}, records)
+	}
+}
+
+func TestGetStats_Errors(t *testing.T) {
+	for query, code := range map[string]int{
+		"format=xml":      http.StatusBadRequest,
+		"tz=Mars/Olympus": http.StatusBadRequest,
+		"since=soon":      http.StatusBadRequest,
+		"":                http.StatusInternalServerError,
+	} {
+		todoStore := new(MockTodoStore)
+		todoStore.On("GetAllTodoDB").Return([]Todo(nil), errors.New("connection refused"))
+		req, _ := http.NewRequest("GET", "/stats?"+query, nil)
+		rr := httptest.NewRecorder()
+		newStatsRouter(MemoryStatsStore{TodoStore: todoStore}).ServeHTTP(rr, req)
+		assert.Equal(t, code, rr.Code, query)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"api/escpos"
+)
+
+// ThermalPrinterPort là cổng in thô (JetDirect/RAW) mặc định của máy in mạng.
+const ThermalPrinterPort = "9100"
+
+// thermalPrinterTimeout giới hạn cả lúc kết nối lẫn lúc gửi: máy in hết giấy
+// hoặc kẹt giấy thường vẫn nhận kết nối nhưng không đọc dữ liệu.
+const thermalPrinterTimeout = 10 * time.Second
+
+// ThermalPrinter là một máy in nhiệt khai báo trong THERMAL_PRINTERS.
+type ThermalPrinter struct {
+	Name string
+	// Addr là host:port, cổng mặc định 9100.
+	Addr    string
+	Options escpos.Options
+}
+
+// ThermalPrintResponse is returned when a print is sent to a thermal printer.
+type ThermalPrintResponse struct {
+	Job     PrintJob `json:"job"`
+	Printer string   `json:"printer"`
+	Bytes   int      `json:"bytes"`
+}
+
+// ParseThermalPrinters đọc danh sách máy in dạng
+// "kho=192.168.1.50/58mm/cp1258,quay=192.168.1.51:9101": tên, địa chỉ (cổng
+// mặc định 9100) rồi khổ giấy và bảng mã tùy chọn, cách nhau bởi "/".
+func ParseThermalPrinters(s string) (map[string]ThermalPrinter, error) {
+	printers := map[string]ThermalPrinter{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("printer %q must be name=host[:port][/paper][/charset]", item)
+		}
+		if _, dup := printers[name]; dup {
+			return nil, fmt.Errorf("printer %q is declared twice", name)
+		}
+
+		parts := strings.Split(spec, "/")
+		addr := strings.TrimSpace(parts[0])
+		if addr == "" {
+			return nil, fmt.Errorf("printer %q has no address", name)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			addr = net.JoinHostPort(strings.Trim(addr, "[]"), ThermalPrinterPort)
+		}
+		printer := ThermalPrinter{Name: name, Addr: addr}
+		for _, opt := range parts[1:] {
+			if paper, err := escpos.ParsePaper(opt); err == nil {
+				printer.Options.Paper = paper
+			} else if charset, err := escpos.ParseCharset(opt); err == nil {
+				printer.Options.Charset = charset
+			} else {
+				return nil, fmt.Errorf("printer %q: unknown option %q, want a paper width or charset", name, opt)
+			}
+		}
+		printers[name] = printer
+	}
+	return printers, nil
+}
+
+// sendToPrinter gửi nguyên dòng byte ESC/POS tới máy in qua TCP rồi đóng kết
+// nối; máy in bắt đầu in khi nhận dữ liệu, không có phản hồi nào để chờ.
+func sendToPrinter(ctx context.Context, addr string, data []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, thermalPrinterTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(data); err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// writePrintESCPOS in job ra phiếu nhiệt: tiêu đề đậm, mô tả tự xuống dòng,
+// barcode short_code của từng todo, QR của mã job để tra lại qua
+// GET /prints/{id}, rồi cắt giấy. single là phiếu của một todo nên bỏ phần
+// đầu danh sách và in tiêu đề lớn.
+func writePrintESCPOS(w *bytes.Buffer, job PrintJob, single bool, opts escpos.Options) error {
+	b := escpos.New(opts)
+	printed := fmt.Sprintf("Printed %s UTC by %s", job.PrintedAt.UTC().Format("2006-01-02 15:04"), job.PrintedBy)
+
+	if !single {
+		b.SetAlign(escpos.AlignCenter)
+		b.SetBold(true)
+		b.SetSize(2, 2)
+		b.Paragraph("TODO LIST")
+		b.SetSize(1, 1)
+		b.SetBold(false)
+		b.Paragraph(fmt.Sprintf("Job #%d, %d todos", job.ID, len(job.Todos)))
+		b.Paragraph(printed)
+		if job.Query != "" {
+			b.Paragraph("Filter: " + job.Query)
+		}
+		b.SetAlign(escpos.AlignLeft)
+		b.Rule()
+		if len(job.Todos) == 0 {
+			b.Paragraph("No todos match this filter.")
+			b.Rule()
+		}
+	}
+
+	for _, todo := range job.Todos {
+		box := "[ ] "
+		if todo.Done {
+			box = "[x] "
+		}
+		b.SetAlign(escpos.AlignLeft)
+		b.SetBold(true)
+		if single {
+			b.SetSize(2, 2)
+		}
+		b.Paragraph(box + todo.Title)
+		b.SetSize(1, 1)
+		b.SetBold(false)
+		if todo.Desc != "" {
+			b.Paragraph(todo.Desc)
+		}
+		if due := printDue(todo); due != "" {
+			b.Paragraph("Due " + due)
+		}
+		if tags := printTags(todo); tags != "" {
+			b.Paragraph(tags)
+		}
+		if todo.ShortCode != "" {
+			b.SetAlign(escpos.AlignCenter)
+			if err := b.Barcode(todo.ShortCode); err != nil {
+				return err
+			}
+		}
+		b.SetAlign(escpos.AlignLeft)
+		b.Rule()
+	}
+
+	// QR thay cho Code128 vì mã job dài, không vừa khổ 58mm.
+	b.SetAlign(escpos.AlignCenter)
+	if single {
+		b.Paragraph(printed)
+	}
+	moduleSize := 6
+	if opts.Paper == escpos.Paper58 {
+		moduleSize = 4
+	}
+	if err := b.QR(job.Code, moduleSize); err != nil {
+		return err
+	}
+	b.Line(job.Code)
+	b.Feed(2)
+	b.Cut()
+
+	_, err := b.WriteTo(w)
+	return err
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"api/escpos"
+)
+
+// fakePrinter nghe trên một cổng TCP cục bộ như máy in cổng 9100 và trả về
+// toàn bộ byte nhận được của mỗi kết nối.
+func fakePrinter(t *testing.T) (string, <-chan []byte) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []byte, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			data, _ := io.ReadAll(conn)
+			conn.Close()
+			received <- data
+		}
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestParseThermalPrinters(t *testing.T) {
+	printers, err := ParseThermalPrinters(" kho=192.168.1.50/58mm/cp1258, quay=192.168.1.51:9101,,ipv6=[fe80::1] ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]ThermalPrinter{
+		"kho":  {Name: "kho", Addr: "192.168.1.50:9100", Options: escpos.Options{Paper: escpos.Paper58, Charset: escpos.CP1258}},
+		"quay": {Name: "quay", Addr: "192.168.1.51:9101"},
+		"ipv6": {Name: "ipv6", Addr: "[fe80::1]:9100"},
+	}, printers)
+
+	printers, err = ParseThermalPrinters("")
+	assert.NoError(t, err)
+	assert.Empty(t, printers)
+
+	for _, s := range []string{"192.168.1.50", "=192.168.1.50", "kho=", "kho=a,kho=b", "kho=a/110mm"} {
+		_, err := ParseThermalPrinters(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPrintTodos_ESCPOSDownload(t *testing.T) {
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(printTestTodos(), nil)
+	printStore := new(MockPrintStore)
+	printStore.On("CreatePrintJobDB", mock.MatchedBy(func(job PrintJob) bool {
+		return job.Format == "escpos" && job.Query == "done=false"
+	})).Return(printedJob(printTestTodos()[:1]), nil)
+
+	req, _ := http.NewRequest("GET", "/todos/print?format=escpos&paper=58mm&done=false", nil)
+	rr := httptest.NewRecorder()
+	newPrintRouter(todoStore, printStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="todos-42.bin"`, rr.Header().Get("Content-Disposition"))
+	assert.Equal(t, "42", rr.Header().Get("X-Print-Job"))
+
+	out := rr.Body.Bytes()
+	assert.True(t, bytes.HasPrefix(out, []byte("\x1b@\x1bt\x00")))
+	assert.True(t, bytes.HasSuffix(out, []byte("\x1dV\x42\x00")))
+	assert.Contains(t, string(out), "TODO LIST\n")
+	assert.Contains(t, string(out), "\x1bE\x01[ ] Kiem ke kho\n\x1d!\x00\x1bE\x00Dem lai ke A & B\nDue 2024-12-24 17:00\n#kho\n")
+	// Barcode short_code của todo và QR mã job, khổ 58mm dùng module QR 4 dot.
+	assert.Contains(t, string(out), "\x1dk\x49\x09{BT000001\n")
+	assert.Contains(t, string(out), "\x1d(k\x03\x001C\x04")
+	assert.Contains(t, string(out), "1P042-20241224093000\x1d(k")
+	assert.Contains(t, string(out), "--------------------------------\n")
+	assert.NotContains(t, string(out), "------------------------------------------------")
+}
+
+func TestPrintTodo_SendToPrinter(t *testing.T) {
+	addr, received := fakePrinter(t)
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetTodoByIdDB", "1").Return(printTestTodos()[0], nil)
+	printStore := new(MockPrintStore)
+	printStore.On("CreatePrintJobDB", mock.MatchedBy(func(job PrintJob) bool {
+		return job.Format == "escpos" && job.Query == "id=1" && job.PrintedBy == "kho-1" && len(job.Todos) == 1
+	})).Return(printedJob(printTestTodos()[:1]), nil)
+	printers := map[string]ThermalPrinter{"kho": {Name: "kho", Addr: addr, Options: escpos.Options{Paper: escpos.Paper58}}}
+
+	req, _ := http.NewRequest("POST", "/todo/1/print?printer=kho&charset=cp1258", nil)
+	req.Header.Set(ActorHeader, "kho-1")
+	rr := httptest.NewRecorder()
+	newPrintRouterWithPrinters(todoStore, printStore, printers).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "42", rr.Header().Get("X-Print-Job"))
+	var resp ThermalPrintResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "kho", resp.Printer)
+	assert.Equal(t, int64(42), resp.Job.ID)
+
+	select {
+	case out := <-received:
+		assert.Equal(t, resp.Bytes, len(out))
+		assert.True(t, bytes.HasPrefix(out, []byte("\x1b@\x1bt\x34")))
+		assert.True(t, bytes.HasSuffix(out, []byte("\x1dV\x42\x00")))
+		assert.NotContains(t, string(out), "TODO LIST")
+		// Phiếu một todo in tiêu đề cỡ gấp đôi; "Kiểm" theo Windows-1258.
+		assert.Contains(t, string(out), "\x1d!\x11[ ] Ki\xea\xd2m k\xea kho\n")
+		assert.Contains(t, string(out), "\x1dk\x49\x09{BT000001\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("printer received nothing")
+	}
+}
+
+func TestPrintTodos_PrinterErrors(t *testing.T) {
+	// Lấy một cổng rồi đóng ngay để chắc chắn không có máy in nào nghe.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down := ln.Addr().String()
+	ln.Close()
+
+	todoStore := new(MockTodoStore)
+	todoStore.On("GetAllTodoDB").Return(printTestTodos(), nil)
+	todoStore.On("GetTodoByIdDB", "9").Return(Todo{}, ErrTodoNotFound)
+	printStore := new(MockPrintStore)
+	printStore.On("CreatePrintJobDB", mock.Anything).Return(printedJob(printTestTodos()), nil)
+	router := newPrintRouterWithPrinters(todoStore, printStore, map[string]ThermalPrinter{"down": {Name: "down", Addr: down}})
+
+	for _, tc := range []struct {
+		method, target string
+		code           int
+	}{
+		{"POST", "/todos/print", http.StatusBadRequest},
+		{"POST", "/todos/print?printer=kho", http.StatusBadRequest},
+		{"POST", "/todos/print?printer=down&format=pdf", http.StatusBadRequest},
+		{"GET", "/todos/print?printer=down", http.StatusBadRequest},
+		{"GET", "/todos/print?format=escpos&paper=110", http.StatusBadRequest},
+		{"GET", "/todos/print?format=escpos&charset=utf-8", http.StatusBadRequest},
+		{"GET", "/todo/9/print?format=escpos", http.StatusNotFound},
+		{"POST", "/todos/print?printer=down", http.StatusBadGateway},
+	} {
+		req, _ := http.NewRequest(tc.method, tc.target, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, tc.code, rr.Code, "%s %s", tc.method, tc.target)
+	}
+}
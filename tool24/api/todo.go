@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +21,27 @@ type Todo struct {
 	Done      bool       `json:"done"`
 	CreatedAt time.Time  `json:"created_at" validate:"required"`
 	DoneAt    *time.Time `json:"done_at,omitempty"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedBy string     `json:"created_by,omitempty"`
+	Version   int        `json:"version"`
+	// Lặp lại: Recurrence là RRULE (xem rrule.go), các lần lặp cùng SeriesID.
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	Recurrence string     `json:"recurrence,omitempty"`
+	SeriesID   string     `json:"series_id,omitempty"`
+	PreviousID string     `json:"previous_id,omitempty"`
+	// RemindAt là các khoảng thời gian trước DueAt để nhắc, vd ["24h", "15m"].
+	RemindAt []string `json:"remind_at,omitempty"`
+	// ListID là danh sách chứa todo; rỗng là danh sách mặc định.
+	ListID string   `json:"list_id,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// ExternalID là ID của todo ở hệ thống nguồn khi nhập (CSV, iCalendar...),
+	// để nhập lại thì cập nhật thay vì tạo bản trùng. Chỉ đặt được khi tạo.
+	ExternalID string `json:"external_id,omitempty"`
+	// Payment là khoản cần thu khi todo dạng "thu tiền của X"; xem payment.go.
+	Payment *TodoPayment `json:"payment,omitempty"`
+	// ShortCode là mã ngắn (vd T000042) do database cấp khi tạo, để in thành
+	// barcode và quét qua POST /scan. Không sửa được.
+	ShortCode string `json:"short_code,omitempty"`
 }
 
 type TodoStore interface {
@@ -31,6 +53,30 @@ type TodoStore interface {
 	ChangeStatusDB(ctx context.Context, id string) error
 }
 
+// todoColumns liệt kê các cột theo đúng thứ tự mà scanTodo đọc.
+const todoColumns = "id, title, description, done, created_at, done_at, deleted_at, COALESCE(created_by, ''), version, " +
+	"due_at, COALESCE(recurrence, ''), COALESCE(series_id, ''), COALESCE(previous_id, ''), " +
+	"COALESCE(remind_at, '{}'), COALESCE(list_id, ''), COALESCE(tags, '{}'), COALESCE(external_id, ''), " +
+	"COALESCE(payment_bank_bin, ''), COALESCE(payment_account, ''), COALESCE(payment_amount, 0), COALESCE(payment_memo, ''), " +
+	"COALESCE(short_code, '')"
+
+func scanTodo(row pgx.Row, todo *Todo) error {
+	var payment TodoPayment
+	err := row.Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt, &todo.DeletedAt, &todo.CreatedBy, &todo.Version,
+		&todo.DueAt, &todo.Recurrence, &todo.SeriesID, &todo.PreviousID,
+		&todo.RemindAt, &todo.ListID, &todo.Tags, &todo.ExternalID,
+		&payment.BankBIN, &payment.AccountNumber, &payment.Amount, &payment.Memo, &todo.ShortCode)
+	if err != nil {
+		return err
+	}
+	if payment.BankBIN != "" {
+		todo.Payment = &payment
+	} else {
+		todo.Payment = nil
+	}
+	return nil
+}
+
 type Db struct {
 	Conn  *pgxpool.Pool
 	mutex sync.Mutex
@@ -41,7 +87,7 @@ func (db *Db) GetAllTodoDB(ctx context.Context) ([]Todo, error) {
 	var wg sync.WaitGroup
 
 	// Thêm ORDER BY vào truy vấn để sắp xếp theo ID
-	rows, err := db.Conn.Query(context.Background(), "SELECT * FROM todo ORDER BY id")
+	rows, err := db.Conn.Query(context.Background(), "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL ORDER BY id")
 	if err != nil {
 		return nil, err
 	}
@@ -49,7 +95,7 @@ func (db *Db) GetAllTodoDB(ctx context.Context) ([]Todo, error) {
 
 	for rows.Next() {
 		var todo Todo
-		err := rows.Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt)
+		err := scanTodo(rows, &todo)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -69,8 +115,7 @@ func (db *Db) GetAllTodoDB(ctx context.Context) ([]Todo, error) {
 
 func (db *Db) GetTodoByIdDB(ctx context.Context, id string) (Todo, error) {
 	var todo Todo
-	err := db.Conn.QueryRow(context.Background(), "SELECT * FROM todo WHERE id = $1", id).
-		Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt)
+	err := scanTodo(db.Conn.QueryRow(context.Background(), "SELECT "+todoColumns+" FROM todo WHERE id = $1 AND deleted_at IS NULL", id), &todo)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -83,8 +128,38 @@ func (db *Db) GetTodoByIdDB(ctx context.Context, id string) (Todo, error) {
 }
 
 func (db *Db) CreateTodoDB(ctx context.Context, todo Todo) (Todo, error) {
+	todo, err := newTodo(ctx, todo)
+	if err != nil {
+		return Todo{}, err
+	}
+
+	err = db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		todo, err = insertTodo(ctx, tx, todo)
+		return err
+	})
+
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// newTodo kiểm tra todo client gửi lên và điền các trường do server quyết định
+// trước khi insertTodo.
+func newTodo(ctx context.Context, todo Todo) (Todo, error) {
 	todo.ID = uuid.New().String()
 	todo.CreatedAt = time.Now()
+	todo.CreatedBy = ActorFromContext(ctx)
+	todo.SeriesID, todo.PreviousID = "", ""
+
+	if err := validateTodo(&todo); err != nil {
+		return Todo{}, err
+	}
+	if todo.Recurrence != "" {
+		todo.SeriesID = todo.ID
+	}
 
 	if todo.Done {
 		now := time.Now()
@@ -92,85 +167,228 @@ func (db *Db) CreateTodoDB(ctx context.Context, todo Todo) (Todo, error) {
 	} else {
 		todo.DoneAt = nil
 	}
+	return todo, nil
+}
 
-	err := db.Conn.QueryRow(ctx,
-		"INSERT INTO todo (id, title, description, done, created_at, done_at) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, title, description, done, created_at, done_at",
-		todo.ID, todo.Title, todo.Desc, todo.Done, todo.CreatedAt, todo.DoneAt).
-		Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt)
+// validateTodo kiểm tra và chuẩn hóa các trường client được phép đặt.
+func validateTodo(todo *Todo) error {
+	if todo.Recurrence != "" {
+		rule, err := ParseRRule(todo.Recurrence)
+		if err != nil {
+			return err
+		}
+		todo.Recurrence = rule.String()
+	}
+	if err := validateRemindAt(todo.RemindAt); err != nil {
+		return err
+	}
+	todo.Tags = normalizeTags(todo.Tags)
+	return validatePayment(todo.Payment)
+}
 
+// insertTodo thêm todo đã được điền ID, CreatedAt và ghi audit tạo mới.
+func insertTodo(ctx context.Context, tx pgx.Tx, todo Todo) (Todo, error) {
+	payment := paymentColumns(todo.ID, todo.Payment)
+	var created Todo
+	err := scanTodo(tx.QueryRow(ctx,
+		"INSERT INTO todo (id, title, description, done, created_at, done_at, created_by, due_at, recurrence, series_id, previous_id, remind_at, list_id, tags, external_id, "+
+			"payment_bank_bin, payment_account, payment_amount, payment_memo) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12, NULLIF($13, ''), $14, NULLIF($15, ''), $16, $17, $18, $19) RETURNING "+todoColumns,
+		todo.ID, todo.Title, todo.Desc, todo.Done, todo.CreatedAt, todo.DoneAt, todo.CreatedBy,
+		todo.DueAt, todo.Recurrence, todo.SeriesID, todo.PreviousID, todo.RemindAt, todo.ListID, todo.Tags, todo.ExternalID,
+		payment[0], payment[1], payment[2], payment[3]), &created)
 	if err != nil {
 		return Todo{}, err
 	}
 
-	return todo, nil
+	return created, recordChange(ctx, tx, AuditCreate, nil, &created)
 }
 
 func (db *Db) UpdateTodoDB(ctx context.Context, id string, todo Todo) (Todo, error) {
-	existingTodo, err := db.GetTodoByIdDB(ctx, id)
+	if err := validateTodo(&todo); err != nil {
+		return Todo{}, err
+	}
+
+	var updatedTodo Todo
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		existingTodo, err := getTodoForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(existingTodo, todo.Version); err != nil {
+			return err
+		}
+
+		updatedTodo, err = updateTodo(ctx, tx, existingTodo, todo)
+		return err
+	})
+
 	if err != nil {
 		return Todo{}, err
 	}
-	todo.CreatedAt = existingTodo.CreatedAt
 
-	if todo.Done {
+	return updatedTodo, nil
+}
+
+// updateTodo ghi các trường của todo (đã qua validateTodo) lên existingTodo đã
+// được khóa, ghi audit và sinh lần lặp tiếp theo nếu todo vừa xong.
+func updateTodo(ctx context.Context, tx pgx.Tx, existingTodo, todo Todo) (Todo, error) {
+	seriesID := ""
+	if todo.Recurrence != "" {
+		seriesID = existingTodo.SeriesID
+		if seriesID == "" {
+			seriesID = existingTodo.ID
+		}
+	}
+
+	if todo.Done && existingTodo.Done {
+		todo.DoneAt = existingTodo.DoneAt
+	} else if todo.Done {
 		now := time.Now()
 		todo.DoneAt = &now
 	} else {
 		todo.DoneAt = nil
 	}
 
+	payment := paymentColumns(existingTodo.ID, todo.Payment)
 	var updatedTodo Todo
-	err = db.Conn.QueryRow(context.Background(),
-		"UPDATE todo SET title=$1, description=$2, done=$3, done_at=$4 WHERE id=$5 RETURNING id, title, description, done, created_at, done_at",
-		todo.Title, todo.Desc, todo.Done, todo.DoneAt, id).
-		Scan(&updatedTodo.ID, &updatedTodo.Title, &updatedTodo.Desc, &updatedTodo.Done, &updatedTodo.CreatedAt, &updatedTodo.DoneAt)
-
+	err := scanTodo(tx.QueryRow(ctx,
+		"UPDATE todo SET title=$1, description=$2, done=$3, done_at=$4, due_at=$5, recurrence=NULLIF($6, ''), series_id=NULLIF($7, ''), "+
+			"remind_at=$8, list_id=NULLIF($9, ''), tags=$10, payment_bank_bin=$11, payment_account=$12, payment_amount=$13, payment_memo=$14, "+
+			"version=version+1 WHERE id=$15 RETURNING "+todoColumns,
+		todo.Title, todo.Desc, todo.Done, todo.DoneAt, todo.DueAt, todo.Recurrence, seriesID, todo.RemindAt, todo.ListID, todo.Tags,
+		payment[0], payment[1], payment[2], payment[3], existingTodo.ID), &updatedTodo)
 	if err != nil {
 		return Todo{}, err
 	}
+	if err := recordChange(ctx, tx, AuditUpdate, &existingTodo, &updatedTodo); err != nil {
+		return Todo{}, err
+	}
 
+	if !existingTodo.Done && updatedTodo.Done {
+		return updatedTodo, spawnNextOccurrence(ctx, tx, updatedTodo)
+	}
 	return updatedTodo, nil
 }
 
 func (db *Db) DeleteTodoByIdDB(ctx context.Context, id string) error {
+	return db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		existingTodo, err := getTodoForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
 
-	_, err := db.GetTodoByIdDB(ctx, id)
-	if err != nil {
-		return err
-	}
+		// Xóa mềm: todo được chuyển vào thùng rác, xem trash.go để khôi phục hoặc xóa hẳn.
+		var deletedTodo Todo
+		err = scanTodo(tx.QueryRow(ctx, "UPDATE todo SET deleted_at = now(), version = version + 1 WHERE id=$1 RETURNING "+todoColumns, id), &deletedTodo)
+		if err != nil {
+			return fmt.Errorf("failed to delete todo: %v", err)
+		}
 
-	_, err = db.Conn.Exec(context.Background(), "DELETE FROM todo WHERE id=$1", id)
-	if err != nil {
-		return fmt.Errorf("failed to delete todo: %v", err)
-	}
+		return recordChange(ctx, tx, AuditDelete, &existingTodo, &deletedTodo)
+	})
+}
 
+// checkVersion kiểm tra xung đột: version khác 0 là revision client đã đọc,
+// chỉ cho ghi khi todo (đã khóa) vẫn đang ở revision đó.
+func checkVersion(existingTodo Todo, version int) error {
+	if version != 0 && version != existingTodo.Version {
+		return fmt.Errorf("todo %s is at version %d, not %d: %w", existingTodo.ID, existingTodo.Version, version, ErrVersionConflict)
+	}
 	return nil
 }
 
+type expectedVersionKey struct{}
+
+// WithExpectedVersion returns a copy of ctx that makes ChangeStatusDB fail
+// with ErrVersionConflict unless the todo is still at version; 0 skips the
+// check, as with Todo.Version in UpdateTodoDB.
+func WithExpectedVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+func expectedVersionFromContext(ctx context.Context) int {
+	version, _ := ctx.Value(expectedVersionKey{}).(int)
+	return version
+}
+
 func (db *Db) ChangeStatusDB(ctx context.Context, id string) error {
-	var todo Todo
+	return db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		todo, err := getTodoForUpdate(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(todo, expectedVersionFromContext(ctx)); err != nil {
+			return err
+		}
 
-	err := db.Conn.QueryRow(ctx, "SELECT done FROM todo WHERE id = $1", id).Scan(&todo.Done)
-	if err != nil {
-		if err == pgx.ErrNoRows {
-			return fmt.Errorf("todo not found with ID %s: %w", id, ErrTodoNotFound)
+		newDoneStatus := !todo.Done
+		var doneAt interface{}
+		if newDoneStatus {
+			now := time.Now()
+			doneAt = now
+		} else {
+			doneAt = nil
 		}
-		return fmt.Errorf("failed to retrieve todo: %v", err)
-	}
 
-	newDoneStatus := !todo.Done
-	var doneAt interface{}
-	if newDoneStatus {
-		now := time.Now()
-		doneAt = now
-	} else {
-		doneAt = nil
+		var changedTodo Todo
+		err = scanTodo(tx.QueryRow(ctx, "UPDATE todo SET done = $1, done_at = $2, version = version + 1 WHERE id = $3 RETURNING "+todoColumns, newDoneStatus, doneAt, id), &changedTodo)
+		if err != nil {
+			return fmt.Errorf("failed to update todo status: %v", err)
+		}
+
+		if err := recordChange(ctx, tx, AuditStatus, &todo, &changedTodo); err != nil {
+			return err
+		}
+
+		if changedTodo.Done {
+			return spawnNextOccurrence(ctx, tx, changedTodo)
+		}
+		return nil
+	})
+}
+
+// recordChange chạy mọi tác vụ đi kèm một thay đổi todo trong cùng transaction:
+// ghi audit, đồng bộ lịch nhắc việc, ghi outbox webhook và báo cho các luồng sự kiện.
+func recordChange(ctx context.Context, tx pgx.Tx, operation string, before, after *Todo) error {
+	auditID, err := writeAudit(ctx, tx, operation, before, after)
+	if err != nil {
+		return err
+	}
+	if err := syncReminders(ctx, tx, before, after); err != nil {
+		return err
+	}
+	if err := writeOutbox(ctx, tx, before, after); err != nil {
+		return err
 	}
+	return notifyChange(ctx, tx, auditID)
+}
 
-	_, err = db.Conn.Exec(ctx, "UPDATE todo SET done = $1, done_at = $2 WHERE id = $3", newDoneStatus, doneAt, id)
+// getTodoForUpdate đọc và khóa một todo chưa bị xóa trong transaction tx.
+func getTodoForUpdate(ctx context.Context, tx pgx.Tx, id string) (Todo, error) {
+	var todo Todo
+	err := scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM todo WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", id), &todo)
 	if err != nil {
-		return fmt.Errorf("failed to update todo status: %v", err)
+		if err == pgx.ErrNoRows {
+			return Todo{}, fmt.Errorf("todo not found with ID %s: %w", id, ErrTodoNotFound)
+		}
+		return Todo{}, fmt.Errorf("failed to retrieve todo: %v", err)
 	}
 
-	return nil
+	return todo, nil
+}
+
+// normalizeTags bỏ khoảng trắng, chuyển về chữ thường và loại tag rỗng hoặc trùng.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
 }
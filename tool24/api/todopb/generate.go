@@ -0,0 +1,5 @@
+// Package todopb contains the protobuf definition of TodoService and the code
+// generated from it.
+package todopb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative todo.proto
@@ -0,0 +1,621 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Các định dạng của /todos/export và /todos/import.
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+)
+
+var formatContentTypes = map[string]string{
+	FormatCSV:    "text/csv",
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+}
+
+// exportColumns là các cột CSV khi xuất, theo thứ tự.
+var exportColumns = []string{"id", "external_id", "title", "description", "done", "created_at", "done_at", "due_at", "recurrence", "list_id", "tags", "remind_at"}
+
+// importFields là các trường đọc được khi nhập. Các cột do server quản lý
+// (id, created_at, done_at...) có trong file xuất được bỏ qua khi nhập lại.
+var importFields = map[string]bool{
+	"external_id": true, "title": true, "description": true, "done": true, "due_at": true,
+	"recurrence": true, "list_id": true, "tags": true, "remind_at": true,
+}
+
+const (
+	defaultImportBatchSize = 500
+	maxImportBatchSize     = 5000
+	maxImportBytes         = 32 << 20
+)
+
+// ImportRow là một todo đã đọc được từ file; Row là số thứ tự bản ghi (bắt đầu từ 1).
+type ImportRow struct {
+	Row  int
+	Todo Todo
+}
+
+type ImportOptions struct {
+	DryRun    bool
+	BatchSize int
+}
+
+type ImportRowError struct {
+	Row        int    `json:"row"`
+	ExternalID string `json:"external_id,omitempty"`
+	Error      string `json:"error"`
+}
+
+// ImportReport tóm tắt một lần nhập. Với dry run, các số liệu là những gì sẽ
+// xảy ra nhưng không có gì được ghi.
+type ImportReport struct {
+	DryRun    bool             `json:"dry_run"`
+	Total     int              `json:"total"`
+	Created   int              `json:"created"`
+	Updated   int              `json:"updated"`
+	Unchanged int              `json:"unchanged"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// TransferStore xuất và nhập todo hàng loạt.
+type TransferStore interface {
+	// ExportTodosDB gọi fn cho từng todo chưa bị xóa, đọc dần từ cursor để không
+	// giữ cả bảng trong bộ nhớ.
+	ExportTodosDB(ctx context.Context, fn func(Todo) error) error
+	ImportTodosDB(ctx context.Context, rows []ImportRow, opts ImportOptions) (ImportReport, error)
+}
+
+func (db *Db) ExportTodosDB(ctx context.Context, fn func(Todo) error) error {
+	rows, err := db.Conn.Query(ctx, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NULL ORDER BY created_at, id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return err
+		}
+		if err := fn(todo); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// ImportTodosDB ghi mọi dòng trong một transaction, nên lỗi DB giữa chừng
+// rollback cả lần nhập thay vì để lại các lô trước đã commit. Các dòng được ghi
+// theo từng lô BatchSize dòng, mỗi lô một savepoint; trong lô, mỗi dòng chạy
+// trong savepoint riêng nên dòng lỗi chỉ được ghi vào báo cáo mà không làm hỏng
+// cả lô. Dòng có ExternalID trùng một todo đã có sẽ cập nhật todo đó. Với
+// DryRun transaction được rollback.
+func (db *Db) ImportTodosDB(ctx context.Context, rows []ImportRow, opts ImportOptions) (ImportReport, error) {
+	report := ImportReport{DryRun: opts.DryRun, Total: len(rows), Errors: []ImportRowError{}}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatchSize
+	}
+
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback(ctx)
+
+	for start := 0; start < len(rows); start += opts.BatchSize {
+		batch := rows[start:min(start+opts.BatchSize, len(rows))]
+		batchReport, err := importBatch(ctx, tx, batch)
+		if err != nil {
+			return report, fmt.Errorf("import rows %d-%d: %w", batch[0].Row, batch[len(batch)-1].Row, err)
+		}
+		report.Created += batchReport.Created
+		report.Updated += batchReport.Updated
+		report.Unchanged += batchReport.Unchanged
+		report.Failed += batchReport.Failed
+		report.Errors = append(report.Errors, batchReport.Errors...)
+	}
+
+	if opts.DryRun {
+		return report, tx.Rollback(ctx)
+	}
+	return report, tx.Commit(ctx)
+}
+
+func importBatch(ctx context.Context, tx pgx.Tx, batch []ImportRow) (ImportReport, error) {
+	var report ImportReport
+	batchTx, err := tx.Begin(ctx)
+	if err != nil {
+		return report, err
+	}
+	defer batchTx.Rollback(ctx)
+
+	for _, row := range batch {
+		sp, err := batchTx.Begin(ctx)
+		if err != nil {
+			return report, err
+		}
+		result, err := importTodo(ctx, sp, row.Todo)
+		if err != nil {
+			sp.Rollback(ctx)
+			report.Failed++
+			report.Errors = append(report.Errors, ImportRowError{Row: row.Row, ExternalID: row.Todo.ExternalID, Error: err.Error()})
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return report, err
+		}
+		switch result {
+		case importCreated:
+			report.Created++
+		case importUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+	}
+
+	return report, batchTx.Commit(ctx)
+}
+
+type importResult int
+
+const (
+	importUnchanged importResult = iota
+	importCreated
+	importUpdated
+)
+
+// importTodo tạo todo mới hoặc cập nhật todo có cùng ExternalID. ExternalID
+// cũng khớp với ID của todo không có external_id, vì feed iCalendar dùng ID làm
+// UID cho các todo đó; nhờ vậy nhập lại feed của chính server không tạo bản trùng.
+func importTodo(ctx context.Context, tx pgx.Tx, todo Todo) (importResult, error) {
+	if todo.ExternalID != "" {
+		var existing Todo
+		err := scanTodo(tx.QueryRow(ctx, "SELECT "+todoColumns+" FROM todo WHERE external_id = $1 OR (id = $1 AND external_id IS NULL) "+
+			"ORDER BY external_id IS NULL LIMIT 1 FOR UPDATE", todo.ExternalID), &existing)
+		switch {
+		case err == nil:
+			if existing.DeletedAt != nil {
+				return importUnchanged, fmt.Errorf("todo %s with this external_id is in the trash", existing.ID)
+			}
+			if sameImportedFields(existing, todo) {
+				return importUnchanged, nil
+			}
+			// Các định dạng nhập không mang payment nên giữ payment của todo đang có.
+			todo.Payment = existing.Payment
+			_, err = updateTodo(ctx, tx, existing, todo)
+			return importUpdated, err
+		case err != pgx.ErrNoRows:
+			return importUnchanged, err
+		}
+	}
+
+	todo, err := newTodo(ctx, todo)
+	if err != nil {
+		return importUnchanged, err
+	}
+	_, err = insertTodo(ctx, tx, todo)
+	return importCreated, err
+}
+
+// sameImportedFields cho biết nhập lại todo có làm thay đổi gì không, để không
+// tăng version và ghi audit vô ích.
+func sameImportedFields(existing, todo Todo) bool {
+	sameTime := func(a, b *time.Time) bool {
+		return a == nil && b == nil || a != nil && b != nil && a.Equal(*b)
+	}
+	return existing.Title == todo.Title && existing.Desc == todo.Desc && existing.Done == todo.Done &&
+		sameTime(existing.DueAt, todo.DueAt) && existing.Recurrence == todo.Recurrence && existing.ListID == todo.ListID &&
+		slices.Equal(existing.Tags, todo.Tags) && slices.Equal(existing.RemindAt, todo.RemindAt)
+}
+
+type TransferHandler struct {
+	transferStore TransferStore
+}
+
+func NewTransferHandler(transferStore TransferStore) *TransferHandler {
+	return &TransferHandler{transferStore: transferStore}
+}
+
+// requestFormat lấy định dạng từ tham số format, hoặc từ header (Content-Type
+// khi nhập, Accept khi xuất), mặc định là JSON.
+func requestFormat(r *http.Request, header string) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", fmt.Errorf("unknown format %q, use csv, json or ndjson", format)
+		}
+		return format, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get(header)); err == nil {
+		for format, contentType := range formatContentTypes {
+			if mediaType == contentType {
+				return format, nil
+			}
+		}
+	}
+	return FormatJSON, nil
+}
+
+// @Summary Export todos
+// @Description Stream all todos as CSV, a JSON array or NDJSON
+// @Tags Todos
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "csv, json or ndjson" Enums(csv, json, ndjson)
+// @Success 200 {array} Todo "OK"
+// @Failure 400 {object} ErrorResponse "Unknown format"
+// @Router /todos/export [get]
+func (h *TransferHandler) ExportTodos(w http.ResponseWriter, r *http.Request) {
+	format, err := requestFormat(r, "Accept")
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", formatContentTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="todos.`+format+`"`)
+	bw := bufio.NewWriter(w)
+
+	var write func(Todo) error
+	finish := func() error { return nil }
+	switch format {
+	case FormatCSV:
+		cw := csv.NewWriter(bw)
+		if err := cw.Write(exportColumns); err != nil {
+			return
+		}
+		write = func(todo Todo) error { return cw.Write(todoCSVRecord(todo)) }
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case FormatNDJSON:
+		enc := json.NewEncoder(bw)
+		write = func(todo Todo) error { return enc.Encode(todo) }
+	default:
+		first := true
+		bw.WriteString("[")
+		write = func(todo Todo) error {
+			if !first {
+				bw.WriteString(",")
+			}
+			first = false
+			b, err := json.Marshal(todo)
+			if err != nil {
+				return err
+			}
+			_, err = bw.Write(b)
+			return err
+		}
+		finish = func() error {
+			_, err := bw.WriteString("]\n")
+			return err
+		}
+	}
+
+	// Header đã gửi đi nên lỗi giữa chừng chỉ có thể được ghi log; client sẽ
+	// nhận một file bị cắt ngang.
+	err = h.transferStore.ExportTodosDB(r.Context(), write)
+	if err == nil {
+		err = finish()
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		log.Printf("Xuất todo thất bại: %v", err)
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func todoCSVRecord(todo Todo) []string {
+	return []string{
+		todo.ID, todo.ExternalID, todo.Title, todo.Desc, strconv.FormatBool(todo.Done),
+		formatTime(&todo.CreatedAt), formatTime(todo.DoneAt), formatTime(todo.DueAt),
+		todo.Recurrence, todo.ListID, strings.Join(todo.Tags, ","), strings.Join(todo.RemindAt, ","),
+	}
+}
+
+// @Summary Import todos
+// @Description Create todos from CSV, a JSON array or NDJSON. Rows with an external_id that is already known update that todo instead. Invalid rows are listed in the report and skipped; the rest is written in one transaction, batch_size rows per savepoint, so a database error writes nothing.
+// @Tags Todos
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param format query string false "csv, json or ndjson; defaults to the Content-Type" Enums(csv, json, ndjson)
+// @Param map query []string false "Column mapping source:field, e.g. Task:title" collectionFormat(multi)
+// @Param dry_run query bool false "Validate and report without writing"
+// @Param batch_size query int false "Rows per savepoint, default 500"
+// @Success 200 {object} ImportReport "OK"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /todos/import [post]
+func (h *TransferHandler) ImportTodos(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	badRequest := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+
+	q := r.URL.Query()
+	format, err := requestFormat(r, "Content-Type")
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	mapping, err := parseColumnMapping(q["map"])
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	opts := ImportOptions{BatchSize: defaultImportBatchSize}
+	if v := q.Get("dry_run"); v != "" {
+		if opts.DryRun, err = strconv.ParseBool(v); err != nil {
+			badRequest(fmt.Errorf("invalid dry_run %q", v))
+			return
+		}
+	}
+	if v := q.Get("batch_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxImportBatchSize {
+			badRequest(fmt.Errorf("batch_size must be between 1 and %d", maxImportBatchSize))
+			return
+		}
+		opts.BatchSize = n
+	}
+
+	records, err := readRecords(http.MaxBytesReader(w, r.Body, maxImportBytes), format)
+	if err != nil {
+		badRequest(err)
+		return
+	}
+
+	var rows []ImportRow
+	var rowErrors []ImportRowError
+	for i, record := range records {
+		todo, err := recordToTodo(record, mapping)
+		if err == nil {
+			err = validateTodo(&todo)
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: i + 1, ExternalID: todo.ExternalID, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, ImportRow{Row: i + 1, Todo: todo})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	defer cancel()
+	report, err := h.transferStore.ImportTodosDB(ctx, rows, opts)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to import todos: " + err.Error()})
+		return
+	}
+
+	report.Total = len(records)
+	report.Failed += len(rowErrors)
+	report.Errors = append(report.Errors, rowErrors...)
+	sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Row < report.Errors[j].Row })
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+// parseColumnMapping đọc các tham số map=nguồn:trường, vd "Task:title".
+func parseColumnMapping(values []string) (map[string]string, error) {
+	mapping := map[string]string{}
+	for _, value := range values {
+		for _, pair := range strings.Split(value, ",") {
+			i := strings.LastIndex(pair, ":")
+			if i <= 0 {
+				return nil, fmt.Errorf("invalid map %q, use source:field", pair)
+			}
+			source, field := normalizeColumn(pair[:i]), normalizeColumn(pair[i+1:])
+			if !importFields[field] {
+				return nil, fmt.Errorf("invalid map %q: unknown field %q", pair, field)
+			}
+			mapping[source] = field
+		}
+	}
+	return mapping, nil
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// readRecords đọc toàn bộ file thành các bản ghi tên cột -> giá trị, để CSV và
+// JSON đi qua cùng một đường chuyển đổi.
+func readRecords(r io.Reader, format string) ([]map[string]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSVRecords(r)
+	case FormatNDJSON:
+		var records []map[string]string
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			record, err := jsonRecord(scanner.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			records = append(records, record)
+		}
+		return records, scanner.Err()
+	default:
+		var raw []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		records := make([]map[string]string, 0, len(raw))
+		for i, item := range raw {
+			record, err := jsonRecord(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i+1, err)
+			}
+			records = append(records, record)
+		}
+		return records, nil
+	}
+}
+
+func readCSVRecords(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+
+	var records []map[string]string
+	for {
+		values, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		record := map[string]string{}
+		for i, value := range values {
+			if i < len(header) {
+				record[normalizeColumn(header[i])] = value
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// jsonRecord chuyển một object JSON thành bản ghi: mảng được nối bằng dấu phẩy
+// giống cột CSV, null thành chuỗi rỗng.
+func jsonRecord(data []byte) (map[string]string, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	record := map[string]string{}
+	for key, value := range obj {
+		switch v := value.(type) {
+		case nil:
+			record[normalizeColumn(key)] = ""
+		case string:
+			record[normalizeColumn(key)] = v
+		case []interface{}:
+			parts := make([]string, 0, len(v))
+			for _, item := range v {
+				parts = append(parts, fmt.Sprint(item))
+			}
+			record[normalizeColumn(key)] = strings.Join(parts, ",")
+		default:
+			record[normalizeColumn(key)] = fmt.Sprint(v)
+		}
+	}
+	return record, nil
+}
+
+// recordToTodo áp mapping rồi đọc các trường đã biết của một bản ghi.
+func recordToTodo(record map[string]string, mapping map[string]string) (Todo, error) {
+	fields := map[string]string{}
+	for column, value := range record {
+		if field, ok := mapping[column]; ok {
+			fields[field] = value
+		} else if _, mapped := fields[column]; !mapped {
+			fields[column] = value
+		}
+	}
+
+	todo := Todo{
+		ExternalID: strings.TrimSpace(fields["external_id"]),
+		Title:      strings.TrimSpace(fields["title"]),
+		Desc:       fields["description"],
+		Recurrence: strings.TrimSpace(fields["recurrence"]),
+		ListID:     strings.TrimSpace(fields["list_id"]),
+		Tags:       splitList(fields["tags"]),
+		RemindAt:   splitList(fields["remind_at"]),
+	}
+	if todo.Title == "" {
+		return todo, errors.New("title is required")
+	}
+
+	done, err := parseDone(fields["done"])
+	if err != nil {
+		return todo, err
+	}
+	todo.Done = done
+
+	if v := strings.TrimSpace(fields["due_at"]); v != "" {
+		due, err := parseImportTime(v)
+		if err != nil {
+			return todo, err
+		}
+		todo.DueAt = &due
+	}
+	return todo, nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseDone nhận thêm các cách ghi thường gặp trong bảng tính như "x" hay "yes".
+func parseDone(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "0", "false", "no", "n":
+		return false, nil
+	case "1", "true", "yes", "y", "x", "done":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid done %q", s)
+}
+
+// parseImportTime nhận RFC3339, hoặc ngày/giờ không có múi giờ (coi là UTC).
+func parseImportTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid due_at %q", s)
+}
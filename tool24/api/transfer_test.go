@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransferStore struct {
+	mock.Mock
+}
+
+// ExportTodosDB gọi fn cho từng todo được cấu hình bằng On(...).Return(todos, err).
+func (m *MockTransferStore) ExportTodosDB(ctx context.Context, fn func(Todo) error) error {
+	args := m.Called()
+	for _, todo := range args.Get(0).([]Todo) {
+		if err := fn(todo); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockTransferStore) ImportTodosDB(ctx context.Context, rows []ImportRow, opts ImportOptions) (ImportReport, error) {
+	args := m.Called(rows, opts)
+	return args.Get(0).(ImportReport), args.Error(1)
+}
+
+func newTransferRouter(store TransferStore) *mux.Router {
+	h := NewTransferHandler(store)
+	router := mux.NewRouter()
+	router.HandleFunc("/todos/export", h.ExportTodos).Methods("GET")
+	router.HandleFunc("/todos/import", h.ImportTodos).Methods("POST")
+	return router
+}
+
+var exportedTodos = func() []Todo {
+	created := time.Date(2024, 12, 1, 8, 0, 0, 0, time.UTC)
+	due := time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)
+	return []Todo{
+		{ID: "1", Title: "Buy milk", Desc: "2 liters, semi-skimmed", CreatedAt: created, DueAt: &due, Tags: []string{"home", "shop"}},
+		{ID: "2", Title: "Call mom", Done: true, CreatedAt: created, ExternalID: "sheet-7"},
+	}
+}()
+
+func TestExportCSV(t *testing.T) {
+	store := new(MockTransferStore)
+	store.On("ExportTodosDB").Return(exportedTodos, nil)
+
+	req, _ := http.NewRequest("GET", "/todos/export?format=csv", nil)
+	rr := httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	records, err := csv.NewReader(rr.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, exportColumns, records[0])
+	assert.Equal(t, []string{"1", "", "Buy milk", "2 liters, semi-skimmed", "false", "2024-12-01T08:00:00Z", "", "2024-12-24T00:00:00Z", "", "", "home,shop", ""}, records[1])
+	assert.Equal(t, "sheet-7", records[2][1])
+}
+
+func TestExportJSONAndNDJSON(t *testing.T) {
+	store := new(MockTransferStore)
+	store.On("ExportTodosDB").Return(exportedTodos, nil)
+
+	req, _ := http.NewRequest("GET", "/todos/export", nil)
+	rr := httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	var todos []Todo
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &todos))
+	assert.Len(t, todos, 2)
+
+	req, _ = http.NewRequest("GET", "/todos/export", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	rr = httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	assert.Equal(t, 2, strings.Count(rr.Body.String(), "\n"))
+}
+
+func TestExportEmptyJSONIsArray(t *testing.T) {
+	store := new(MockTransferStore)
+	store.On("ExportTodosDB").Return([]Todo{}, nil)
+
+	req, _ := http.NewRequest("GET", "/todos/export?format=json", nil)
+	rr := httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	assert.JSONEq(t, `[]`, rr.Body.String())
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/todos/export?format=xlsx", nil)
+	rr := httptest.NewRecorder()
+	newTransferRouter(new(MockTransferStore)).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestImportCSVWithMappingAndRowErrors(t *testing.T) {
+	body := "Task,Notes,Done,Due,Tags,ID\n" +
+		"Buy milk,2 liters,x,2024-12-24,\"home, shop\",sheet-1\n" +
+		",no title,,,,sheet-2\n" +
+		"Pay rent,,no,someday,,sheet-3\n" +
+		"Water plants,,,,,\n"
+
+	store := new(MockTransferStore)
+	due := time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)
+	store.On("ImportTodosDB", mock.MatchedBy(func(rows []ImportRow) bool {
+		return len(rows) == 2 &&
+			rows[0].Row == 1 && rows[0].Todo.Title == "Buy milk" && rows[0].Todo.Desc == "2 liters" && rows[0].Todo.Done &&
+			rows[0].Todo.DueAt.Equal(due) && rows[0].Todo.ExternalID == "sheet-1" &&
+			assert.ObjectsAreEqual([]string{"home", "shop"}, rows[0].Todo.Tags) &&
+			rows[1].Row == 4 && rows[1].Todo.Title == "Water plants"
+	}), ImportOptions{BatchSize: 100}).
+		Return(ImportReport{Created: 1, Updated: 1, Errors: []ImportRowError{}}, nil)
+
+	req, _ := http.NewRequest("POST", "/todos/import?format=csv&map=Task:title,Notes:description&map=Due:due_at&map=ID:external_id&batch_size=100", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.Equal(t, 4, report.Total)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, []ImportRowError{
+		{Row: 2, ExternalID: "sheet-2", Error: "title is required"},
+		{Row: 3, ExternalID: "sheet-3", Error: `invalid due_at "someday"`},
+	}, report.Errors)
+	store.AssertExpectations(t)
+}
+
+func TestImportNDJSONDryRun(t *testing.T) {
+	body := `{"title":"A","tags":["Work"," work "],"done":true,"external_id":"a"}
+
+{"title":"B","recurrence":"FREQ=SOMETIMES"}
+`
+	store := new(MockTransferStore)
+	store.On("ImportTodosDB", mock.MatchedBy(func(rows []ImportRow) bool {
+		return len(rows) == 1 && rows[0].Todo.Done && assert.ObjectsAreEqual([]string{"work"}, rows[0].Todo.Tags)
+	}), ImportOptions{DryRun: true, BatchSize: defaultImportBatchSize}).
+		Return(ImportReport{DryRun: true, Created: 1, Errors: []ImportRowError{}}, nil)
+
+	req, _ := http.NewRequest("POST", "/todos/import?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rr := httptest.NewRecorder()
+	newTransferRouter(store).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var report ImportReport
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, 2, report.Errors[0].Row)
+	store.AssertExpectations(t)
+}
+
+func TestImportRejectsBadRequests(t *testing.T) {
+	for _, target := range []string{
+		"/todos/import?format=xml",
+		"/todos/import?map=Task:owner",
+		"/todos/import?map=Task",
+		"/todos/import?batch_size=0",
+		"/todos/import?dry_run=maybe",
+	} {
+		req, _ := http.NewRequest("POST", target, strings.NewReader(`[]`))
+		rr := httptest.NewRecorder()
+		newTransferRouter(new(MockTransferStore)).ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+	}
+
+	req, _ := http.NewRequest("POST", "/todos/import", strings.NewReader(`{"title":"not an array"}`))
+	rr := httptest.NewRecorder()
+	newTransferRouter(new(MockTransferStore)).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestSameImportedFields(t *testing.T) {
+	due := time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC)
+	localDue := due.In(time.FixedZone("ICT", 7*3600))
+	existing := Todo{ID: "1", Title: "A", DueAt: &localDue, Tags: []string{"x"}, Version: 3}
+
+	assert.True(t, sameImportedFields(existing, Todo{Title: "A", DueAt: &due, Tags: []string{"x"}}))
+	assert.False(t, sameImportedFields(existing, Todo{Title: "A", Tags: []string{"x"}}))
+	assert.False(t, sameImportedFields(existing, Todo{Title: "A", DueAt: &due, Tags: []string{"x"}, Done: true}))
+}
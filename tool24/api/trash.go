@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// TrashStore manages soft-deleted todos. DeleteTodoByIdDB only moves a todo
+// to the trash; these methods list, restore or permanently remove them.
+type TrashStore interface {
+	GetTrashDB(ctx context.Context) ([]Todo, error)
+	RestoreTodoDB(ctx context.Context, id string) (Todo, error)
+	PurgeTodoByIdDB(ctx context.Context, id string) error
+	PurgeTrashDB(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+func (db *Db) GetTrashDB(ctx context.Context) ([]Todo, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT "+todoColumns+" FROM todo WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+func (db *Db) RestoreTodoDB(ctx context.Context, id string) (Todo, error) {
+	var todo Todo
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var deletedTodo Todo
+		err := scanTodo(tx.QueryRow(ctx,
+			"SELECT "+todoColumns+" FROM todo WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE", id), &deletedTodo)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("todo not found in trash with ID %s: %w", id, ErrTodoNotFound)
+			}
+			return fmt.Errorf("failed to restore todo: %v", err)
+		}
+
+		err = scanTodo(tx.QueryRow(ctx, "UPDATE todo SET deleted_at = NULL, version = version + 1 WHERE id = $1 RETURNING "+todoColumns, id), &todo)
+		if err != nil {
+			return fmt.Errorf("failed to restore todo: %v", err)
+		}
+
+		return recordChange(ctx, tx, AuditRestore, &deletedTodo, &todo)
+	})
+
+	if err != nil {
+		return Todo{}, err
+	}
+
+	return todo, nil
+}
+
+func (db *Db) PurgeTodoByIdDB(ctx context.Context, id string) error {
+	return db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var todo Todo
+		err := scanTodo(tx.QueryRow(ctx, "DELETE FROM todo WHERE id = $1 RETURNING "+todoColumns, id), &todo)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("todo not found with ID %s: %w", id, ErrTodoNotFound)
+			}
+			return fmt.Errorf("failed to purge todo: %v", err)
+		}
+
+		return recordChange(ctx, tx, AuditPurge, &todo, nil)
+	})
+}
+
+func (db *Db) PurgeTrashDB(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	ctx = WithActor(ctx, "system")
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, "DELETE FROM todo WHERE deleted_at IS NOT NULL AND deleted_at < $1 RETURNING "+todoColumns, deletedBefore)
+		if err != nil {
+			return err
+		}
+
+		var todos []Todo
+		for rows.Next() {
+			var todo Todo
+			if err := scanTodo(rows, &todo); err != nil {
+				rows.Close()
+				return err
+			}
+			todos = append(todos, todo)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for i := range todos {
+			if err := recordChange(ctx, tx, AuditPurge, &todos[i], nil); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(todos))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %v", err)
+	}
+
+	return purged, nil
+}
+
+type TrashHandler struct {
+	trashStore TrashStore
+}
+
+func NewTrashHandler(trashStore TrashStore) *TrashHandler {
+	return &TrashHandler{trashStore: trashStore}
+}
+
+// @Summary List deleted todos
+// @Description Retrieve all soft-deleted todo items, most recently deleted first
+// @Tags Trash
+// @Produce json
+// @Success 200 {array} Todo "OK"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /trash [get]
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	todos, err := h.trashStore.GetTrashDB(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get trash: " + err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(todos)
+}
+
+// @Summary Restore a deleted todo
+// @Description Move a todo item out of the trash
+// @Tags Trash
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} Todo "Restored"
+// @Failure 404 {object} ErrorResponse "Todo not found in trash"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /todo/{id}/restore [post]
+func (h *TrashHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	todo, err := h.trashStore.RestoreTodoDB(ctx, idStr)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Todo not found in trash with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to restore todo: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(todo)
+}
+
+// PurgeTodo handles DELETE /todo/{id}?permanent=true and removes the todo
+// from the database whether or not it is in the trash.
+func (h *TrashHandler) PurgeTodo(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	err := h.trashStore.PurgeTodoByIdDB(ctx, idStr)
+	if err != nil {
+		if errors.Is(err, ErrTodoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Todo not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete todo: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunTrashPurger hard-deletes todos that have been in the trash longer than
+// retention, checking every interval until ctx is cancelled.
+func RunTrashPurger(ctx context.Context, trashStore TrashStore, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := trashStore.PurgeTrashDB(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Không thể dọn thùng rác: %v", err)
+		} else if n > 0 {
+			log.Printf("Đã xóa vĩnh viễn %d todo trong thùng rác", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTrashStore struct {
+	mock.Mock
+}
+
+func (m *MockTrashStore) GetTrashDB(ctx context.Context) ([]Todo, error) {
+	args := m.Called()
+	return args.Get(0).([]Todo), args.Error(1)
+}
+
+func (m *MockTrashStore) RestoreTodoDB(ctx context.Context, id string) (Todo, error) {
+	args := m.Called(id)
+	return args.Get(0).(Todo), args.Error(1)
+}
+
+func (m *MockTrashStore) PurgeTodoByIdDB(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTrashStore) PurgeTrashDB(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	args := m.Called(deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func newTrashRouter(todoStore TodoStore, trashStore TrashStore) *mux.Router {
+	h := NewTodoHandler(todoStore)
+	th := NewTrashHandler(trashStore)
+	router := mux.NewRouter()
+	router.HandleFunc("/todo/{id}", th.PurgeTodo).Methods("DELETE").Queries("permanent", "true")
+	router.HandleFunc("/todo/{id}", h.DeleteTodoByID).Methods("DELETE")
+	router.HandleFunc("/trash", th.GetTrash).Methods("GET")
+	router.HandleFunc("/todo/{id}/restore", th.RestoreTodo).Methods("POST")
+	return router
+}
+
+func TestGetTrash(t *testing.T) {
+	deletedAt := time.Now()
+	mockTrash := new(MockTrashStore)
+	mockTrash.On("GetTrashDB").Return([]Todo{{ID: "1", Title: "Todo 1", DeletedAt: &deletedAt}}, nil)
+
+	req, _ := http.NewRequest("GET", "/trash", nil)
+	rr := httptest.NewRecorder()
+	newTrashRouter(new(MockTodoStore), mockTrash).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	assert.Contains(t, rr.Body.String(), `"deleted_at"`)
+	mockTrash.AssertExpectations(t)
+}
+
+func TestRestoreTodo(t *testing.T) {
+	mockTrash := new(MockTrashStore)
+	mockTrash.On("RestoreTodoDB", "1").Return(Todo{ID: "1", Title: "Todo 1"}, nil)
+
+	req, _ := http.NewRequest("POST", "/todo/1/restore", nil)
+	rr := httptest.NewRecorder()
+	newTrashRouter(new(MockTodoStore), mockTrash).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Expected status code 200")
+	mockTrash.AssertExpectations(t)
+}
+
+func TestRestoreTodo_NotFound(t *testing.T) {
+	mockTrash := new(MockTrashStore)
+	mockTrash.On("RestoreTodoDB", "999").Return(Todo{}, ErrTodoNotFound)
+
+	req, _ := http.NewRequest("POST", "/todo/999/restore", nil)
+	rr := httptest.NewRecorder()
+	newTrashRouter(new(MockTodoStore), mockTrash).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Expected status code 404")
+	mockTrash.AssertExpectations(t)
+}
+
+func TestDeleteTodo_Permanent(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockTrash := new(MockTrashStore)
+	mockTrash.On("PurgeTodoByIdDB", "1").Return(nil)
+
+	req, _ := http.NewRequest("DELETE", "/todo/1?permanent=true", nil)
+	rr := httptest.NewRecorder()
+	newTrashRouter(mockStore, mockTrash).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code, "Expected status code 204")
+	mockTrash.AssertExpectations(t)
+	mockStore.AssertNotCalled(t, "DeleteTodoByIdDB", "1")
+}
+
+func TestDeleteTodo_SoftByDefault(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("DeleteTodoByIdDB", "1").Return(nil)
+	mockTrash := new(MockTrashStore)
+
+	req, _ := http.NewRequest("DELETE", "/todo/1", nil)
+	rr := httptest.NewRecorder()
+	newTrashRouter(mockStore, mockTrash).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code, "Expected status code 204")
+	mockStore.AssertExpectations(t)
+	mockTrash.AssertNotCalled(t, "PurgeTodoByIdDB", "1")
+}
+
+func TestRunTrashPurger(t *testing.T) {
+	mockTrash := new(MockTrashStore)
+	purged := make(chan time.Time, 1)
+	mockTrash.On("PurgeTrashDB", mock.Anything).Return(int64(2), nil).Run(func(args mock.Arguments) {
+		select {
+		case purged <- args.Get(0).(time.Time):
+		default:
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunTrashPurger(ctx, mockTrash, 24*time.Hour, time.Hour)
+		close(done)
+	}()
+
+	select {
+	case before := <-purged:
+		assert.WithinDuration(t, time.Now().Add(-24*time.Hour), before, time.Minute)
+	case <-time.After(time.Second):
+		t.Fatal("purger did not run")
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,376 @@
+package vietqr
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrInvalidDirectory is wrapped by every error of LoadDirectory.
+	ErrInvalidDirectory = errors.New("invalid bank directory")
+	// ErrUnknownBank means no bank matches the input.
+	ErrUnknownBank = errors.New("unknown bank")
+	// ErrAmbiguousBank means several banks match the input equally well.
+	ErrAmbiguousBank = errors.New("ambiguous bank")
+)
+
+// Bank is one NAPAS member that VietQR transfers can be sent to.
+type Bank struct {
+	// BIN is the 6-digit NAPAS bank identification number used in payloads.
+	BIN string `json:"bin"`
+	// Code is the short code banks use among themselves, e.g. VCB.
+	Code      string `json:"code"`
+	ShortName string `json:"short_name"`
+	Name      string `json:"name"`
+	// SWIFT is the BIC, empty for digital-only banks that have none.
+	SWIFT string `json:"swift,omitempty"`
+}
+
+// Directory is a versioned list of banks. Use DefaultDirectory for the copy
+// bundled in the binary and LoadDirectory to read an updated one.
+type Directory struct {
+	Version string `json:"version"`
+	Banks   []Bank `json:"banks"`
+
+	// Các chỉ mục dựng một lần khi load; Directory không đổi sau đó.
+	byBIN map[string]int
+	keys  []bankKeys
+}
+
+// bankKeys là các trường của một ngân hàng đã chuẩn hóa bằng fold để tìm kiếm.
+type bankKeys struct {
+	bin, code, shortName, name, swift string
+	// compact là short name bỏ khoảng trắng, để "viet com bank" khớp "vietcombank".
+	compact string
+	words   []string
+}
+
+//go:embed banks.json
+var bundledDirectory []byte
+
+var defaultDirectory = func() *Directory {
+	d, err := LoadDirectory(strings.NewReader(string(bundledDirectory)))
+	if err != nil {
+		panic(err)
+	}
+	return d
+}()
+
+// DefaultDirectory returns the directory bundled at build time.
+func DefaultDirectory() *Directory {
+	return defaultDirectory
+}
+
+// LoadDirectory reads and validates a directory in the JSON format of
+// banks.json: a version and banks with unique 6-digit BINs.
+func LoadDirectory(r io.Reader) (*Directory, error) {
+	var d Directory
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDirectory, err)
+	}
+	if d.Version == "" {
+		return nil, fmt.Errorf("%w: version is required", ErrInvalidDirectory)
+	}
+	if len(d.Banks) == 0 {
+		return nil, fmt.Errorf("%w: banks is empty", ErrInvalidDirectory)
+	}
+
+	d.byBIN = make(map[string]int, len(d.Banks))
+	d.keys = make([]bankKeys, len(d.Banks))
+	for i, b := range d.Banks {
+		switch {
+		case len(b.BIN) != 6 || !isDigits(b.BIN):
+			return nil, fmt.Errorf("%w: bank %d: bin must be 6 digits", ErrInvalidDirectory, i)
+		case b.Code == "" || b.ShortName == "" || b.Name == "":
+			return nil, fmt.Errorf("%w: bank %s: code, short_name and name are required", ErrInvalidDirectory, b.BIN)
+		case b.SWIFT != "" && ((len(b.SWIFT) != 8 && len(b.SWIFT) != 11) || !isAlphanumeric(b.SWIFT)):
+			return nil, fmt.Errorf("%w: bank %s: swift must be 8 or 11 letters or digits", ErrInvalidDirectory, b.BIN)
+		}
+		if _, ok := d.byBIN[b.BIN]; ok {
+			return nil, fmt.Errorf("%w: bin %s appears more than once", ErrInvalidDirectory, b.BIN)
+		}
+		d.byBIN[b.BIN] = i
+
+		shortName := fold(b.ShortName)
+		name := fold(b.Name)
+		d.keys[i] = bankKeys{
+			bin:       b.BIN,
+			code:      fold(b.Code),
+			shortName: shortName,
+			name:      name,
+			swift:     fold(b.SWIFT),
+			compact:   strings.ReplaceAll(shortName, " ", ""),
+			words:     strings.Fields(shortName + " " + name),
+		}
+	}
+	return &d, nil
+}
+
+// LoadDirectoryFile is LoadDirectory for a file on disk.
+func LoadDirectoryFile(path string) (*Directory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadDirectory(f)
+}
+
+// Lookup returns the bank with the given BIN.
+func (d *Directory) Lookup(bin string) (Bank, bool) {
+	i, ok := d.byBIN[bin]
+	if !ok {
+		return Bank{}, false
+	}
+	return d.Banks[i], true
+}
+
+// fold chuẩn hóa chuỗi để so khớp: chữ thường, bỏ dấu tiếng Việt (kể cả đ),
+// các ký tự không phải chữ hoặc số thành một khoảng trắng.
+func fold(s string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ' || r == 'Đ':
+			r = 'd'
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			r = unicode.ToLower(r)
+		default:
+			if !space {
+				b.WriteByte(' ')
+				space = true
+			}
+			continue
+		}
+		b.WriteRune(r)
+		space = false
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Mức khớp của Search, nhỏ hơn là tốt hơn.
+const (
+	matchExact = iota
+	matchExactName
+	matchPrefix
+	matchWords
+	matchSubstring
+	matchFuzzy
+	noMatch
+)
+
+// match chấm điểm một ngân hàng theo truy vấn đã fold.
+func (k bankKeys) match(query string) int {
+	compactQuery := strings.ReplaceAll(query, " ", "")
+	switch {
+	case query == k.bin || query == k.code || query == k.shortName || compactQuery == k.compact || query == k.swift:
+		return matchExact
+	case query == k.name:
+		return matchExactName
+	case strings.HasPrefix(k.bin, query) || strings.HasPrefix(k.code, query) || strings.HasPrefix(k.compact, compactQuery) || strings.HasPrefix(k.name, query):
+		return matchPrefix
+	}
+
+	// Mọi từ của truy vấn là tiền tố của một từ trong tên, vd "ngoai thuong".
+	tokens := strings.Fields(query)
+	if everyToken(tokens, func(t string) bool { return anyWord(k.words, func(w string) bool { return strings.HasPrefix(w, t) }) }) {
+		return matchWords
+	}
+	if strings.Contains(k.compact, compactQuery) || strings.Contains(k.name, query) {
+		return matchSubstring
+	}
+	// Cho phép gõ sai: mỗi từ cách một từ trong tên không quá maxTypos ký tự.
+	if len(compactQuery) >= 4 && editDistance(compactQuery, k.compact) <= maxTypos(compactQuery) {
+		return matchFuzzy
+	}
+	if everyToken(tokens, func(t string) bool {
+		return len(t) >= 4 && anyWord(k.words, func(w string) bool { return editDistance(t, w) <= maxTypos(t) })
+	}) {
+		return matchFuzzy
+	}
+	return noMatch
+}
+
+func everyToken(tokens []string, ok func(string) bool) bool {
+	for _, t := range tokens {
+		if !ok(t) {
+			return false
+		}
+	}
+	return len(tokens) > 0
+}
+
+func anyWord(words []string, ok func(string) bool) bool {
+	for _, w := range words {
+		if ok(w) {
+			return true
+		}
+	}
+	return false
+}
+
+func maxTypos(s string) int {
+	if len(s) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+// editDistance là khoảng cách Levenshtein giữa hai chuỗi đã fold (ASCII).
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Search returns the banks matching query, best matches first. Matching
+// ignores case, Vietnamese diacritics and punctuation, accepts prefixes of
+// any word of the name and tolerates small typos. An empty query returns
+// every bank; limit <= 0 means no limit.
+func (d *Directory) Search(query string, limit int) []Bank {
+	results := d.search(fold(query))
+	banks := make([]Bank, 0, len(results))
+	for _, r := range results {
+		if limit > 0 && len(banks) == limit {
+			break
+		}
+		banks = append(banks, d.Banks[r.index])
+	}
+	return banks
+}
+
+type searchResult struct {
+	index int
+	score int
+}
+
+func (d *Directory) search(query string) []searchResult {
+	var results []searchResult
+	for i, k := range d.keys {
+		score := matchExact
+		if query != "" {
+			score = k.match(query)
+		}
+		if score != noMatch {
+			results = append(results, searchResult{i, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].score != results[j].score {
+			return results[i].score < results[j].score
+		}
+		return d.keys[results[i].index].shortName < d.keys[results[j].index].shortName
+	})
+	// Chỉ đoán lỗi gõ khi không có kết quả nào khớp thật, để "cong thuong" không
+	// kéo theo "phuong dong".
+	if len(results) > 0 && results[0].score < matchFuzzy {
+		for i, r := range results {
+			if r.score == matchFuzzy {
+				return results[:i]
+			}
+		}
+	}
+	return results
+}
+
+// Resolve turns what a user typed into a bank: a BIN, code, SWIFT code,
+// short or full name, with or without diacritics. It fails with
+// ErrUnknownBank or ErrAmbiguousBank rather than guess between several
+// equally good matches.
+func (d *Directory) Resolve(input string) (Bank, error) {
+	input = strings.TrimSpace(input)
+	if b, ok := d.Lookup(input); ok {
+		return b, nil
+	}
+	results := d.search(fold(input))
+	if fold(input) == "" || len(results) == 0 {
+		return Bank{}, fmt.Errorf("%w: %q", ErrUnknownBank, input)
+	}
+	best := results[0].score
+	var tied []string
+	for _, r := range results {
+		if r.score == best {
+			tied = append(tied, d.Banks[r.index].ShortName)
+		}
+	}
+	if len(tied) > 1 {
+		return Bank{}, fmt.Errorf("%w: %q matches %s", ErrAmbiguousBank, input, strings.Join(tied, ", "))
+	}
+	return d.Banks[results[0].index], nil
+}
+
+// Registry holds the directory in use and lets it be replaced while
+// requests read it, e.g. when the JSON file on disk is updated.
+type Registry struct {
+	current atomic.Pointer[Directory]
+}
+
+// NewRegistry returns a registry serving d.
+func NewRegistry(d *Directory) *Registry {
+	r := &Registry{}
+	r.Set(d)
+	return r
+}
+
+// Directory returns the current directory.
+func (r *Registry) Directory() *Directory {
+	return r.current.Load()
+}
+
+// Set replaces the current directory.
+func (r *Registry) Set(d *Directory) {
+	r.current.Store(d)
+}
+
+// Resolve fills BankBIN from Bank, which may be a BIN, code or bank name,
+// and checks that the BIN belongs to a bank of d. Payload still validates
+// the rest of p.
+func (p Payment) Resolve(d *Directory) (Payment, error) {
+	if p.Bank != "" {
+		b, err := d.Resolve(p.Bank)
+		if err != nil {
+			return p, fmt.Errorf("%w: %v", ErrInvalidPayment, err)
+		}
+		if p.BankBIN != "" && p.BankBIN != b.BIN {
+			return p, fmt.Errorf("%w: bank %q has BIN %s, not %s", ErrInvalidPayment, p.Bank, b.BIN, p.BankBIN)
+		}
+		p.BankBIN = b.BIN
+		return p, nil
+	}
+	if p.BankBIN == "" {
+		return p, fmt.Errorf("%w: bank or bank_bin is required", ErrInvalidPayment)
+	}
+	if _, ok := d.Lookup(p.BankBIN); !ok && len(p.BankBIN) == 6 && isDigits(p.BankBIN) {
+		return p, fmt.Errorf("%w: no bank has BIN %s", ErrInvalidPayment, p.BankBIN)
+	}
+	return p, nil
+}
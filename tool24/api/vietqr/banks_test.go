@@ -0,0 +1,131 @@
+package vietqr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func shortNames(banks []Bank) []string {
+	names := make([]string, len(banks))
+	for i, b := range banks {
+		names[i] = b.ShortName
+	}
+	return names
+}
+
+func TestDefaultDirectory(t *testing.T) {
+	d := DefaultDirectory()
+	assert.NotEmpty(t, d.Version)
+
+	b, ok := d.Lookup("970436")
+	assert.True(t, ok)
+	assert.Equal(t, Bank{BIN: "970436", Code: "VCB", ShortName: "Vietcombank", Name: "Ngân hàng TMCP Ngoại Thương Việt Nam", SWIFT: "BFTVVNVX"}, b)
+	_, ok = d.Lookup("000000")
+	assert.False(t, ok)
+}
+
+func TestFold(t *testing.T) {
+	assert.Equal(t, "ngan hang tmcp dau tu va phat trien viet nam", fold("Ngân hàng TMCP Đầu tư và Phát triển Việt Nam"))
+	assert.Equal(t, "sai gon ha noi", fold("  Sài Gòn - Hà Nội! "))
+}
+
+func TestSearch(t *testing.T) {
+	d := DefaultDirectory()
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"vietcombank", "Vietcombank"},
+		{"VCB", "Vietcombank"},
+		{"viet com bank", "Vietcombank"},
+		{"ngoai thuong", "Vietcombank"},
+		{"Ngoại Thương", "Vietcombank"},
+		{"BFTVVNVX", "Vietcombank"},
+		{"970436", "Vietcombank"},
+		{"techcom", "Techcombank"},
+		{"techcombnk", "Techcombank"},
+		{"dau tu phat trien", "BIDV"},
+		{"nong nghiep", "Agribank"},
+		{"Ngân hàng TMCP Sài Gòn", "SCB"},
+	} {
+		results := d.Search(tc.query, 3)
+		if assert.NotEmpty(t, results, tc.query) {
+			assert.Equal(t, tc.want, results[0].ShortName, tc.query)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"SaigonBank", "VietinBank"}, shortNames(d.Search("cong thuong", 0)))
+	assert.Len(t, d.Search("9704", 0), 44)
+	assert.Empty(t, d.Search("xyzzy", 0))
+	assert.Len(t, d.Search("", 0), len(d.Banks))
+	assert.Len(t, d.Search("", 5), 5)
+}
+
+func TestResolve(t *testing.T) {
+	d := DefaultDirectory()
+	for input, bin := range map[string]string{
+		"970415":       "970415",
+		"vietinbank":   "970415",
+		"ICB":          "970415",
+		"Ngoại thương": "970436",
+		"MB":           "970422",
+		"techcombnk":   "970407",
+	} {
+		b, err := d.Resolve(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, bin, b.BIN, input)
+	}
+
+	_, err := d.Resolve("Công thương")
+	assert.True(t, errors.Is(err, ErrAmbiguousBank), "%v", err)
+	assert.Contains(t, err.Error(), "SaigonBank, VietinBank")
+	for _, input := range []string{"", "  ", "no such bank", "999999"} {
+		_, err := d.Resolve(input)
+		assert.True(t, errors.Is(err, ErrUnknownBank), input)
+	}
+}
+
+func TestLoadDirectoryInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"not json":      `banks`,
+		"no version":    `{"banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương"}]}`,
+		"no banks":      `{"version":"1","banks":[]}`,
+		"short bin":     `{"version":"1","banks":[{"bin":"97043","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương"}]}`,
+		"missing name":  `{"version":"1","banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank"}]}`,
+		"bad swift":     `{"version":"1","banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương","swift":"BFTV"}]}`,
+		"unknown field": `{"version":"1","banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương","logo":"x"}]}`,
+		"duplicate bin": `{"version":"1","banks":[{"bin":"970436","code":"VCB","short_name":"Vietcombank","name":"Ngoại Thương"},` +
+			`{"bin":"970436","code":"VCB2","short_name":"Vietcombank 2","name":"Ngoại Thương 2"}]}`,
+	} {
+		_, err := LoadDirectory(strings.NewReader(body))
+		assert.True(t, errors.Is(err, ErrInvalidDirectory), name)
+	}
+}
+
+func TestPaymentResolve(t *testing.T) {
+	d := DefaultDirectory()
+
+	p, err := Payment{Bank: "Vietcombank", AccountNumber: "0011001234567"}.Resolve(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "970436", p.BankBIN)
+	payload, err := p.Payload()
+	assert.NoError(t, err)
+	assert.Equal(t, "00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB", payload)
+
+	p, err = Payment{BankBIN: "970436", AccountNumber: "1"}.Resolve(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "970436", p.BankBIN)
+
+	for name, payment := range map[string]Payment{
+		"no bank":      {AccountNumber: "1"},
+		"unknown bank": {Bank: "no such bank", AccountNumber: "1"},
+		"unknown bin":  {BankBIN: "999999", AccountNumber: "1"},
+		"mismatch":     {Bank: "VCB", BankBIN: "970415", AccountNumber: "1"},
+	} {
+		_, err := payment.Resolve(d)
+		assert.True(t, errors.Is(err, ErrInvalidPayment), name)
+	}
+}
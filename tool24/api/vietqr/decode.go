@@ -0,0 +1,558 @@
+package vietqr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field is one data object of a payload. Offset is the byte position of its
+// ID in the payload; Children holds the data objects of a template.
+type Field struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name,omitempty"`
+	Length   int     `json:"length"`
+	Value    string  `json:"value"`
+	Offset   int     `json:"offset"`
+	Children []Field `json:"children,omitempty"`
+}
+
+// Violation codes reported by Decode.
+const (
+	// ViolationMalformed means the TLV structure cannot be parsed past Offset.
+	ViolationMalformed = "malformed"
+	ViolationCRC       = "crc"
+	ViolationMissing   = "missing"
+	ViolationLength    = "length"
+	ViolationFormat    = "format"
+	ViolationDuplicate = "duplicate"
+	ViolationOrder     = "order"
+)
+
+// Violation is one rule of the EMVCo or VietQR specification that a payload
+// breaks. Path is the dot-separated IDs leading to the data object, e.g.
+// "38.01.00", and is empty for the payload as a whole. Offset is the byte
+// position of the data object, or of its enclosing template when it is
+// missing.
+type Violation struct {
+	Path    string `json:"path"`
+	Offset  int    `json:"offset"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// MerchantAccount is a merchant account information data object (ID 02 to
+// 51). The beneficiary fields are only set for the NAPAS VietQR scheme.
+type MerchantAccount struct {
+	Tag string `json:"tag"`
+	// Value is the content of a primitive data object (ID 02 to 25), which
+	// card networks use for their own merchant identifiers.
+	Value         string `json:"value,omitempty"`
+	GUID          string `json:"guid,omitempty"`
+	BankBIN       string `json:"bank_bin,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	Service       string `json:"service,omitempty"`
+}
+
+// AdditionalData is the additional data field template (ID 62).
+type AdditionalData struct {
+	BillNumber      string `json:"bill_number,omitempty"`
+	MobileNumber    string `json:"mobile_number,omitempty"`
+	StoreLabel      string `json:"store_label,omitempty"`
+	LoyaltyNumber   string `json:"loyalty_number,omitempty"`
+	ReferenceLabel  string `json:"reference_label,omitempty"`
+	CustomerLabel   string `json:"customer_label,omitempty"`
+	TerminalLabel   string `json:"terminal_label,omitempty"`
+	Purpose         string `json:"purpose,omitempty"`
+	ConsumerRequest string `json:"consumer_data_request,omitempty"`
+}
+
+// Decoded is the result of Decode: the raw data object tree, the values
+// banking apps act on, and every violation found. Amounts are kept as the
+// decimal strings of the payload.
+type Decoded struct {
+	Fields                   []Field           `json:"fields"`
+	PayloadFormat            string            `json:"payload_format,omitempty"`
+	InitiationMethod         string            `json:"initiation_method,omitempty"`
+	MerchantAccounts         []MerchantAccount `json:"merchant_accounts"`
+	MerchantCategoryCode     string            `json:"merchant_category_code,omitempty"`
+	Currency                 string            `json:"currency,omitempty"`
+	Amount                   string            `json:"amount,omitempty"`
+	TipIndicator             string            `json:"tip_indicator,omitempty"`
+	ConvenienceFeeFixed      string            `json:"convenience_fee_fixed,omitempty"`
+	ConvenienceFeePercentage string            `json:"convenience_fee_percentage,omitempty"`
+	Country                  string            `json:"country,omitempty"`
+	MerchantName             string            `json:"merchant_name,omitempty"`
+	MerchantCity             string            `json:"merchant_city,omitempty"`
+	PostalCode               string            `json:"postal_code,omitempty"`
+	AdditionalData           *AdditionalData   `json:"additional_data,omitempty"`
+	CRC                      string            `json:"crc,omitempty"`
+	// ExpectedCRC is the checksum computed over the payload, set whenever
+	// the payload has a CRC data object.
+	ExpectedCRC string      `json:"expected_crc,omitempty"`
+	Valid       bool        `json:"valid"`
+	Violations  []Violation `json:"violations"`
+}
+
+// ID bổ sung cho các data object mà Payload không sinh ra.
+const (
+	idMerchantCategory = "52"
+	idTipIndicator     = "55"
+	idFeeFixed         = "56"
+	idFeePercentage    = "57"
+	idMerchantName     = "59"
+	idMerchantCity     = "60"
+	idPostalCode       = "61"
+	idLanguage         = "64"
+)
+
+// Tên các data object cấp cao nhất; 02-51 và 80-99 đặt tên theo khoảng trong fieldName.
+var topLevelNames = map[string]string{
+	idPayloadFormat:    "Payload Format Indicator",
+	idInitiationMethod: "Point of Initiation Method",
+	idMerchantCategory: "Merchant Category Code",
+	idCurrency:         "Transaction Currency",
+	idAmount:           "Transaction Amount",
+	idTipIndicator:     "Tip or Convenience Indicator",
+	idFeeFixed:         "Value of Convenience Fee Fixed",
+	idFeePercentage:    "Value of Convenience Fee Percentage",
+	idCountry:          "Country Code",
+	idMerchantName:     "Merchant Name",
+	idMerchantCity:     "Merchant City",
+	idPostalCode:       "Postal Code",
+	idAdditionalData:   "Additional Data Field Template",
+	idCRC:              "CRC",
+	idLanguage:         "Merchant Information - Language Template",
+}
+
+var additionalDataNames = map[string]string{
+	"01": "Bill Number",
+	"02": "Mobile Number",
+	"03": "Store Label",
+	"04": "Loyalty Number",
+	"05": "Reference Label",
+	"06": "Customer Label",
+	"07": "Terminal Label",
+	"08": "Purpose of Transaction",
+	"09": "Additional Consumer Data Request",
+}
+
+var languageNames = map[string]string{
+	"00": "Language Preference",
+	"01": "Merchant Name - Alternate Language",
+	"02": "Merchant City - Alternate Language",
+}
+
+// idIn cho biết ID hai chữ số nằm trong khoảng [lo, hi].
+func idIn(id string, lo, hi int) bool {
+	n, err := strconv.Atoi(id)
+	return err == nil && n >= lo && n <= hi
+}
+
+func isMerchantAccount(id string) bool {
+	return idIn(id, 2, 51)
+}
+
+// isMerchantTemplate: ID 02-25 là giá trị đơn của các tổ chức thẻ, 26-51 là template.
+func isMerchantTemplate(id string) bool {
+	return idIn(id, 26, 51)
+}
+
+func fieldName(path string) string {
+	ids := strings.Split(path, ".")
+	top := ids[0]
+	switch {
+	case len(ids) == 1 && isMerchantAccount(top):
+		return "Merchant Account Information"
+	case len(ids) == 1 && idIn(top, 80, 99):
+		return "Unreserved Template"
+	case len(ids) == 1:
+		return topLevelNames[top]
+	case top == idAdditionalData && len(ids) == 2:
+		return additionalDataNames[ids[1]]
+	case top == idLanguage && len(ids) == 2:
+		return languageNames[ids[1]]
+	case isMerchantTemplate(top):
+		switch strings.Join(ids[1:], ".") {
+		case idGUID:
+			return "Globally Unique Identifier"
+		case idBeneficiary:
+			return "Beneficiary Organization"
+		case idBeneficiary + "." + idBeneficiaryBank:
+			return "Acquirer ID"
+		case idBeneficiary + "." + idBeneficiaryNumber:
+			return "Merchant ID"
+		case idService:
+			return "Service Code"
+		}
+	}
+	return ""
+}
+
+// isTemplate cho biết data object cấp cao nhất có chứa các data object con;
+// template 01 của VietQR được tách riêng khi đã biết GUID là NAPAS.
+func isTemplate(id string) bool {
+	return isMerchantTemplate(id) || id == idAdditionalData || id == idLanguage || idIn(id, 80, 99)
+}
+
+// Decode parses an EMVCo Merchant-Presented Mode payload and checks it
+// against the specification and the NAPAS VietQR profile: the CRC, the
+// mandatory data objects, their order, lengths and formats. It never fails;
+// problems are listed in Violations and Valid is true only when there are
+// none.
+func Decode(payload string) *Decoded {
+	d := &Decoded{Fields: []Field{}, MerchantAccounts: []MerchantAccount{}, Violations: []Violation{}}
+	d.Fields = d.parse(payload, 0, "")
+	d.validate(payload)
+	d.Valid = len(d.Violations) == 0
+	return d
+}
+
+func (d *Decoded) violate(path string, offset int, code, format string, args ...any) {
+	d.Violations = append(d.Violations, Violation{Path: path, Offset: offset, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+func joinPath(parent, id string) string {
+	if parent == "" {
+		return id
+	}
+	return parent + "." + id
+}
+
+// parse tách chuỗi s (bắt đầu tại vị trí base của payload) thành các data
+// object. Gặp cấu trúc hỏng thì dừng, vì không còn biết data object tiếp theo
+// bắt đầu ở đâu.
+func (d *Decoded) parse(s string, base int, parent string) []Field {
+	fields := []Field{}
+	seen := map[string]bool{}
+	for pos := 0; pos < len(s); {
+		if len(s)-pos < 4 {
+			d.violate(parent, base+pos, ViolationMalformed, "%d trailing characters are too short for an ID and a length", len(s)-pos)
+			break
+		}
+		id, length := s[pos:pos+2], s[pos+2:pos+4]
+		if !isDigits(id) || !isDigits(length) {
+			d.violate(parent, base+pos, ViolationMalformed, "expected a 2-digit ID and a 2-digit length, got %q", s[pos:pos+4])
+			break
+		}
+		path := joinPath(parent, id)
+		n, _ := strconv.Atoi(length)
+		if pos+4+n > len(s) {
+			d.violate(path, base+pos, ViolationMalformed, "length %d exceeds the %d characters left", n, len(s)-pos-4)
+			break
+		}
+
+		f := Field{ID: id, Name: fieldName(path), Length: n, Value: s[pos+4 : pos+4+n], Offset: base + pos}
+		if n == 0 {
+			d.violate(path, f.Offset, ViolationLength, "length must be between 01 and 99")
+		}
+		if seen[id] {
+			d.violate(path, f.Offset, ViolationDuplicate, "data object %s appears more than once", path)
+		}
+		seen[id] = true
+		if parent == "" && isTemplate(id) {
+			f.Children = d.parse(f.Value, f.Offset+4, path)
+		}
+		fields = append(fields, f)
+		pos += 4 + n
+	}
+	return fields
+}
+
+func find(fields []Field, id string) *Field {
+	for i := range fields {
+		if fields[i].ID == id {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// require trả về data object id trong fields, hoặc ghi vi phạm missing tại
+// template cha (offset parentOffset) nếu không có.
+func (d *Decoded) require(fields []Field, parent string, parentOffset int, id string) *Field {
+	f := find(fields, id)
+	if f == nil {
+		path := joinPath(parent, id)
+		name := fieldName(path)
+		if name == "" {
+			name = "data object " + path
+		}
+		d.violate(path, parentOffset, ViolationMissing, "%s (%s) is required", name, path)
+	}
+	return f
+}
+
+// checkLength ghi vi phạm nếu độ dài nằm ngoài [lo, hi]; độ dài 0 đã được
+// báo khi parse.
+func (d *Decoded) checkLength(path string, f *Field, lo, hi int) bool {
+	switch {
+	case f.Length == 0:
+		return false
+	case lo == hi && f.Length != lo:
+		d.violate(path, f.Offset, ViolationLength, "length must be %d, got %d", lo, f.Length)
+		return false
+	case f.Length < lo || f.Length > hi:
+		d.violate(path, f.Offset, ViolationLength, "length must be between %d and %d, got %d", lo, hi, f.Length)
+		return false
+	}
+	return true
+}
+
+func (d *Decoded) checkFormat(path string, f *Field, ok bool, format string, args ...any) {
+	if !ok {
+		d.violate(path, f.Offset, ViolationFormat, format, args...)
+	}
+}
+
+// isAmount: chữ số, có thể có một dấu chấm thập phân ngăn cách với phần lẻ.
+func isAmount(s string) bool {
+	whole, fraction, found := strings.Cut(s, ".")
+	return whole != "" && isDigits(whole) && isDigits(fraction) && (!found || fraction != "")
+}
+
+func isUpperLetters(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isUpperHex(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// validate kiểm tra cây data object theo EMVCo MPM và hồ sơ VietQR của NAPAS,
+// đồng thời điền các trường có cấu trúc của d.
+func (d *Decoded) validate(payload string) {
+	top := d.Fields
+
+	if len(top) > 0 && top[0].ID != idPayloadFormat {
+		d.violate(idPayloadFormat, top[0].Offset, ViolationOrder, "Payload Format Indicator (00) must be the first data object")
+	}
+	for i, f := range top {
+		if f.ID == idCRC && i != len(top)-1 {
+			d.violate(top[i+1].ID, top[i+1].Offset, ViolationOrder, "CRC (63) must be the last data object")
+			break
+		}
+	}
+
+	if f := d.require(top, "", 0, idPayloadFormat); f != nil {
+		d.PayloadFormat = f.Value
+		d.checkFormat(f.ID, f, f.Value == "01", "Payload Format Indicator must be 01, got %q", f.Value)
+	}
+	if f := find(top, idInitiationMethod); f != nil {
+		d.InitiationMethod = f.Value
+		d.checkFormat(f.ID, f, f.Value == initiationStatic || f.Value == initiationDynamic,
+			"Point of Initiation Method must be %s (static) or %s (dynamic), got %q", initiationStatic, initiationDynamic, f.Value)
+	}
+
+	vietQR := false
+	for i := range top {
+		if isMerchantAccount(top[i].ID) {
+			if d.merchantAccount(&top[i]) {
+				vietQR = true
+			}
+		}
+	}
+	if len(d.MerchantAccounts) == 0 {
+		d.violate("02-51", 0, ViolationMissing, "at least one Merchant Account Information data object (02-51) is required")
+	}
+
+	// NAPAS bỏ 52, 59, 60 khỏi danh sách bắt buộc của hồ sơ VietQR.
+	optional := func(id string) *Field {
+		if vietQR {
+			return find(top, id)
+		}
+		return d.require(top, "", 0, id)
+	}
+	if f := optional(idMerchantCategory); f != nil {
+		d.MerchantCategoryCode = f.Value
+		if d.checkLength(f.ID, f, 4, 4) {
+			d.checkFormat(f.ID, f, isDigits(f.Value), "Merchant Category Code must be 4 digits")
+		}
+	}
+	if f := d.require(top, "", 0, idCurrency); f != nil {
+		d.Currency = f.Value
+		if d.checkLength(f.ID, f, 3, 3) {
+			d.checkFormat(f.ID, f, isDigits(f.Value), "Transaction Currency must be a 3-digit ISO 4217 code")
+		}
+	}
+	if f := find(top, idAmount); f != nil {
+		d.Amount = f.Value
+		if d.checkLength(f.ID, f, 1, 13) {
+			d.checkFormat(f.ID, f, isAmount(f.Value), "Transaction Amount must be digits with an optional decimal point, got %q", f.Value)
+		}
+	}
+	d.convenienceFee(top)
+	if f := d.require(top, "", 0, idCountry); f != nil {
+		d.Country = f.Value
+		if d.checkLength(f.ID, f, 2, 2) {
+			d.checkFormat(f.ID, f, isUpperLetters(f.Value),
+				"Country Code must be 2 uppercase letters (ISO 3166-1 alpha-2), got %q", f.Value)
+		}
+	}
+	if f := optional(idMerchantName); f != nil {
+		d.MerchantName = f.Value
+		d.checkLength(f.ID, f, 1, 25)
+	}
+	if f := optional(idMerchantCity); f != nil {
+		d.MerchantCity = f.Value
+		d.checkLength(f.ID, f, 1, 15)
+	}
+	if f := find(top, idPostalCode); f != nil {
+		d.PostalCode = f.Value
+		d.checkLength(f.ID, f, 1, 10)
+	}
+	if f := find(top, idAdditionalData); f != nil {
+		d.additionalData(f)
+	}
+	if f := find(top, idLanguage); f != nil {
+		d.language(f)
+	}
+
+	if f := d.require(top, "", 0, idCRC); f != nil {
+		d.CRC = f.Value
+		d.ExpectedCRC = fmt.Sprintf("%04X", CRC16([]byte(payload[:f.Offset+4])))
+		if d.checkLength(f.ID, f, 4, 4) {
+			if !isUpperHex(f.Value) {
+				d.violate(f.ID, f.Offset, ViolationFormat, "CRC must be 4 uppercase hexadecimal digits, got %q", f.Value)
+			} else if f.Value != d.ExpectedCRC {
+				d.violate(f.ID, f.Offset, ViolationCRC, "CRC is %s but the payload checksums to %s", f.Value, d.ExpectedCRC)
+			}
+		}
+	}
+}
+
+// merchantAccount kiểm tra một data object 02-51 và cho biết nó có phải tài
+// khoản VietQR của NAPAS không.
+func (d *Decoded) merchantAccount(f *Field) bool {
+	account := MerchantAccount{Tag: f.ID}
+	defer func() { d.MerchantAccounts = append(d.MerchantAccounts, account) }()
+	if !isMerchantTemplate(f.ID) {
+		account.Value = f.Value
+		return false
+	}
+
+	guid := d.require(f.Children, f.ID, f.Offset, idGUID)
+	if guid == nil {
+		return false
+	}
+	account.GUID = guid.Value
+	d.checkLength(joinPath(f.ID, idGUID), guid, 1, 32)
+	if guid.Value != NAPAS {
+		return false
+	}
+
+	if beneficiary := d.require(f.Children, f.ID, f.Offset, idBeneficiary); beneficiary != nil {
+		path := joinPath(f.ID, idBeneficiary)
+		beneficiary.Name = fieldName(path)
+		beneficiary.Children = d.parse(beneficiary.Value, beneficiary.Offset+4, path)
+		if bin := d.require(beneficiary.Children, path, beneficiary.Offset, idBeneficiaryBank); bin != nil {
+			account.BankBIN = bin.Value
+			binPath := joinPath(path, idBeneficiaryBank)
+			if d.checkLength(binPath, bin, 6, 6) {
+				d.checkFormat(binPath, bin, isDigits(bin.Value), "bank BIN must be 6 digits, got %q", bin.Value)
+			}
+		}
+		if number := d.require(beneficiary.Children, path, beneficiary.Offset, idBeneficiaryNumber); number != nil {
+			account.AccountNumber = number.Value
+			numberPath := joinPath(path, idBeneficiaryNumber)
+			if d.checkLength(numberPath, number, 1, 19) {
+				d.checkFormat(numberPath, number, isAlphanumeric(number.Value), "account number must be letters or digits, got %q", number.Value)
+			}
+		}
+	}
+	if service := d.require(f.Children, f.ID, f.Offset, idService); service != nil {
+		account.Service = service.Value
+		d.checkFormat(joinPath(f.ID, idService), service,
+			Service(service.Value) == ServiceAccount || Service(service.Value) == ServiceCard,
+			"service code must be %s or %s, got %q", ServiceAccount, ServiceCard, service.Value)
+	}
+	return true
+}
+
+// convenienceFee kiểm tra 55 và hai trường phí 56, 57 phụ thuộc vào nó.
+func (d *Decoded) convenienceFee(top []Field) {
+	indicator := find(top, idTipIndicator)
+	fixed, percentage := find(top, idFeeFixed), find(top, idFeePercentage)
+	value := ""
+	if indicator != nil {
+		value = indicator.Value
+		d.TipIndicator = value
+		if d.checkLength(indicator.ID, indicator, 2, 2) {
+			d.checkFormat(indicator.ID, indicator, value == "01" || value == "02" || value == "03",
+				"Tip or Convenience Indicator must be 01, 02 or 03, got %q", value)
+		}
+	}
+
+	if value == "02" && fixed == nil {
+		d.require(top, "", 0, idFeeFixed)
+	}
+	if fixed != nil {
+		d.ConvenienceFeeFixed = fixed.Value
+		d.checkFormat(fixed.ID, fixed, value == "02", "Value of Convenience Fee Fixed is only allowed when 55 is 02")
+		if d.checkLength(fixed.ID, fixed, 1, 13) {
+			d.checkFormat(fixed.ID, fixed, isAmount(fixed.Value), "convenience fee must be digits with an optional decimal point, got %q", fixed.Value)
+		}
+	}
+
+	if value == "03" && percentage == nil {
+		d.require(top, "", 0, idFeePercentage)
+	}
+	if percentage != nil {
+		d.ConvenienceFeePercentage = percentage.Value
+		d.checkFormat(percentage.ID, percentage, value == "03", "Value of Convenience Fee Percentage is only allowed when 55 is 03")
+		if d.checkLength(percentage.ID, percentage, 1, 5) {
+			p, err := strconv.ParseFloat(percentage.Value, 64)
+			d.checkFormat(percentage.ID, percentage, isAmount(percentage.Value) && err == nil && p > 0 && p < 100,
+				"convenience fee percentage must be between 00.01 and 99.99, got %q", percentage.Value)
+		}
+	}
+}
+
+func (d *Decoded) additionalData(f *Field) {
+	data := &AdditionalData{}
+	targets := map[string]*string{
+		"01": &data.BillNumber, "02": &data.MobileNumber, "03": &data.StoreLabel, "04": &data.LoyaltyNumber,
+		"05": &data.ReferenceLabel, "06": &data.CustomerLabel, "07": &data.TerminalLabel, idPurpose: &data.Purpose,
+		"09": &data.ConsumerRequest,
+	}
+	for i := range f.Children {
+		child := &f.Children[i]
+		path := joinPath(f.ID, child.ID)
+		target, ok := targets[child.ID]
+		if !ok {
+			continue
+		}
+		*target = child.Value
+		if child.ID == "09" {
+			if d.checkLength(path, child, 1, 3) {
+				d.checkFormat(path, child, strings.Trim(child.Value, "AME") == "",
+					"Additional Consumer Data Request may only contain A, M and E, got %q", child.Value)
+			}
+			continue
+		}
+		d.checkLength(path, child, 1, maxPurposeLength)
+	}
+	d.AdditionalData = data
+}
+
+func (d *Decoded) language(f *Field) {
+	if preference := d.require(f.Children, f.ID, f.Offset, "00"); preference != nil {
+		d.checkLength(joinPath(f.ID, "00"), preference, 2, 2)
+	}
+	if name := d.require(f.Children, f.ID, f.Offset, "01"); name != nil {
+		d.checkLength(joinPath(f.ID, "01"), name, 1, 25)
+	}
+	if city := find(f.Children, "02"); city != nil {
+		d.checkLength(joinPath(f.ID, "02"), city, 1, 15)
+	}
+}
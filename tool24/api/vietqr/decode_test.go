@@ -0,0 +1,153 @@
+package vietqr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const dynamicPayload = "00020101021238570010A00000072701270006970436011300110012345670208QRIBFTTA53037045405500005802VN62120808Order 42630430BA"
+
+func TestDecodeVietQR(t *testing.T) {
+	d := Decode(dynamicPayload)
+
+	assert.True(t, d.Valid)
+	assert.Empty(t, d.Violations)
+	assert.Equal(t, "01", d.PayloadFormat)
+	assert.Equal(t, "12", d.InitiationMethod)
+	assert.Equal(t, []MerchantAccount{{Tag: "38", GUID: NAPAS, BankBIN: "970436", AccountNumber: "0011001234567", Service: "QRIBFTTA"}}, d.MerchantAccounts)
+	assert.Equal(t, "704", d.Currency)
+	assert.Equal(t, "50000", d.Amount)
+	assert.Equal(t, "VN", d.Country)
+	assert.Equal(t, &AdditionalData{Purpose: "Order 42"}, d.AdditionalData)
+	assert.Equal(t, "30BA", d.CRC)
+	assert.Equal(t, "30BA", d.ExpectedCRC)
+
+	merchant := d.Fields[2]
+	assert.Equal(t, Field{ID: "00", Name: "Acquirer ID", Length: 6, Value: "970436", Offset: 34}, merchant.Children[1].Children[0])
+	assert.Equal(t, "Merchant Account Information", merchant.Name)
+	assert.Equal(t, dynamicPayload[34:44], "0006970436")
+}
+
+func TestDecodeGenericMerchant(t *testing.T) {
+	payload := appendCRC(tlv("00", "01") + tlv("26", tlv("00", "com.example.pay")) + tlv("52", "5812") + tlv("53", "840") +
+		tlv("54", "12.50") + tlv("55", "02") + tlv("56", "1.0") + tlv("58", "US") + tlv("59", "Coffee Shop") +
+		tlv("60", "New York") + tlv("61", "10001") + tlv("62", tlv("01", "INV-42")+tlv("07", "TERMINAL1")))
+
+	d := Decode(payload)
+	assert.True(t, d.Valid, "%v", d.Violations)
+	assert.Equal(t, []MerchantAccount{{Tag: "26", GUID: "com.example.pay"}}, d.MerchantAccounts)
+	assert.Equal(t, "5812", d.MerchantCategoryCode)
+	assert.Equal(t, "12.50", d.Amount)
+	assert.Equal(t, "1.0", d.ConvenienceFeeFixed)
+	assert.Equal(t, "Coffee Shop", d.MerchantName)
+	assert.Equal(t, "New York", d.MerchantCity)
+	assert.Equal(t, "10001", d.PostalCode)
+	assert.Equal(t, &AdditionalData{BillNumber: "INV-42", TerminalLabel: "TERMINAL1"}, d.AdditionalData)
+}
+
+func TestDecodeViolations(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payload string
+		want    []Violation
+	}{
+		{
+			name:    "wrong crc",
+			payload: strings.Replace(dynamicPayload, "30BA", "30BB", 1),
+			want:    []Violation{{Path: "63", Offset: 111, Code: ViolationCRC, Message: "CRC is 30BB but the payload checksums to 30BA"}},
+		},
+		{
+			name:    "lowercase crc",
+			payload: strings.Replace(dynamicPayload, "30BA", "30ba", 1),
+			want:    []Violation{{Path: "63", Offset: 111, Code: ViolationFormat, Message: `CRC must be 4 uppercase hexadecimal digits, got "30ba"`}},
+		},
+		{
+			name:    "missing currency",
+			payload: appendCRC("00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA5802VN"),
+			want:    []Violation{{Path: "53", Offset: 0, Code: ViolationMissing, Message: "Transaction Currency (53) is required"}},
+		},
+		{
+			name:    "bad bin and service",
+			payload: appendCRC("00020101021138570010A00000072701270006ABC436011300110012345670208QRPUSHXX53037045802VN"),
+			want: []Violation{
+				{Path: "38.01.00", Offset: 34, Code: ViolationFormat, Message: `bank BIN must be 6 digits, got "ABC436"`},
+				{Path: "38.02", Offset: 61, Code: ViolationFormat, Message: `service code must be QRIBFTTA or QRIBFTTC, got "QRPUSHXX"`},
+			},
+		},
+		{
+			name: "missing account number",
+			payload: appendCRC(tlv("00", "01") + tlv("01", "11") +
+				tlv("38", tlv("00", NAPAS)+tlv("01", tlv("00", "970436"))+tlv("02", "QRIBFTTA")) + tlv("53", "704") + tlv("58", "VN")),
+			want: []Violation{{Path: "38.01.01", Offset: 30, Code: ViolationMissing, Message: "Merchant ID (38.01.01) is required"}},
+		},
+		{
+			name:    "too long",
+			payload: appendCRC("00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045414123456789012345802VN62290825Thanh toan don hang 42 ok"),
+			want:    []Violation{{Path: "54", Offset: 80, Code: ViolationLength, Message: "length must be between 1 and 13, got 14"}},
+		},
+		{
+			name:    "bad amount",
+			payload: appendCRC("00020101021238570010A00000072701270006970436011300110012345670208QRIBFTTA5303704540350.5802VN"),
+			want:    []Violation{{Path: "54", Offset: 80, Code: ViolationFormat, Message: `Transaction Amount must be digits with an optional decimal point, got "50."`}},
+		},
+		{
+			name:    "duplicate and order",
+			payload: appendCRC("01021100020138570010A00000072701270006970436011300110012345670208QRIBFTTA530370453037045802VN"),
+			want: []Violation{
+				{Path: "53", Offset: 80, Code: ViolationDuplicate, Message: "data object 53 appears more than once"},
+				{Path: "00", Offset: 0, Code: ViolationOrder, Message: "Payload Format Indicator (00) must be the first data object"},
+			},
+		},
+		{
+			name:    "data after crc",
+			payload: dynamicPayload + "7003abc",
+			want:    []Violation{{Path: "70", Offset: 119, Code: ViolationOrder, Message: "CRC (63) must be the last data object"}},
+		},
+		{
+			name:    "generic merchant without mandatory fields",
+			payload: appendCRC(tlv("00", "01") + tlv("26", tlv("00", "com.example")) + tlv("53", "840") + tlv("58", "US")),
+			want: []Violation{
+				{Path: "52", Offset: 0, Code: ViolationMissing, Message: "Merchant Category Code (52) is required"},
+				{Path: "59", Offset: 0, Code: ViolationMissing, Message: "Merchant Name (59) is required"},
+				{Path: "60", Offset: 0, Code: ViolationMissing, Message: "Merchant City (60) is required"},
+			},
+		},
+		{
+			name:    "fee without indicator",
+			payload: appendCRC("00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045502035802VN"),
+			want:    []Violation{{Path: "57", Offset: 0, Code: ViolationMissing, Message: "Value of Convenience Fee Percentage (57) is required"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := Decode(tc.payload)
+			assert.False(t, d.Valid)
+			assert.Equal(t, tc.want, d.Violations)
+		})
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	d := Decode("000201010211385700")
+	assert.False(t, d.Valid)
+	assert.Equal(t, Violation{Path: "38", Offset: 12, Code: ViolationMalformed, Message: "length 57 exceeds the 2 characters left"}, d.Violations[0])
+	assert.Len(t, d.Fields, 2)
+
+	d = Decode("hello")
+	assert.Equal(t, Violation{Path: "", Offset: 0, Code: ViolationMalformed, Message: `expected a 2-digit ID and a 2-digit length, got "hell"`}, d.Violations[0])
+	assert.Empty(t, d.Fields)
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	payload, err := Payment{BankBIN: "970415", AccountNumber: "9704150123456789", Service: ServiceCard, Amount: 120000, Purpose: "Todo 7"}.Payload()
+	assert.NoError(t, err)
+
+	d := Decode(payload)
+	assert.True(t, d.Valid)
+	assert.Equal(t, "970415", d.MerchantAccounts[0].BankBIN)
+	assert.Equal(t, "9704150123456789", d.MerchantAccounts[0].AccountNumber)
+	assert.Equal(t, string(ServiceCard), d.MerchantAccounts[0].Service)
+	assert.Equal(t, "120000", d.Amount)
+	assert.Equal(t, "Todo 7", d.AdditionalData.Purpose)
+}
@@ -0,0 +1,193 @@
+// Package vietqr builds EMVCo Merchant-Presented Mode QR payloads in the
+// NAPAS VietQR profile, the format Vietnamese banking apps scan to prefill a
+// transfer.
+//
+//	payload, err := vietqr.Payment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Purpose: "Order 42"}.Payload()
+package vietqr
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidPayment is wrapped by every validation error of Payment.Payload.
+var ErrInvalidPayment = errors.New("invalid payment")
+
+// Service is the NAPAS service code of the beneficiary.
+type Service string
+
+const (
+	// ServiceAccount transfers to a bank account number.
+	ServiceAccount Service = "QRIBFTTA"
+	// ServiceCard transfers to a card number.
+	ServiceCard Service = "QRIBFTTC"
+)
+
+// CurrencyVND is the ISO 4217 numeric code of the Vietnamese dong.
+const CurrencyVND = "704"
+
+// NAPAS is the globally unique identifier of the VietQR scheme.
+const NAPAS = "A000000727"
+
+// ID các data object của EMVCo; các ID lồng bên trong 38 và 62 dùng lại số từ 00.
+const (
+	idPayloadFormat     = "00"
+	idInitiationMethod  = "01"
+	idMerchantAccount   = "38"
+	idCurrency          = "53"
+	idAmount            = "54"
+	idCountry           = "58"
+	idAdditionalData    = "62"
+	idCRC               = "63"
+	idGUID              = "00"
+	idBeneficiary       = "01"
+	idService           = "02"
+	idBeneficiaryBank   = "00"
+	idBeneficiaryNumber = "01"
+	idPurpose           = "08"
+)
+
+// Point of initiation: mã tĩnh trả được nhiều lần với số tiền bất kỳ, mã động
+// mang sẵn số tiền.
+const (
+	initiationStatic  = "11"
+	initiationDynamic = "12"
+)
+
+// maxPurposeLength là giới hạn của trường purpose of transaction (62-08) theo EMVCo.
+const maxPurposeLength = 25
+
+// Payment is the transfer a QR code asks the payer to make.
+type Payment struct {
+	// BankBIN is the 6-digit NAPAS bank identification number, e.g. 970436.
+	BankBIN string `json:"bank_bin"`
+	// Bank is what the user typed to pick the bank (a BIN, code or name);
+	// Resolve turns it into BankBIN.
+	Bank string `json:"bank,omitempty"`
+	// AccountNumber is the beneficiary account (or card) number.
+	AccountNumber string `json:"account_number"`
+	// Service defaults to ServiceAccount.
+	Service Service `json:"service,omitempty"`
+	// Amount in whole units of Currency; 0 makes a static code where the
+	// payer enters the amount.
+	Amount int64 `json:"amount,omitempty"`
+	// Currency is the ISO 4217 numeric code and defaults to CurrencyVND.
+	Currency string `json:"currency,omitempty"`
+	// Purpose is shown to the payer and prefilled as the transfer note. It is
+	// limited to 25 printable ASCII characters, since banking apps reject or
+	// mangle accented text.
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// Payload validates p and returns the EMVCo payload string, including the
+// trailing CRC, ready to be encoded as a QR code.
+func (p Payment) Payload() (string, error) {
+	if p.Service == "" {
+		p.Service = ServiceAccount
+	}
+	if p.Currency == "" {
+		p.Currency = CurrencyVND
+	}
+	if err := p.validate(); err != nil {
+		return "", err
+	}
+
+	beneficiary := tlv(idBeneficiaryBank, p.BankBIN) + tlv(idBeneficiaryNumber, p.AccountNumber)
+	merchant := tlv(idGUID, NAPAS) + tlv(idBeneficiary, beneficiary) + tlv(idService, string(p.Service))
+
+	var b strings.Builder
+	b.WriteString(tlv(idPayloadFormat, "01"))
+	if p.Amount > 0 {
+		b.WriteString(tlv(idInitiationMethod, initiationDynamic))
+	} else {
+		b.WriteString(tlv(idInitiationMethod, initiationStatic))
+	}
+	b.WriteString(tlv(idMerchantAccount, merchant))
+	b.WriteString(tlv(idCurrency, p.Currency))
+	if p.Amount > 0 {
+		b.WriteString(tlv(idAmount, strconv.FormatInt(p.Amount, 10)))
+	}
+	b.WriteString(tlv(idCountry, "VN"))
+	if p.Purpose != "" {
+		b.WriteString(tlv(idAdditionalData, tlv(idPurpose, p.Purpose)))
+	}
+	return appendCRC(b.String()), nil
+}
+
+func (p Payment) validate() error {
+	if len(p.BankBIN) != 6 || !isDigits(p.BankBIN) {
+		return fmt.Errorf("%w: bank_bin must be 6 digits", ErrInvalidPayment)
+	}
+	if len(p.AccountNumber) == 0 || len(p.AccountNumber) > 19 || !isAlphanumeric(p.AccountNumber) {
+		return fmt.Errorf("%w: account_number must be 1 to 19 letters or digits", ErrInvalidPayment)
+	}
+	if p.Service != ServiceAccount && p.Service != ServiceCard {
+		return fmt.Errorf("%w: service must be %s or %s", ErrInvalidPayment, ServiceAccount, ServiceCard)
+	}
+	if len(p.Currency) != 3 || !isDigits(p.Currency) {
+		return fmt.Errorf("%w: currency must be a 3-digit ISO 4217 code", ErrInvalidPayment)
+	}
+	// Trường 54 dài tối đa 13 ký tự.
+	if p.Amount < 0 || p.Amount > 9999999999999 {
+		return fmt.Errorf("%w: amount must be between 0 and 9999999999999", ErrInvalidPayment)
+	}
+	if len(p.Purpose) > maxPurposeLength {
+		return fmt.Errorf("%w: purpose must be at most %d characters", ErrInvalidPayment, maxPurposeLength)
+	}
+	for _, r := range p.Purpose {
+		if r < 0x20 || r > 0x7e {
+			return fmt.Errorf("%w: purpose must be printable ASCII without accents", ErrInvalidPayment)
+		}
+	}
+	return nil
+}
+
+// tlv mã hóa một data object thành ID, độ dài hai chữ số và giá trị. Độ dài
+// đã được validate trước nên mọi giá trị đều không quá 99 byte.
+func tlv(id, value string) string {
+	return fmt.Sprintf("%s%02d%s", id, len(value), value)
+}
+
+// appendCRC thêm data object 63, có giá trị là CRC của toàn bộ phần trước nó
+// kể cả ID và độ dài của chính nó ("6304").
+func appendCRC(payload string) string {
+	payload += idCRC + "04"
+	return payload + fmt.Sprintf("%04X", CRC16([]byte(payload)))
+}
+
+// CRC16 is the CRC-16/CCITT-FALSE checksum (polynomial 0x1021, initial value
+// 0xFFFF) that EMVCo uses for data object 63.
+func CRC16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package vietqr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCRC16(t *testing.T) {
+	// Giá trị kiểm tra chuẩn của CRC-16/CCITT-FALSE.
+	assert.Equal(t, uint16(0x29B1), CRC16([]byte("123456789")))
+}
+
+func TestPayload(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		payment Payment
+		want    string
+	}{
+		{
+			name:    "dynamic with purpose",
+			payment: Payment{BankBIN: "970436", AccountNumber: "0011001234567", Amount: 50000, Purpose: "Order 42"},
+			want:    "00020101021238570010A00000072701270006970436011300110012345670208QRIBFTTA53037045405500005802VN62120808Order 42630430BA",
+		},
+		{
+			name:    "static",
+			payment: Payment{BankBIN: "970436", AccountNumber: "0011001234567"},
+			want:    "00020101021138570010A00000072701270006970436011300110012345670208QRIBFTTA53037045802VN6304E8DB",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := tc.payment.Payload()
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, payload)
+		})
+	}
+}
+
+func TestPayloadCard(t *testing.T) {
+	payload, err := Payment{BankBIN: "970415", AccountNumber: "9704150123456789", Service: ServiceCard, Amount: 1}.Payload()
+	assert.NoError(t, err)
+	assert.Contains(t, payload, "0208QRIBFTTC")
+	assert.Contains(t, payload, "54011")
+}
+
+func TestPayloadInvalid(t *testing.T) {
+	valid := Payment{BankBIN: "970436", AccountNumber: "0011001234567"}
+	for name, mutate := range map[string]func(*Payment){
+		"short bin":        func(p *Payment) { p.BankBIN = "97043" },
+		"letters in bin":   func(p *Payment) { p.BankBIN = "97043A" },
+		"empty account":    func(p *Payment) { p.AccountNumber = "" },
+		"long account":     func(p *Payment) { p.AccountNumber = "12345678901234567890" },
+		"account spaces":   func(p *Payment) { p.AccountNumber = "0011 0012" },
+		"unknown service":  func(p *Payment) { p.Service = "QRPUSH" },
+		"bad currency":     func(p *Payment) { p.Currency = "VND" },
+		"negative amount":  func(p *Payment) { p.Amount = -1 },
+		"huge amount":      func(p *Payment) { p.Amount = 10000000000000 },
+		"long purpose":     func(p *Payment) { p.Purpose = "12345678901234567890123456" },
+		"accented purpose": func(p *Payment) { p.Purpose = "Tiền nhà" },
+	} {
+		p := valid
+		mutate(&p)
+		_, err := p.Payload()
+		assert.True(t, errors.Is(err, ErrInvalidPayment), name)
+	}
+}
@@ -0,0 +1,629 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+)
+
+// Các sự kiện todo mà webhook có thể đăng ký.
+const (
+	EventTodoCreated   = "todo.created"
+	EventTodoUpdated   = "todo.updated"
+	EventTodoCompleted = "todo.completed"
+	EventTodoDeleted   = "todo.deleted"
+)
+
+var webhookEvents = map[string]bool{
+	EventTodoCreated: true, EventTodoUpdated: true, EventTodoCompleted: true, EventTodoDeleted: true,
+}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
+
+// Header của mỗi lần gửi webhook. Chữ ký là HMAC-SHA256 của body với secret.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookEvent là nội dung JSON được POST tới URL đăng ký.
+type WebhookEvent struct {
+	ID         int64     `json:"id"`
+	Event      string    `json:"event"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	Todo       Todo      `json:"todo"`
+}
+
+// todoEvents phân loại một thay đổi thành các sự kiện webhook.
+func todoEvents(before, after *Todo) []string {
+	switch {
+	case after == nil:
+		if before != nil && before.DeletedAt == nil {
+			return []string{EventTodoDeleted}
+		}
+		return nil
+	case before == nil:
+		return []string{EventTodoCreated}
+	case after.DeletedAt != nil && before.DeletedAt == nil:
+		return []string{EventTodoDeleted}
+	case after.DeletedAt == nil && before.DeletedAt != nil:
+		return []string{EventTodoCreated}
+	case after.Done && !before.Done:
+		return []string{EventTodoUpdated, EventTodoCompleted}
+	default:
+		return []string{EventTodoUpdated}
+	}
+}
+
+// writeOutbox ghi sự kiện vào outbox trong cùng transaction với thay đổi, để
+// WebhookDispatcher gửi đi sau khi commit.
+func writeOutbox(ctx context.Context, tx pgx.Tx, before, after *Todo) error {
+	todo := after
+	if todo == nil {
+		todo = before
+	}
+
+	for _, event := range todoEvents(before, after) {
+		payload, err := json.Marshal(todo)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			"INSERT INTO webhook_outbox (event, todo_id, actor, payload) VALUES ($1, $2, $3, $4)",
+			event, todo.ID, ActorFromContext(ctx), payload)
+		if err != nil {
+			return fmt.Errorf("failed to write webhook outbox: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateWebhook(wh *Webhook) error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	if len(wh.Events) == 0 {
+		return fmt.Errorf("%w: at least one event is required", ErrInvalidWebhook)
+	}
+	for _, event := range wh.Events {
+		if !webhookEvents[event] {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, event)
+		}
+	}
+	if wh.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		wh.Secret = hex.EncodeToString(buf)
+	}
+	return nil
+}
+
+type WebhookStore interface {
+	CreateWebhookDB(ctx context.Context, wh Webhook) (Webhook, error)
+	ListWebhooksDB(ctx context.Context) ([]Webhook, error)
+	DeleteWebhookDB(ctx context.Context, id string) error
+	ListWebhookDeliveriesDB(ctx context.Context, id string, status string, limit int) ([]WebhookDelivery, error)
+}
+
+func (db *Db) CreateWebhookDB(ctx context.Context, wh Webhook) (Webhook, error) {
+	wh.ID = uuid.New().String()
+	err := db.Conn.QueryRow(ctx,
+		"INSERT INTO webhook_subscription (id, url, events, secret) VALUES ($1, $2, $3, $4) RETURNING created_at",
+		wh.ID, wh.URL, wh.Events, wh.Secret).Scan(&wh.CreatedAt)
+	if err != nil {
+		return Webhook{}, fmt.Errorf("failed to create webhook: %v", err)
+	}
+
+	return wh, nil
+}
+
+func (db *Db) ListWebhooksDB(ctx context.Context) ([]Webhook, error) {
+	rows, err := db.Conn.Query(ctx, "SELECT id, url, events, created_at FROM webhook_subscription ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %v", err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var wh Webhook
+		if err := rows.Scan(&wh.ID, &wh.URL, &wh.Events, &wh.CreatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, wh)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (db *Db) DeleteWebhookDB(ctx context.Context, id string) error {
+	tag, err := db.Conn.Exec(ctx, "DELETE FROM webhook_subscription WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %v", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("webhook %s: %w", id, ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+func (db *Db) ListWebhookDeliveriesDB(ctx context.Context, id string, status string, limit int) ([]WebhookDelivery, error) {
+	var exists bool
+	if err := db.Conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM webhook_subscription WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("failed to retrieve webhook: %v", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("webhook %s: %w", id, ErrWebhookNotFound)
+	}
+
+	rows, err := db.Conn.Query(ctx,
+		"SELECT id, subscription_id, event, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, created_at "+
+			"FROM webhook_delivery WHERE subscription_id = $1 AND ($2 = '' OR status = $2) ORDER BY id DESC LIMIT $3",
+		id, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatusCode, &d.LastError, &d.DeliveredAt, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// WebhookJob là một lần gửi đang chờ, đã được ProcessWebhookDeliveriesDB claim.
+type WebhookJob struct {
+	DeliveryID int64
+	URL        string
+	Secret     string
+	Event      string
+	Attempts   int
+	Body       []byte
+}
+
+type WebhookQueue interface {
+	// FanOutWebhookEventsDB turns outbox events into one pending delivery per
+	// matching subscription.
+	FanOutWebhookEventsDB(ctx context.Context, limit int) (int, error)
+	// ProcessWebhookDeliveriesDB claims due deliveries and commits the claim,
+	// then calls deliver for each outside any transaction and records the
+	// outcome, retrying with backoff and dead-lettering after backoff.MaxAttempts.
+	ProcessWebhookDeliveriesDB(ctx context.Context, limit int, backoff Backoff, deliver func(WebhookJob) (int, error)) (int, error)
+}
+
+func (db *Db) FanOutWebhookEventsDB(ctx context.Context, limit int) (int, error) {
+	fannedOut := 0
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT id, event, actor, payload, created_at FROM webhook_outbox WHERE dispatched_at IS NULL "+
+				"ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED", limit)
+		if err != nil {
+			return err
+		}
+
+		var events []WebhookEvent
+		for rows.Next() {
+			var ev WebhookEvent
+			var payload []byte
+			if err := rows.Scan(&ev.ID, &ev.Event, &ev.Actor, &payload, &ev.OccurredAt); err != nil {
+				rows.Close()
+				return err
+			}
+			if err := json.Unmarshal(payload, &ev.Todo); err != nil {
+				rows.Close()
+				return err
+			}
+			events = append(events, ev)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, ev := range events {
+			body, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx,
+				"INSERT INTO webhook_delivery (subscription_id, outbox_id, event, payload, next_attempt_at) "+
+					"SELECT id, $1, $2, $3, now() FROM webhook_subscription WHERE $2 = ANY(events)",
+				ev.ID, ev.Event, body)
+			if err != nil {
+				return fmt.Errorf("failed to fan out webhook event: %v", err)
+			}
+			if _, err := tx.Exec(ctx, "UPDATE webhook_outbox SET dispatched_at = now() WHERE id = $1", ev.ID); err != nil {
+				return err
+			}
+			fannedOut++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return fannedOut, nil
+}
+
+// webhookDeliverTimeout giới hạn thời gian POST một sự kiện tới subscriber.
+const webhookDeliverTimeout = 10 * time.Second
+
+func (db *Db) ProcessWebhookDeliveriesDB(ctx context.Context, limit int, backoff Backoff, deliver func(WebhookJob) (int, error)) (int, error) {
+	jobs, err := db.claimWebhookDeliveries(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, job := range jobs {
+		statusCode, deliveryErr := deliver(job)
+		if err := db.recordWebhookDelivery(ctx, job, backoff, statusCode, deliveryErr); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// claimWebhookDeliveries khóa các lần gửi đến hạn rồi đẩy next_attempt_at qua
+// hết thời gian gửi cả lô trước khi commit, giống claimDueReminders: POST chạy
+// ngoài transaction, và nếu tiến trình chết giữa chừng thì lần gửi lại đến hạn
+// khi hết hạn claim.
+func (db *Db) claimWebhookDeliveries(ctx context.Context, limit int) ([]WebhookJob, error) {
+	var jobs []WebhookJob
+	err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx,
+			"SELECT d.id, s.url, s.secret, d.event, d.attempts, d.payload "+
+				"FROM webhook_delivery d JOIN webhook_subscription s ON s.id = d.subscription_id "+
+				"WHERE d.status = 'pending' AND d.next_attempt_at <= now() "+
+				"ORDER BY d.next_attempt_at LIMIT $1 FOR UPDATE OF d SKIP LOCKED", limit)
+		if err != nil {
+			return err
+		}
+
+		var ids []int64
+		for rows.Next() {
+			var job WebhookJob
+			if err := rows.Scan(&job.DeliveryID, &job.URL, &job.Secret, &job.Event, &job.Attempts, &job.Body); err != nil {
+				rows.Close()
+				return err
+			}
+			jobs = append(jobs, job)
+			ids = append(ids, job.DeliveryID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		lease := time.Duration(len(ids))*webhookDeliverTimeout + time.Minute
+		_, err = tx.Exec(ctx, "UPDATE webhook_delivery SET next_attempt_at = $1 WHERE id = ANY($2)", time.Now().Add(lease), ids)
+		if err != nil {
+			return fmt.Errorf("failed to claim deliveries: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// recordWebhookDelivery ghi kết quả một lần gửi và lên lịch lần thử lại nếu cần.
+func (db *Db) recordWebhookDelivery(ctx context.Context, job WebhookJob, backoff Backoff, statusCode int, deliveryErr error) error {
+	attempt := job.Attempts + 1
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+
+	var query string
+	var args []interface{}
+	switch {
+	case deliveryErr == nil:
+		query = "UPDATE webhook_delivery SET status = 'delivered', attempts = $1, last_status_code = $2, last_error = NULL, delivered_at = now() WHERE id = $3 AND status = 'pending'"
+		args = []interface{}{attempt, code, job.DeliveryID}
+	case attempt >= backoff.MaxAttempts:
+		// Hết số lần thử: chuyển vào dead letter, xem qua GET /webhooks/{id}/deliveries?status=dead.
+		query = "UPDATE webhook_delivery SET status = 'dead', attempts = $1, last_status_code = $2, last_error = $3 WHERE id = $4 AND status = 'pending'"
+		args = []interface{}{attempt, code, deliveryErr.Error(), job.DeliveryID}
+	default:
+		query = "UPDATE webhook_delivery SET attempts = $1, last_status_code = $2, last_error = $3, next_attempt_at = $4 WHERE id = $5 AND status = 'pending'"
+		args = []interface{}{attempt, code, deliveryErr.Error(), time.Now().Add(backoff.Delay(attempt)), job.DeliveryID}
+	}
+
+	// Một câu UPDATE đủ là transaction riêng của lần gửi này.
+	if _, err := db.Conn.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update delivery: %v", err)
+	}
+	return nil
+}
+
+// WebhookDispatcher moves events from the outbox to subscribers.
+type WebhookDispatcher struct {
+	queue    WebhookQueue
+	client   *http.Client
+	interval time.Duration
+	batch    int
+	backoff  Backoff
+}
+
+func NewWebhookDispatcher(queue WebhookQueue, interval time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		queue:    queue,
+		client:   &http.Client{Timeout: webhookDeliverTimeout},
+		interval: interval,
+		batch:    50,
+		backoff:  Backoff{Base: 10 * time.Second, Max: time.Hour, MaxAttempts: 10},
+	}
+}
+
+// deliver POST một sự kiện đã ký tới subscriber, trả về mã HTTP nhận được.
+func (d *WebhookDispatcher) deliver(ctx context.Context, job WebhookJob) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.client.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.URL, bytes.NewReader(job.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, job.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(job.DeliveryID, 10))
+	req.Header.Set(WebhookSignatureHeader, signPayload(job.Secret, job.Body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *WebhookDispatcher) RunOnce(ctx context.Context) (int, error) {
+	if _, err := d.queue.FanOutWebhookEventsDB(ctx, d.batch); err != nil {
+		return 0, err
+	}
+	return d.queue.ProcessWebhookDeliveriesDB(ctx, d.batch, d.backoff, func(job WebhookJob) (int, error) {
+		return d.deliver(ctx, job)
+	})
+}
+
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.RunOnce(ctx); err != nil {
+			log.Printf("Không thể gửi webhook: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type WebhookHandler struct {
+	webhookStore WebhookStore
+}
+
+func NewWebhookHandler(webhookStore WebhookStore) *WebhookHandler {
+	return &WebhookHandler{webhookStore: webhookStore}
+}
+
+// @Summary Subscribe a webhook
+// @Description Register a URL to receive signed todo events. The secret is generated when omitted and only returned here.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body Webhook true "Webhook to create"
+// @Success 201 {object} Webhook "Created"
+// @Failure 400 {object} ErrorResponse "Invalid input"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks [post]
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	var wh Webhook
+	if err := json.NewDecoder(r.Body).Decode(&wh); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Request body is empty"})
+		return
+	}
+	if err := validateWebhook(&wh); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	created, err := h.webhookStore.CreateWebhookDB(ctx, wh)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create webhook: " + err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// @Summary List webhooks
+// @Description Retrieve all webhook subscriptions, without their secrets
+// @Tags Webhooks
+// @Produce json
+// @Success 200 {array} Webhook "OK"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks [get]
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+
+	webhooks, err := h.webhookStore.ListWebhooksDB(ctx)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list webhooks: " + err.Error()})
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// @Summary Delete a webhook
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 204 "No Content"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	err := h.webhookStore.DeleteWebhookDB(ctx, idStr)
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Webhook not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to delete webhook: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List webhook deliveries
+// @Description Retrieve the delivery log of a webhook, newest first. Use status=dead to see dead-lettered deliveries.
+// @Tags Webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "pending, delivered or dead"
+// @Param limit query int false "Maximum number of deliveries (default 100)"
+// @Success 200 {array} WebhookDelivery "OK"
+// @Failure 400 {object} ErrorResponse "Invalid filter"
+// @Failure 404 {object} ErrorResponse "Webhook not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	w.Header().Set("Content-Type", "application/json")
+	idStr := mux.Vars(r)["id"]
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "pending" && status != "delivered" && status != "dead" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "status must be pending, delivered or dead"})
+		return
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.webhookStore.ListWebhookDeliveriesDB(ctx, idStr, status, limit)
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Webhook not found with ID " + idStr})
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to list deliveries: " + err.Error()})
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deliveries)
+}
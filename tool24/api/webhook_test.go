@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookStore struct {
+	mock.Mock
+}
+
+func (m *MockWebhookStore) CreateWebhookDB(ctx context.Context, wh Webhook) (Webhook, error) {
+	args := m.Called(wh)
+	return args.Get(0).(Webhook), args.Error(1)
+}
+
+func (m *MockWebhookStore) ListWebhooksDB(ctx context.Context) ([]Webhook, error) {
+	args := m.Called()
+	return args.Get(0).([]Webhook), args.Error(1)
+}
+
+func (m *MockWebhookStore) DeleteWebhookDB(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) ListWebhookDeliveriesDB(ctx context.Context, id string, status string, limit int) ([]WebhookDelivery, error) {
+	args := m.Called(id, status, limit)
+	return args.Get(0).([]WebhookDelivery), args.Error(1)
+}
+
+func newWebhookRouter(store WebhookStore) *mux.Router {
+	wh := NewWebhookHandler(store)
+	router := mux.NewRouter()
+	router.HandleFunc("/webhooks", wh.CreateWebhook).Methods("POST")
+	router.HandleFunc("/webhooks", wh.ListWebhooks).Methods("GET")
+	router.HandleFunc("/webhooks/{id}", wh.DeleteWebhook).Methods("DELETE")
+	router.HandleFunc("/webhooks/{id}/deliveries", wh.ListDeliveries).Methods("GET")
+	return router
+}
+
+func TestCreateWebhook_GeneratesSecret(t *testing.T) {
+	mockStore := new(MockWebhookStore)
+	mockStore.On("CreateWebhookDB", mock.MatchedBy(func(wh Webhook) bool {
+		return wh.URL == "https://ci.example.com/hook" && len(wh.Secret) == 64
+	})).Return(Webhook{ID: "w1", URL: "https://ci.example.com/hook", Events: []string{EventTodoCompleted}, Secret: "s"}, nil)
+
+	body := `{"url":"https://ci.example.com/hook","events":["todo.completed"]}`
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"secret":"s"`)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateWebhook_InvalidInput(t *testing.T) {
+	for _, body := range []string{
+		`{"url":"ftp://example.com","events":["todo.created"]}`,
+		`{"url":"https://example.com","events":[]}`,
+		`{"url":"https://example.com","events":["todo.archived"]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		newWebhookRouter(new(MockWebhookStore)).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestListWebhooks_HidesSecret(t *testing.T) {
+	mockStore := new(MockWebhookStore)
+	mockStore.On("ListWebhooksDB").Return([]Webhook{{ID: "w1", URL: "https://example.com", Secret: "top-secret"}}, nil)
+
+	req, _ := http.NewRequest("GET", "/webhooks", nil)
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "top-secret")
+}
+
+func TestDeleteWebhook_NotFound(t *testing.T) {
+	mockStore := new(MockWebhookStore)
+	mockStore.On("DeleteWebhookDB", "w9").Return(ErrWebhookNotFound)
+
+	req, _ := http.NewRequest("DELETE", "/webhooks/w9", nil)
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestListDeliveries(t *testing.T) {
+	mockStore := new(MockWebhookStore)
+	mockStore.On("ListWebhookDeliveriesDB", "w1", "dead", 10).Return([]WebhookDelivery{{ID: 3, Status: "dead", Attempts: 10}}, nil)
+
+	req, _ := http.NewRequest("GET", "/webhooks/w1/deliveries?status=dead&limit=10", nil)
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockStore).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":"dead"`)
+
+	req, _ = http.NewRequest("GET", "/webhooks/w1/deliveries?status=lost", nil)
+	rr = httptest.NewRecorder()
+	newWebhookRouter(mockStore).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestTodoEvents(t *testing.T) {
+	deletedAt := time.Now()
+	open := &Todo{ID: "1"}
+	done := &Todo{ID: "1", Done: true}
+	trashed := &Todo{ID: "1", DeletedAt: &deletedAt}
+
+	assert.Equal(t, []string{EventTodoCreated}, todoEvents(nil, open))
+	assert.Equal(t, []string{EventTodoUpdated}, todoEvents(open, open))
+	assert.Equal(t, []string{EventTodoUpdated, EventTodoCompleted}, todoEvents(open, done))
+	assert.Equal(t, []string{EventTodoDeleted}, todoEvents(open, trashed))
+	assert.Equal(t, []string{EventTodoCreated}, todoEvents(trashed, open))
+	assert.Equal(t, []string{EventTodoDeleted}, todoEvents(open, nil))
+	assert.Nil(t, todoEvents(trashed, nil), "purging a trashed todo was already announced as deleted")
+}
+
+type fakeWebhookQueue struct {
+	jobs    []WebhookJob
+	codes   map[int64]int
+	results map[int64]error
+}
+
+func (f *fakeWebhookQueue) FanOutWebhookEventsDB(ctx context.Context, limit int) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeWebhookQueue) ProcessWebhookDeliveriesDB(ctx context.Context, limit int, backoff Backoff, deliver func(WebhookJob) (int, error)) (int, error) {
+	f.codes, f.results = map[int64]int{}, map[int64]error{}
+	for _, job := range f.jobs {
+		f.codes[job.DeliveryID], f.results[job.DeliveryID] = deliver(job)
+	}
+	return len(f.jobs), nil
+}
+
+func TestWebhookDispatcher_SignsDeliveries(t *testing.T) {
+	var gotSignature, gotEvent string
+	var gotBody []byte
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+		gotEvent = r.Header.Get(WebhookEventHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	body, _ := json.Marshal(WebhookEvent{ID: 7, Event: EventTodoCompleted, Todo: Todo{ID: "1", Done: true}})
+	queue := &fakeWebhookQueue{jobs: []WebhookJob{
+		{DeliveryID: 1, URL: ok.URL, Secret: "s3cret", Event: EventTodoCompleted, Body: body},
+		{DeliveryID: 2, URL: failing.URL, Secret: "s3cret", Event: EventTodoCompleted, Body: body},
+	}}
+
+	n, err := NewWebhookDispatcher(queue, time.Minute).RunOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, EventTodoCompleted, gotEvent)
+	assert.Equal(t, body, gotBody)
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), gotSignature)
+
+	assert.NoError(t, queue.results[1])
+	assert.Equal(t, http.StatusBadGateway, queue.codes[2])
+	assert.Error(t, queue.results[2], "non-2xx responses must be retried")
+}
+
+// Lúc POST webhook, claim đã được commit.
+func TestWebhookDeliveriesPostedAfterClaimDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	wh, err := db.CreateWebhookDB(ctx, Webhook{URL: "https://example.com/hooks/" + uuid.New().String(), Events: []string{EventTodoCreated}, Secret: "s3cret"})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	defer db.DeleteWebhookDB(ctx, wh.ID)
+	if _, err := db.CreateTodoDB(ctx, Todo{Title: "Gửi webhook"}); err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	_, err = db.FanOutWebhookEventsDB(ctx, 100)
+	assert.NoError(t, err)
+
+	posted := 0
+	_, err = db.ProcessWebhookDeliveriesDB(ctx, 100, Backoff{Base: time.Second, Max: time.Second, MaxAttempts: 3}, func(job WebhookJob) (int, error) {
+		if job.URL != wh.URL {
+			return http.StatusOK, nil
+		}
+		posted++
+		// Không còn khóa hàng và replica khác không lấy lại lần gửi này.
+		err := db.Conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+			_, err := tx.Exec(ctx, "SELECT id FROM webhook_delivery WHERE id = $1 FOR UPDATE NOWAIT", job.DeliveryID)
+			return err
+		})
+		assert.NoError(t, err)
+		_, err = db.ProcessWebhookDeliveriesDB(ctx, 100, DefaultBackoff, func(other WebhookJob) (int, error) {
+			assert.NotEqual(t, job.DeliveryID, other.DeliveryID)
+			return http.StatusOK, nil
+		})
+		assert.NoError(t, err)
+		return http.StatusOK, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, posted)
+
+	deliveries, err := db.ListWebhookDeliveriesDB(ctx, wh.ID, "delivered", 10)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries, 1)
+}
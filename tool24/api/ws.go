@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Các loại tin nhắn trên /ws.
+const (
+	WSSubscribe   = "subscribe"
+	WSUnsubscribe = "unsubscribe"
+	WSCreate      = "create"
+	WSUpdate      = "update"
+	WSToggle      = "toggle"
+	WSMove        = "move"
+
+	WSAck   = "ack"
+	WSError = "error"
+	WSEvent = "event"
+)
+
+// WSRequest is a message sent by a client. ID is echoed back in the reply so
+// the client can match acknowledgements to requests.
+type WSRequest struct {
+	ID     string   `json:"id"`
+	Type   string   `json:"type"`
+	Lists  []string `json:"lists,omitempty"`
+	Todo   *Todo    `json:"todo,omitempty"`
+	TodoID string   `json:"todo_id,omitempty"`
+	// Version là revision client đã thấy; 0 nghĩa là bỏ qua kiểm tra xung đột.
+	Version int    `json:"version,omitempty"`
+	ListID  string `json:"list_id,omitempty"`
+}
+
+// WSResponse is a message sent by the server: an ack carrying the
+// authoritative todo, an error, or an event for a subscribed list.
+type WSResponse struct {
+	ID    string     `json:"id,omitempty"`
+	Type  string     `json:"type"`
+	Todo  *Todo      `json:"todo,omitempty"`
+	Lists []string   `json:"lists,omitempty"`
+	Event *TodoEvent `json:"event,omitempty"`
+	Code  string     `json:"code,omitempty"`
+	Error string     `json:"error,omitempty"`
+}
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+type WSHandler struct {
+	todoStore TodoStore
+	broker    *EventBroker
+	upgrader  websocket.Upgrader
+}
+
+func NewWSHandler(todoStore TodoStore, broker *EventBroker) *WSHandler {
+	return &WSHandler{todoStore: todoStore, broker: broker}
+}
+
+// wsSession là trạng thái của một kết nối: danh sách đang theo dõi và hàng đợi gửi.
+type wsSession struct {
+	mu    sync.Mutex
+	lists map[string]bool
+	send  chan WSResponse
+}
+
+func (s *wsSession) subscribed(listID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lists[listID]
+}
+
+func (s *wsSession) setLists(lists []string, on bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, list := range lists {
+		if on {
+			s.lists[list] = true
+		} else {
+			delete(s.lists, list)
+		}
+	}
+	current := make([]string, 0, len(s.lists))
+	for list := range s.lists {
+		current = append(current, list)
+	}
+	return current
+}
+
+// wants cho biết sự kiện có thuộc danh sách đang theo dõi không; todo vừa
+// chuyển đi thì danh sách cũ cũng nhận để bỏ nó ra.
+func (s *wsSession) wants(ev TodoEvent) bool {
+	if ev.PreviousListID != nil && s.subscribed(*ev.PreviousListID) {
+		return true
+	}
+	return s.subscribed(ev.Todo.ListID)
+}
+
+// @Summary Collaborative editing over WebSocket
+// @Description Subscribe to lists and send create, update, toggle and move mutations. Each mutation is answered with an ack carrying the authoritative todo, or an error with code conflict when its version is stale.
+// @Tags Todos
+// @Router /ws [get]
+func (h *WSHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade đã tự trả lỗi HTTP cho client.
+		return
+	}
+	defer conn.Close()
+
+	actor := ActorFromContext(r.Context())
+	session := &wsSession{lists: map[string]bool{}, send: make(chan WSResponse, 64)}
+
+	_, _, events, cancel := h.broker.Subscribe(0)
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+	go h.writeLoop(conn, session, events, done)
+
+	conn.SetReadLimit(64 * 1024)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var req WSRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("Kết nối WebSocket bị đóng: %v", err)
+			}
+			return
+		}
+
+		ctx, cancelReq := context.WithTimeout(WithActor(context.Background(), actor), 5*time.Second)
+		resp := h.handle(ctx, session, req)
+		cancelReq()
+
+		select {
+		case session.send <- resp:
+		case <-done:
+			return
+		}
+	}
+}
+
+// writeLoop là goroutine duy nhất ghi vào conn: trả lời, sự kiện và ping.
+func (h *WSHandler) writeLoop(conn *websocket.Conn, session *wsSession, events chan TodoEvent, done chan struct{}) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	write := func(resp WSResponse) bool {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(resp) == nil
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case resp := <-session.send:
+			if !write(resp) {
+				conn.Close()
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				// Bị broker loại vì quá chậm; client cần kết nối lại và tải lại danh sách.
+				conn.Close()
+				return
+			}
+			if !session.wants(ev) {
+				continue
+			}
+			if !write(WSResponse{Type: WSEvent, Event: &ev}) {
+				conn.Close()
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (h *WSHandler) handle(ctx context.Context, session *wsSession, req WSRequest) WSResponse {
+	switch req.Type {
+	case WSSubscribe, WSUnsubscribe:
+		lists := session.setLists(req.Lists, req.Type == WSSubscribe)
+		return WSResponse{ID: req.ID, Type: WSAck, Lists: lists}
+
+	case WSCreate:
+		if req.Todo == nil {
+			return wsError(req.ID, "invalid", "todo is required")
+		}
+		todo, err := h.todoStore.CreateTodoDB(ctx, *req.Todo)
+		return wsResult(req.ID, todo, err)
+
+	case WSUpdate:
+		if req.Todo == nil || req.TodoID == "" {
+			return wsError(req.ID, "invalid", "todo_id and todo are required")
+		}
+		todo := *req.Todo
+		if req.Version != 0 {
+			todo.Version = req.Version
+		}
+		updated, err := h.todoStore.UpdateTodoDB(ctx, req.TodoID, todo)
+		return wsResult(req.ID, updated, err)
+
+	case WSToggle:
+		if req.TodoID == "" {
+			return wsError(req.ID, "invalid", "todo_id is required")
+		}
+		// Toggle đi qua ChangeStatusDB như POST /todo/changeStatus/{id} để audit,
+		// SSE và webhook ghi đúng loại "status"; version được kiểm tra trong
+		// cùng transaction nên không cần đọc todo trước. Ack trả todo mà chính
+		// transaction đó ghi, không đọc lại để khỏi lẫn thay đổi chen vào sau.
+		opCtx, op := WithOperation(WithExpectedVersion(ctx, req.Version))
+		if err := h.todoStore.ChangeStatusDB(opCtx, req.TodoID); err != nil {
+			return wsResult(req.ID, Todo{}, err)
+		}
+		return wsResult(req.ID, *op.Todo, nil)
+
+	case WSMove:
+		if req.TodoID == "" {
+			return wsError(req.ID, "invalid", "todo_id is required")
+		}
+		// Move đi qua UpdateTodoDB để dùng chung kiểm tra version, validate và
+		// audit với PUT /todo/{id}. Todo được đọc ngoài transaction nên luôn ghim
+		// version vừa đọc: client không gửi version thì một thay đổi chen vào giữa
+		// sẽ thành xung đột thay vì bị ghi đè bằng bản cũ.
+		current, err := h.todoStore.GetTodoByIdDB(ctx, req.TodoID)
+		if err != nil {
+			return wsResult(req.ID, Todo{}, err)
+		}
+		current.ListID = req.ListID
+		if req.Version != 0 {
+			current.Version = req.Version
+		}
+		updated, err := h.todoStore.UpdateTodoDB(ctx, req.TodoID, current)
+		return wsResult(req.ID, updated, err)
+
+	default:
+		return wsError(req.ID, "invalid", "unknown message type "+req.Type)
+	}
+}
+
+func wsResult(id string, todo Todo, err error) WSResponse {
+	switch {
+	case err == nil:
+		return WSResponse{ID: id, Type: WSAck, Todo: &todo}
+	case errors.Is(err, ErrTodoNotFound):
+		return wsError(id, "not_found", err.Error())
+	case errors.Is(err, ErrVersionConflict):
+		return wsError(id, "conflict", err.Error())
+	case errors.Is(err, ErrInvalidRecurrence), errors.Is(err, ErrInvalidReminder), errors.Is(err, ErrInvalidPayment):
+		return wsError(id, "invalid", err.Error())
+	default:
+		return wsError(id, "internal", err.Error())
+	}
+}
+
+func wsError(id, code, message string) WSResponse {
+	return WSResponse{ID: id, Type: WSError, Code: code, Error: message}
+}
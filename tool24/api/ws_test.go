@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func dialWS(t *testing.T, store TodoStore, broker *EventBroker) (*websocket.Conn, func()) {
+	router := mux.NewRouter()
+	router.HandleFunc("/ws", NewWSHandler(store, broker).ServeWS).Methods("GET")
+	server := httptest.NewServer(router)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		server.Close()
+		t.Fatalf("dial: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return conn, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+func roundTrip(t *testing.T, conn *websocket.Conn, req WSRequest) WSResponse {
+	assert.NoError(t, conn.WriteJSON(req))
+	var resp WSResponse
+	assert.NoError(t, conn.ReadJSON(&resp))
+	return resp
+}
+
+func TestWS_CreateAck(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("CreateTodoDB", Todo{Title: "Buy milk", ListID: "home"}).
+		Return(Todo{ID: "1", Title: "Buy milk", ListID: "home", Version: 1}, nil)
+
+	conn, closeWS := dialWS(t, mockStore, NewEventBroker(10))
+	defer closeWS()
+
+	resp := roundTrip(t, conn, WSRequest{ID: "r1", Type: WSCreate, Todo: &Todo{Title: "Buy milk", ListID: "home"}})
+
+	assert.Equal(t, WSAck, resp.Type)
+	assert.Equal(t, "r1", resp.ID)
+	assert.Equal(t, "1", resp.Todo.ID)
+	assert.Equal(t, 1, resp.Todo.Version)
+}
+
+func TestWS_ToggleGoesThroughChangeStatus(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("ChangeStatusDB", "1").Return(nil).Twice()
+	// Một thay đổi khác đã chen vào sau khi toggle commit.
+	mockStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "B", Done: true, Version: 6}, nil).Maybe()
+	store := versionedStore{MockTodoStore: mockStore, version: 4, written: Todo{ID: "1", Title: "A", Done: true, Version: 5}}
+
+	conn, closeWS := dialWS(t, store, NewEventBroker(10))
+	defer closeWS()
+
+	// Ack là todo do chính toggle ghi, không phải bản đọc lại sau đó.
+	resp := roundTrip(t, conn, WSRequest{ID: "r1", Type: WSToggle, TodoID: "1", Version: 4})
+	assert.Equal(t, WSAck, resp.Type)
+	assert.True(t, resp.Todo.Done)
+	assert.Equal(t, "A", resp.Todo.Title)
+	assert.Equal(t, 5, resp.Todo.Version)
+
+	// Không gửi version thì đảo trạng thái hiện tại như POST /todo/changeStatus/{id}.
+	resp = roundTrip(t, conn, WSRequest{ID: "r2", Type: WSToggle, TodoID: "1"})
+	assert.Equal(t, WSAck, resp.Type)
+	mockStore.AssertNotCalled(t, "UpdateTodoDB", mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "GetTodoByIdDB", mock.Anything)
+	mockStore.AssertExpectations(t)
+}
+
+func TestWS_ToggleConflict(t *testing.T) {
+	mockStore := new(MockTodoStore)
+
+	conn, closeWS := dialWS(t, versionedStore{MockTodoStore: mockStore, version: 4}, NewEventBroker(10))
+	defer closeWS()
+
+	resp := roundTrip(t, conn, WSRequest{ID: "r2", Type: WSToggle, TodoID: "1", Version: 3})
+
+	assert.Equal(t, WSError, resp.Type)
+	assert.Equal(t, "conflict", resp.Code)
+	mockStore.AssertNotCalled(t, "ChangeStatusDB", mock.Anything)
+}
+
+func TestWS_MoveGoesThroughUpdate(t *testing.T) {
+	mockStore := new(MockTodoStore)
+	mockStore.On("GetTodoByIdDB", "1").Return(Todo{ID: "1", Title: "A", ListID: "home", Version: 2}, nil)
+	mockStore.On("UpdateTodoDB", "1", mock.MatchedBy(func(todo Todo) bool {
+		return todo.ListID == "work" && todo.Title == "A" && todo.Version == 2
+	})).Return(Todo{ID: "1", Title: "A", ListID: "work", Version: 3}, nil)
+
+	conn, closeWS := dialWS(t, mockStore, NewEventBroker(10))
+	defer closeWS()
+
+	resp := roundTrip(t, conn, WSRequest{ID: "r3", Type: WSMove, TodoID: "1", ListID: "work", Version: 2})
+
+	assert.Equal(t, WSAck, resp.Type)
+	assert.Equal(t, "work", resp.Todo.ListID)
+
+	// Không gửi version vẫn ghim version vừa đọc để UpdateTodoDB phát hiện thay
+	// đổi chen vào giữa lúc đọc và lúc ghi.
+	resp = roundTrip(t, conn, WSRequest{ID: "r4", Type: WSMove, TodoID: "1", ListID: "work"})
+	assert.Equal(t, WSAck, resp.Type)
+	mockStore.AssertNumberOfCalls(t, "UpdateTodoDB", 2)
+}
+
+func TestWS_EventsForSubscribedLists(t *testing.T) {
+	broker := NewEventBroker(10)
+	conn, closeWS := dialWS(t, new(MockTodoStore), broker)
+	defer closeWS()
+
+	resp := roundTrip(t, conn, WSRequest{ID: "s1", Type: WSSubscribe, Lists: []string{"work"}})
+	assert.Equal(t, WSAck, resp.Type)
+	assert.Equal(t, []string{"work"}, resp.Lists)
+
+	now := time.Now()
+	broker.Publish(TodoEvent{ID: 1, Type: StreamCreate, Todo: Todo{ID: "a", ListID: "home", CreatedAt: now}})
+	broker.Publish(TodoEvent{ID: 2, Type: StreamCreate, Todo: Todo{ID: "b", ListID: "work", CreatedAt: now}})
+
+	var ev WSResponse
+	assert.NoError(t, conn.ReadJSON(&ev))
+	assert.Equal(t, WSEvent, ev.Type)
+	assert.Equal(t, int64(2), ev.Event.ID, "events for unsubscribed lists are filtered out")
+}
+
+func TestWS_MoveReachesPreviousList(t *testing.T) {
+	broker := NewEventBroker(10)
+	conn, closeWS := dialWS(t, new(MockTodoStore), broker)
+	defer closeWS()
+
+	roundTrip(t, conn, WSRequest{ID: "s1", Type: WSSubscribe, Lists: []string{"home"}})
+
+	home := "home"
+	broker.Publish(TodoEvent{ID: 1, Type: StreamUpdate, Todo: Todo{ID: "a", ListID: "work", CreatedAt: time.Now()}, PreviousListID: &home})
+
+	var ev WSResponse
+	assert.NoError(t, conn.ReadJSON(&ev))
+	assert.Equal(t, int64(1), ev.Event.ID, "subscribers of the old list learn the todo moved away")
+	assert.Equal(t, "work", ev.Event.Todo.ListID)
+}
+
+func TestWS_PurgeOnlyReachesItsList(t *testing.T) {
+	broker := NewEventBroker(10)
+	conn, closeWS := dialWS(t, new(MockTodoStore), broker)
+	defer closeWS()
+
+	roundTrip(t, conn, WSRequest{ID: "s1", Type: WSSubscribe, Lists: []string{"work"}})
+
+	// Xóa vĩnh viễn không có ảnh chụp, chỉ có ID và list của todo.
+	broker.Publish(TodoEvent{ID: 1, Type: StreamDelete, Todo: Todo{ID: "a", ListID: "home"}})
+	broker.Publish(TodoEvent{ID: 2, Type: StreamDelete, Todo: Todo{ID: "b", ListID: "work"}})
+
+	var ev WSResponse
+	assert.NoError(t, conn.ReadJSON(&ev))
+	assert.Equal(t, int64(2), ev.Event.ID, "purges in unsubscribed lists are filtered out")
+}
+
+func TestWS_UnknownType(t *testing.T) {
+	conn, closeWS := dialWS(t, new(MockTodoStore), NewEventBroker(10))
+	defer closeWS()
+
+	resp := roundTrip(t, conn, WSRequest{ID: "x", Type: "archive"})
+
+	assert.Equal(t, WSError, resp.Type)
+	assert.Equal(t, "invalid", resp.Code)
+}
+
+// ChangeStatusDB kiểm tra version đặt bằng WithExpectedVersion và trả todo vừa
+// ghi qua Operation, như WSToggle dùng.
+func TestChangeStatusExpectedVersionDB(t *testing.T) {
+	db, err := NewDb()
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Conn.Close()
+
+	ctx := context.Background()
+	createdTodo, err := db.CreateTodoDB(ctx, Todo{Title: "Test Todo for Versioned Status"})
+	if err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+
+	err = db.ChangeStatusDB(WithExpectedVersion(ctx, createdTodo.Version+1), createdTodo.ID)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	opCtx, op := WithOperation(WithExpectedVersion(ctx, createdTodo.Version))
+	assert.NoError(t, db.ChangeStatusDB(opCtx, createdTodo.ID))
+
+	changed, err := db.GetTodoByIdDB(ctx, createdTodo.ID)
+	assert.NoError(t, err)
+	assert.True(t, changed.Done)
+	// Operation mang todo do chính transaction ghi, dùng cho ack của WSToggle.
+	assert.Equal(t, changed, *op.Todo)
+}